@@ -8,6 +8,7 @@ import (
 	rabbitmq "feedsystem_video_go/internal/middleware/rabbitmq"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
 	"feedsystem_video_go/internal/observability"
+	"feedsystem_video_go/internal/storage"
 	"log"
 	"os"
 	"strconv"
@@ -89,8 +90,14 @@ func main() {
 		defer pprofServer.Close()
 	}
 
+	// 对象存储
+	store, err := storage.NewFromConfig(&cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to init storage: %v", err)
+	}
+
 	// 设置路由
-	r := apphttp.SetRouter(sqlDB, cache, rmq)
+	r := apphttp.SetRouter(sqlDB, cache, rmq, store, cfg)
 	log.Printf("Server is running on port %d", cfg.Server.Port)
 	if err := r.Run(":" + strconv.Itoa(cfg.Server.Port)); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
@@ -3,11 +3,15 @@ package main
 import (
 	"context"
 	"feedsystem_video_go/internal/config"
+	"feedsystem_video_go/internal/creator"
 	"feedsystem_video_go/internal/db"
+	"feedsystem_video_go/internal/feed"
 	mqrabbit "feedsystem_video_go/internal/middleware/rabbitmq"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
 	"feedsystem_video_go/internal/observability"
 	"feedsystem_video_go/internal/social"
+	"feedsystem_video_go/internal/storage"
+	"feedsystem_video_go/internal/transcode"
 	"feedsystem_video_go/internal/video"
 	"feedsystem_video_go/internal/worker"
 	"log"
@@ -37,6 +41,20 @@ const (
 	popularityExchange   = "video.popularity.events"
 	popularityQueue      = "video.popularity.events"
 	popularityBindingKey = "video.popularity.*"
+
+	fanoutExchange   = "video.timeline.events"
+	fanoutQueue      = "video.timeline.fanout.queue"
+	fanoutBindingKey = "video.timeline.publish"
+	// 已发布视频改为公开或粉丝可见时补写收件箱
+	fanoutVisibilityBindingKey = "video.timeline.visibility"
+
+	playExchange   = "video.play.events"
+	playQueue      = "video.play.events"
+	playBindingKey = "video.play.*"
+
+	transcodeExchange   = "video.transcode.events"
+	transcodeQueue      = "video.transcode.events"
+	transcodeBindingKey = "video.transcode.*"
 )
 
 func connectWithRetry(name string, maxRetries int, fn func() error) {
@@ -121,25 +139,62 @@ func main() {
 	if err := declareCommentTopology(ch); err != nil {
 		log.Fatalf("Failed to declare comment topology: %v", err)
 	}
+	if err := declarePlayTopology(ch); err != nil {
+		log.Fatalf("Failed to declare play topology: %v", err)
+	}
 	if cache != nil {
 		if err := declarePopularityTopology(ch); err != nil {
 			log.Fatalf("Failed to declare popularity topology: %v", err)
 		}
+		if err := declareFanoutTopology(ch); err != nil {
+			log.Fatalf("Failed to declare fanout topology: %v", err)
+		}
+	}
+	var transcoder transcode.Transcoder
+	if cfg.Transcode.Enabled {
+		ff, err := transcode.NewFFmpegTranscoder(cfg.Transcode.FFmpegPath)
+		if err != nil {
+			log.Fatalf("Transcode enabled but ffmpeg unavailable: %v", err)
+		}
+		transcoder = ff
+		if err := declareTranscodeTopology(ch); err != nil {
+			log.Fatalf("Failed to declare transcode topology: %v", err)
+		}
 	}
 	if err := ch.Qos(50, 0, false); err != nil {
 		log.Fatalf("Failed to set qos: %v", err)
 	}
+	// PlayWorker 按 delivery tag 批量 ack（multiple=true），必须独占通道，否则会顺带确认其他 worker 的消息
+	playCh, err := conn.Channel()
+	if err != nil {
+		log.Fatalf("Failed to open play channel: %v", err)
+	}
+	defer playCh.Close()
+	if err := playCh.Qos(worker.PlayPrefetch, 0, false); err != nil {
+		log.Fatalf("Failed to set play qos: %v", err)
+	}
 
 	repo := social.NewSocialRepository(sqlDB)
-	socialWorker := worker.NewSocialWorker(ch, repo, socialQueue)
+	inbox := feed.NewInbox(cache)
+	statRepo := creator.NewStatRepository(sqlDB)
+	socialWorker := worker.NewSocialWorker(ch, inbox, statRepo, worker.NewDBLedger(sqlDB, "social"), socialQueue)
 	videoRepo := video.NewVideoRepository(sqlDB)
-	likeRepo := video.NewLikeRepository(sqlDB)
-	commentRepo := video.NewCommentRepository(sqlDB)
-	likeWorker := worker.NewLikeWorker(ch, likeRepo, videoRepo, likeQueue)
-	commentWorker := worker.NewCommentWorker(ch, commentRepo, videoRepo, commentQueue)
+	likeWorker := worker.NewLikeWorker(ch, videoRepo, statRepo, worker.NewDBLedger(sqlDB, "like"), likeQueue)
+	commentWorker := worker.NewCommentWorker(ch, videoRepo, statRepo, worker.NewDBLedger(sqlDB, "comment"), commentQueue)
+	playWorker := worker.NewPlayWorker(playCh, video.NewPlayRecorder(video.NewPlayRepository(sqlDB), cache), playQueue)
+	var transcodeWorker *worker.TranscodeWorker
+	if transcoder != nil {
+		store, err := storage.NewFromConfig(&cfg.Storage)
+		if err != nil {
+			log.Fatalf("Failed to init storage: %v", err)
+		}
+		transcodeWorker = worker.NewTranscodeWorker(ch, videoRepo, cache, transcoder, store, transcodeQueue)
+	}
 	var popularityWorker *worker.PopularityWorker
+	var fanoutWorker *worker.FanoutWorker
 	if cache != nil {
 		popularityWorker = worker.NewPopularityWorker(ch, cache, popularityQueue)
+		fanoutWorker = worker.NewFanoutWorker(ch, videoRepo, repo, inbox, fanoutQueue)
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -157,17 +212,29 @@ func main() {
 		defer pprofServer.Close()
 	}
 
-	errCh := make(chan error, 4)
+	go worker.RunLedgerPruner(ctx, sqlDB)
+
+	errCh := make(chan error, 7)
 	log.Printf("Worker started, consuming queue=%s", socialQueue)
 	go func() { errCh <- socialWorker.Run(ctx) }()
 	log.Printf("Worker started, consuming queue=%s", likeQueue)
 	go func() { errCh <- likeWorker.Run(ctx) }()
 	log.Printf("Worker started, consuming queue=%s", commentQueue)
 	go func() { errCh <- commentWorker.Run(ctx) }()
+	log.Printf("Worker started, consuming queue=%s", playQueue)
+	go func() { errCh <- playWorker.Run(ctx) }()
 	if popularityWorker != nil {
 		log.Printf("Worker started, consuming queue=%s", popularityQueue)
 		go func() { errCh <- popularityWorker.Run(ctx) }()
 	}
+	if fanoutWorker != nil {
+		log.Printf("Worker started, consuming queue=%s", fanoutQueue)
+		go func() { errCh <- fanoutWorker.Run(ctx) }()
+	}
+	if transcodeWorker != nil {
+		log.Printf("Worker started, consuming queue=%s", transcodeQueue)
+		go func() { errCh <- transcodeWorker.Run(ctx) }()
+	}
 
 	err = <-errCh
 	if err != nil && err != context.Canceled {
@@ -314,3 +381,110 @@ func declareCommentTopology(ch *amqp.Channel) error {
 		nil,
 	)
 }
+
+func declarePlayTopology(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(
+		playExchange,
+		"topic",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return err
+	}
+
+	q, err := ch.QueueDeclare(
+		playQueue,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{"x-dead-letter-exchange": mqrabbit.DLXExchange},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		q.Name,
+		playBindingKey,
+		playExchange,
+		false,
+		nil,
+	)
+}
+
+func declareFanoutTopology(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(
+		fanoutExchange,
+		"topic",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return err
+	}
+
+	q, err := ch.QueueDeclare(
+		fanoutQueue,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{"x-dead-letter-exchange": mqrabbit.DLXExchange},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{fanoutBindingKey, fanoutVisibilityBindingKey} {
+		if err := ch.QueueBind(
+			q.Name,
+			key,
+			fanoutExchange,
+			false,
+			nil,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func declareTranscodeTopology(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(
+		transcodeExchange,
+		"topic",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return err
+	}
+
+	q, err := ch.QueueDeclare(
+		transcodeQueue,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{"x-dead-letter-exchange": mqrabbit.DLXExchange},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		q.Name,
+		transcodeBindingKey,
+		transcodeExchange,
+		false,
+		nil,
+	)
+}
@@ -6,13 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,10 +20,11 @@ import (
 
 type AccountHandler struct {
 	accountService *AccountService
+	store          storage.Store
 }
 
-func NewAccountHandler(accountService *AccountService) *AccountHandler {
-	return &AccountHandler{accountService: accountService}
+func NewAccountHandler(accountService *AccountService, store storage.Store) *AccountHandler {
+	return &AccountHandler{accountService: accountService, store: store}
 }
 func (h *AccountHandler) CreateAccount(c *gin.Context) {
 	var req CreateAccountRequest
@@ -168,24 +169,23 @@ func (h *AccountHandler) UploadAvatar(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "only .jpg/.jpeg/.png/.webp allowed"})
 		return
 	}
-	dir := filepath.Join(".run", "uploads", "avatars", strconv.FormatUint(uint64(accountID), 10))
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	filename, err := randHex(16)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	filename, err := randHex(16)
+	key := path.Join("avatars", strconv.FormatUint(uint64(accountID), 10), filename+ext)
+	src, err := f.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	filename = filename + ext
-	absPath := filepath.Join(dir, filename)
-	if err := c.SaveUploadedFile(f, absPath); err != nil {
+	defer src.Close()
+	if err := h.store.Put(c.Request.Context(), key, src, f.Size, storage.ContentTypeByKey(key)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	urlPath := path.Join("/static", "avatars", strconv.FormatUint(uint64(accountID), 10), filename)
-	avatarURL := buildAbsoluteURL(c, urlPath)
+	avatarURL := h.store.URL(key)
 	if err := h.accountService.UpdateAvatar(c.Request.Context(), accountID, avatarURL); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -233,17 +233,6 @@ func randHex(n int) (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-func buildAbsoluteURL(c *gin.Context, p string) string {
-	scheme := "http"
-	if c.Request.TLS != nil {
-		scheme = "https"
-	}
-	if xf := c.GetHeader("X-Forwarded-Proto"); xf != "" {
-		scheme = xf
-	}
-	return fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, p)
-}
-
 func getAccountID(c *gin.Context) (uint, error) {
 	value, exists := c.Get("accountID")
 	if !exists {
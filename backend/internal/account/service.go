@@ -19,6 +19,12 @@ import (
 type AccountService struct {
 	accountRepository *AccountRepository
 	cache             *rediscache.Client
+	profileSync       ProfileSync
+}
+
+// ProfileSync 账号的用户名、简介、头像变化时同步用户搜索索引，由 search.UserIndexer 实现
+type ProfileSync interface {
+	AccountChanged(ctx context.Context, accountID uint)
 }
 
 var (
@@ -30,6 +36,16 @@ func NewAccountService(accountRepository *AccountRepository, cache *rediscache.C
 	return &AccountService{accountRepository: accountRepository, cache: cache}
 }
 
+func (as *AccountService) SetProfileSync(sync ProfileSync) {
+	as.profileSync = sync
+}
+
+func (as *AccountService) syncProfile(ctx context.Context, accountID uint) {
+	if as.profileSync != nil {
+		as.profileSync.AccountChanged(ctx, accountID)
+	}
+}
+
 func (as *AccountService) CreateAccount(ctx context.Context, account *Account) error {
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(account.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -39,6 +55,7 @@ func (as *AccountService) CreateAccount(ctx context.Context, account *Account) e
 	if err := as.accountRepository.CreateAccount(ctx, account); err != nil {
 		return err
 	}
+	as.syncProfile(ctx, account.ID)
 	return nil
 }
 
@@ -62,6 +79,7 @@ func (as *AccountService) Rename(ctx context.Context, accountID uint, newUsernam
 		}
 		return "", err
 	}
+	as.syncProfile(ctx, accountID)
 	if as.cache != nil {
 		cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
 		defer cancel()
@@ -174,7 +192,11 @@ func (as *AccountService) Logout(ctx context.Context, accountID uint) error {
 }
 
 func (as *AccountService) UpdateAvatar(ctx context.Context, accountID uint, avatarURL string) error {
-	return as.accountRepository.UpdateAvatar(ctx, accountID, avatarURL)
+	if err := as.accountRepository.UpdateAvatar(ctx, accountID, avatarURL); err != nil {
+		return err
+	}
+	as.syncProfile(ctx, accountID)
+	return nil
 }
 
 func (as *AccountService) FindAll(ctx context.Context) ([]*Account, error) {
@@ -192,7 +214,11 @@ func (as *AccountService) UpdateProfile(ctx context.Context, accountID uint, req
 	if len(updates) == 0 {
 		return errors.New("nothing to update")
 	}
-	return as.accountRepository.UpdateFields(ctx, accountID, updates)
+	if err := as.accountRepository.UpdateFields(ctx, accountID, updates); err != nil {
+		return err
+	}
+	as.syncProfile(ctx, accountID)
+	return nil
 }
 
 func (as *AccountService) RefreshAccessToken(ctx context.Context, refreshToken string) (string, uint, string, error) {
@@ -59,6 +59,50 @@ func GenerateRefreshToken(accountID uint) (string, error) {
 }
 
 func ParseToken(tokenString string) (*Claims, error) {
+	claims, err := parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// WebSocket 票据不能当 access token 用
+	if len(claims.Audience) > 0 {
+		return nil, jwt.ErrTokenInvalidAudience
+	}
+	return claims, nil
+}
+
+// WSTicketTTL WebSocket 票据只用于建立连接，拿到后立即使用
+const WSTicketTTL = 30 * time.Second
+
+const wsTicketAudience = "ws"
+
+// GenerateWSTicket 浏览器 WebSocket 不能带 header，凭证只能放在 URL 上；
+// 用短时效的一次性票据代替 access token，即使出现在日志里也很快失效
+func GenerateWSTicket(accountID uint, username string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := Claims{
+		AccountID: accountID,
+		Username:  username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(b),
+			Audience:  jwt.ClaimStrings{wsTicketAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(WSTicketTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret())
+}
+
+// ParseWSTicket 只接受 GenerateWSTicket 签发的票据；是否已被使用由调用方用 ID 判断
+func ParseWSTicket(ticket string) (*Claims, error) {
+	return parse(ticket, jwt.WithAudience(wsTicketAudience))
+}
+
+func parse(tokenString string, opts ...jwt.ParserOption) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
@@ -68,6 +112,7 @@ func ParseToken(tokenString string) (*Claims, error) {
 			}
 			return jwtSecret(), nil
 		},
+		opts...,
 	)
 	if err != nil {
 		return nil, err
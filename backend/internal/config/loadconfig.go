@@ -15,10 +15,15 @@ type Config struct {
 	Redis               RedisConfig         `yaml:"redis"`
 	RabbitMQ            RabbitMQConfig      `yaml:"rabbitmq"`
 	ObservabilityConfig ObservabilityConfig `yaml:"observability"`
+	Transcode           TranscodeConfig     `yaml:"transcode"`
+	Storage             StorageConfig       `yaml:"storage"`
+	Notification        NotificationConfig  `yaml:"notification"`
 }
 
+// ServerConfig allowed_origins 为同源之外允许建立 WebSocket 的页面来源，开发环境的 Vite 地址需要加在这里
 type ServerConfig struct {
-	Port int `yaml:"port"`
+	Port           int      `yaml:"port"`
+	AllowedOrigins []string `yaml:"allowed_origins"`
 }
 
 type DatabaseConfig struct {
@@ -43,6 +48,29 @@ type RabbitMQConfig struct {
 	Password string `yaml:"password"`
 }
 
+// TranscodeConfig API 与 worker 需保持一致：API 据此决定新视频是否先进入 pending
+type TranscodeConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	FFmpegPath string `yaml:"ffmpeg_path"`
+}
+
+// StorageConfig driver 为 local 时使用 root；为 s3 时使用 endpoint/bucket/密钥。base_url 为对外访问前缀
+type StorageConfig struct {
+	Driver    string `yaml:"driver"`
+	Root      string `yaml:"root"`
+	BaseURL   string `yaml:"base_url"`
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+// NotificationConfig bus 为 redis 时多副本之间互相投递 SSE 通知和私信推送；memory 只适合单实例
+type NotificationConfig struct {
+	Bus string `yaml:"bus"`
+}
+
 type ObservabilityConfig struct {
 	Pprof PprofConfig `yaml:"pprof"`
 }
@@ -126,6 +154,32 @@ func ApplyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("RABBITMQ_PASS"); v != "" {
 		cfg.RabbitMQ.Password = v
 	}
+	if v := os.Getenv("TRANSCODE_ENABLED"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			cfg.Transcode.Enabled = enabled
+		}
+	}
+	if v := os.Getenv("FFMPEG_PATH"); v != "" {
+		cfg.Transcode.FFmpegPath = v
+	}
+	if v := os.Getenv("STORAGE_DRIVER"); v != "" {
+		cfg.Storage.Driver = v
+	}
+	if v := os.Getenv("STORAGE_ENDPOINT"); v != "" {
+		cfg.Storage.Endpoint = v
+	}
+	if v := os.Getenv("STORAGE_BUCKET"); v != "" {
+		cfg.Storage.Bucket = v
+	}
+	if v := os.Getenv("STORAGE_ACCESS_KEY"); v != "" {
+		cfg.Storage.AccessKey = v
+	}
+	if v := os.Getenv("STORAGE_SECRET_KEY"); v != "" {
+		cfg.Storage.SecretKey = v
+	}
+	if v := os.Getenv("NOTIFICATION_BUS"); v != "" {
+		cfg.Notification.Bus = v
+	}
 }
 
 // bool用来表示是否使用了默认配置，true表示使用了默认配置
@@ -143,7 +197,8 @@ func LoadLocalDev(filename string) (Config, bool, error) {
 func DefaultLocalConfig() Config {
 	cfg := Config{
 		Server: ServerConfig{
-			Port: 8080,
+			Port:           8080,
+			AllowedOrigins: []string{"http://localhost:5173", "http://127.0.0.1:5173"},
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -171,6 +226,18 @@ func DefaultLocalConfig() Config {
 				WorkerAddr: "localhost:6061",
 			},
 		},
+		Transcode: TranscodeConfig{
+			Enabled:    false,
+			FFmpegPath: "ffmpeg",
+		},
+		Storage: StorageConfig{
+			Driver:  "local",
+			Root:    ".run/uploads",
+			BaseURL: "/static",
+		},
+		Notification: NotificationConfig{
+			Bus: "redis",
+		},
 	}
 	ApplyEnvOverrides(&cfg)
 	return cfg
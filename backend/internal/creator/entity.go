@@ -0,0 +1,67 @@
+package creator
+
+import "time"
+
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+
+	// 单次查询允许的最大桶数，按小时约 31 天，按天约 1 年
+	maxHourBuckets = 24 * 31
+	maxDayBuckets  = 366
+)
+
+// StatRollup 创作者指标按小时/按天的汇总，由点赞、评论、关注 worker 增量写入。
+// VideoID 为 0 的行是账号维度（所有视频之和加上新增粉丝），非 0 的行是单个视频维度
+type StatRollup struct {
+	ID              uint      `gorm:"primaryKey" json:"-"`
+	AuthorID        uint      `gorm:"not null;uniqueIndex:idx_creator_stat_bucket,priority:1" json:"author_id"`
+	VideoID         uint      `gorm:"not null;default:0;uniqueIndex:idx_creator_stat_bucket,priority:2" json:"video_id"`
+	Granularity     string    `gorm:"type:varchar(8);not null;uniqueIndex:idx_creator_stat_bucket,priority:3" json:"granularity"`
+	BucketStart     time.Time `gorm:"not null;uniqueIndex:idx_creator_stat_bucket,priority:4" json:"bucket_start"`
+	Likes           int64     `gorm:"not null;default:0" json:"likes"`
+	Comments        int64     `gorm:"not null;default:0" json:"comments"`
+	NewFollowers    int64     `gorm:"not null;default:0" json:"new_followers"`
+	PopularityDelta int64     `gorm:"not null;default:0" json:"popularity_delta"`
+}
+
+// StatDelta 一次事件带来的增量；点赞和关注取净值，取消时为负
+type StatDelta struct {
+	Likes           int64
+	Comments        int64
+	NewFollowers    int64
+	PopularityDelta int64
+}
+
+func (d StatDelta) IsZero() bool {
+	return d == StatDelta{}
+}
+
+type GetStatsRequest struct {
+	VideoID     uint   `json:"video_id"`    // 0 表示账号维度
+	Granularity string `json:"granularity"` // hour / day，默认 day
+	From        string `json:"from"`        // YYYY-MM-DD，含
+	To          string `json:"to"`          // YYYY-MM-DD，含
+}
+
+type StatPoint struct {
+	BucketStart     int64 `json:"bucket_start"` // unix 秒
+	Likes           int64 `json:"likes"`
+	Comments        int64 `json:"comments"`
+	NewFollowers    int64 `json:"new_followers"`
+	PopularityDelta int64 `json:"popularity_delta"`
+}
+
+type StatTotals struct {
+	Likes           int64 `json:"likes"`
+	Comments        int64 `json:"comments"`
+	NewFollowers    int64 `json:"new_followers"`
+	PopularityDelta int64 `json:"popularity_delta"`
+}
+
+type GetStatsResponse struct {
+	VideoID     uint        `json:"video_id"`
+	Granularity string      `json:"granularity"`
+	Points      []StatPoint `json:"points"`
+	Totals      StatTotals  `json:"totals"`
+}
@@ -0,0 +1,37 @@
+package creator
+
+import (
+	"net/http"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatHandler struct {
+	service *StatService
+}
+
+func NewStatHandler(service *StatService) *StatHandler {
+	return &StatHandler{service: service}
+}
+
+func (h *StatHandler) GetStats(c *gin.Context) {
+	var req GetStatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.service.GetStats(c.Request.Context(), authorID, req)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
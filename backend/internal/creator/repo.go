@@ -0,0 +1,87 @@
+package creator
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatRepository struct {
+	db *gorm.DB
+}
+
+func NewStatRepository(db *gorm.DB) *StatRepository {
+	return &StatRepository{db: db}
+}
+
+// WithTx 返回绑定到调用方事务的仓库，统计和消费台账一起提交
+func (r *StatRepository) WithTx(tx *gorm.DB) *StatRepository {
+	if r == nil {
+		return nil
+	}
+	return &StatRepository{db: tx}
+}
+
+// bucketStart 按服务器本地时区对齐到小时或自然日
+func bucketStart(t time.Time, granularity string) time.Time {
+	t = t.In(time.Local)
+	if granularity == GranularityHour {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// Record 把一次事件的增量同时累加到小时桶和天桶；videoID 非 0 时视频维度与账号维度各写一份。
+// 为 nil 时直接忽略，方便未接入统计的 worker 复用
+func (r *StatRepository) Record(ctx context.Context, authorID, videoID uint, at time.Time, d StatDelta) error {
+	if r == nil || r.db == nil || authorID == 0 || d.IsZero() {
+		return nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	videoIDs := []uint{0}
+	if videoID != 0 {
+		videoIDs = append(videoIDs, videoID)
+	}
+	rows := make([]StatRollup, 0, 2*len(videoIDs))
+	for _, g := range []string{GranularityHour, GranularityDay} {
+		start := bucketStart(at, g)
+		for _, vid := range videoIDs {
+			rows = append(rows, StatRollup{
+				AuthorID:        authorID,
+				VideoID:         vid,
+				Granularity:     g,
+				BucketStart:     start,
+				Likes:           d.Likes,
+				Comments:        d.Comments,
+				NewFollowers:    d.NewFollowers,
+				PopularityDelta: d.PopularityDelta,
+			})
+		}
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "author_id"}, {Name: "video_id"}, {Name: "granularity"}, {Name: "bucket_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"likes":            gorm.Expr("likes + ?", d.Likes),
+			"comments":         gorm.Expr("comments + ?", d.Comments),
+			"new_followers":    gorm.Expr("new_followers + ?", d.NewFollowers),
+			"popularity_delta": gorm.Expr("popularity_delta + ?", d.PopularityDelta),
+		}),
+	}).Create(&rows).Error
+}
+
+// ListRange 返回 [from, to) 内已有的桶，按时间升序；没有事件的桶不落库，由调用方补零
+func (r *StatRepository) ListRange(ctx context.Context, authorID, videoID uint, granularity string, from, to time.Time) ([]StatRollup, error) {
+	var rows []StatRollup
+	if err := r.db.WithContext(ctx).
+		Where("author_id = ? AND video_id = ? AND granularity = ?", authorID, videoID, granularity).
+		Where("bucket_start >= ? AND bucket_start < ?", from, to).
+		Order("bucket_start ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,133 @@
+package creator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/video"
+
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+type StatService struct {
+	repo      *StatRepository
+	videoRepo *video.VideoRepository
+	now       func() time.Time
+}
+
+func NewStatService(repo *StatRepository, videoRepo *video.VideoRepository) *StatService {
+	return &StatService{repo: repo, videoRepo: videoRepo, now: time.Now}
+}
+
+func nextBucket(t time.Time, granularity string) time.Time {
+	if granularity == GranularityHour {
+		return bucketStart(t.Add(time.Hour), GranularityHour)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// parseRange 把按天的闭区间转换成 [from, to)；缺省时按天取最近 7 天，按小时取当天
+func parseRange(req GetStatsRequest, granularity string, now time.Time) (time.Time, time.Time, error) {
+	today := bucketStart(now, GranularityDay)
+	to := today
+	if req.To != "" {
+		t, err := time.ParseInLocation(dateLayout, req.To, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", apierror.ErrValidation)
+		}
+		to = t
+	}
+	from := to
+	if granularity == GranularityDay {
+		from = to.AddDate(0, 0, -6)
+	}
+	if req.From != "" {
+		t, err := time.ParseInLocation(dateLayout, req.From, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", apierror.ErrValidation)
+		}
+		from = t
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must not be after to", apierror.ErrValidation)
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// fillSeries 按桶补零，保证返回的序列连续
+func fillSeries(rows []StatRollup, granularity string, from, to time.Time) ([]StatPoint, StatTotals, error) {
+	limit := maxDayBuckets
+	if granularity == GranularityHour {
+		limit = maxHourBuckets
+	}
+	byStart := make(map[int64]*StatRollup, len(rows))
+	for i := range rows {
+		byStart[rows[i].BucketStart.Unix()] = &rows[i]
+	}
+	var (
+		points []StatPoint
+		totals StatTotals
+	)
+	for t := from; t.Before(to); t = nextBucket(t, granularity) {
+		if len(points) >= limit {
+			return nil, StatTotals{}, fmt.Errorf("%w: range exceeds %d %s buckets", apierror.ErrValidation, limit, granularity)
+		}
+		p := StatPoint{BucketStart: t.Unix()}
+		if row := byStart[p.BucketStart]; row != nil {
+			p.Likes = row.Likes
+			p.Comments = row.Comments
+			p.NewFollowers = row.NewFollowers
+			p.PopularityDelta = row.PopularityDelta
+		}
+		totals.Likes += p.Likes
+		totals.Comments += p.Comments
+		totals.NewFollowers += p.NewFollowers
+		totals.PopularityDelta += p.PopularityDelta
+		points = append(points, p)
+	}
+	return points, totals, nil
+}
+
+// GetStats 查询当前创作者账号或其某个视频在日期范围内的指标序列
+func (s *StatService) GetStats(ctx context.Context, authorID uint, req GetStatsRequest) (GetStatsResponse, error) {
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	if granularity != GranularityHour && granularity != GranularityDay {
+		return GetStatsResponse{}, fmt.Errorf("%w: granularity must be hour or day", apierror.ErrValidation)
+	}
+	from, to, err := parseRange(req, granularity, s.now())
+	if err != nil {
+		return GetStatsResponse{}, err
+	}
+	// 先校验区间长度，避免超大范围打到数据库
+	if _, _, err := fillSeries(nil, granularity, from, to); err != nil {
+		return GetStatsResponse{}, err
+	}
+
+	if req.VideoID != 0 {
+		v, err := s.videoRepo.GetByID(ctx, req.VideoID)
+		if err != nil {
+			return GetStatsResponse{}, err
+		}
+		// 别人的视频按不存在处理，不暴露视频是否存在
+		if v.AuthorID != authorID {
+			return GetStatsResponse{}, gorm.ErrRecordNotFound
+		}
+	}
+
+	rows, err := s.repo.ListRange(ctx, authorID, req.VideoID, granularity, from, to)
+	if err != nil {
+		return GetStatsResponse{}, err
+	}
+	points, totals, err := fillSeries(rows, granularity, from, to)
+	if err != nil {
+		return GetStatsResponse{}, err
+	}
+	return GetStatsResponse{VideoID: req.VideoID, Granularity: granularity, Points: points, Totals: totals}, nil
+}
@@ -0,0 +1,79 @@
+package creator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+)
+
+func TestBucketStart(t *testing.T) {
+	at := time.Date(2024, 3, 5, 14, 37, 12, 0, time.Local)
+	if got := bucketStart(at, GranularityHour); !got.Equal(time.Date(2024, 3, 5, 14, 0, 0, 0, time.Local)) {
+		t.Fatalf("hour bucket = %v", got)
+	}
+	if got := bucketStart(at, GranularityDay); !got.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("day bucket = %v", got)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.Local)
+
+	from, to, err := parseRange(GetStatsRequest{}, GranularityDay, now)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	if !from.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)) || !to.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("default day range = [%v, %v)", from, to)
+	}
+
+	from, to, err = parseRange(GetStatsRequest{From: "2024-03-01", To: "2024-03-02"}, GranularityHour, now)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	if !from.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)) || !to.Equal(time.Date(2024, 3, 3, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("explicit range = [%v, %v)", from, to)
+	}
+
+	for _, req := range []GetStatsRequest{{From: "2024/03/01"}, {From: "2024-03-05", To: "2024-03-01"}} {
+		if _, _, err := parseRange(req, GranularityDay, now); !errors.Is(err, apierror.ErrValidation) {
+			t.Fatalf("parseRange(%+v) err = %v, want validation error", req, err)
+		}
+	}
+}
+
+func TestFillSeries(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 3)
+	rows := []StatRollup{
+		{BucketStart: from.AddDate(0, 0, 1), Likes: 3, Comments: 1, PopularityDelta: 4},
+		{BucketStart: from.AddDate(0, 0, 2), Likes: -1, NewFollowers: 2, PopularityDelta: -1},
+	}
+	points, totals, err := fillSeries(rows, GranularityDay, from, to)
+	if err != nil {
+		t.Fatalf("fillSeries: %v", err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("len(points) = %d, want 3", len(points))
+	}
+	if points[0] != (StatPoint{BucketStart: from.Unix()}) {
+		t.Fatalf("empty bucket not zero-filled: %+v", points[0])
+	}
+	if points[1].Likes != 3 || points[2].NewFollowers != 2 {
+		t.Fatalf("unexpected points: %+v", points)
+	}
+	want := StatTotals{Likes: 2, Comments: 1, NewFollowers: 2, PopularityDelta: 3}
+	if totals != want {
+		t.Fatalf("totals = %+v, want %+v", totals, want)
+	}
+
+	hours, _, err := fillSeries(nil, GranularityHour, from, from.AddDate(0, 0, 1))
+	if err != nil || len(hours) != 24 {
+		t.Fatalf("hour series len = %d, err = %v", len(hours), err)
+	}
+	if _, _, err := fillSeries(nil, GranularityHour, from, from.AddDate(0, 2, 0)); !errors.Is(err, apierror.ErrValidation) {
+		t.Fatalf("oversized hour range err = %v, want validation error", err)
+	}
+}
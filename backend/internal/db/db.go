@@ -1,9 +1,12 @@
 package db
 
 import (
+	"context"
 	"feedsystem_video_go/internal/account"
 	"feedsystem_video_go/internal/config"
+	"feedsystem_video_go/internal/creator"
 	"feedsystem_video_go/internal/message"
+	"feedsystem_video_go/internal/outbox"
 	"feedsystem_video_go/internal/social"
 	"feedsystem_video_go/internal/video"
 	"feedsystem_video_go/internal/worker"
@@ -26,11 +29,19 @@ func NewDB(dbcfg config.DatabaseConfig) (*gorm.DB, error) {
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&account.Account{}, &video.Video{}, &video.Like{}, &video.Comment{},
-		&social.Social{}, &video.OutboxMsg{}, &video.Tag{}, &video.VideoTag{},
-		&message.Message{}, &worker.Notification{},
-	)
+	if err := db.AutoMigrate(
+		&account.Account{}, &video.Video{}, &video.Like{}, &video.Comment{}, &video.CommentLike{},
+		&social.Social{}, &outbox.Event{}, &video.Tag{}, &video.VideoTag{}, &video.VideoPlayStat{},
+		&worker.Notification{},
+		&worker.NotificationPreference{}, &worker.NotificationMute{}, &creator.StatRollup{},
+		&video.TagFollow{}, &video.VideoEdit{}, &worker.ProcessedEvent{},
+	); err != nil {
+		return err
+	}
+	if err := message.NewRepository(db).AutoMigrate(context.Background()); err != nil {
+		return err
+	}
+	return worker.BackfillNotificationTimes(context.Background(), db)
 }
 
 func CloseDB(db *gorm.DB) error {
@@ -0,0 +1,58 @@
+//go:build cgo
+
+package dbtest
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const driverName = "sqlite3_dbtest"
+
+var registerOnce sync.Once
+
+// register 补上仓库 SQL 里用到的 MySQL 函数
+func register() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("greatest", func(args ...int64) int64 {
+				m := int64(math.MinInt64)
+				for _, a := range args {
+					if a > m {
+						m = a
+					}
+				}
+				return m
+			}, true)
+		},
+	})
+}
+
+// Open 每个测试一个临时 SQLite 文件并迁移 models。SQLite 不支持行锁，FOR UPDATE 子句会被忽略，
+// 依赖行锁的逻辑用 OpenMySQL 测
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	registerOnce.Do(register)
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: driverName, DSN: dsn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,58 @@
+// Package dbtest 给仓库层测试提供临时数据库，只应在 _test.go 中引用。
+// Open 用 SQLite，需要 cgo；OpenMySQL 连真实 MySQL，用于行锁等 SQLite 模拟不了的场景
+package dbtest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// MySQLDSNEnv 指向可建库的 MySQL 账号，如 root:123456@tcp(127.0.0.1:3307)/?parseTime=true&loc=Local
+const MySQLDSNEnv = "DBTEST_MYSQL_DSN"
+
+// OpenMySQL 每个测试建一个临时库并迁移 models，结束时删库；未设置 DBTEST_MYSQL_DSN 时跳过
+func OpenMySQL(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(MySQLDSNEnv)
+	if dsn == "" {
+		t.Skipf("dbtest: set %s to run MySQL tests", MySQLDSNEnv)
+	}
+	cfg, err := driver.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("parse %s: %v", MySQLDSNEnv, err)
+	}
+	cfg.ParseTime = true
+	admin, err := gorm.Open(mysql.Open(cfg.FormatDSN()), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	name := fmt.Sprintf("dbtest_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	cfg.DBName = name
+	db, err := gorm.Open(mysql.Open(cfg.FormatDSN()), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		_ = admin.Exec("DROP DATABASE " + name).Error
+		if sqlDB, err := admin.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
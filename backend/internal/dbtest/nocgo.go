@@ -0,0 +1,16 @@
+//go:build !cgo
+
+package dbtest
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// Open go-sqlite3 需要 cgo，CGO_ENABLED=0 时依赖数据库的测试直接跳过
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	t.Skip("dbtest: SQLite tests require cgo (CGO_ENABLED=1 and a C compiler)")
+	return nil
+}
@@ -16,7 +16,9 @@ type FeedVideoItem struct {
 	CoverURL    string     `json:"cover_url"`
 	CreateTime  int64      `json:"create_time"`
 	LikesCount  int64      `json:"likes_count"`
+	PlayCount   int64      `json:"play_count"`
 	IsLiked     bool       `json:"is_liked"`
+	Source      string     `json:"source,omitempty"` // 推荐流召回来源
 }
 
 type ListLatestRequest struct {
@@ -49,34 +51,80 @@ type ListLikesCountResponse struct {
 }
 
 type ListByFollowingRequest struct {
-	Limit      int   `json:"limit"`
-	LatestTime int64 `json:"latest_time"`
+	Limit       int    `json:"limit"`
+	LatestTime  int64  `json:"latest_time"`      // 兼容旧客户端（秒）；优先使用 cursor
+	Cursor      string `json:"cursor,omitempty"` // 上一页返回的 next_cursor；第一页不传
+	IncludeTags bool   `json:"include_tags"`     // 同时合并已关注话题下的视频；翻页时需保持一致
+}
+
+// FollowingCursor 关注流复合游标：create_time(ms) + id，保证同一毫秒内也不重不漏
+type FollowingCursor struct {
+	CreateTime int64
+	ID         uint
+}
+
+type ListByTagRequest struct {
+	TagName string `json:"tag_name"`
+	Limit   int    `json:"limit"`
+	Cursor  string `json:"cursor,omitempty"` // 上一页返回的 next_cursor；第一页不传
+}
+
+type ListByTagResponse struct {
+	VideoList  []FeedVideoItem `json:"video_list"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	HasMore    bool            `json:"has_more"`
 }
 
 type ListByFollowingResponse struct {
-	VideoList []FeedVideoItem `json:"video_list"`
-	NextTime  int64           `json:"next_time"`
-	HasMore   bool            `json:"has_more"`
+	VideoList  []FeedVideoItem `json:"video_list"`
+	NextTime   int64           `json:"next_time"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	HasMore    bool            `json:"has_more"`
 }
 
 type ListByPopularityRequest struct {
-	Limit          int   `json:"limit"`
-	AsOf           int64 `json:"as_of"`  // 服务器返回的分钟时间戳；第一页传0
-	Offset         int   `json:"offset"` // 下一页从这里开始；第一页传0
-	LatestIDBefore *uint `json:"latest_id_before,omitempty"`
+	Limit          int    `json:"limit"`
+	Cursor         string `json:"cursor,omitempty"` // 上一页返回的 next_cursor；第一页不传，优先于 as_of + offset
+	AsOf           int64  `json:"as_of"`            // 兼容旧客户端：服务器返回的分钟时间戳；第一页传0
+	Offset         int    `json:"offset"`           // 兼容旧客户端：下一页从这里开始；第一页传0
+	LatestIDBefore *uint  `json:"latest_id_before,omitempty"`
 
 	// DB fallback 用（可选）
 	LatestPopularity int64     `json:"latest_popularity"`
 	LatestBefore     time.Time `json:"latest_before"`
 }
 
+// PopularityCursor 热榜复合游标：快照版本 as_of + 上一页最后一条的 (score, id)，
+// 按 (score desc, id desc) 严格比较，同分的视频也不重不漏
+type PopularityCursor struct {
+	AsOf  int64
+	Score float64
+	ID    uint
+}
+
 type ListByPopularityResponse struct {
 	VideoList  []FeedVideoItem `json:"video_list"`
 	AsOf       int64           `json:"as_of"`
 	NextOffset int             `json:"next_offset"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 	HasMore    bool            `json:"has_more"`
 
 	NextLatestPopularity *int64     `json:"next_latest_popularity,omitempty"`
 	NextLatestBefore     *time.Time `json:"next_latest_before,omitempty"`
 	NextLatestIDBefore   *uint      `json:"next_latest_id_before,omitempty"`
 }
+
+type ListForYouRequest struct {
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor,omitempty"` // 上一页返回的 next_cursor；第一页不传，开启新会话
+}
+
+type ListForYouResponse struct {
+	VideoList  []FeedVideoItem `json:"video_list"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	HasMore    bool            `json:"has_more"`
+}
+
+type ReportImpressionsRequest struct {
+	VideoIDs []uint `json:"video_ids"`
+}
@@ -0,0 +1,395 @@
+package feed
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"feedsystem_video_go/internal/video"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// 每个来源召回的候选数
+	forYouPoolSize = 200
+	// 统计标签兴趣时回看的点赞数与取用的标签数
+	forYouLikeWindow = 100
+	forYouTagLimit   = 10
+	// 同一作者一页最多出现的次数
+	forYouMaxPerAuthor = 2
+	// 会话内已下发视频的保留时间，超过后游标失效、重新开始
+	forYouSessionTTL = 30 * time.Minute
+)
+
+// ForYouCursor 推荐流会话游标，对客户端不透明：base64url("<session>:<page>")
+type ForYouCursor struct {
+	Session string
+	Page    int
+}
+
+func EncodeForYouCursor(c ForYouCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", c.Session, c.Page)))
+}
+
+func DecodeForYouCursor(s string) (*ForYouCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	session, page, ok := strings.Cut(string(b), ":")
+	if !ok || session == "" {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := hex.DecodeString(session); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.Atoi(page)
+	if err != nil || n <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &ForYouCursor{Session: session, Page: n}, nil
+}
+
+func newForYouSession() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// SetRanker 替换推荐流排序函数与来源配额，nil 表示沿用默认
+func (f *FeedService) SetRanker(ranker Ranker, quotas Quotas) {
+	if ranker != nil {
+		f.ranker = ranker
+	}
+	if quotas != nil {
+		f.quotas = quotas
+	}
+}
+
+// ListForYou 个性化推荐流：多路召回（关注 / 兴趣标签 / 热榜 / 新发布）→ 过滤已看 → 打分并按配额混排。
+// 同一会话内已下发的视频记录在 served 集合里，翻页时排除
+func (f *FeedService) ListForYou(ctx context.Context, limit int, cursor *ForYouCursor, viewerAccountID uint) (ListForYouResponse, error) {
+	session := ForYouCursor{Session: newForYouSession()}
+	if cursor != nil {
+		session = *cursor
+	}
+
+	served, err := f.loadServed(ctx, viewerAccountID, session.Session)
+	if err != nil {
+		return ListForYouResponse{}, err
+	}
+	cands, err := f.collectCandidates(ctx, viewerAccountID)
+	if err != nil {
+		return ListForYouResponse{}, err
+	}
+	cands, err = f.filterSeen(ctx, cands, served, viewerAccountID)
+	if err != nil {
+		return ListForYouResponse{}, err
+	}
+
+	picked := Blend(cands, f.ranker, f.quotas, limit, forYouMaxPerAuthor, time.Now())
+	videos := make([]*video.Video, len(picked))
+	for i, c := range picked {
+		videos[i] = c.Video
+	}
+	if err := f.recordServed(ctx, viewerAccountID, session.Session, videos); err != nil {
+		return ListForYouResponse{}, err
+	}
+
+	items, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
+	if err != nil {
+		return ListForYouResponse{}, err
+	}
+	for i, c := range picked {
+		items[i].Source = primarySource(c)
+	}
+	resp := ListForYouResponse{VideoList: items, HasMore: len(videos) == limit}
+	if resp.HasMore {
+		resp.NextCursor = EncodeForYouCursor(ForYouCursor{Session: session.Session, Page: session.Page + 1})
+	}
+	return resp, nil
+}
+
+// primarySource 多路召回时取先验最高的来源，用于前端展示推荐理由
+func primarySource(c *Candidate) string {
+	for _, s := range allSources {
+		if c.Has(s) {
+			return s.String()
+		}
+	}
+	return ""
+}
+
+// collectCandidates 各来源并发召回并按视频合并；单个来源失败只降级，不影响整页
+func (f *FeedService) collectCandidates(ctx context.Context, viewerAccountID uint) ([]*Candidate, error) {
+	sources := []func(context.Context, uint) ([]*Candidate, error){
+		f.followingCandidates,
+		f.tagCandidates,
+		f.hotCandidates,
+		f.freshCandidates,
+	}
+	results := make([][]*Candidate, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, src func(context.Context, uint) ([]*Candidate, error)) {
+			defer wg.Done()
+			results[i], errs[i] = src(ctx, viewerAccountID)
+		}(i, src)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			log.Printf("for-you source %s failed: %v", allSources[i], err)
+		}
+	}
+	if failed == len(sources) {
+		return nil, errs[0]
+	}
+
+	byID := make(map[uint]*Candidate)
+	merged := make([]*Candidate, 0, forYouPoolSize)
+	for _, res := range results {
+		for _, c := range res {
+			if c == nil || c.Video == nil {
+				continue
+			}
+			if exist, ok := byID[c.Video.ID]; ok {
+				exist.Sources |= c.Sources
+				exist.Affinity = max(exist.Affinity, c.Affinity)
+				exist.HotScore = max(exist.HotScore, c.HotScore)
+				continue
+			}
+			byID[c.Video.ID] = c
+			merged = append(merged, c)
+		}
+	}
+	return merged, nil
+}
+
+func toCandidates(videos []*video.Video, src Source) []*Candidate {
+	cands := make([]*Candidate, 0, len(videos))
+	for _, v := range videos {
+		cands = append(cands, &Candidate{Video: v, Sources: src})
+	}
+	return cands
+}
+
+func (f *FeedService) followingCandidates(ctx context.Context, viewerAccountID uint) ([]*Candidate, error) {
+	if viewerAccountID == 0 || f.socialRepo == nil {
+		return nil, nil
+	}
+	authorIDs, err := f.socialRepo.ListVloggerIDs(ctx, viewerAccountID)
+	if err != nil || len(authorIDs) == 0 {
+		return nil, err
+	}
+	videos, err := f.repo.ListByAuthorIDs(ctx, authorIDs, forYouPoolSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toCandidates(videos, SourceFollowing), nil
+}
+
+// tagCandidates 观看者点赞过的视频所带标签 → 这些标签下的新视频，Affinity 为命中标签的兴趣之和
+func (f *FeedService) tagCandidates(ctx context.Context, viewerAccountID uint) ([]*Candidate, error) {
+	if viewerAccountID == 0 {
+		return nil, nil
+	}
+	tags, err := f.repo.ListEngagedTags(ctx, viewerAccountID, forYouLikeWindow, forYouTagLimit)
+	if err != nil || len(tags) == 0 {
+		return nil, err
+	}
+	weight := make(map[uint]float64, len(tags))
+	tagIDs := make([]uint, 0, len(tags))
+	for _, t := range tags {
+		weight[t.TagID] = float64(t.Count)
+		tagIDs = append(tagIDs, t.TagID)
+	}
+	hits, err := f.repo.ListTagHits(ctx, tagIDs, forYouPoolSize*2)
+	if err != nil {
+		return nil, err
+	}
+	affinity := make(map[uint]float64)
+	ids := make([]uint, 0, len(hits))
+	for _, h := range hits {
+		if _, ok := affinity[h.VideoID]; !ok {
+			ids = append(ids, h.VideoID)
+		}
+		affinity[h.VideoID] += weight[h.TagID]
+	}
+	if len(ids) > forYouPoolSize {
+		ids = ids[:forYouPoolSize]
+	}
+	videos, err := f.GetVideoByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	cands := toCandidates(videos, SourceTag)
+	for _, c := range cands {
+		c.Affinity = affinity[c.Video.ID]
+	}
+	return cands, nil
+}
+
+// hotCandidates 优先读 Redis 热榜快照，不可用或为空时按 popularity 回源 MySQL
+func (f *FeedService) hotCandidates(ctx context.Context, _ uint) ([]*Candidate, error) {
+	if f.rediscache != nil {
+		opCtx, cancel := context.WithTimeout(ctx, 80*time.Millisecond)
+		zs, err := f.rediscache.ZRevRangeByScoreWithScores(opCtx, f.hotSnapshot(opCtx, time.Now().UTC().Truncate(time.Minute)), "+inf", "-inf", 0, forYouPoolSize)
+		cancel()
+		if err == nil && len(zs) > 0 {
+			return f.hotCandidatesFromZSet(ctx, zs)
+		}
+	}
+	videos, err := f.repo.ListByPopularity(ctx, forYouPoolSize, 0, time.Time{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	cands := toCandidates(videos, SourceHot)
+	for _, c := range cands {
+		c.HotScore = float64(c.Video.Popularity)
+	}
+	return cands, nil
+}
+
+func (f *FeedService) hotCandidatesFromZSet(ctx context.Context, zs []redis.Z) ([]*Candidate, error) {
+	scores := make(map[uint]float64, len(zs))
+	ids := make([]uint, 0, len(zs))
+	for _, z := range zs {
+		member, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		scores[uint(id)] = z.Score
+		ids = append(ids, uint(id))
+	}
+	videos, err := f.GetVideoByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	cands := toCandidates(videos, SourceHot)
+	for _, c := range cands {
+		c.HotScore = scores[c.Video.ID]
+	}
+	return cands, nil
+}
+
+func (f *FeedService) freshCandidates(ctx context.Context, _ uint) ([]*Candidate, error) {
+	videos, err := f.repo.ListLatest(ctx, forYouPoolSize, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return toCandidates(videos, SourceFresh), nil
+}
+
+// filterSeen 去掉本会话已下发、近期曝光过、观看者已点赞、观看者自己发布以及无权查看的视频
+func (f *FeedService) filterSeen(ctx context.Context, cands []*Candidate, served map[uint]bool, viewerAccountID uint) ([]*Candidate, error) {
+	videos := make([]*video.Video, len(cands))
+	for i, c := range cands {
+		videos[i] = c.Video
+	}
+	hidden, err := f.hiddenFrom(ctx, viewerAccountID, videos)
+	if err != nil {
+		return nil, err
+	}
+	var liked, seen map[uint]bool
+	if viewerAccountID != 0 && len(cands) > 0 {
+		ids := make([]uint, len(cands))
+		for i, c := range cands {
+			ids[i] = c.Video.ID
+		}
+		if liked, err = f.likeRepo.BatchGetLiked(ctx, ids, viewerAccountID); err != nil {
+			return nil, err
+		}
+		if seen, err = f.seen.Seen(ctx, viewerAccountID, ids); err != nil {
+			log.Printf("seen set unavailable, skip filtering: %v", err)
+		}
+	}
+	out := cands[:0]
+	for _, c := range cands {
+		id := c.Video.ID
+		if served[id] || hidden[id] || liked[id] || seen[id] || (viewerAccountID != 0 && c.Video.AuthorID == viewerAccountID) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// served 集合按 (观看者, 会话) 隔离，游标被别的账号拿去用也看不到对方的记录；
+// Redis 不可用时退化到本地缓存，多实例下翻页可能出现少量重复
+func (f *FeedService) servedKey(viewerAccountID uint, session string) string {
+	return f.rediscache.Key("feed:foryou:served:%d:%s", viewerAccountID, session)
+}
+
+func (f *FeedService) loadServed(ctx context.Context, viewerAccountID uint, session string) (map[uint]bool, error) {
+	key := f.servedKey(viewerAccountID, session)
+	served := make(map[uint]bool)
+	if f.rediscache == nil {
+		if v, ok := f.localcache.Get(key); ok {
+			for _, id := range v.([]uint) {
+				served[id] = true
+			}
+		}
+		return served, nil
+	}
+	zs, err := f.rediscache.ZRangeWithScores(ctx, key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range zs {
+		member, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		if id, err := strconv.ParseUint(member, 10, 64); err == nil {
+			served[uint(id)] = true
+		}
+	}
+	return served, nil
+}
+
+func (f *FeedService) recordServed(ctx context.Context, viewerAccountID uint, session string, videos []*video.Video) error {
+	if len(videos) == 0 {
+		return nil
+	}
+	key := f.servedKey(viewerAccountID, session)
+	if f.rediscache == nil {
+		var ids []uint
+		if v, ok := f.localcache.Get(key); ok {
+			ids = append(ids, v.([]uint)...)
+		}
+		for _, v := range videos {
+			ids = append(ids, v.ID)
+		}
+		f.localcache.Set(key, ids, forYouSessionTTL)
+		return nil
+	}
+	now := float64(time.Now().UnixMilli())
+	members := make([]redis.Z, 0, len(videos))
+	for _, v := range videos {
+		members = append(members, redis.Z{Score: now, Member: strconv.FormatUint(uint64(v.ID), 10)})
+	}
+	if err := f.rediscache.ZAdd(ctx, key, members...); err != nil {
+		return err
+	}
+	return f.rediscache.Expire(ctx, key, forYouSessionTTL)
+}
@@ -3,6 +3,7 @@ package feed
 import (
 	"feedsystem_video_go/internal/apierror"
 	"feedsystem_video_go/internal/middleware/jwt"
+	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -104,11 +105,18 @@ func (f *FeedHandler) ListByFollowing(c *gin.Context) {
 	if err != nil {
 		viewerAccountID = 0
 	}
-	var latestTime time.Time
-	if req.LatestTime > 0 {
-		latestTime = time.Unix(req.LatestTime, 0)
+	var cursor *FollowingCursor
+	if req.Cursor != "" {
+		cursor, err = DecodeFollowingCursor(req.Cursor)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	} else if req.LatestTime > 0 {
+		// 旧客户端按秒传 latest_time：等价于 create_time < latest_time
+		cursor = &FollowingCursor{CreateTime: req.LatestTime*1000 - 1, ID: ^uint(0)}
 	}
-	feedItems, err := f.service.ListByFollowing(c.Request.Context(), req.Limit, latestTime, viewerAccountID)
+	feedItems, err := f.service.ListByFollowing(c.Request.Context(), req.Limit, cursor, viewerAccountID, req.IncludeTags)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -131,6 +139,15 @@ func (f *FeedHandler) ListByPopularity(c *gin.Context) {
 		viewerAccountID = 0
 	}
 
+	var cursor *PopularityCursor
+	if req.Cursor != "" {
+		cursor, err = DecodePopularityCursor(req.Cursor)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	var latestPopularity int64
 	var latestBefore time.Time
 	var latestIDBefore uint
@@ -153,6 +170,7 @@ func (f *FeedHandler) ListByPopularity(c *gin.Context) {
 	resp, err := f.service.ListByPopularity(
 		c.Request.Context(),
 		req.Limit,
+		cursor,
 		req.AsOf,
 		req.Offset,
 		viewerAccountID,
@@ -168,6 +186,59 @@ func (f *FeedHandler) ListByPopularity(c *gin.Context) {
 	c.JSON(200, resp)
 }
 
+func (f *FeedHandler) ListForYou(c *gin.Context) {
+	var req ListForYouRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 50 {
+		req.Limit = 10
+	}
+	viewerAccountID, err := jwt.GetAccountID(c)
+	if err != nil {
+		viewerAccountID = 0
+	}
+	var cursor *ForYouCursor
+	if req.Cursor != "" {
+		cursor, err = DecodeForYouCursor(req.Cursor)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	resp, err := f.service.ListForYou(c.Request.Context(), req.Limit, cursor, viewerAccountID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	resp.VideoList = nonNilFeedVideoItems(resp.VideoList)
+	c.JSON(200, resp)
+}
+
+// ReportImpressions 客户端上报已展示的视频，写入已看集合，之后各个列表会跳过它们
+func (f *FeedHandler) ReportImpressions(c *gin.Context) {
+	var req ReportImpressionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.VideoIDs) == 0 || len(req.VideoIDs) > MaxImpressionBatch {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("video_ids must contain 1-%d ids", MaxImpressionBatch)})
+		return
+	}
+	accountID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := f.service.ReportImpressions(c.Request.Context(), accountID, req.VideoIDs); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "ok"})
+}
+
 func nonNilFeedVideoItems(items []FeedVideoItem) []FeedVideoItem {
 	if items == nil {
 		return []FeedVideoItem{}
@@ -176,10 +247,7 @@ func nonNilFeedVideoItems(items []FeedVideoItem) []FeedVideoItem {
 }
 
 func (h *FeedHandler) ListByTag(c *gin.Context) {
-	var req struct {
-		TagName string `json:"tag_name"`
-		Limit   int    `json:"limit"`
-	}
+	var req ListByTagRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
@@ -191,11 +259,21 @@ func (h *FeedHandler) ListByTag(c *gin.Context) {
 	if req.Limit <= 0 || req.Limit > 50 {
 		req.Limit = 10
 	}
+	var cursor *FollowingCursor
+	if req.Cursor != "" {
+		var err error
+		cursor, err = DecodeFollowingCursor(req.Cursor)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	viewerAccountID, _ := jwt.GetAccountID(c)
-	items, err := h.service.ListByTag(c.Request.Context(), req.TagName, req.Limit, viewerAccountID)
+	resp, err := h.service.ListByTag(c.Request.Context(), req.TagName, req.Limit, cursor, viewerAccountID)
 	if err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"video_list": nonNilFeedVideoItems(items)})
+	resp.VideoList = nonNilFeedVideoItems(resp.VideoList)
+	c.JSON(200, resp)
 }
@@ -0,0 +1,150 @@
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// EncodePopularityCursor 热榜游标对客户端不透明：base64url("<as_of>:<score>:<id>")
+func EncodePopularityCursor(c PopularityCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s:%d", c.AsOf, formatScore(c.Score), c.ID)))
+}
+
+func DecodePopularityCursor(s string) (*PopularityCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	asOf, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || asOf <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil || id == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &PopularityCursor{AsOf: asOf, Score: score, ID: uint(id)}, nil
+}
+
+// formatScore 最短且可精确还原的十进制表示，Redis 按同样的 double 解析
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+// hotEntry 热榜快照里的一条，整体按 (score desc, id desc) 排序
+type hotEntry struct {
+	score float64
+	id    uint
+}
+
+func sortHotEntries(entries []hotEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].score != entries[j].score {
+			return entries[i].score > entries[j].score
+		}
+		return entries[i].id > entries[j].id
+	})
+}
+
+// hotTies 取快照里分数恰好为 score 的全部成员
+func (f *FeedService) hotTies(ctx context.Context, dest string, score float64) ([]hotEntry, error) {
+	s := formatScore(score)
+	zs, err := f.rediscache.ZRevRangeByScoreWithScores(ctx, dest, s, s, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return toHotEntries(zs), nil
+}
+
+// hotRange 从快照里取严格排在 after 之后的 n 条（after 为 nil 表示从头开始）。
+// Redis 同分成员按 member 字典序排列，和数值 id 顺序不一致，所以边界上的同分成员整组取出后在 Go 里排序
+func (f *FeedService) hotRange(ctx context.Context, dest string, after *hotEntry, n int) ([]hotEntry, bool, error) {
+	var out []hotEntry
+	max := "+inf"
+	if after != nil {
+		ties, err := f.hotTies(ctx, dest, after.score)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, e := range ties {
+			if e.id < after.id {
+				out = append(out, e)
+			}
+		}
+		sortHotEntries(out)
+		if len(out) >= n {
+			return out[:n], true, nil
+		}
+		max = "(" + formatScore(after.score)
+	}
+
+	want := n - len(out)
+	zs, err := f.rediscache.ZRevRangeByScoreWithScores(ctx, dest, max, "-inf", 0, int64(want))
+	if err != nil {
+		return nil, false, err
+	}
+	batch := toHotEntries(zs)
+	more := len(zs) == want
+	if more && len(batch) > 0 {
+		// 最后一个分数可能还有同分成员没取到：去掉这一组，整组重新取
+		lastScore := batch[len(batch)-1].score
+		for len(batch) > 0 && batch[len(batch)-1].score == lastScore {
+			batch = batch[:len(batch)-1]
+		}
+		ties, err := f.hotTies(ctx, dest, lastScore)
+		if err != nil {
+			return nil, false, err
+		}
+		batch = append(batch, ties...)
+	}
+	sortHotEntries(batch)
+	out = append(out, batch...)
+	if len(out) > n {
+		out, more = out[:n], true
+	}
+	return out, more, nil
+}
+
+func toHotEntries(zs []redis.Z) []hotEntry {
+	entries := make([]hotEntry, 0, len(zs))
+	for _, z := range zs {
+		member, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		entries = append(entries, hotEntry{score: z.Score, id: uint(id)})
+	}
+	return entries
+}
+
+// hotEntryAt 取快照里第 rank 名（从 0 开始）；超出范围返回 nil
+func (f *FeedService) hotEntryAt(ctx context.Context, dest string, rank int) (*hotEntry, error) {
+	zs, err := f.rediscache.ZRevRangeByScoreWithScores(ctx, dest, "+inf", "-inf", int64(rank), 1)
+	if err != nil {
+		return nil, err
+	}
+	entries := toHotEntries(zs)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return &entries[0], nil
+}
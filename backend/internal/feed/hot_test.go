@@ -0,0 +1,60 @@
+package feed
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestHotRangePagesByScoreThenID(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	client := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "")
+	defer client.Close()
+	f := &FeedService{rediscache: client}
+	ctx := context.Background()
+
+	// 同分成员在 Redis 里按字典序排列（"9" > "12" > "10"），分页要按数值 id 倒序
+	for member, score := range map[string]float64{"12": 2.5, "9": 2.5, "10": 2.5, "3": 1.1, "7": 1.1, "4": 0.3} {
+		mr.ZAdd("snap", score, member)
+	}
+	var got []uint
+	var after *hotEntry
+	for pages := 0; pages < 10; pages++ {
+		entries, more, err := f.hotRange(ctx, "snap", after, 2)
+		if err != nil {
+			t.Fatalf("hot range: %v", err)
+		}
+		for _, e := range entries {
+			got = append(got, e.id)
+		}
+		if !more || len(entries) == 0 {
+			break
+		}
+		after = &entries[len(entries)-1]
+	}
+	if want := []uint{12, 10, 9, 7, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestPopularityCursorRoundTrip(t *testing.T) {
+	c := PopularityCursor{AsOf: 1700000040, Score: 0.1 + 0.2, ID: 42}
+	got, err := DecodePopularityCursor(EncodePopularityCursor(c))
+	if err != nil || *got != c {
+		t.Fatalf("round trip: %+v %v", got, err)
+	}
+	for _, bad := range []string{"", "!!", "MTox", "MDoxOjE"} {
+		if _, err := DecodePopularityCursor(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
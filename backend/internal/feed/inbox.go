@@ -0,0 +1,222 @@
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/video"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// 粉丝数达到该阈值的作者不做写扩散，由读端实时拉取后合并
+	PushFollowerLimit = 5000
+
+	inboxMaxLen      = 1000
+	inboxTTL         = 7 * 24 * time.Hour
+	inboxRebuildSize = 500
+	// 占位成员：保证“没有任何视频”的收件箱也存在，避免每次请求都回源重建
+	inboxPlaceholder = "0"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Inbox 关注流收件箱：每个粉丝一个 ZSET，member=videoID，score=create_time(ms)
+type Inbox struct {
+	cache *rediscache.Client
+}
+
+func NewInbox(cache *rediscache.Client) *Inbox {
+	if cache == nil {
+		return nil
+	}
+	return &Inbox{cache: cache}
+}
+
+func (i *Inbox) Key(accountID uint) string {
+	return i.cache.Key("feed:inbox:%d", accountID)
+}
+
+// Push 写扩散：只写入已经构建过的收件箱，未构建的等读时回源
+func (i *Inbox) Push(ctx context.Context, followerIDs []uint, videoID uint, createTime time.Time) error {
+	if i == nil || len(followerIDs) == 0 || videoID == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(followerIDs))
+	for _, id := range followerIDs {
+		keys = append(keys, i.Key(id))
+	}
+	member := strconv.FormatUint(uint64(videoID), 10)
+	return i.cache.ZAddIfExistsMulti(ctx, keys, float64(createTime.UnixMilli()), member, inboxMaxLen)
+}
+
+// Invalidate 关注关系变化后删除收件箱，下次读取时按最新关注列表重建
+func (i *Inbox) Invalidate(ctx context.Context, accountID uint) error {
+	if i == nil || accountID == 0 {
+		return nil
+	}
+	return i.cache.Del(ctx, i.Key(accountID))
+}
+
+func (i *Inbox) Exists(ctx context.Context, accountID uint) (bool, error) {
+	if i == nil {
+		return false, nil
+	}
+	return i.cache.Exists(ctx, i.Key(accountID))
+}
+
+func (i *Inbox) Rebuild(ctx context.Context, accountID uint, videos []*video.Video) error {
+	if i == nil {
+		return nil
+	}
+	key := i.Key(accountID)
+	members := make([]redis.Z, 0, len(videos)+1)
+	members = append(members, redis.Z{Score: 0, Member: inboxPlaceholder})
+	for _, v := range videos {
+		members = append(members, redis.Z{
+			Score:  float64(v.CreateTime.UnixMilli()),
+			Member: strconv.FormatUint(uint64(v.ID), 10),
+		})
+	}
+	if err := i.cache.Del(ctx, key); err != nil {
+		return err
+	}
+	if err := i.cache.ZAdd(ctx, key, members...); err != nil {
+		return err
+	}
+	return i.cache.Expire(ctx, key, inboxTTL)
+}
+
+type inboxEntry struct {
+	score int64
+	id    uint
+}
+
+// Range 按 (create_time, id) 倒序读取游标之后的视频 ID，最多 count 条。
+// Redis 同分成员按 member 字典序排列，和数值 id 顺序不一致：游标所在分数和本页最后一个分数的同分成员整组取出，
+// 在 Go 里按数值排序后再截断，否则同毫秒发布的视频会被跳过或让这一页变短
+func (i *Inbox) Range(ctx context.Context, accountID uint, cursor *FollowingCursor, count int) ([]uint, error) {
+	if i == nil || count <= 0 {
+		return nil, nil
+	}
+	key := i.Key(accountID)
+	var out []inboxEntry
+	max := "+inf"
+	if cursor != nil {
+		ties, err := i.ties(ctx, key, cursor.CreateTime)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ties {
+			if e.id < cursor.ID {
+				out = append(out, e)
+			}
+		}
+		sortInboxEntries(out)
+		if len(out) >= count {
+			return inboxIDs(out[:count]), nil
+		}
+		max = "(" + strconv.FormatInt(cursor.CreateTime, 10)
+	}
+
+	want := count - len(out)
+	zs, err := i.cache.ZRevRangeByScoreWithScores(ctx, key, max, "(0", 0, int64(want))
+	if err != nil {
+		return nil, err
+	}
+	batch := toInboxEntries(zs)
+	if len(zs) == want && len(batch) > 0 {
+		// 最后一个分数可能还有同分成员没取到：去掉这一组，整组重新取
+		lastScore := batch[len(batch)-1].score
+		for len(batch) > 0 && batch[len(batch)-1].score == lastScore {
+			batch = batch[:len(batch)-1]
+		}
+		ties, err := i.ties(ctx, key, lastScore)
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, ties...)
+	}
+	sortInboxEntries(batch)
+	out = append(out, batch...)
+	if len(out) > count {
+		out = out[:count]
+	}
+	return inboxIDs(out), nil
+}
+
+// ties 取收件箱里 create_time 恰好为 score 的全部视频
+func (i *Inbox) ties(ctx context.Context, key string, score int64) ([]inboxEntry, error) {
+	s := strconv.FormatInt(score, 10)
+	zs, err := i.cache.ZRevRangeByScoreWithScores(ctx, key, s, s, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return toInboxEntries(zs), nil
+}
+
+func toInboxEntries(zs []redis.Z) []inboxEntry {
+	entries := make([]inboxEntry, 0, len(zs))
+	for _, z := range zs {
+		member, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		entries = append(entries, inboxEntry{score: int64(z.Score), id: uint(id)})
+	}
+	return entries
+}
+
+func sortInboxEntries(entries []inboxEntry) {
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].score != entries[b].score {
+			return entries[a].score > entries[b].score
+		}
+		return entries[a].id > entries[b].id
+	})
+}
+
+func inboxIDs(entries []inboxEntry) []uint {
+	ids := make([]uint, len(entries))
+	for k, e := range entries {
+		ids[k] = e.id
+	}
+	return ids
+}
+
+// EncodeFollowingCursor 游标格式：base64url("<create_time_ms>:<id>")
+func EncodeFollowingCursor(c FollowingCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.CreateTime, c.ID)))
+}
+
+func DecodeFollowingCursor(s string) (*FollowingCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	ms, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || ms <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || id == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &FollowingCursor{CreateTime: ms, ID: uint(id)}, nil
+}
@@ -0,0 +1,162 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/video"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestInbox(t *testing.T) (*Inbox, *miniredis.Miniredis) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	client := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "")
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	return NewInbox(client), mr
+}
+
+func TestInboxPushSkipsUnbuiltInbox(t *testing.T) {
+	inbox, mr := newTestInbox(t)
+	ctx := context.Background()
+
+	if err := inbox.Push(ctx, []uint{1}, 100, time.UnixMilli(1000)); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if mr.Exists(inbox.Key(1)) {
+		t.Fatalf("expected unbuilt inbox to stay absent")
+	}
+
+	if err := inbox.Rebuild(ctx, 1, nil); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if err := inbox.Push(ctx, []uint{1}, 100, time.UnixMilli(1000)); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	ids, err := inbox.Range(ctx, 1, nil, 10)
+	if err != nil {
+		t.Fatalf("range: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != 100 {
+		t.Fatalf("expected [100], got %v", ids)
+	}
+}
+
+func TestInboxRangeWithCursor(t *testing.T) {
+	inbox, _ := newTestInbox(t)
+	ctx := context.Background()
+
+	same := time.UnixMilli(2000)
+	videos := []*video.Video{
+		{ID: 1, CreateTime: time.UnixMilli(1000)},
+		{ID: 2, CreateTime: same},
+		{ID: 3, CreateTime: same},
+		{ID: 4, CreateTime: time.UnixMilli(3000)},
+	}
+	if err := inbox.Rebuild(ctx, 7, videos); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	ids, err := inbox.Range(ctx, 7, &FollowingCursor{CreateTime: 2000, ID: 3}, 10)
+	if err != nil {
+		t.Fatalf("range: %v", err)
+	}
+	got := map[uint]bool{}
+	for _, id := range ids {
+		got[id] = true
+	}
+	if len(ids) != 2 || !got[2] || !got[1] {
+		t.Fatalf("expected ids {2,1} after cursor (2000,3), got %v", ids)
+	}
+}
+
+func TestFollowingCursorRoundTrip(t *testing.T) {
+	want := FollowingCursor{CreateTime: 1700000000123, ID: 42}
+	got, err := DecodeFollowingCursor(EncodeFollowingCursor(want))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if *got != want {
+		t.Fatalf("expected %+v, got %+v", want, *got)
+	}
+	if _, err := DecodeFollowingCursor("not-a-cursor"); err == nil {
+		t.Fatalf("expected error for malformed cursor")
+	}
+}
+
+func TestMergeByTimeDedupsAcrossSources(t *testing.T) {
+	base := time.UnixMilli(1700000000000)
+	v := func(id, author uint, offset time.Duration) *video.Video {
+		return &video.Video{ID: id, AuthorID: author, CreateTime: base.Add(offset)}
+	}
+	creators := []*video.Video{v(5, 1, 3*time.Second), v(3, 1, time.Second)}
+	tagged := []*video.Video{v(5, 1, 3*time.Second), v(4, 2, 2*time.Second), v(6, 9, 4*time.Second), v(2, 3, time.Second)}
+
+	got := mergeByTime(4, func(v *video.Video) bool { return v.AuthorID != 9 }, creators, tagged)
+	want := []uint{5, 4, 3, 2}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d videos, got %d", len(want), len(got))
+	}
+	for i, id := range want {
+		if got[i].ID != id {
+			t.Fatalf("position %d: expected video %d, got %d", i, id, got[i].ID)
+		}
+	}
+}
+
+func TestInboxRangePagesSameMillisecondByNumericID(t *testing.T) {
+	inbox, _ := newTestInbox(t)
+	ctx := context.Background()
+
+	// 字典序下 "9" > "11" > "10"，按数值应为 11、10、9
+	same := time.UnixMilli(2000)
+	videos := []*video.Video{
+		{ID: 9, CreateTime: same},
+		{ID: 10, CreateTime: same},
+		{ID: 11, CreateTime: same},
+		{ID: 12, CreateTime: time.UnixMilli(3000)},
+		{ID: 2, CreateTime: time.UnixMilli(1000)},
+	}
+	if err := inbox.Rebuild(ctx, 7, videos); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	var got []uint
+	var cursor *FollowingCursor
+	scores := map[uint]int64{9: 2000, 10: 2000, 11: 2000, 12: 3000, 2: 1000}
+	for page := 0; page < 5; page++ {
+		ids, err := inbox.Range(ctx, 7, cursor, 2)
+		if err != nil {
+			t.Fatalf("range: %v", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		// 没到末尾时每页都是满的
+		if len(got)+len(ids) < len(videos) && len(ids) != 2 {
+			t.Fatalf("page %d came up short: %v", page, ids)
+		}
+		got = append(got, ids...)
+		last := ids[len(ids)-1]
+		cursor = &FollowingCursor{CreateTime: scores[last], ID: last}
+	}
+	want := []uint{12, 11, 10, 9, 2}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for k := range want {
+		if got[k] != want[k] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"feedsystem_video_go/internal/video"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// RunCacheInvalidation 订阅视频变更广播，清掉本实例 L1 里的视频实体；阻塞直到 ctx 结束。
+// 订阅断开后按退避重新订阅，断开期间的广播可能丢失，所以重连后清空整个 L1
+func (f *FeedService) RunCacheInvalidation(ctx context.Context) error {
+	if f.rediscache == nil || f.localcache == nil {
+		return errors.New("feed cache invalidation requires redis")
+	}
+	backoff := time.Second
+	reconnect := false
+	for {
+		subscribed, err := f.consumeInvalidation(ctx, reconnect)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if subscribed {
+			reconnect = true
+			backoff = time.Second
+		}
+		log.Printf("feed cache invalidation: %v (resubscribe in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// consumeInvalidation 订阅一次并处理广播直到连接出错；subscribed 表示订阅曾经建立
+func (f *FeedService) consumeInvalidation(ctx context.Context, flush bool) (subscribed bool, err error) {
+	ps, err := f.rediscache.Subscribe(ctx, video.VideoChangedChannel(f.rediscache))
+	if err != nil {
+		return false, err
+	}
+	defer ps.Close()
+	// Receive 不一定响应 ctx，结束时关掉订阅让它返回
+	stop := context.AfterFunc(ctx, func() { _ = ps.Close() })
+	defer stop()
+	if flush {
+		f.localcache.Flush()
+	}
+	for {
+		msg, err := ps.Receive(ctx)
+		if err != nil {
+			return true, err
+		}
+		m, ok := msg.(*redis.Message)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(m.Payload, 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		f.localcache.Delete(f.rediscache.Key("video:entity:%d", id))
+	}
+}
@@ -0,0 +1,97 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/video"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/patrickmn/go-cache"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestCacheInvalidationDropsLocalEntity(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	client := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	defer client.Close()
+
+	f := &FeedService{rediscache: client, localcache: cache.New(time.Minute, time.Minute)}
+	changed := client.Key("video:entity:%d", 7)
+	untouched := client.Key("video:entity:%d", 8)
+	f.localcache.Set(changed, video.Video{ID: 7}, time.Minute)
+	f.localcache.Set(untouched, video.Video{ID: 8}, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = f.RunCacheInvalidation(ctx) }()
+
+	// 订阅在后台建立，重复广播直到本地缓存被清掉
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		video.InvalidateVideoCaches(ctx, client, 7)
+		if _, found := f.localcache.Get(changed); !found {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("local entity was not invalidated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, found := f.localcache.Get(untouched); !found {
+		t.Fatalf("unrelated entity should stay cached")
+	}
+}
+
+func TestCacheInvalidationResubscribesAndFlushes(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	client := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	defer client.Close()
+
+	f := &FeedService{rediscache: client, localcache: cache.New(time.Minute, time.Minute)}
+	channel := video.VideoChangedChannel(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = f.RunCacheInvalidation(ctx) }()
+
+	waitSubscribed := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for mr.PubSubNumSub(channel)[channel] == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("invalidation did not subscribe")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitSubscribed()
+
+	// 断开期间写入的条目可能错过了广播，重连后必须被清掉
+	stale := client.Key("video:entity:%d", 9)
+	mr.Close()
+	f.localcache.Set(stale, video.Video{ID: 9}, time.Minute)
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("restart miniredis: %v", err)
+	}
+	waitSubscribed()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, found := f.localcache.Get(stale); !found {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("local cache was not flushed after resubscribing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,187 @@
+package feed
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"feedsystem_video_go/internal/video"
+)
+
+// Source 推荐候选来源
+type Source uint8
+
+const (
+	SourceFollowing Source = 1 << iota // 关注的作者
+	SourceTag                          // 点赞过的视频所带标签
+	SourceHot                          // 全局热榜
+	SourceFresh                        // 最新上传
+)
+
+var allSources = []Source{SourceFollowing, SourceTag, SourceHot, SourceFresh}
+
+func (s Source) String() string {
+	switch s {
+	case SourceFollowing:
+		return "following"
+	case SourceTag:
+		return "tag"
+	case SourceHot:
+		return "hot"
+	case SourceFresh:
+		return "fresh"
+	}
+	return "unknown"
+}
+
+// Candidate 一条候选；同一视频可能被多个来源召回，Sources 为来源位图
+type Candidate struct {
+	Video   *video.Video
+	Sources Source
+	// Affinity 与观看者的兴趣相关度：命中标签的权重之和
+	Affinity float64
+	// HotScore 热榜窗口内的分数
+	HotScore float64
+}
+
+func (c *Candidate) Has(s Source) bool {
+	return c.Sources&s != 0
+}
+
+// Ranker 排序函数，只依赖候选本身，便于替换与单测
+type Ranker interface {
+	Score(c *Candidate, now time.Time) float64
+}
+
+// RankerFunc 允许直接用函数作为 Ranker
+type RankerFunc func(c *Candidate, now time.Time) float64
+
+func (f RankerFunc) Score(c *Candidate, now time.Time) float64 {
+	return f(c, now)
+}
+
+// DefaultRanker 来源先验 + 兴趣相关度 + 互动量 + 新鲜度衰减；多路召回的视频额外加分
+type DefaultRanker struct {
+	SourceWeights map[Source]float64
+	HalfLife      time.Duration
+}
+
+func NewDefaultRanker() *DefaultRanker {
+	return &DefaultRanker{
+		SourceWeights: map[Source]float64{
+			SourceFollowing: 1.0,
+			SourceTag:       0.8,
+			SourceHot:       0.6,
+			SourceFresh:     0.4,
+		},
+		HalfLife: 48 * time.Hour,
+	}
+}
+
+func (r *DefaultRanker) Score(c *Candidate, now time.Time) float64 {
+	if c == nil || c.Video == nil {
+		return 0
+	}
+	var prior float64
+	hits := 0
+	for _, s := range allSources {
+		if !c.Has(s) {
+			continue
+		}
+		hits++
+		if w := r.SourceWeights[s]; w > prior {
+			prior = w
+		}
+	}
+	score := prior + 0.2*float64(max(hits-1, 0))
+	score += 0.5 * math.Log1p(math.Max(c.Affinity, 0))
+	score += 0.1 * math.Log1p(float64(max(c.Video.LikesCount, 0)))
+	score += 0.1 * math.Log1p(math.Max(c.HotScore, 0))
+
+	if r.HalfLife > 0 {
+		age := max(now.Sub(c.Video.CreateTime), 0)
+		score += math.Pow(0.5, float64(age)/float64(r.HalfLife))
+	}
+	return score
+}
+
+// Quotas 每个来源在一页中保底的比例；剩余名额按总分补齐
+type Quotas map[Source]float64
+
+var DefaultQuotas = Quotas{
+	SourceFollowing: 0.35,
+	SourceTag:       0.3,
+	SourceHot:       0.2,
+	SourceFresh:     0.15,
+}
+
+type scoredCandidate struct {
+	c     *Candidate
+	score float64
+}
+
+func sortScored(s []scoredCandidate) {
+	sort.SliceStable(s, func(i, j int) bool {
+		if s[i].score != s[j].score {
+			return s[i].score > s[j].score
+		}
+		return s[i].c.Video.ID > s[j].c.Video.ID
+	})
+}
+
+// Blend 打分后按来源配额混排出 limit 条：先按配额从各来源取高分候选，不足的名额再按总分补齐；
+// 同一作者一页最多 maxPerAuthor 条（<=0 不限制）。结果按分数倒序
+func Blend(cands []*Candidate, ranker Ranker, quotas Quotas, limit, maxPerAuthor int, now time.Time) []*Candidate {
+	if limit <= 0 || len(cands) == 0 {
+		return nil
+	}
+	all := make([]scoredCandidate, 0, len(cands))
+	for _, c := range cands {
+		if c == nil || c.Video == nil {
+			continue
+		}
+		all = append(all, scoredCandidate{c: c, score: ranker.Score(c, now)})
+	}
+	sortScored(all)
+
+	picked := make(map[uint]bool, limit)
+	perAuthor := make(map[uint]int)
+	out := make([]scoredCandidate, 0, limit)
+	take := func(s scoredCandidate) bool {
+		if picked[s.c.Video.ID] || len(out) >= limit {
+			return false
+		}
+		if maxPerAuthor > 0 && perAuthor[s.c.Video.AuthorID] >= maxPerAuthor {
+			return false
+		}
+		picked[s.c.Video.ID] = true
+		perAuthor[s.c.Video.AuthorID]++
+		out = append(out, s)
+		return true
+	}
+
+	for _, src := range allSources {
+		quota := int(math.Round(quotas[src] * float64(limit)))
+		for _, s := range all {
+			if quota <= 0 {
+				break
+			}
+			if s.c.Has(src) && take(s) {
+				quota--
+			}
+		}
+	}
+	for _, s := range all {
+		if len(out) >= limit {
+			break
+		}
+		take(s)
+	}
+
+	sortScored(out)
+	res := make([]*Candidate, len(out))
+	for i, s := range out {
+		res[i] = s.c
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/video"
+)
+
+func cand(id, author uint, src Source) *Candidate {
+	return &Candidate{Video: &video.Video{ID: id, AuthorID: author}, Sources: src}
+}
+
+func TestBlendReservesQuotaPerSource(t *testing.T) {
+	// 热榜候选分数都更高，但新发布仍按配额拿到名额
+	ranker := RankerFunc(func(c *Candidate, _ time.Time) float64 {
+		if c.Has(SourceHot) {
+			return 10 + float64(c.Video.ID)
+		}
+		return float64(c.Video.ID)
+	})
+	var cands []*Candidate
+	for i := uint(1); i <= 10; i++ {
+		cands = append(cands, cand(100+i, 100+i, SourceHot), cand(i, i, SourceFresh))
+	}
+
+	got := Blend(cands, ranker, Quotas{SourceHot: 0.5, SourceFresh: 0.5}, 4, 0, time.Now())
+	if len(got) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(got))
+	}
+	fresh := 0
+	for _, c := range got {
+		if c.Has(SourceFresh) {
+			fresh++
+		}
+	}
+	if fresh != 2 {
+		t.Fatalf("expected 2 fresh items by quota, got %d", fresh)
+	}
+	if got[0].Video.ID != 110 || got[3].Video.ID != 9 {
+		t.Fatalf("expected result ordered by score, got %d..%d", got[0].Video.ID, got[3].Video.ID)
+	}
+
+	// 配额用不完的名额按总分补齐
+	got = Blend(cands, ranker, Quotas{SourceFollowing: 1}, 3, 0, time.Now())
+	if len(got) != 3 || got[0].Video.ID != 110 || got[2].Video.ID != 108 {
+		t.Fatalf("expected top hot items as backfill, got %+v", got)
+	}
+}
+
+func TestBlendCapsPerAuthor(t *testing.T) {
+	ranker := RankerFunc(func(c *Candidate, _ time.Time) float64 { return float64(c.Video.ID) })
+	cands := []*Candidate{cand(5, 1, SourceHot), cand(4, 1, SourceHot), cand(3, 1, SourceHot), cand(2, 2, SourceHot)}
+
+	got := Blend(cands, ranker, nil, 3, 2, time.Now())
+	if len(got) != 3 || got[2].Video.ID != 2 {
+		t.Fatalf("expected third item from another author, got %+v", got)
+	}
+}
+
+func TestDefaultRankerPrefersAffinityAndFreshness(t *testing.T) {
+	now := time.Now()
+	r := NewDefaultRanker()
+	old := &Candidate{Video: &video.Video{ID: 1, CreateTime: now.Add(-30 * 24 * time.Hour)}, Sources: SourceTag, Affinity: 3}
+	fresh := &Candidate{Video: &video.Video{ID: 2, CreateTime: now}, Sources: SourceTag, Affinity: 3}
+	if r.Score(fresh, now) <= r.Score(old, now) {
+		t.Fatalf("expected fresh video to score higher")
+	}
+	related := &Candidate{Video: &video.Video{ID: 3, CreateTime: now}, Sources: SourceTag, Affinity: 10}
+	if r.Score(related, now) <= r.Score(fresh, now) {
+		t.Fatalf("expected higher affinity to score higher")
+	}
+	multi := &Candidate{Video: &video.Video{ID: 4, CreateTime: now}, Sources: SourceTag | SourceHot, Affinity: 3}
+	if r.Score(multi, now) <= r.Score(fresh, now) {
+		t.Fatalf("expected multi-source candidate to score higher")
+	}
+}
+
+func TestForYouCursorRoundTrip(t *testing.T) {
+	want := ForYouCursor{Session: "0a1b2c3d4e5f6071", Page: 3}
+	got, err := DecodeForYouCursor(EncodeForYouCursor(want))
+	if err != nil || *got != want {
+		t.Fatalf("round trip: %+v %v", got, err)
+	}
+	for _, bad := range []string{"", "!!", EncodeForYouCursor(ForYouCursor{Session: "zz", Page: 1}), EncodeForYouCursor(ForYouCursor{Session: "ab", Page: 0})} {
+		if _, err := DecodeForYouCursor(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
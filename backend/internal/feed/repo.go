@@ -20,6 +20,7 @@ func NewFeedRepository(db *gorm.DB) *FeedRepository {
 func (repo *FeedRepository) ListLatest(ctx context.Context, limit int, latestBefore time.Time) ([]*video.Video, error) {
 	var videos []*video.Video
 	query := repo.db.WithContext(ctx).Model(&video.Video{}).
+		Scopes(publicVideos).
 		Order("create_time DESC")
 	if !latestBefore.IsZero() {
 		query = query.Where("create_time < ?", latestBefore)
@@ -33,6 +34,7 @@ func (repo *FeedRepository) ListLatest(ctx context.Context, limit int, latestBef
 func (repo *FeedRepository) ListLikesCountWithCursor(ctx context.Context, limit int, cursor *LikesCountCursor) ([]*video.Video, error) {
 	var videos []*video.Video
 	query := repo.db.WithContext(ctx).Model(&video.Video{}).
+		Scopes(publicVideos).
 		Order("likes_count DESC, id DESC")
 
 	if cursor != nil {
@@ -49,10 +51,11 @@ func (repo *FeedRepository) ListLikesCountWithCursor(ctx context.Context, limit
 	return videos, nil
 }
 
-func (repo *FeedRepository) ListByFollowing(ctx context.Context, limit int, viewerAccountID uint, latestBefore time.Time) ([]*video.Video, error) {
+func (repo *FeedRepository) ListByFollowing(ctx context.Context, limit int, viewerAccountID uint, cursor *FollowingCursor) ([]*video.Video, error) {
 	var videos []*video.Video
 	query := repo.db.WithContext(ctx).Model(&video.Video{}).
-		Order("create_time DESC")
+		Scopes(followingVisibleVideos).
+		Order("create_time DESC, id DESC")
 	if viewerAccountID > 0 {
 		followingSubQuery := repo.db.WithContext(ctx).
 			Model(&social.Social{}).
@@ -60,18 +63,60 @@ func (repo *FeedRepository) ListByFollowing(ctx context.Context, limit int, view
 			Where("follower_id = ?", viewerAccountID)
 		query = query.Where("author_id IN (?)", followingSubQuery)
 	}
-	if !latestBefore.IsZero() {
-		query = query.Where("create_time < ?", latestBefore)
+	query = applyFollowingCursor(query, cursor)
+	if err := query.Limit(limit).Find(&videos).Error; err != nil {
+		return nil, err
 	}
+	return videos, nil
+}
+
+func (repo *FeedRepository) ListByAuthorIDs(ctx context.Context, authorIDs []uint, limit int, cursor *FollowingCursor) ([]*video.Video, error) {
+	var videos []*video.Video
+	if len(authorIDs) == 0 {
+		return videos, nil
+	}
+	query := repo.db.WithContext(ctx).Model(&video.Video{}).
+		Scopes(followingVisibleVideos).
+		Where("author_id IN ?", authorIDs).
+		Order("create_time DESC, id DESC")
+	query = applyFollowingCursor(query, cursor)
 	if err := query.Limit(limit).Find(&videos).Error; err != nil {
 		return nil, err
 	}
 	return videos, nil
 }
 
+// readyVideos 只放出转码完成且不在回收站的视频；从 video_tags 等表 JOIN 进来时 GORM 不会自动加软删除条件
+func readyVideos(db *gorm.DB) *gorm.DB {
+	return db.Where("videos.status = ? AND videos.deleted_at IS NULL", video.VideoStatusReady)
+}
+
+// publicVideos 面向所有人的列表（最新、热榜、话题、推荐）只放公开视频
+func publicVideos(db *gorm.DB) *gorm.DB {
+	return readyVideos(db).Where("videos.visibility = ?", video.VisibilityPublic)
+}
+
+// followingVisibleVideos 关注流里作者都是观看者关注的人，粉丝可见的视频也能放出
+func followingVisibleVideos(db *gorm.DB) *gorm.DB {
+	return readyVideos(db).Where("videos.visibility IN ?", video.FollowingVisibilities)
+}
+
+func applyFollowingCursor(query *gorm.DB, cursor *FollowingCursor) *gorm.DB {
+	if cursor == nil {
+		return query
+	}
+	before := time.UnixMilli(cursor.CreateTime)
+	return query.Where(
+		"(create_time < ?) OR (create_time = ? AND id < ?)",
+		before,
+		before, cursor.ID,
+	)
+}
+
 func (repo *FeedRepository) ListByPopularity(ctx context.Context, limit int, popularityBefore int64, timeBefore time.Time, idBefore uint) ([]*video.Video, error) {
 	var videos []*video.Video
 	query := repo.db.WithContext(ctx).Model(&video.Video{}).
+		Scopes(publicVideos).
 		Order("popularity DESC, create_time DESC, id DESC")
 
 	// 只有当游标完整提供时才加过滤（popularity 允许为 0）
@@ -96,20 +141,103 @@ func (repo *FeedRepository) GetByIDs(ctx context.Context, ids []uint) ([]*video.
 		return videos, nil
 	}
 	if err := repo.db.WithContext(ctx).Model(&video.Video{}).
+		Scopes(readyVideos).
 		Where("id IN ?", ids).Find(&videos).Error; err != nil {
 		return nil, err
 	}
 	return videos, nil
 }
 
-func (repo *FeedRepository) ListByTag(ctx context.Context, tagName string, limit int) ([]*video.Video, error) {
+func (repo *FeedRepository) ListByTag(ctx context.Context, tagName string, limit int, cursor *FollowingCursor) ([]*video.Video, error) {
 	var videos []*video.Video
-	err := repo.db.WithContext(ctx).Model(&video.Video{}).Table("videos").
+	query := repo.db.WithContext(ctx).Model(&video.Video{}).Table("videos").
 		Joins("JOIN video_tags ON video_tags.video_id = videos.id").
 		Joins("JOIN tags ON tags.id = video_tags.tag_id").
+		Scopes(publicVideos).
 		Where("tags.name = ?", tagName).
-		Order("videos.create_time desc").
-		Limit(limit).
-		Find(&videos).Error
+		Order("videos.create_time desc, videos.id desc")
+	if cursor != nil {
+		before := time.UnixMilli(cursor.CreateTime)
+		query = query.Where(
+			"(videos.create_time < ?) OR (videos.create_time = ? AND videos.id < ?)",
+			before,
+			before, cursor.ID,
+		)
+	}
+	err := query.Limit(limit).Find(&videos).Error
 	return videos, err
 }
+
+// ListByTagIDs 带有任一标签的视频，用子查询去重（一个视频命中多个标签只返回一次）
+func (repo *FeedRepository) ListByTagIDs(ctx context.Context, tagIDs []uint, limit int, cursor *FollowingCursor) ([]*video.Video, error) {
+	var videos []*video.Video
+	if len(tagIDs) == 0 {
+		return videos, nil
+	}
+	taggedSubQuery := repo.db.WithContext(ctx).
+		Model(&video.VideoTag{}).
+		Select("video_id").
+		Where("tag_id IN ?", tagIDs)
+	query := repo.db.WithContext(ctx).Model(&video.Video{}).
+		Scopes(publicVideos).
+		Where("id IN (?)", taggedSubQuery).
+		Order("create_time DESC, id DESC")
+	query = applyFollowingCursor(query, cursor)
+	if err := query.Limit(limit).Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// TagAffinity 观看者对某个标签的兴趣：最近点赞视频中带该标签的次数
+type TagAffinity struct {
+	TagID uint
+	Count int64
+}
+
+// ListEngagedTags 从最近 likeWindow 个点赞视频的 VideoTag 统计标签兴趣，按次数倒序
+func (repo *FeedRepository) ListEngagedTags(ctx context.Context, accountID uint, likeWindow, limit int) ([]TagAffinity, error) {
+	var likedIDs []uint
+	if err := repo.db.WithContext(ctx).Model(&video.Like{}).
+		Where("account_id = ?", accountID).
+		Order("id DESC").
+		Limit(likeWindow).
+		Pluck("video_id", &likedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(likedIDs) == 0 {
+		return nil, nil
+	}
+	var tags []TagAffinity
+	err := repo.db.WithContext(ctx).Model(&video.VideoTag{}).
+		Select("tag_id, COUNT(*) AS count").
+		Where("video_id IN ?", likedIDs).
+		Group("tag_id").
+		Order("count DESC, tag_id DESC").
+		Limit(limit).
+		Scan(&tags).Error
+	return tags, err
+}
+
+// VideoTagHit 候选视频命中的标签
+type VideoTagHit struct {
+	VideoID uint
+	TagID   uint
+}
+
+// ListTagHits 取带有这些标签的最新视频（每行一个命中的标签），按视频发布时间倒序
+func (repo *FeedRepository) ListTagHits(ctx context.Context, tagIDs []uint, limit int) ([]VideoTagHit, error) {
+	var hits []VideoTagHit
+	if len(tagIDs) == 0 {
+		return hits, nil
+	}
+	err := repo.db.WithContext(ctx).Model(&video.VideoTag{}).
+		Select("video_tags.video_id, video_tags.tag_id").
+		Joins("JOIN videos ON videos.id = video_tags.video_id").
+		Scopes(publicVideos).
+		Where("video_tags.tag_id IN ?", tagIDs).
+		Order("videos.create_time DESC, videos.id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	return hits, err
+}
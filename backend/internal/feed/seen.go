@@ -0,0 +1,160 @@
+package feed
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/video"
+)
+
+const (
+	// 每段一个 2^17 bit（16KB）的布隆过滤器，k=4；每天 5000 次曝光时误判率约 0.04%
+	seenBits   = 1 << 17
+	seenHashes = 4
+	// 按天分段，保留最近 3 段：看过的视频约 2~3 天后重新放出
+	seenSegment  = 24 * time.Hour
+	seenSegments = 3
+	// 单次上报的曝光数上限
+	MaxImpressionBatch = 100
+	// 过滤已看后最多再回源补几轮，避免整页都被看过时无限扫描
+	seenBackfillRounds = 3
+)
+
+// SeenSet 每个观看者的已看集合：Redis bitmap 实现的分段布隆过滤器，整段过期即自动遗忘
+type SeenSet struct {
+	cache *rediscache.Client
+	now   func() time.Time
+}
+
+func NewSeenSet(cache *rediscache.Client) *SeenSet {
+	if cache == nil {
+		return nil
+	}
+	return &SeenSet{cache: cache, now: time.Now}
+}
+
+func (s *SeenSet) segmentKey(accountID uint, segment int64) string {
+	return s.cache.Key("feed:seen:%d:%d", accountID, segment)
+}
+
+func (s *SeenSet) currentSegment() int64 {
+	return s.now().Unix() / int64(seenSegment/time.Second)
+}
+
+// seenOffsets 双重哈希得到 k 个 bit 位置
+func seenOffsets(videoID uint) []int64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatUint(uint64(videoID), 10)))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	offsets := make([]int64, seenHashes)
+	for i := range offsets {
+		offsets[i] = int64((h1 + uint64(i)*h2) % seenBits)
+	}
+	return offsets
+}
+
+// Add 记录曝光，只写当前段
+func (s *SeenSet) Add(ctx context.Context, accountID uint, videoIDs []uint) error {
+	if s == nil || accountID == 0 || len(videoIDs) == 0 {
+		return nil
+	}
+	offsets := make([]int64, 0, len(videoIDs)*seenHashes)
+	for _, id := range videoIDs {
+		offsets = append(offsets, seenOffsets(id)...)
+	}
+	return s.cache.SetBits(ctx, s.segmentKey(accountID, s.currentSegment()), offsets, seenSegment*seenSegments)
+}
+
+// Seen 任意一段命中全部 k 个 bit 即视为看过
+func (s *SeenSet) Seen(ctx context.Context, accountID uint, videoIDs []uint) (map[uint]bool, error) {
+	seen := make(map[uint]bool)
+	if s == nil || accountID == 0 || len(videoIDs) == 0 {
+		return seen, nil
+	}
+	cur := s.currentSegment()
+	keys := make([]string, seenSegments)
+	for i := range keys {
+		keys[i] = s.segmentKey(accountID, cur-int64(i))
+	}
+	offsets := make([]int64, 0, len(videoIDs)*seenHashes)
+	for _, id := range videoIDs {
+		offsets = append(offsets, seenOffsets(id)...)
+	}
+	bits, err := s.cache.GetBits(ctx, keys, offsets)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range videoIDs {
+		for _, seg := range bits {
+			hit := true
+			for _, b := range seg[i*seenHashes : (i+1)*seenHashes] {
+				if !b {
+					hit = false
+					break
+				}
+			}
+			if hit {
+				seen[id] = true
+				break
+			}
+		}
+	}
+	return seen, nil
+}
+
+// ReportImpressions 客户端上报真正展示过的视频
+func (f *FeedService) ReportImpressions(ctx context.Context, viewerAccountID uint, videoIDs []uint) error {
+	return f.seen.Add(ctx, viewerAccountID, videoIDs)
+}
+
+// pageFetcher 从 after（nil 表示从请求游标开始）之后取 n 条；more 表示源里可能还有
+type pageFetcher func(after *video.Video, n int) (videos []*video.Video, more bool, err error)
+
+// unseenPage 按源顺序拉取并跳过观看者已看或无权查看的视频，不够一页时继续往后补，直到凑满 limit 或源耗尽。
+// last 是最后扫描过的视频（可能被过滤掉），下一页游标要从它之后开始
+func (f *FeedService) unseenPage(ctx context.Context, viewerAccountID uint, limit int, fetch pageFetcher) (kept []*video.Video, last *video.Video, more bool, err error) {
+	filter := f.seen != nil && viewerAccountID != 0
+	kept = make([]*video.Video, 0, limit)
+	for round := 0; len(kept) < limit && round <= seenBackfillRounds; round++ {
+		batch, batchMore, err := fetch(last, limit)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		more = batchMore
+		seen := map[uint]bool{}
+		if filter && len(batch) > 0 {
+			ids := make([]uint, len(batch))
+			for i, v := range batch {
+				ids[i] = v.ID
+			}
+			if seen, err = f.seen.Seen(ctx, viewerAccountID, ids); err != nil {
+				// 已看集合不可用时不过滤，保证列表可用
+				log.Printf("seen set unavailable, skip filtering: %v", err)
+				seen, filter = map[uint]bool{}, false
+			}
+		}
+		hidden, err := f.hiddenFrom(ctx, viewerAccountID, batch)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		for _, v := range batch {
+			if len(kept) == limit {
+				more = true
+				break
+			}
+			last = v
+			if !seen[v.ID] && !hidden[v.ID] {
+				kept = append(kept, v)
+			}
+		}
+		if !more || (!filter && len(hidden) == 0) {
+			break
+		}
+	}
+	return kept, last, more, nil
+}
@@ -0,0 +1,150 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/video"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestSeenSet(t *testing.T, now *time.Time) *SeenSet {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	client := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "")
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	s := NewSeenSet(client)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestSeenSetExpiresBySegment(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestSeenSet(t, &now)
+	ctx := context.Background()
+
+	if err := s.Add(ctx, 1, []uint{10, 11}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	seen, err := s.Seen(ctx, 1, []uint{10, 11, 12})
+	if err != nil {
+		t.Fatalf("seen: %v", err)
+	}
+	if !seen[10] || !seen[11] || seen[12] {
+		t.Fatalf("unexpected seen set %v", seen)
+	}
+	if other, _ := s.Seen(ctx, 2, []uint{10}); other[10] {
+		t.Fatalf("seen set leaked across viewers")
+	}
+
+	// 跨段后仍然记得，超出保留段数后重新放出
+	now = now.Add(seenSegment)
+	if seen, _ := s.Seen(ctx, 1, []uint{10}); !seen[10] {
+		t.Fatalf("expected video to stay seen in the next segment")
+	}
+	now = now.Add(seenSegment * seenSegments)
+	if seen, _ := s.Seen(ctx, 1, []uint{10}); seen[10] {
+		t.Fatalf("expected video to be forgotten after %d segments", seenSegments)
+	}
+}
+
+func TestUnseenPageBackfills(t *testing.T) {
+	now := time.Now()
+	s := newTestSeenSet(t, &now)
+	f := &FeedService{seen: s}
+	ctx := context.Background()
+
+	var all []*video.Video
+	for id := uint(1); id <= 10; id++ {
+		all = append(all, &video.Video{ID: id})
+	}
+	fetch := func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		start := 0
+		if after != nil {
+			start = int(after.ID)
+		}
+		end := min(start+n, len(all))
+		return all[start:end], end < len(all), nil
+	}
+	if err := s.Add(ctx, 1, []uint{1, 2, 3, 5}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	kept, last, more, err := f.unseenPage(ctx, 1, 3, fetch)
+	if err != nil {
+		t.Fatalf("unseen page: %v", err)
+	}
+	if len(kept) != 3 || kept[0].ID != 4 || kept[1].ID != 6 || kept[2].ID != 7 {
+		t.Fatalf("expected [4 6 7], got %v", kept)
+	}
+	if last.ID != 7 || !more {
+		t.Fatalf("expected cursor at 7 with more, got %d %v", last.ID, more)
+	}
+
+	// 匿名用户不过滤
+	kept, last, _, _ = f.unseenPage(ctx, 0, 3, fetch)
+	if len(kept) != 3 || kept[0].ID != 1 || last.ID != 3 {
+		t.Fatalf("expected anonymous page [1 2 3], got %v", kept)
+	}
+
+	// 源耗尽时 has_more=false
+	kept, _, more, _ = f.unseenPage(ctx, 1, 3, func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		if after != nil {
+			return nil, false, nil
+		}
+		return all[:2], false, nil
+	})
+	if len(kept) != 0 || more {
+		t.Fatalf("expected empty exhausted page, got %v %v", kept, more)
+	}
+}
+
+func TestUnseenPageSkipsHiddenVideos(t *testing.T) {
+	f := &FeedService{}
+	ctx := context.Background()
+
+	all := []*video.Video{
+		{ID: 1, AuthorID: 7},
+		{ID: 2, AuthorID: 7, Visibility: video.VisibilityPrivate},
+		{ID: 3, AuthorID: 8, Visibility: video.VisibilityUnlisted},
+		{ID: 4, AuthorID: 8, Visibility: video.VisibilityFollowers},
+		{ID: 5, AuthorID: 7, Visibility: video.VisibilityPublic},
+		{ID: 6, AuthorID: 8},
+	}
+	fetch := func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		start := 0
+		if after != nil {
+			start = int(after.ID)
+		}
+		end := min(start+n, len(all))
+		return all[start:end], end < len(all), nil
+	}
+
+	// 匿名用户也要补页，跳过私密、仅链接和粉丝可见的视频
+	kept, last, _, err := f.unseenPage(ctx, 0, 3, fetch)
+	if err != nil {
+		t.Fatalf("unseen page: %v", err)
+	}
+	if len(kept) != 3 || kept[0].ID != 1 || kept[1].ID != 5 || kept[2].ID != 6 {
+		t.Fatalf("expected [1 5 6], got %v", kept)
+	}
+	if last.ID != 6 {
+		t.Fatalf("expected cursor at 6, got %d", last.ID)
+	}
+
+	// 作者本人能看到自己的私密视频
+	kept, _, _, _ = f.unseenPage(ctx, 7, 2, fetch)
+	if len(kept) != 2 || kept[0].ID != 1 || kept[1].ID != 2 {
+		t.Fatalf("expected author page [1 2], got %v", kept)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/social"
 	"feedsystem_video_go/internal/video"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -19,6 +21,12 @@ import (
 type FeedService struct {
 	repo         *FeedRepository
 	likeRepo     *video.LikeRepository
+	socialRepo   *social.SocialRepository
+	inbox        *Inbox
+	tags         FollowedTags
+	seen         *SeenSet
+	ranker       Ranker
+	quotas       Quotas
 	rediscache   *rediscache.Client
 	localcache   *cache.Cache
 	cacheTTL     time.Duration
@@ -29,8 +37,8 @@ type CachedFeedData struct {
 	PublicVideos []video.Video `json:"public_videos"`
 }
 
-func NewFeedService(repo *FeedRepository, likeRepo *video.LikeRepository, rediscache *rediscache.Client) *FeedService {
-	return &FeedService{repo: repo, likeRepo: likeRepo, rediscache: rediscache, localcache: cache.New(3*time.Second, 5*time.Second), cacheTTL: 24 * time.Hour}
+func NewFeedService(repo *FeedRepository, likeRepo *video.LikeRepository, socialRepo *social.SocialRepository, rediscache *rediscache.Client) *FeedService {
+	return &FeedService{repo: repo, likeRepo: likeRepo, socialRepo: socialRepo, inbox: NewInbox(rediscache), seen: NewSeenSet(rediscache), ranker: NewDefaultRanker(), quotas: DefaultQuotas, rediscache: rediscache, localcache: cache.New(3*time.Second, 5*time.Second), cacheTTL: 24 * time.Hour}
 }
 
 func (f *FeedService) GetVideoByIDs(ctx context.Context, videoIDs []uint) ([]*video.Video, error) {
@@ -145,17 +153,45 @@ func (f *FeedService) GetVideoByIDs(ctx context.Context, videoIDs []uint) ([]*vi
 	return buildOrderedResult(videoIDs, videoMap), nil
 }
 
-// 查询最新视频 (冷热分离 + 游标分页)
+// 查询最新视频 (冷热分离 + 游标分页)，跳过观看者已看过的视频
 func (f *FeedService) ListLatest(ctx context.Context, limit int, latestBefore time.Time, viewerAccountID uint) (ListLatestResponse, error) {
+	videos, last, more, err := f.unseenPage(ctx, viewerAccountID, limit, func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		before := latestBefore
+		if after != nil {
+			before = after.CreateTime
+		}
+		vs, err := f.listLatestVideos(ctx, n, before)
+		return vs, len(vs) == n, err
+	})
+	if err != nil {
+		return ListLatestResponse{}, err
+	}
+	feedVideos, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
+	if err != nil {
+		return ListLatestResponse{}, err
+	}
+	var nextTime int64
+	if last != nil {
+		// 将本页最后扫描到的视频时间作为下一次请求的游标
+		nextTime = last.CreateTime.UnixMilli()
+	}
+	return ListLatestResponse{
+		VideoList: feedVideos,
+		NextTime:  nextTime,
+		HasMore:   more,
+	}, nil
+}
+
+func (f *FeedService) listLatestVideos(ctx context.Context, limit int, latestBefore time.Time) ([]*video.Video, error) {
 	if f.rediscache == nil {
-		return f.listLatestFromDB(ctx, limit, latestBefore, viewerAccountID)
+		return f.repo.ListLatest(ctx, limit, latestBefore)
 	}
 
 	// 获取 ZSET 中最老的一条数据
 	zsetTail, err := f.rediscache.ZRangeWithScores(ctx, f.rediscache.Key("feed:global_timeline"), 0, 0)
 
 	if err != nil {
-		return f.listLatestFromDB(ctx, limit, latestBefore, viewerAccountID)
+		return f.repo.ListLatest(ctx, limit, latestBefore)
 	}
 
 	isZsetEmpty := len(zsetTail) == 0
@@ -189,14 +225,14 @@ func (f *FeedService) ListLatest(ctx context.Context, limit int, latestBefore ti
 		})
 
 		if err != nil {
-			return ListLatestResponse{}, err
+			return nil, err
 		}
 		if v == "EMPTY_DB" {
-			return ListLatestResponse{HasMore: false}, nil
+			return nil, nil
 		}
 
 		// 让所有被阻塞的请求重新查一遍
-		return f.ListLatest(ctx, limit, latestBefore, viewerAccountID)
+		return f.listLatestVideos(ctx, limit, latestBefore)
 	}
 
 	watermark := int64(zsetTail[0].Score)
@@ -216,7 +252,7 @@ func (f *FeedService) ListLatest(ctx context.Context, limit int, latestBefore ti
 			return f.repo.ListLatest(ctx, limit, latestBefore)
 		})
 		if err != nil {
-			return ListLatestResponse{}, err
+			return nil, err
 		}
 		baseVideos = v.([]*video.Video)
 		// 不回写 ZSET，防止冷数据污染热点时间线
@@ -230,7 +266,7 @@ func (f *FeedService) ListLatest(ctx context.Context, limit int, latestBefore ti
 
 		videoIDsStr, err := f.rediscache.ZRevRangeByScore(ctx, f.rediscache.Key("feed:global_timeline"), maxScore, "-inf", 0, int64(limit))
 		if err != nil {
-			return ListLatestResponse{}, err
+			return nil, err
 		}
 
 		var videoIDs []uint
@@ -243,7 +279,7 @@ func (f *FeedService) ListLatest(ctx context.Context, limit int, latestBefore ti
 		if len(videoIDs) > 0 {
 			baseVideos, err = f.GetVideoByIDs(ctx, videoIDs)
 			if err != nil {
-				return ListLatestResponse{}, err
+				return nil, err
 			}
 		}
 
@@ -270,62 +306,31 @@ func (f *FeedService) ListLatest(ctx context.Context, limit int, latestBefore ti
 		}
 	}
 
-	var nextTime int64
-	if len(baseVideos) > 0 {
-		// 将本页最后一条视频的时间作为下一次请求的游标
-		nextTime = baseVideos[len(baseVideos)-1].CreateTime.UnixMilli()
-	}
-	hasMore := len(baseVideos) == limit
-
-	feedVideos, err := f.buildFeedVideos(ctx, baseVideos, viewerAccountID)
-	if err != nil {
-		return ListLatestResponse{}, err
-	}
-
-	return ListLatestResponse{
-		VideoList: feedVideos,
-		NextTime:  nextTime,
-		HasMore:   hasMore,
-	}, nil
-}
-
-func (f *FeedService) listLatestFromDB(ctx context.Context, limit int, latestBefore time.Time, viewerAccountID uint) (ListLatestResponse, error) {
-	videos, err := f.repo.ListLatest(ctx, limit, latestBefore)
-	if err != nil {
-		return ListLatestResponse{}, err
-	}
-	feedVideos, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
-	if err != nil {
-		return ListLatestResponse{}, err
-	}
-	var nextTime int64
-	if len(videos) > 0 {
-		nextTime = videos[len(videos)-1].CreateTime.UnixMilli()
-	}
-	return ListLatestResponse{
-		VideoList: feedVideos,
-		NextTime:  nextTime,
-		HasMore:   len(videos) == limit,
-	}, nil
+	return baseVideos, nil
 }
 
 // 按照点赞数查询视频
 func (f *FeedService) ListLikesCount(ctx context.Context, limit int, cursor *LikesCountCursor, viewerAccountID uint) (ListLikesCountResponse, error) {
-	videos, err := f.repo.ListLikesCountWithCursor(ctx, limit, cursor)
+	videos, last, more, err := f.unseenPage(ctx, viewerAccountID, limit, func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		c := cursor
+		if after != nil {
+			c = &LikesCountCursor{LikesCount: after.LikesCount, ID: after.ID}
+		}
+		vs, err := f.repo.ListLikesCountWithCursor(ctx, n, c)
+		return vs, len(vs) == n, err
+	})
 	if err != nil {
 		return ListLikesCountResponse{}, err
 	}
-	hasMore := len(videos) == limit
 	feedVideos, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
 	if err != nil {
 		return ListLikesCountResponse{}, err
 	}
 	resp := ListLikesCountResponse{
 		VideoList: feedVideos,
-		HasMore:   hasMore,
+		HasMore:   more,
 	}
-	if len(videos) > 0 {
-		last := videos[len(videos)-1]
+	if last != nil {
 		nextLikesCountBefore := last.LikesCount
 		nextIDBefore := last.ID
 		resp.NextLikesCountBefore = &nextLikesCountBefore
@@ -334,146 +339,226 @@ func (f *FeedService) ListLikesCount(ctx context.Context, limit int, cursor *Lik
 	return resp, nil
 }
 
-// 按照关注列表查询视频
-func (f *FeedService) ListByFollowing(ctx context.Context, limit int, latestBefore time.Time, viewerAccountID uint) (ListByFollowingResponse, error) {
-	doListByFollowingFromDB := func() (ListByFollowingResponse, error) {
-		videos, err := f.repo.ListByFollowing(ctx, limit, viewerAccountID, latestBefore)
+// FollowedTags 提供观看者关注的话题 ID（带缓存），由 video.TagService 实现
+type FollowedTags interface {
+	FollowedTagIDs(ctx context.Context, accountID uint) ([]uint, error)
+}
+
+// SetFollowedTags 开启关注流的话题合并；未设置时 include_tags 不生效
+func (f *FeedService) SetFollowedTags(tags FollowedTags) {
+	f.tags = tags
+}
+
+// 按照关注列表查询视频（推拉结合）
+// 普通作者：发布时由 FanoutWorker 写入粉丝收件箱 feed:inbox:<id>
+// 大 V（粉丝数 >= PushFollowerLimit）：读时从 MySQL 拉取，与收件箱合并后按 (create_time, id) 倒序分页
+// includeTags 为 true 时再合并已关注话题下的视频，与作者视频去重后共用同一个游标
+func (f *FeedService) ListByFollowing(ctx context.Context, limit int, cursor *FollowingCursor, viewerAccountID uint, includeTags bool) (ListByFollowingResponse, error) {
+	var tagIDs []uint
+	if includeTags && f.tags != nil && viewerAccountID != 0 {
+		ids, err := f.tags.FollowedTagIDs(ctx, viewerAccountID)
 		if err != nil {
 			return ListByFollowingResponse{}, err
 		}
-		var nextTime int64
-		if len(videos) > 0 {
-			nextTime = videos[len(videos)-1].CreateTime.Unix()
-		} else {
-			nextTime = 0
+		tagIDs = ids
+	}
+	videos, last, more, err := f.unseenPage(ctx, viewerAccountID, limit, func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		c := cursor
+		if after != nil {
+			c = &FollowingCursor{CreateTime: after.CreateTime.UnixMilli(), ID: after.ID}
 		}
-		hasMore := len(videos) == limit
-		feedVideos, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
-		if err != nil {
-			return ListByFollowingResponse{}, err
+		vs, err := f.listFollowingVideos(ctx, n, c, viewerAccountID)
+		if err != nil || len(tagIDs) == 0 {
+			return vs, len(vs) == n, err
 		}
-		resp := ListByFollowingResponse{
-			VideoList: feedVideos,
-			NextTime:  nextTime,
-			HasMore:   hasMore,
+		tagged, err := f.repo.ListByTagIDs(ctx, tagIDs, n, c)
+		if err != nil {
+			return nil, false, err
 		}
-		return resp, nil
+		// 任一来源取满都可能还有下一页；自己发的视频不通过话题出现在关注流里
+		more := len(vs) == n || len(tagged) == n
+		return mergeByTime(n, func(v *video.Video) bool { return v.AuthorID != viewerAccountID }, vs, tagged), more, nil
+	})
+	if err != nil {
+		return ListByFollowingResponse{}, err
 	}
-	var cacheKey string
-	if viewerAccountID != 0 && f.rediscache != nil {
-		before := int64(0)
-		if !latestBefore.IsZero() {
-			before = latestBefore.Unix()
+	feedVideos, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
+	if err != nil {
+		return ListByFollowingResponse{}, err
+	}
+	resp := ListByFollowingResponse{
+		VideoList: feedVideos,
+		HasMore:   more,
+	}
+	if last != nil {
+		resp.NextTime = last.CreateTime.Unix()
+		resp.NextCursor = EncodeFollowingCursor(FollowingCursor{CreateTime: last.CreateTime.UnixMilli(), ID: last.ID})
+	}
+	return resp, nil
+}
+
+func (f *FeedService) listFollowingVideos(ctx context.Context, limit int, cursor *FollowingCursor, viewerAccountID uint) ([]*video.Video, error) {
+	if f.inbox == nil || f.socialRepo == nil || viewerAccountID == 0 {
+		return f.repo.ListByFollowing(ctx, limit, viewerAccountID, cursor)
+	}
+
+	vloggerIDs, err := f.socialRepo.ListVloggerIDs(ctx, viewerAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(vloggerIDs) == 0 {
+		return nil, nil
+	}
+	pullAuthors, err := f.socialRepo.FilterByFollowerCount(ctx, vloggerIDs, PushFollowerLimit)
+	if err != nil {
+		return nil, err
+	}
+	following := make(map[uint]bool, len(vloggerIDs))
+	for _, id := range vloggerIDs {
+		following[id] = true
+	}
+	isPull := make(map[uint]bool, len(pullAuthors))
+	for _, id := range pullAuthors {
+		isPull[id] = true
+	}
+	pushAuthors := make([]uint, 0, len(vloggerIDs))
+	for _, id := range vloggerIDs {
+		if !isPull[id] {
+			pushAuthors = append(pushAuthors, id)
 		}
-		cacheKey = f.rediscache.Key("feed:listByFollowing:limit=%d:accountID=%d:before=%d", limit, viewerAccountID, before)
-		cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
-		defer cancel()
+	}
 
-		b, err := f.rediscache.GetBytes(cacheCtx, cacheKey)
-		if err == nil {
-			var cached ListByFollowingResponse
-			if err := json.Unmarshal(b, &cached); err == nil {
-				return cached, nil
-			}
-		} else if rediscache.IsMiss(err) { // 缓存未命中
-			lockKey := "lock:" + cacheKey
-			// 缓存未命中，尝试加锁
-			token, locked, _ := f.rediscache.Lock(cacheCtx, lockKey, 500*time.Millisecond)
-			if locked {
-				defer func() { _ = f.rediscache.Unlock(context.Background(), lockKey, token) }()
-				if b, err := f.rediscache.GetBytes(cacheCtx, cacheKey); err == nil {
-					var cached ListByFollowingResponse
-					if err := json.Unmarshal(b, &cached); err == nil {
-						return cached, nil
-					}
-				} else { // 缓存未命中，从数据库中查询
-					resp, err := doListByFollowingFromDB()
-					if err != nil {
-						return ListByFollowingResponse{}, err
-					}
-					if b, err := json.Marshal(resp); err == nil {
-						_ = f.rediscache.SetBytes(cacheCtx, cacheKey, b, f.cacheTTL)
-					}
-					return resp, nil
-				}
-			} else {
-				for i := 0; i < 5; i++ {
-					time.Sleep(20 * time.Millisecond)
-					if b, err := f.rediscache.GetBytes(cacheCtx, cacheKey); err == nil {
-						var cached ListByFollowingResponse
-						if err := json.Unmarshal(b, &cached); err == nil {
-							return cached, nil
-						}
-					}
-				}
+	pushed, err := f.listFromInbox(ctx, viewerAccountID, pushAuthors, limit, cursor)
+	if err != nil {
+		log.Printf("following inbox unavailable, fallback to MySQL: %v", err)
+		return f.repo.ListByFollowing(ctx, limit, viewerAccountID, cursor)
+	}
+	pulled, err := f.repo.ListByAuthorIDs(ctx, pullAuthors, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeFollowingVideos(limit, following, pushed, pulled), nil
+}
+
+// listFromInbox 收件箱不存在时（冷启动/关注关系变化/过期）按普通作者回源重建
+func (f *FeedService) listFromInbox(ctx context.Context, viewerAccountID uint, pushAuthors []uint, limit int, cursor *FollowingCursor) ([]*video.Video, error) {
+	exists, err := f.inbox.Exists(ctx, viewerAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		sfKey := f.rediscache.Key("sf:inbox:rebuild:%d", viewerAccountID)
+		_, err, _ := f.requestGroup.Do(sfKey, func() (interface{}, error) {
+			videos, err := f.repo.ListByAuthorIDs(ctx, pushAuthors, inboxRebuildSize, nil)
+			if err != nil {
+				return nil, err
 			}
+			return nil, f.inbox.Rebuild(ctx, viewerAccountID, videos)
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	resp, err := doListByFollowingFromDB()
+	// 多取一些，抵消已取关作者的过滤
+	ids, err := f.inbox.Range(ctx, viewerAccountID, cursor, limit*2)
 	if err != nil {
-		return ListByFollowingResponse{}, err
+		return nil, err
 	}
-	if cacheKey != "" {
-		if b, err := json.Marshal(resp); err == nil {
-			cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
-			defer cancel()
-			_ = f.rediscache.SetBytes(cacheCtx, cacheKey, b, f.cacheTTL)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return f.GetVideoByIDs(ctx, ids)
+}
+
+// mergeFollowingVideos 去重、过滤已取关作者，按 (create_time, id) 倒序截取 limit 条
+func mergeFollowingVideos(limit int, following map[uint]bool, sources ...[]*video.Video) []*video.Video {
+	return mergeByTime(limit, func(v *video.Video) bool { return following[v.AuthorID] }, sources...)
+}
+
+// mergeByTime 去重并只保留 keep 为 true 的视频，按 (create_time, id) 倒序截取 limit 条
+func mergeByTime(limit int, keep func(*video.Video) bool, sources ...[]*video.Video) []*video.Video {
+	seen := make(map[uint]bool)
+	merged := make([]*video.Video, 0, limit)
+	for _, src := range sources {
+		for _, v := range src {
+			if v == nil || seen[v.ID] || !keep(v) {
+				continue
+			}
+			seen[v.ID] = true
+			merged = append(merged, v)
 		}
 	}
-	return resp, nil
+	sort.SliceStable(merged, func(i, j int) bool {
+		ti, tj := merged[i].CreateTime.UnixMilli(), merged[j].CreateTime.UnixMilli()
+		if ti != tj {
+			return ti > tj
+		}
+		return merged[i].ID > merged[j].ID
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
 }
 
-func (f *FeedService) ListByPopularity(ctx context.Context, limit int, reqAsOf int64, offset int, viewerAccountID uint, latestPopularity int64, latestBefore time.Time, latestIDBefore uint) (ListByPopularityResponse, error) {
-	// Redis 热榜（稳定分页：as_of + offset）
+func (f *FeedService) ListByPopularity(ctx context.Context, limit int, cursor *PopularityCursor, reqAsOf int64, offset int, viewerAccountID uint, latestPopularity int64, latestBefore time.Time, latestIDBefore uint) (ListByPopularityResponse, error) {
+	// Redis 热榜（稳定分页：as_of 快照 + (score, id) 游标）
 	if f.rediscache != nil {
 		asOf := time.Now().UTC().Truncate(time.Minute)
-		if reqAsOf > 0 {
+		switch {
+		case cursor != nil:
+			asOf = time.Unix(cursor.AsOf, 0).UTC().Truncate(time.Minute)
+		case reqAsOf > 0:
 			asOf = time.Unix(reqAsOf, 0).UTC().Truncate(time.Minute)
 		}
 
-		const win = 60
-		keys := make([]string, 0, win)
-		for i := 0; i < win; i++ {
-			keys = append(keys, f.rediscache.Key("hot:video:1m:%s", asOf.Add(-time.Duration(i)*time.Minute).Format("200601021504")))
-		}
-
-		dest := f.rediscache.Key("hot:video:merge:1m:%s", asOf.Format("200601021504")) // 快照key：同一个as_of页内复用
 		opCtx, cancel := context.WithTimeout(ctx, 80*time.Millisecond)
-		defer cancel()
-
-		exists, _ := f.rediscache.Exists(opCtx, dest)
-		if !exists {
-			_ = f.rediscache.ZUnionStore(opCtx, dest, keys, "SUM")
-			_ = f.rediscache.Expire(opCtx, dest, 2*time.Minute) // 给翻页留时间
+		dest := f.hotSnapshot(opCtx, asOf)
+		var after *hotEntry
+		var err error
+		if cursor != nil {
+			after = &hotEntry{score: cursor.Score, id: cursor.ID}
+		} else if offset > 0 {
+			// 旧客户端按 offset 翻页：以快照里第 offset 名之前的那条作为起点
+			after, err = f.hotEntryAt(opCtx, dest, offset-1)
 		}
+		cancel()
 
-		start := int64(offset)
-		stop := start + int64(limit) - 1
-		members, err := f.rediscache.ZRevRange(opCtx, dest, start, stop)
-		if err == nil && len(members) == 0 {
-			if offset > 0 {
-				return ListByPopularityResponse{
-					VideoList:  []FeedVideoItem{},
-					AsOf:       asOf.Unix(),
-					NextOffset: offset,
-					HasMore:    false,
-				}, nil
-			}
-		}
-		if err == nil && len(members) > 0 {
-			ids := make([]uint, 0, len(members))
-			for _, m := range members {
-				u, err := strconv.ParseUint(m, 10, 64)
-				if err == nil && u > 0 {
-					ids = append(ids, uint(u))
+		// pos 为已经从快照里取过的条数；rank/scores 记录每条视频的名次和分数，用于生成下一页游标
+		pos := offset
+		rank := make(map[uint]int)
+		scores := make(map[uint]float64)
+		var videos []*video.Video
+		var last *video.Video
+		var more bool
+		if err == nil && (after != nil || offset == 0) {
+			videos, last, more, err = f.unseenPage(ctx, viewerAccountID, limit, func(_ *video.Video, n int) ([]*video.Video, bool, error) {
+				// 每轮补齐单独计时，前几轮耗时不挤占后面的 Redis 读取
+				roundCtx, cancel := context.WithTimeout(ctx, 80*time.Millisecond)
+				entries, more, err := f.hotRange(roundCtx, dest, after, n)
+				cancel()
+				if err != nil {
+					return nil, false, err
 				}
-			}
-
-			videos, err := f.repo.GetByIDs(ctx, ids)
-			if err == nil {
-				byID := make(map[uint]*video.Video, len(videos))
-				for _, v := range videos {
+				ids := make([]uint, len(entries))
+				for i, e := range entries {
+					ids[i] = e.id
+					rank[e.id] = pos + i
+					scores[e.id] = e.score
+				}
+				pos += len(entries)
+				if len(entries) == 0 {
+					return nil, false, nil
+				}
+				after = &entries[len(entries)-1]
+				vs, err := f.repo.GetByIDs(ctx, ids)
+				if err != nil {
+					return nil, false, err
+				}
+				byID := make(map[uint]*video.Video, len(vs))
+				for _, v := range vs {
 					byID[v.ID] = v
 				}
 				ordered := make([]*video.Video, 0, len(ids))
@@ -482,31 +567,48 @@ func (f *FeedService) ListByPopularity(ctx context.Context, limit int, reqAsOf i
 						ordered = append(ordered, v)
 					}
 				}
-				items, err := f.buildFeedVideos(ctx, ordered, viewerAccountID)
-				if err != nil {
-					return ListByPopularityResponse{}, err
-				}
-				resp := ListByPopularityResponse{
-					VideoList:  items,
-					AsOf:       asOf.Unix(),
-					NextOffset: offset + len(items),
-					HasMore:    len(items) == limit,
-				}
-				if len(ordered) > 0 {
-					last := ordered[len(ordered)-1]
-					nextPopularity := last.Popularity
-					nextBefore := last.CreateTime
-					nextID := last.ID
-					resp.NextLatestPopularity = &nextPopularity
-					resp.NextLatestBefore = &nextBefore
-					resp.NextLatestIDBefore = &nextID
+				return ordered, more, nil
+			})
+		}
+		// 快照为空且是第一页时回源 MySQL；读 Redis 出错同样回源
+		if err == nil && (cursor != nil || offset > 0 || pos > 0) {
+			items, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
+			if err != nil {
+				return ListByPopularityResponse{}, err
+			}
+			nextOffset := pos
+			if last != nil && more {
+				nextOffset = rank[last.ID] + 1
+			}
+			resp := ListByPopularityResponse{
+				VideoList:  items,
+				AsOf:       asOf.Unix(),
+				NextOffset: nextOffset,
+				HasMore:    more,
+			}
+			if last != nil {
+				if more {
+					resp.NextCursor = EncodePopularityCursor(PopularityCursor{AsOf: asOf.Unix(), Score: scores[last.ID], ID: last.ID})
 				}
-				return resp, nil
+				nextPopularity := last.Popularity
+				nextBefore := last.CreateTime
+				nextID := last.ID
+				resp.NextLatestPopularity = &nextPopularity
+				resp.NextLatestBefore = &nextBefore
+				resp.NextLatestIDBefore = &nextID
 			}
+			return resp, nil
 		}
 	}
 
-	videos, err := f.repo.ListByPopularity(ctx, limit, latestPopularity, latestBefore, latestIDBefore)
+	videos, last, more, err := f.unseenPage(ctx, viewerAccountID, limit, func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		popularity, before, idBefore := latestPopularity, latestBefore, latestIDBefore
+		if after != nil {
+			popularity, before, idBefore = after.Popularity, after.CreateTime, after.ID
+		}
+		vs, err := f.repo.ListByPopularity(ctx, n, popularity, before, idBefore)
+		return vs, len(vs) == n, err
+	})
 	if err != nil {
 		return ListByPopularityResponse{}, err
 	}
@@ -518,10 +620,9 @@ func (f *FeedService) ListByPopularity(ctx context.Context, limit int, reqAsOf i
 		VideoList:  items,
 		AsOf:       0,
 		NextOffset: 0,
-		HasMore:    len(items) == limit,
+		HasMore:    more,
 	}
-	if len(videos) > 0 {
-		last := videos[len(videos)-1]
+	if last != nil {
 		nextPopularity := last.Popularity
 		nextBefore := last.CreateTime
 		nextID := last.ID
@@ -532,6 +633,23 @@ func (f *FeedService) ListByPopularity(ctx context.Context, limit int, reqAsOf i
 	return resp, nil
 }
 
+// hotSnapshot 合并最近 60 个分钟窗口为热榜快照，同一个 as_of 内复用
+func (f *FeedService) hotSnapshot(ctx context.Context, asOf time.Time) string {
+	const win = 60
+	keys := make([]string, 0, win)
+	for i := 0; i < win; i++ {
+		keys = append(keys, f.rediscache.Key("hot:video:1m:%s", asOf.Add(-time.Duration(i)*time.Minute).Format("200601021504")))
+	}
+
+	dest := f.rediscache.Key("hot:video:merge:1m:%s", asOf.Format("200601021504"))
+	exists, _ := f.rediscache.Exists(ctx, dest)
+	if !exists {
+		_ = f.rediscache.ZUnionStore(ctx, dest, keys, "SUM")
+		_ = f.rediscache.Expire(ctx, dest, 2*time.Minute) // 给翻页留时间
+	}
+	return dest
+}
+
 func (f *FeedService) buildFeedVideos(ctx context.Context, videos []*video.Video, viewerAccountID uint) ([]FeedVideoItem, error) {
 	feedVideos := make([]FeedVideoItem, 0, len(videos))
 	videoIDs := make([]uint, len(videos))
@@ -552,6 +670,7 @@ func (f *FeedService) buildFeedVideos(ctx context.Context, videos []*video.Video
 			CoverURL:    video.CoverURL,
 			CreateTime:  video.CreateTime.Unix(),
 			LikesCount:  video.LikesCount,
+			PlayCount:   video.PlayCount,
 			IsLiked:     likedMap[video.ID],
 		})
 	}
@@ -568,10 +687,26 @@ func buildOrderedResult(orderedIDs []uint, dataMap map[uint]*video.Video) []*vid
 	return res
 }
 
-func (f *FeedService) ListByTag(ctx context.Context, tagName string, limit int, viewerAccountID uint) ([]FeedVideoItem, error) {
-	videos, err := f.repo.ListByTag(ctx, tagName, limit)
+// ListByTag 话题下的视频，按 (create_time, id) 倒序游标分页
+func (f *FeedService) ListByTag(ctx context.Context, tagName string, limit int, cursor *FollowingCursor, viewerAccountID uint) (ListByTagResponse, error) {
+	videos, last, more, err := f.unseenPage(ctx, viewerAccountID, limit, func(after *video.Video, n int) ([]*video.Video, bool, error) {
+		c := cursor
+		if after != nil {
+			c = &FollowingCursor{CreateTime: after.CreateTime.UnixMilli(), ID: after.ID}
+		}
+		vs, err := f.repo.ListByTag(ctx, tagName, n, c)
+		return vs, len(vs) == n, err
+	})
 	if err != nil {
-		return nil, err
+		return ListByTagResponse{}, err
 	}
-	return f.buildFeedVideos(ctx, videos, viewerAccountID)
+	feedVideos, err := f.buildFeedVideos(ctx, videos, viewerAccountID)
+	if err != nil {
+		return ListByTagResponse{}, err
+	}
+	resp := ListByTagResponse{VideoList: feedVideos, HasMore: more}
+	if last != nil {
+		resp.NextCursor = EncodeFollowingCursor(FollowingCursor{CreateTime: last.CreateTime.UnixMilli(), ID: last.ID})
+	}
+	return resp, nil
 }
@@ -0,0 +1,42 @@
+package feed
+
+import (
+	"context"
+
+	"feedsystem_video_go/internal/video"
+)
+
+// hiddenFrom 返回观看者无权看到的视频。SQL 查询已经按可见范围过滤，这里兜底
+// 走缓存的路径（全站时间线、收件箱、热榜、实体缓存），视频发布后改了可见范围也不会漏出去
+func (f *FeedService) hiddenFrom(ctx context.Context, viewerAccountID uint, videos []*video.Video) (map[uint]bool, error) {
+	hidden := make(map[uint]bool)
+	var pending []*video.Video
+	authorSet := make(map[uint]bool)
+	for _, v := range videos {
+		switch {
+		case v.CanView(viewerAccountID, false, ""):
+		case v.NeedsFollowCheck(viewerAccountID) && f.socialRepo != nil:
+			pending = append(pending, v)
+			authorSet[v.AuthorID] = true
+		default:
+			hidden[v.ID] = true
+		}
+	}
+	if len(pending) == 0 {
+		return hidden, nil
+	}
+	authorIDs := make([]uint, 0, len(authorSet))
+	for id := range authorSet {
+		authorIDs = append(authorIDs, id)
+	}
+	following, _, err := f.socialRepo.ListRelations(ctx, viewerAccountID, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range pending {
+		if !following[v.AuthorID] {
+			hidden[v.ID] = true
+		}
+	}
+	return hidden, nil
+}
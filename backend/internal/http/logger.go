@@ -0,0 +1,50 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveQueryParams 出现在 URL 上的凭证：SSE 的 token、WebSocket 的 ticket
+var sensitiveQueryParams = []string{"token", "ticket", "share_token"}
+
+// accessLogger 与 gin.Logger 格式一致，但把 query 里的凭证替换掉再写日志
+func accessLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			redactQuery(p.Path),
+			p.ErrorMessage,
+		)
+	})
+}
+
+func redactQuery(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+	q, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		// 解析不了就整段去掉，宁可少记也不漏凭证
+		return path[:i] + "?REDACTED"
+	}
+	changed := false
+	for _, k := range sensitiveQueryParams {
+		if _, ok := q[k]; ok {
+			q.Set(k, "REDACTED")
+			changed = true
+		}
+	}
+	if !changed {
+		return path
+	}
+	return path[:i+1] + q.Encode()
+}
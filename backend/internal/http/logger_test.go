@@ -0,0 +1,18 @@
+package http
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	cases := map[string]string{
+		"/message/ws?ticket=abc&since=3":     "/message/ws?since=3&ticket=REDACTED",
+		"/notification/stream?token=eyJ.x.y": "/notification/stream?token=REDACTED",
+		"/video/list?page=2":                 "/video/list?page=2",
+		"/healthz":                           "/healthz",
+		"/message/ws?ticket=%zz":             "/message/ws?REDACTED",
+	}
+	for in, want := range cases {
+		if got := redactQuery(in); got != want {
+			t.Errorf("redactQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
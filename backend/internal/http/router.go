@@ -3,13 +3,17 @@ package http
 import (
 	"context"
 	"feedsystem_video_go/internal/account"
+	"feedsystem_video_go/internal/config"
+	"feedsystem_video_go/internal/creator"
 	"feedsystem_video_go/internal/feed"
 	"feedsystem_video_go/internal/message"
 	"feedsystem_video_go/internal/middleware/jwt"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	"feedsystem_video_go/internal/middleware/ratelimit"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/search"
 	"feedsystem_video_go/internal/social"
+	"feedsystem_video_go/internal/storage"
 	"feedsystem_video_go/internal/video"
 	"feedsystem_video_go/internal/worker"
 	"log"
@@ -19,15 +23,15 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *gin.Engine {
-	r := gin.Default()
+func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ, store storage.Store, cfg config.Config) *gin.Engine {
+	r := gin.New()
+	r.Use(accessLogger(), gin.Recovery())
 	if err := r.SetTrustedProxies(nil); err != nil {
 		log.Printf("SetTrustedProxies failed: %v", err)
 	}
 	r.GET("/healthz", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
-	r.Static("/static", "./.run/uploads")
 	// rate_limit
 	loginLimiter := ratelimit.Limit(cache, "account_login", 10, time.Minute, ratelimit.KeyByIP)
 	registerLimiter := ratelimit.Limit(cache, "account_register", 5, time.Hour, ratelimit.KeyByIP)
@@ -35,11 +39,16 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 	likeLimiter := ratelimit.Limit(cache, "like_write", 30, time.Minute, ratelimit.KeyByAccount)
 	commentLimiter := ratelimit.Limit(cache, "comment_write", 10, time.Minute, ratelimit.KeyByAccount)
 	socialLimiter := ratelimit.Limit(cache, "social_write", 20, time.Minute, ratelimit.KeyByAccount)
+	tagFollowLimiter := ratelimit.Limit(cache, "tag_follow_write", 20, time.Minute, ratelimit.KeyByAccount)
+	playLimiter := ratelimit.Limit(cache, "play_report", 120, time.Minute, ratelimit.KeyByIP)
+	searchLimiter := ratelimit.Limit(cache, "search", 60, time.Minute, ratelimit.KeyByIP)
+	// 输入联想每次按键都会请求，额度放宽
+	typeaheadLimiter := ratelimit.Limit(cache, "search_typeahead", 300, time.Minute, ratelimit.KeyByIP)
 
 	// account
 	accountRepository := account.NewAccountRepository(db)
 	accountService := account.NewAccountService(accountRepository, cache)
-	accountHandler := account.NewAccountHandler(accountService)
+	accountHandler := account.NewAccountHandler(accountService, store)
 	accountGroup := r.Group("/account")
 	{
 		accountGroup.POST("/register", registerLimiter, accountHandler.CreateAccount)
@@ -59,18 +68,34 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 	}
 	// video
 	videoRepository := video.NewVideoRepository(db)
-	popularityMQ, err := rabbitmq.NewPopularityMQ(rmq)
+	socialRepository := social.NewSocialRepository(db)
+	// 静态文件：带上登录态，作者和粉丝才能读到非公开视频的文件
+	staticHandler := storage.ServeHandler(store, video.NewStaticAccess(videoRepository, socialRepository, store))
+	staticAuth := jwt.SoftJWTAuth(accountRepository, cache)
+	r.GET("/static/*key", staticAuth, staticHandler)
+	r.HEAD("/static/*key", staticAuth, staticHandler)
+	// 转码请求经 outbox 投递，RabbitMQ 不可用时 relay 不会启动，只能直接以原文件发布
+	transcodeEnabled := cfg.Transcode.Enabled && rmq != nil
+	if cfg.Transcode.Enabled && !transcodeEnabled {
+		log.Printf("Transcode disabled: rabbitmq is not initialized")
+	}
+	videoService := video.NewVideoService(videoRepository, cache, transcodeEnabled, socialRepository)
+	videoHandler := video.NewVideoHandler(videoService, accountService, store)
+	go video.NewScheduler(videoService, cache).Run(context.Background())
+	go video.NewPurger(videoRepository, store, cache).Run(context.Background())
+	chunkHandler := video.NewChunkUploadHandler(cache, store)
+	playMQ, err := rabbitmq.NewPlayMQ(rmq)
 	if err != nil {
-		log.Printf("PopularityMQ init failed (mq disabled): %v", err)
-		popularityMQ = nil
+		log.Printf("PlayMQ init failed (mq disabled): %v", err)
+		playMQ = nil
 	}
-	videoService := video.NewVideoService(videoRepository, cache, popularityMQ)
-	videoHandler := video.NewVideoHandler(videoService, accountService)
-	chunkHandler := video.NewChunkUploadHandler(cache)
+	playRecorder := video.NewPlayRecorder(video.NewPlayRepository(db), cache)
+	playHandler := video.NewPlayHandler(video.NewPlayService(playRecorder, cache, playMQ))
 	videoGroup := r.Group("/video")
 	{
-		videoGroup.POST("/listByAuthorID", videoHandler.ListByAuthorID)
-		videoGroup.POST("/getDetail", videoHandler.GetDetail)
+		videoGroup.POST("/listByAuthorID", jwt.SoftJWTAuth(accountRepository, cache), videoHandler.ListByAuthorID)
+		videoGroup.POST("/getDetail", jwt.SoftJWTAuth(accountRepository, cache), videoHandler.GetDetail)
+		videoGroup.POST("/reportPlay", jwt.SoftJWTAuth(accountRepository, cache), playLimiter, playHandler.ReportPlay)
 	}
 	protectedVideoGroup := videoGroup.Group("")
 	protectedVideoGroup.Use(jwt.JWTAuth(accountRepository, cache))
@@ -78,19 +103,25 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 		protectedVideoGroup.POST("/uploadVideo", videoHandler.UploadVideo)
 		protectedVideoGroup.POST("/uploadCover", videoHandler.UploadCover)
 		protectedVideoGroup.POST("/publish", videoHandler.PublishVideo)
+		protectedVideoGroup.POST("/update", videoHandler.UpdateVideo)
+		protectedVideoGroup.POST("/delete", videoHandler.DeleteVideo)
+		protectedVideoGroup.POST("/trash/list", videoHandler.ListTrash)
+		protectedVideoGroup.POST("/trash/restore", videoHandler.RestoreVideo)
+		protectedVideoGroup.POST("/editHistory", videoHandler.ListEdits)
+		protectedVideoGroup.POST("/setVisibility", videoHandler.SetVisibility)
+		protectedVideoGroup.POST("/shareToken", videoHandler.ShareToken)
+		protectedVideoGroup.POST("/draft/list", videoHandler.ListDrafts)
+		protectedVideoGroup.POST("/draft/update", videoHandler.UpdateDraft)
+		protectedVideoGroup.POST("/draft/publish", videoHandler.PublishDraft)
+		protectedVideoGroup.POST("/draft/cancel", videoHandler.CancelDraft)
 		protectedVideoGroup.POST("/chunk/init", chunkHandler.InitChunkUpload)
 		protectedVideoGroup.POST("/chunk/upload", chunkHandler.UploadChunk)
 		protectedVideoGroup.POST("/chunk/status", chunkHandler.ChunkStatus)
 		protectedVideoGroup.POST("/chunk/complete", chunkHandler.CompleteChunkUpload)
 	}
 	// like
-	likeMQ, err := rabbitmq.NewLikeMQ(rmq)
-	if err != nil {
-		log.Printf("LikeMQ init failed (mq disabled): %v", err)
-		likeMQ = nil
-	}
 	likeRepository := video.NewLikeRepository(db)
-	likeService := video.NewLikeService(likeRepository, videoRepository, cache, likeMQ, popularityMQ)
+	likeService := video.NewLikeService(likeRepository, videoRepository, socialRepository, cache)
 	likeHandler := video.NewLikeHandler(likeService)
 	likeGroup := r.Group("/like")
 	protectedLikeGroup := likeGroup.Group("")
@@ -103,31 +134,26 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 	}
 	// comment
 	commentRepository := video.NewCommentRepository(db)
-	commentMQ, err := rabbitmq.NewCommentMQ(rmq)
-	if err != nil {
-		log.Printf("CommentMQ init failed (mq disabled): %v", err)
-		commentMQ = nil
-	}
-	commentService := video.NewCommentService(commentRepository, videoRepository, cache, commentMQ, popularityMQ)
+	commentService := video.NewCommentService(commentRepository, videoRepository, socialRepository, cache)
 	commentHandler := video.NewCommentHandler(commentService, accountService)
 	commentGroup := r.Group("/comment")
+	commentSoftAuth := jwt.SoftJWTAuth(accountRepository, cache)
 	{
-		commentGroup.POST("/listAll", commentHandler.GetAllComments)
+		commentGroup.POST("/listAll", commentSoftAuth, commentHandler.GetAllComments)
+		commentGroup.POST("/listTop", commentSoftAuth, commentHandler.ListTopComments)
+		commentGroup.POST("/listReplies", commentSoftAuth, commentHandler.ListReplies)
 	}
 	protectedCommentGroup := commentGroup.Group("")
 	protectedCommentGroup.Use(jwt.JWTAuth(accountRepository, cache))
 	{
 		protectedCommentGroup.POST("/publish", commentLimiter, commentHandler.PublishComment)
 		protectedCommentGroup.POST("/delete", commentLimiter, commentHandler.DeleteComment)
+		protectedCommentGroup.POST("/like", likeLimiter, commentHandler.LikeComment)
+		protectedCommentGroup.POST("/unlike", likeLimiter, commentHandler.UnlikeComment)
+		protectedCommentGroup.POST("/pin", commentLimiter, commentHandler.PinComment)
 	}
 	// social
-	socialMQ, err := rabbitmq.NewSocialMQ(rmq)
-	if err != nil {
-		log.Printf("SocialMQ init failed (mq disabled): %v", err)
-		socialMQ = nil
-	}
-	socialRepository := social.NewSocialRepository(db)
-	socialService := social.NewSocialService(socialRepository, accountRepository, socialMQ)
+	socialService := social.NewSocialService(socialRepository, accountRepository, feed.NewInbox(cache))
 	socialHandler := social.NewSocialHandler(socialService)
 	socialGroup := r.Group("/social")
 	protectedSocialGroup := socialGroup.Group("")
@@ -166,10 +192,23 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 			FollowerCount: followerCount, VloggerCount: vloggerCount,
 		})
 	})
+	// creator
+	statService := creator.NewStatService(creator.NewStatRepository(db), videoRepository)
+	statHandler := creator.NewStatHandler(statService)
+	creatorGroup := r.Group("/creator")
+	creatorGroup.Use(jwt.JWTAuth(accountRepository, cache))
+	{
+		creatorGroup.POST("/stats", statHandler.GetStats)
+	}
 	// feed
 	feedRepository := feed.NewFeedRepository(db)
-	feedService := feed.NewFeedService(feedRepository, likeRepository, cache)
+	feedService := feed.NewFeedService(feedRepository, likeRepository, socialRepository, cache)
 	feedHandler := feed.NewFeedHandler(feedService)
+	go func() {
+		if err := feedService.RunCacheInvalidation(context.Background()); err != nil {
+			log.Printf("feed cache invalidation stopped: %v", err)
+		}
+	}()
 	feedGroup := r.Group("/feed")
 	feedGroup.Use(jwt.SoftJWTAuth(accountRepository, cache))
 	{
@@ -177,22 +216,90 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 		feedGroup.POST("/listLikesCount", feedHandler.ListLikesCount)
 		feedGroup.POST("/listByPopularity", feedHandler.ListByPopularity)
 		feedGroup.POST("/listByTag", feedHandler.ListByTag)
+		feedGroup.POST("/listForYou", feedHandler.ListForYou)
 	}
 	protectedFeedGroup := feedGroup.Group("")
 	protectedFeedGroup.Use(jwt.JWTAuth(accountRepository, cache))
 	{
 		protectedFeedGroup.POST("/listByFollowing", feedHandler.ListByFollowing)
+		protectedFeedGroup.POST("/reportImpressions", feedHandler.ReportImpressions)
 	}
 	// message
 	messageRepo := message.NewRepository(db)
-	messageService := message.NewService(messageRepo)
+	messageHub := newMessageHub(cache, cfg.Notification)
+	go messageHub.Run(context.Background())
+	messageService := message.NewService(messageRepo, messageHub)
 	messageHandler := message.NewHandler(messageService)
+	messageWSHandler := message.NewWSHandler(messageHub, messageService, cfg.Server.AllowedOrigins)
 	messageGroup := r.Group("/message")
+	messageGroup.GET("/ws", jwt.WSTicketAuth(cache), messageWSHandler.Connect)
 	protectedMessageGroup := messageGroup.Group("")
 	protectedMessageGroup.Use(jwt.JWTAuth(accountRepository, cache))
 	{
+		protectedMessageGroup.POST("/wsTicket", messageWSHandler.Ticket)
 		protectedMessageGroup.POST("/send", messageHandler.Send)
 		protectedMessageGroup.POST("/list", messageHandler.List)
+		protectedMessageGroup.POST("/conversations", messageHandler.ListConversations)
+		protectedMessageGroup.POST("/markRead", messageHandler.MarkRead)
+		protectedMessageGroup.POST("/unreadCount", messageHandler.UnreadCount)
+	}
+	// tag
+	tagService := video.NewTagService(video.NewTagRepository(db), cache)
+	tagHandler := video.NewTagHandler(tagService)
+	feedService.SetFollowedTags(tagService)
+	tagGroup := r.Group("/tag")
+	{
+		tagGroup.POST("/trending", tagHandler.Trending)
+		tagGroup.POST("/detail", jwt.SoftJWTAuth(accountRepository, cache), tagHandler.Detail)
+	}
+	protectedTagGroup := tagGroup.Group("")
+	protectedTagGroup.Use(jwt.JWTAuth(accountRepository, cache))
+	{
+		protectedTagGroup.POST("/follow", tagFollowLimiter, tagHandler.Follow)
+		protectedTagGroup.POST("/unfollow", tagFollowLimiter, tagHandler.Unfollow)
+		protectedTagGroup.POST("/listFollowed", tagHandler.ListFollowed)
+	}
+	// search
+	searchMQ, err := rabbitmq.NewSearchMQ(rmq)
+	if err != nil {
+		log.Printf("SearchMQ init failed (mq disabled): %v", err)
+		searchMQ = nil
+	}
+	searchIndex := search.NewMemoryIndex()
+	searchRepository := search.NewSearchRepository(db)
+	searchIndexer := search.NewIndexer(searchIndex, searchRepository, searchMQ)
+	videoService.SetSearchSync(searchIndexer)
+	go func() {
+		if err := searchIndexer.Rebuild(context.Background()); err != nil {
+			log.Printf("search index rebuild failed: %v", err)
+		}
+		if searchMQ == nil {
+			return
+		}
+		if err := searchIndexer.Run(context.Background()); err != nil {
+			log.Printf("search indexer stopped: %v", err)
+		}
+	}()
+	searchHandler := search.NewSearchHandler(search.NewSearchService(searchIndex, searchRepository))
+	userIndex := search.NewUserIndex()
+	userIndexer := search.NewUserIndexer(userIndex, accountRepository, searchMQ)
+	accountService.SetProfileSync(userIndexer)
+	go func() {
+		if err := userIndexer.Rebuild(context.Background()); err != nil {
+			log.Printf("user index rebuild failed: %v", err)
+		}
+		if searchMQ == nil {
+			return
+		}
+		if err := userIndexer.Run(context.Background()); err != nil {
+			log.Printf("user indexer stopped: %v", err)
+		}
+	}()
+	userSearchHandler := search.NewUserSearchHandler(search.NewUserSearchService(userIndex, socialRepository))
+	searchGroup := r.Group("/search")
+	{
+		searchGroup.POST("/videos", searchLimiter, searchHandler.SearchVideos)
+		searchGroup.POST("/users", jwt.SoftJWTAuth(accountRepository, cache), typeaheadLimiter, userSearchHandler.SearchUsers)
 	}
 	//worker
 	timelineMQ, err := rabbitmq.NewTimelineMQ(rmq)
@@ -200,7 +307,7 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 		log.Printf("timelineMQ init failed (mq disabled): %v", err)
 		timelineMQ = nil
 	}
-	worker.StartOutboxPoller(db, timelineMQ)
+	worker.StartOutboxRelay(db, rmq, searchIndexer, tagService, videoService)
 	worker.StartConsumer(timelineMQ, "video.timeline.update.queue", cache)
 
 	// SSE notification
@@ -215,10 +322,13 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 			log.Printf("notification social topic init failed: %v", err)
 		}
 	}
-	sseHub := worker.NewSSEHub(db)
+	sseHub := newSSEHub(db, cache, cfg.Notification)
+	go sseHub.Run(context.Background())
 	notifGroup := r.Group("/notification")
 	notifGroup.Use(sseHub.SSERequireAuth())
 	sseHub.RegisterRoutes(r, notifGroup)
+	notifPrefs := worker.NewPreferenceStore(db, cache)
+	notifPrefs.RegisterRoutes(notifGroup)
 
 	go func() {
 		if rmq != nil && rmq.Ch != nil {
@@ -232,7 +342,7 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 					return
 				}
 				defer ch.Close()
-				w := worker.NewNotificationWorker(ch, db, "notification.like", hub)
+				w := worker.NewNotificationWorker(ch, db, "notification.like", hub, notifPrefs)
 				if err := w.Run(ctx); err != nil {
 					log.Printf("notification-like worker: %v", err)
 				}
@@ -244,7 +354,7 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 					return
 				}
 				defer ch.Close()
-				w := worker.NewNotificationWorker(ch, db, "notification.comment", hub)
+				w := worker.NewNotificationWorker(ch, db, "notification.comment", hub, notifPrefs)
 				if err := w.Run(ctx); err != nil {
 					log.Printf("notification-comment worker: %v", err)
 				}
@@ -256,7 +366,7 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 					return
 				}
 				defer ch.Close()
-				w := worker.NewNotificationWorker(ch, db, "notification.social", hub)
+				w := worker.NewNotificationWorker(ch, db, "notification.social", hub, notifPrefs)
 				if err := w.Run(ctx); err != nil {
 					log.Printf("notification-social worker: %v", err)
 				}
@@ -268,3 +378,24 @@ func SetRouter(db *gorm.DB, cache *rediscache.Client, rmq *rabbitmq.RabbitMQ) *g
 
 	return r
 }
+
+// newSSEHub 按配置选择通知总线；Redis 不可用时退化为进程内总线
+func newSSEHub(db *gorm.DB, cache *rediscache.Client, cfg config.NotificationConfig) *worker.SSEHub {
+	if cfg.Bus == "redis" {
+		if cache != nil {
+			instanceID := worker.NewInstanceID()
+			log.Printf("Notification bus: redis (instance %s)", instanceID)
+			return worker.NewSSEHub(db, worker.NewRedisBus(cache), worker.NewRedisPresence(cache, instanceID))
+		}
+		log.Printf("Notification bus: redis unavailable, falling back to memory")
+	}
+	return worker.NewSSEHub(db, worker.NewMemoryBus(), worker.NewMemoryPresence())
+}
+
+// newMessageHub 私信推送与 SSE 通知共用总线配置：redis 时发给用户连在任意实例上的所有设备
+func newMessageHub(cache *rediscache.Client, cfg config.NotificationConfig) *message.Hub {
+	if cfg.Bus == "redis" && cache != nil {
+		return message.NewHub(message.NewRedisBus(cache))
+	}
+	return message.NewHub(nil)
+}
@@ -0,0 +1,106 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+// Bus 跨实例分发私信推送：任意实例按接收方 Publish，所有实例收到后推给该用户在本实例的连接
+type Bus interface {
+	Publish(ctx context.Context, env Envelope) error
+	// Subscribe 阻塞直到 ctx 结束或订阅断开
+	Subscribe(ctx context.Context, deliver func(env Envelope)) error
+}
+
+// Envelope 发给某个用户所有设备的一帧；NeedAck 为 true 时按 ack 机制投递并重发
+type Envelope struct {
+	UserID  uint  `json:"user_id"`
+	Frame   Frame `json:"frame"`
+	NeedAck bool  `json:"need_ack,omitempty"`
+}
+
+// MemoryBus 进程内实现，适用于单实例部署和测试
+type MemoryBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]func(env Envelope)
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[int]func(env Envelope))}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, env Envelope) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, deliver := range b.subs {
+		deliver(env)
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, deliver func(env Envelope)) error {
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	b.subs[id] = deliver
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.subs, id)
+	b.mu.Unlock()
+	return ctx.Err()
+}
+
+// RedisBus 基于 Redis pub/sub，与 SSE 通知总线同样的做法，所有 API 实例订阅同一个 channel
+type RedisBus struct {
+	cache   *rediscache.Client
+	channel string
+}
+
+func NewRedisBus(cache *rediscache.Client) *RedisBus {
+	return &RedisBus{cache: cache, channel: cache.Key("message:bus")}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, env Envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return b.cache.Publish(ctx, b.channel, payload)
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, deliver func(env Envelope)) error {
+	ps, err := b.cache.Subscribe(ctx, b.channel)
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+	msgs := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-msgs:
+			if !ok {
+				return errors.New("message bus subscription closed")
+			}
+			var env Envelope
+			if err := json.Unmarshal([]byte(m.Payload), &env); err != nil || env.UserID == 0 {
+				continue
+			}
+			deliver(env)
+		}
+	}
+}
+
+var (
+	_ Bus = (*MemoryBus)(nil)
+	_ Bus = (*RedisBus)(nil)
+)
@@ -11,15 +11,66 @@ type Message struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
 
+// Conversation 每个参与者各有一行：(owner, peer) 唯一，未读数和已读位置按 owner 维护
+type Conversation struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	OwnerID           uint      `gorm:"uniqueIndex:idx_conversation_owner_peer;index:idx_conversation_owner_last,priority:1;not null" json:"owner_id"`
+	PeerID            uint      `gorm:"uniqueIndex:idx_conversation_owner_peer;not null" json:"peer_id"`
+	LastMessageID     uint      `gorm:"index:idx_conversation_owner_last,priority:2;not null;default:0" json:"last_message_id"`
+	LastMessageFromID uint      `gorm:"not null;default:0" json:"last_message_from_id"`
+	LastMessage       string    `gorm:"type:varchar(255);not null;default:''" json:"last_message"`
+	LastMessageAt     time.Time `json:"last_message_at"`
+	UnreadCount       int64     `gorm:"not null;default:0" json:"unread_count"`
+	LastReadMessageID uint      `gorm:"not null;default:0" json:"last_read_message_id"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	PeerUsername  string `gorm:"-" json:"peer_username,omitempty"`
+	PeerAvatarURL string `gorm:"-" json:"peer_avatar_url,omitempty"`
+}
+
 type SendRequest struct {
 	ToID    uint   `json:"to_id"`
 	Content string `json:"content"`
 }
 
+// ListRequest Cursor 为上一页最早一条消息的 ID，0 表示从最新开始
 type ListRequest struct {
 	PeerID uint `json:"peer_id"`
+	Cursor uint `json:"cursor,omitempty"`
+	Limit  int  `json:"limit,omitempty"`
 }
 
 type ListResponse struct {
-	Messages []Message `json:"messages"`
+	Messages   []Message `json:"messages"`
+	NextCursor uint      `json:"next_cursor"`
+	HasMore    bool      `json:"has_more"`
+	// PeerLastReadID 对方已读到的消息 ID，用于展示已读回执
+	PeerLastReadID uint `json:"peer_last_read_id"`
+}
+
+// ListConversationsRequest Cursor 为上一页最后一个会话的 last_message_id
+type ListConversationsRequest struct {
+	Cursor uint `json:"cursor,omitempty"`
+	Limit  int  `json:"limit,omitempty"`
+}
+
+type ListConversationsResponse struct {
+	Conversations []Conversation `json:"conversations"`
+	NextCursor    uint           `json:"next_cursor"`
+	HasMore       bool           `json:"has_more"`
+}
+
+// MarkReadRequest MessageID 为 0 表示全部已读
+type MarkReadRequest struct {
+	PeerID    uint `json:"peer_id"`
+	MessageID uint `json:"message_id,omitempty"`
+}
+
+type WSTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
 }
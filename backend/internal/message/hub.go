@@ -0,0 +1,252 @@
+package message
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	FrameSend    = "send"    // 客户端发消息
+	FrameSent    = "sent"    // 发送结果，按 client_id 对应
+	FrameMessage = "message" // 新消息推送，需要 ack
+	FrameTyping  = "typing"  // 正在输入
+	FrameRead    = "read"    // 已读回执
+	FrameAck     = "ack"     // 客户端确认收到 ack_id
+	FramePing    = "ping"
+	FramePong    = "pong"
+	FrameError   = "error"
+)
+
+const (
+	clientSendBuffer = 64
+	maxDeliveries    = 3
+)
+
+// ackTimeout 超过该时间未确认即重发，测试中会调小
+var ackTimeout = 5 * time.Second
+
+// Frame WebSocket 上下行统一的 JSON 帧
+type Frame struct {
+	Type      string   `json:"type"`
+	AckID     uint64   `json:"ack_id,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	ToID      uint     `json:"to_id,omitempty"`
+	FromID    uint     `json:"from_id,omitempty"`
+	PeerID    uint     `json:"peer_id,omitempty"`
+	MessageID uint     `json:"message_id,omitempty"`
+	Content   string   `json:"content,omitempty"`
+	Message   *Message `json:"message,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type pendingFrame struct {
+	frame    Frame
+	sentAt   time.Time
+	attempts int
+}
+
+// client 一个设备上的一条连接；需要 ack 的帧在确认前留在 pending 里等待重发
+type client struct {
+	userID  uint
+	send    chan Frame
+	closed  chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	nextAck uint64
+	pending map[uint64]*pendingFrame
+}
+
+func newClient(userID uint) *client {
+	return &client{
+		userID:  userID,
+		send:    make(chan Frame, clientSendBuffer),
+		closed:  make(chan struct{}),
+		pending: make(map[uint64]*pendingFrame),
+	}
+}
+
+func (c *client) close() {
+	c.once.Do(func() { close(c.closed) })
+}
+
+// enqueue 非阻塞写入；缓冲满说明客户端太慢，直接断开让它重连后补拉
+func (c *client) enqueue(f Frame) bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+	select {
+	case c.send <- f:
+		return true
+	default:
+		c.close()
+		return false
+	}
+}
+
+func (c *client) deliver(f Frame) {
+	c.mu.Lock()
+	c.nextAck++
+	f.AckID = c.nextAck
+	c.pending[f.AckID] = &pendingFrame{frame: f, sentAt: time.Now(), attempts: 1}
+	c.mu.Unlock()
+	c.enqueue(f)
+}
+
+func (c *client) ack(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// redeliver 重发超时未确认的帧；超过次数则断开连接，由客户端重连补拉
+func (c *client) redeliver(now time.Time) {
+	var resend []Frame
+	c.mu.Lock()
+	for _, p := range c.pending {
+		if now.Sub(p.sentAt) < ackTimeout {
+			continue
+		}
+		if p.attempts >= maxDeliveries {
+			c.mu.Unlock()
+			c.close()
+			return
+		}
+		p.attempts++
+		p.sentAt = now
+		resend = append(resend, p.frame)
+	}
+	c.mu.Unlock()
+	for _, f := range resend {
+		c.enqueue(f)
+	}
+}
+
+// Hub 维护本实例上每个用户的所有在线连接。用户的设备可能连在不同实例上，
+// 推送先按接收方发到 bus，各实例收到后再推给本地连接；bus 为 nil 时只推本实例
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*client]struct{}
+	bus     Bus
+}
+
+func NewHub(bus Bus) *Hub {
+	return &Hub{clients: make(map[uint]map[*client]struct{}), bus: bus}
+}
+
+// Run 订阅 bus，断开后退避重连，直到 ctx 结束
+func (h *Hub) Run(ctx context.Context) {
+	if h.bus == nil {
+		return
+	}
+	backoff := time.Second
+	for {
+		err := h.bus.Subscribe(ctx, h.deliverLocal)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("message bus subscribe: %v (retry in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// publish 发给 userID 的所有设备；bus 发布失败时至少推给本实例的连接，其他设备重连后补拉
+func (h *Hub) publish(env Envelope) {
+	if h.bus == nil {
+		h.deliverLocal(env)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.bus.Publish(ctx, env); err != nil {
+		log.Printf("message bus publish to user %d failed: %v", env.UserID, err)
+		h.deliverLocal(env)
+	}
+}
+
+func (h *Hub) deliverLocal(env Envelope) {
+	for _, c := range h.connections(env.UserID) {
+		if env.NeedAck {
+			c.deliver(env.Frame)
+		} else {
+			c.enqueue(env.Frame)
+		}
+	}
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set, ok := h.clients[c.userID]
+	if !ok {
+		set = make(map[*client]struct{})
+		h.clients[c.userID] = set
+	}
+	set[c] = struct{}{}
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set := h.clients[c.userID]
+	delete(set, c)
+	if len(set) == 0 {
+		delete(h.clients, c.userID)
+	}
+	c.close()
+}
+
+func (h *Hub) connections(userID uint) []*client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]*client, 0, len(h.clients[userID]))
+	for c := range h.clients[userID] {
+		out = append(out, c)
+	}
+	return out
+}
+
+// Online 用户在本实例是否有连接
+func (h *Hub) Online(userID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
+
+// DeliverMessage 推给双方的所有设备（发送方的其他设备需要同步），客户端按 message.id 去重
+func (h *Hub) DeliverMessage(m *Message) {
+	if h == nil || m == nil {
+		return
+	}
+	for _, uid := range []uint{m.ToID, m.FromID} {
+		h.publish(Envelope{UserID: uid, Frame: Frame{Type: FrameMessage, Message: m}, NeedAck: true})
+	}
+}
+
+func (h *Hub) PushTyping(fromID, toID uint) {
+	if h == nil {
+		return
+	}
+	h.publish(Envelope{UserID: toID, Frame: Frame{Type: FrameTyping, FromID: fromID}})
+}
+
+// PushRead 已读回执推给对方，同时同步给自己的其他设备
+func (h *Hub) PushRead(userID, peerID, messageID uint) {
+	if h == nil {
+		return
+	}
+	f := Frame{Type: FrameRead, FromID: userID, PeerID: peerID, MessageID: messageID}
+	for _, uid := range []uint{peerID, userID} {
+		h.publish(Envelope{UserID: uid, Frame: f})
+	}
+}
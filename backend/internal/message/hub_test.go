@@ -0,0 +1,77 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func recvFrame(t *testing.T, c *client) Frame {
+	t.Helper()
+	select {
+	case f := <-c.send:
+		return f
+	case <-time.After(2 * time.Second):
+		t.Fatal("frame was not delivered")
+		return Frame{}
+	}
+}
+
+func TestHubDeliversToDevicesOnOtherInstances(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newHub := func() *Hub {
+		cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "v1:")
+		t.Cleanup(func() { _ = cache.Close() })
+		h := NewHub(NewRedisBus(cache))
+		go h.Run(ctx)
+		return h
+	}
+	hubA, hubB := newHub(), newHub()
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub("v1:message:bus")["v1:message:bus"] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("hubs did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 接收方 2 的手机连在 A、电脑连在 B；发送方 1 只连在 B
+	phone, desktop, sender := newClient(2), newClient(2), newClient(1)
+	hubA.register(phone)
+	hubB.register(desktop)
+	hubB.register(sender)
+
+	hubA.DeliverMessage(&Message{ID: 5, FromID: 1, ToID: 2, Content: "hi"})
+	for _, c := range []*client{phone, desktop, sender} {
+		f := recvFrame(t, c)
+		if f.Type != FrameMessage || f.Message == nil || f.Message.ID != 5 || f.AckID == 0 {
+			t.Fatalf("unexpected frame %+v", f)
+		}
+	}
+
+	hubB.PushTyping(1, 2)
+	for _, c := range []*client{phone, desktop} {
+		if f := recvFrame(t, c); f.Type != FrameTyping || f.FromID != 1 {
+			t.Fatalf("unexpected typing frame %+v", f)
+		}
+	}
+
+	hubA.PushRead(2, 1, 5)
+	for _, c := range []*client{sender, phone, desktop} {
+		if f := recvFrame(t, c); f.Type != FrameRead || f.MessageID != 5 {
+			t.Fatalf("unexpected read frame %+v", f)
+		}
+	}
+}
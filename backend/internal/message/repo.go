@@ -0,0 +1,329 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct{ db *gorm.DB }
+
+func NewRepository(db *gorm.DB) *Repository { return &Repository{db: db} }
+
+const (
+	previewRunes  = 80
+	backfillBatch = 500
+)
+
+// AutoMigrate 建表；conversations 是新建的或还是空表时，从已有消息一次性补出会话
+func (r *Repository) AutoMigrate(ctx context.Context) error {
+	existed := r.db.Migrator().HasTable(&Conversation{})
+	if err := r.db.WithContext(ctx).AutoMigrate(&Message{}, &Conversation{}); err != nil {
+		return err
+	}
+	if existed {
+		var n int64
+		if err := r.db.WithContext(ctx).Model(&Conversation{}).Limit(1).Count(&n).Error; err != nil || n > 0 {
+			return err
+		}
+	}
+	return r.BackfillConversations(ctx)
+}
+
+// BackfillConversations 按 messages 为每对 (owner, peer) 补会话行：最后一条消息、未读数和已读位置。
+// 和 Send 一致，回复过就视为读到了自己最后发出的那条；已有的会话行不覆盖，重复执行无副作用
+func (r *Repository) BackfillConversations(ctx context.Context) error {
+	type pairStat struct {
+		FromID   uint
+		ToID     uint
+		LastID   uint
+		Unread   int64
+		LastRead uint
+	}
+	var stats []pairStat
+	if err := r.db.WithContext(ctx).Model(&Message{}).
+		Select("from_id, to_id, MAX(id) AS last_id, " +
+			"SUM(CASE WHEN is_read THEN 0 ELSE 1 END) AS unread, " +
+			"MAX(CASE WHEN is_read THEN id ELSE 0 END) AS last_read").
+		Group("from_id, to_id").
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+
+	type pair struct{ owner, peer uint }
+	convs := make(map[pair]*Conversation)
+	get := func(owner, peer uint) *Conversation {
+		c, ok := convs[pair{owner, peer}]
+		if !ok {
+			c = &Conversation{OwnerID: owner, PeerID: peer}
+			convs[pair{owner, peer}] = c
+		}
+		return c
+	}
+	for _, s := range stats {
+		// 发送方那一行：最后一条消息，发出的都算已读
+		sender := get(s.FromID, s.ToID)
+		sender.LastMessageID = max(sender.LastMessageID, s.LastID)
+		sender.LastReadMessageID = max(sender.LastReadMessageID, s.LastID)
+		// 接收方那一行：最后一条消息、未读数和读到的位置
+		recipient := get(s.ToID, s.FromID)
+		recipient.LastMessageID = max(recipient.LastMessageID, s.LastID)
+		recipient.LastReadMessageID = max(recipient.LastReadMessageID, s.LastRead)
+		recipient.UnreadCount += s.Unread
+	}
+
+	for _, c := range convs {
+		if c.UnreadCount == 0 || c.LastReadMessageID == 0 {
+			continue
+		}
+		// 已读位置被自己的回复推到了未读消息之后，只数位置之后的未读
+		if err := r.db.WithContext(ctx).Model(&Message{}).
+			Where("from_id = ? AND to_id = ? AND id > ? AND is_read = ?", c.PeerID, c.OwnerID, c.LastReadMessageID, false).
+			Count(&c.UnreadCount).Error; err != nil {
+			return err
+		}
+	}
+
+	ids := make([]uint, 0, len(convs))
+	seen := make(map[uint]bool, len(convs))
+	for _, c := range convs {
+		if !seen[c.LastMessageID] {
+			seen[c.LastMessageID] = true
+			ids = append(ids, c.LastMessageID)
+		}
+	}
+	lasts := make(map[uint]Message, len(ids))
+	for start := 0; start < len(ids); start += backfillBatch {
+		var msgs []Message
+		if err := r.db.WithContext(ctx).Where("id IN ?", ids[start:min(start+backfillBatch, len(ids))]).Find(&msgs).Error; err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			lasts[m.ID] = m
+		}
+	}
+
+	rows := make([]Conversation, 0, len(convs))
+	for _, c := range convs {
+		last := lasts[c.LastMessageID]
+		c.LastMessageFromID = last.FromID
+		c.LastMessage = preview(last.Content)
+		c.LastMessageAt = last.CreatedAt
+		rows = append(rows, *c)
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(rows, backfillBatch).Error
+}
+
+// Send 写消息并同步更新双方会话：发送方视为已读，接收方未读数 +1
+func (r *Repository) Send(ctx context.Context, m *Message) error {
+	m.Content = strings.TrimSpace(m.Content)
+	if m.Content == "" {
+		return errors.New("content is required")
+	}
+	m.CreatedAt = time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 按 owner_id 固定加锁顺序，避免双方同时发消息时死锁；
+		// 持锁期间插入消息，保证 last_message_id 不会被较早的消息覆盖
+		first, second := m.FromID, m.ToID
+		if first > second {
+			first, second = second, first
+		}
+		for _, owner := range []uint{first, second} {
+			peer := m.FromID + m.ToID - owner
+			if err := lockConversation(tx, owner, peer, m.CreatedAt); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(m).Error; err != nil {
+			return err
+		}
+		last := map[string]interface{}{
+			"last_message_id":      m.ID,
+			"last_message_from_id": m.FromID,
+			"last_message":         preview(m.Content),
+			"last_message_at":      m.CreatedAt,
+		}
+		senderUpdates := map[string]interface{}{"last_read_message_id": m.ID, "unread_count": 0}
+		recipientUpdates := map[string]interface{}{"unread_count": gorm.Expr("unread_count + 1")}
+		for k, v := range last {
+			senderUpdates[k] = v
+			recipientUpdates[k] = v
+		}
+		if err := tx.Model(&Conversation{}).
+			Where("owner_id = ? AND peer_id = ?", m.FromID, m.ToID).
+			Updates(senderUpdates).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Conversation{}).
+			Where("owner_id = ? AND peer_id = ?", m.ToID, m.FromID).
+			Updates(recipientUpdates).Error
+	})
+}
+
+// lockConversation 不存在则创建，并对会话行加排他锁
+func lockConversation(tx *gorm.DB, ownerID, peerID uint, now time.Time) error {
+	conv := Conversation{OwnerID: ownerID, PeerID: peerID, LastMessageAt: now}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&conv).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("owner_id = ? AND peer_id = ?", ownerID, peerID).
+		First(&Conversation{}).Error
+}
+
+func preview(content string) string {
+	if utf8.RuneCountInString(content) <= previewRunes {
+		return content
+	}
+	return string([]rune(content)[:previewRunes]) + "…"
+}
+
+// List 按 ID 倒序翻页，cursor 为上一页最早一条消息的 ID
+func (r *Repository) List(ctx context.Context, userID, peerID uint, cursor uint, limit int) ([]Message, error) {
+	var msgs []Message
+	query := r.db.WithContext(ctx).
+		Where("(from_id = ? AND to_id = ?) OR (from_id = ? AND to_id = ?)", userID, peerID, peerID, userID)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	err := query.Order("id desc").
+		Limit(limit).
+		Find(&msgs).Error
+	return msgs, err
+}
+
+// ListSince 用户收发的、ID 大于 sinceID 的消息，按 ID 正序，用于断线重连补发
+func (r *Repository) ListSince(ctx context.Context, userID uint, sinceID uint, limit int) ([]Message, error) {
+	var msgs []Message
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND (from_id = ? OR to_id = ?)", sinceID, userID, userID).
+		Order("id asc").
+		Limit(limit).
+		Find(&msgs).Error
+	return msgs, err
+}
+
+func (r *Repository) GetConversation(ctx context.Context, ownerID, peerID uint) (*Conversation, error) {
+	var conv Conversation
+	err := r.db.WithContext(ctx).Where("owner_id = ? AND peer_id = ?", ownerID, peerID).First(&conv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// ListConversations 按最后一条消息倒序，cursor 为上一页最后一个会话的 last_message_id
+func (r *Repository) ListConversations(ctx context.Context, ownerID uint, cursor uint, limit int) ([]Conversation, error) {
+	var convs []Conversation
+	query := r.db.WithContext(ctx).
+		Where("owner_id = ? AND last_message_id > 0", ownerID)
+	if cursor > 0 {
+		query = query.Where("last_message_id < ?", cursor)
+	}
+	err := query.Order("last_message_id desc").
+		Limit(limit).
+		Find(&convs).Error
+	return convs, err
+}
+
+// fillPeers 批量补齐会话对方的用户名和头像
+func (r *Repository) fillPeers(ctx context.Context, convs []Conversation) error {
+	if len(convs) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(convs))
+	for _, c := range convs {
+		ids = append(ids, c.PeerID)
+	}
+	var peers []struct {
+		ID        uint
+		Username  string
+		AvatarURL string
+	}
+	if err := r.db.WithContext(ctx).Table("accounts").
+		Select("id, username, avatar_url").
+		Where("id IN ?", ids).
+		Scan(&peers).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]int, len(peers))
+	for i, p := range peers {
+		byID[p.ID] = i
+	}
+	for i := range convs {
+		if j, ok := byID[convs[i].PeerID]; ok {
+			convs[i].PeerUsername = peers[j].Username
+			convs[i].PeerAvatarURL = peers[j].AvatarURL
+		}
+	}
+	return nil
+}
+
+// MarkRead 标记对方发来的、ID 不超过 upTo 的消息为已读；已读位置只前进不后退
+func (r *Repository) MarkRead(ctx context.Context, userID, peerID uint, upTo uint) (*Conversation, error) {
+	var conv Conversation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("owner_id = ? AND peer_id = ?", userID, peerID).
+			First(&conv).Error; err != nil {
+			return err
+		}
+		if upTo == 0 || upTo > conv.LastMessageID {
+			upTo = conv.LastMessageID
+		}
+		if upTo <= conv.LastReadMessageID {
+			return nil
+		}
+		if err := tx.Model(&Message{}).
+			Where("from_id = ? AND to_id = ? AND id <= ? AND is_read = ?", peerID, userID, upTo, false).
+			Update("is_read", true).Error; err != nil {
+			return err
+		}
+		var unread int64
+		if err := tx.Model(&Message{}).
+			Where("from_id = ? AND to_id = ? AND id > ?", peerID, userID, upTo).
+			Count(&unread).Error; err != nil {
+			return err
+		}
+		conv.LastReadMessageID = upTo
+		conv.UnreadCount = unread
+		return tx.Model(&Conversation{}).Where("id = ?", conv.ID).
+			Updates(map[string]interface{}{"last_read_message_id": upTo, "unread_count": unread}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("conversation not found: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// UnreadTotal 所有会话未读数之和，走 owner_id 索引，适合做角标
+func (r *Repository) UnreadTotal(ctx context.Context, ownerID uint) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&Conversation{}).
+		Where("owner_id = ?", ownerID).
+		Select("COALESCE(SUM(unread_count), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *Repository) AccountExists(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("accounts").Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
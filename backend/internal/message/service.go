@@ -0,0 +1,115 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"feedsystem_video_go/internal/apierror"
+)
+
+type Service struct {
+	repo *Repository
+	hub  *Hub
+}
+
+func NewService(repo *Repository, hub *Hub) *Service { return &Service{repo: repo, hub: hub} }
+
+func normalizeLimit(limit, def, max int) int {
+	if limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+func (s *Service) Send(ctx context.Context, m *Message) error {
+	if m.FromID == m.ToID {
+		return fmt.Errorf("%w: cannot send message to yourself", apierror.ErrValidation)
+	}
+	exists, err := s.repo.AccountExists(ctx, m.ToID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("account not found")
+	}
+	if err := s.repo.Send(ctx, m); err != nil {
+		return err
+	}
+	s.hub.DeliverMessage(m)
+	return nil
+}
+
+func (s *Service) List(ctx context.Context, userID, peerID uint, cursor uint, limit int) (ListResponse, error) {
+	limit = normalizeLimit(limit, 50, 100)
+	msgs, err := s.repo.List(ctx, userID, peerID, cursor, limit+1)
+	if err != nil {
+		return ListResponse{}, err
+	}
+	resp := ListResponse{Messages: msgs}
+	if len(msgs) > limit {
+		resp.Messages = msgs[:limit]
+		resp.HasMore = true
+	}
+	if resp.Messages == nil {
+		resp.Messages = []Message{}
+	}
+	if n := len(resp.Messages); n > 0 {
+		resp.NextCursor = resp.Messages[n-1].ID
+	}
+	peerConv, err := s.repo.GetConversation(ctx, peerID, userID)
+	if err != nil {
+		return ListResponse{}, err
+	}
+	if peerConv != nil {
+		resp.PeerLastReadID = peerConv.LastReadMessageID
+	}
+	return resp, nil
+}
+
+func (s *Service) ListConversations(ctx context.Context, ownerID uint, cursor uint, limit int) (ListConversationsResponse, error) {
+	limit = normalizeLimit(limit, 20, 50)
+	convs, err := s.repo.ListConversations(ctx, ownerID, cursor, limit+1)
+	if err != nil {
+		return ListConversationsResponse{}, err
+	}
+	resp := ListConversationsResponse{Conversations: convs}
+	if len(convs) > limit {
+		resp.Conversations = convs[:limit]
+		resp.HasMore = true
+	}
+	if resp.Conversations == nil {
+		resp.Conversations = []Conversation{}
+	}
+	if n := len(resp.Conversations); n > 0 {
+		resp.NextCursor = resp.Conversations[n-1].LastMessageID
+	}
+	if err := s.repo.fillPeers(ctx, resp.Conversations); err != nil {
+		return ListConversationsResponse{}, err
+	}
+	return resp, nil
+}
+
+func (s *Service) MarkRead(ctx context.Context, userID, peerID uint, upTo uint) (*Conversation, error) {
+	conv, err := s.repo.MarkRead(ctx, userID, peerID, upTo)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.PushRead(userID, peerID, conv.LastReadMessageID)
+	return conv, nil
+}
+
+func (s *Service) ListSince(ctx context.Context, userID uint, sinceID uint, limit int) ([]Message, error) {
+	return s.repo.ListSince(ctx, userID, sinceID, limit)
+}
+
+func (s *Service) PushTyping(fromID, toID uint) {
+	s.hub.PushTyping(fromID, toID)
+}
+
+func (s *Service) UnreadTotal(ctx context.Context, userID uint) (int64, error) {
+	return s.repo.UnreadTotal(ctx, userID)
+}
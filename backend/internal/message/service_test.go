@@ -0,0 +1,221 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"feedsystem_video_go/internal/account"
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/dbtest"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	db := dbtest.Open(t, &account.Account{}, &Message{}, &Conversation{})
+	for _, a := range []account.Account{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob", AvatarURL: "b.png"}, {ID: 3, Username: "carol"}} {
+		if err := db.Create(&a).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewService(NewRepository(db), NewHub(nil))
+}
+
+func sendMessage(t *testing.T, s *Service, from, to uint, content string) *Message {
+	t.Helper()
+	m := &Message{FromID: from, ToID: to, Content: content}
+	if err := s.Send(context.Background(), m); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	return m
+}
+
+func TestSendValidatesRecipient(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	if err := s.Send(ctx, &Message{FromID: 1, ToID: 1, Content: "hi"}); !errors.Is(err, apierror.ErrValidation) {
+		t.Fatalf("expected validation error for self message, got %v", err)
+	}
+	if err := s.Send(ctx, &Message{FromID: 1, ToID: 99, Content: "hi"}); err == nil {
+		t.Fatal("expected error for unknown recipient")
+	}
+	if err := s.Send(ctx, &Message{FromID: 1, ToID: 2, Content: "   "}); err == nil {
+		t.Fatal("expected error for blank content")
+	}
+}
+
+func TestSendUpdatesBothConversations(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	sendMessage(t, s, 1, 2, "hello")
+	long := strings.Repeat("长", previewRunes+10)
+	last := sendMessage(t, s, 1, 2, long)
+
+	sender, err := s.repo.GetConversation(ctx, 1, 2)
+	if err != nil || sender == nil {
+		t.Fatalf("sender conversation: %v %v", sender, err)
+	}
+	if sender.UnreadCount != 0 || sender.LastReadMessageID != last.ID || sender.LastMessageID != last.ID {
+		t.Fatalf("unexpected sender conversation %+v", sender)
+	}
+	recipient, err := s.repo.GetConversation(ctx, 2, 1)
+	if err != nil || recipient == nil {
+		t.Fatalf("recipient conversation: %v %v", recipient, err)
+	}
+	if recipient.UnreadCount != 2 || recipient.LastReadMessageID != 0 {
+		t.Fatalf("unexpected recipient conversation %+v", recipient)
+	}
+	if want := string([]rune(long)[:previewRunes]) + "…"; recipient.LastMessage != want {
+		t.Fatalf("expected truncated preview, got %q", recipient.LastMessage)
+	}
+	if total, _ := s.UnreadTotal(ctx, 2); total != 2 {
+		t.Fatalf("expected unread total 2, got %d", total)
+	}
+}
+
+func TestListPagesAndReportsPeerRead(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		ids = append(ids, sendMessage(t, s, 1, 2, "m").ID)
+	}
+	sendMessage(t, s, 1, 3, "other")
+
+	page, err := s.List(ctx, 1, 2, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Messages) != 2 || !page.HasMore || page.Messages[0].ID != ids[2] || page.NextCursor != ids[1] {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page, err = s.List(ctx, 1, 2, page.NextCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Messages) != 1 || page.HasMore || page.Messages[0].ID != ids[0] {
+		t.Fatalf("unexpected second page %+v", page)
+	}
+
+	if _, err := s.MarkRead(ctx, 2, 1, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	page, _ = s.List(ctx, 1, 2, 0, 10)
+	if page.PeerLastReadID != ids[1] {
+		t.Fatalf("expected peer read up to %d, got %d", ids[1], page.PeerLastReadID)
+	}
+}
+
+func TestMarkReadOnlyMovesForward(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	first := sendMessage(t, s, 1, 2, "a")
+	second := sendMessage(t, s, 1, 2, "b")
+	last := sendMessage(t, s, 1, 2, "c")
+
+	conv, err := s.MarkRead(ctx, 2, 1, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.LastReadMessageID != second.ID || conv.UnreadCount != 1 {
+		t.Fatalf("unexpected conversation after partial read %+v", conv)
+	}
+	// 已读位置不后退
+	conv, _ = s.MarkRead(ctx, 2, 1, first.ID)
+	if conv.LastReadMessageID != second.ID || conv.UnreadCount != 1 {
+		t.Fatalf("read position moved backwards: %+v", conv)
+	}
+	// 0 表示全部已读
+	conv, _ = s.MarkRead(ctx, 2, 1, 0)
+	if conv.LastReadMessageID != last.ID || conv.UnreadCount != 0 {
+		t.Fatalf("unexpected conversation after read all %+v", conv)
+	}
+	var unreadMsgs int64
+	s.repo.db.Model(&Message{}).Where("to_id = ? AND is_read = ?", 2, false).Count(&unreadMsgs)
+	if unreadMsgs != 0 {
+		t.Fatalf("expected all messages read, %d left", unreadMsgs)
+	}
+
+	if _, err := s.MarkRead(ctx, 3, 1, 0); err == nil {
+		t.Fatal("expected error for missing conversation")
+	}
+}
+
+func TestListConversationsFillsPeers(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	sendMessage(t, s, 1, 2, "to bob")
+	sendMessage(t, s, 3, 1, "from carol")
+
+	page, err := s.ListConversations(ctx, 1, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Conversations) != 1 || !page.HasMore || page.Conversations[0].PeerID != 3 || page.Conversations[0].PeerUsername != "carol" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page, err = s.ListConversations(ctx, 1, page.NextCursor, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Conversations) != 1 || page.HasMore {
+		t.Fatalf("unexpected second page %+v", page)
+	}
+	if c := page.Conversations[0]; c.PeerID != 2 || c.PeerUsername != "bob" || c.PeerAvatarURL != "b.png" {
+		t.Fatalf("unexpected peer %+v", c)
+	}
+}
+
+func TestBackfillConversationsFromMessages(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	// 会话表上线前的消息：直接写 messages，不经过 Send
+	for _, m := range []Message{
+		{FromID: 1, ToID: 2, Content: "a", IsRead: true},
+		{FromID: 2, ToID: 1, Content: "b"},
+		{FromID: 1, ToID: 2, Content: "c"},
+		{FromID: 1, ToID: 2, Content: "d"},
+		{FromID: 3, ToID: 1, Content: "e"},
+	} {
+		if err := s.repo.db.Create(&m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 已有的会话行不被覆盖
+	if err := s.repo.db.Create(&Conversation{OwnerID: 1, PeerID: 3, LastMessageID: 5, LastMessage: "kept", LastReadMessageID: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repo.BackfillConversations(ctx); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if err := s.repo.BackfillConversations(ctx); err != nil {
+		t.Fatalf("backfill again: %v", err)
+	}
+
+	cases := []struct {
+		owner, peer    uint
+		last, lastRead uint
+		unread         int64
+		preview        string
+	}{
+		{1, 2, 4, 4, 0, "d"}, // b 之后又发了 c、d，视为读过 b
+		{2, 1, 4, 2, 2, "d"}, // 读过 a，自己发了 b，c、d 未读
+		{3, 1, 5, 5, 0, "e"},
+		{1, 3, 5, 5, 0, "kept"},
+	}
+	for _, tc := range cases {
+		c, err := s.repo.GetConversation(ctx, tc.owner, tc.peer)
+		if err != nil || c == nil {
+			t.Fatalf("conversation %d->%d: %v %v", tc.owner, tc.peer, c, err)
+		}
+		if c.LastMessageID != tc.last || c.LastReadMessageID != tc.lastRead || c.UnreadCount != tc.unread || c.LastMessage != tc.preview {
+			t.Fatalf("conversation %d->%d = %+v", tc.owner, tc.peer, c)
+		}
+	}
+	var n int64
+	s.repo.db.Model(&Conversation{}).Count(&n)
+	if n != 4 {
+		t.Fatalf("expected 4 conversations, got %d", n)
+	}
+}
@@ -0,0 +1,230 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedsystem_video_go/internal/auth"
+	"feedsystem_video_go/internal/middleware/jwt"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	wsReadTimeout    = 75 * time.Second
+	wsWriteTimeout   = 10 * time.Second
+	wsPingInterval   = 30 * time.Second
+	wsMaxPayload     = 64 << 10
+	wsReplayLimit    = 200
+	wsRequestTimeout = 5 * time.Second
+)
+
+// Chat WebSocket 依赖的消息能力，*Service 实现；测试里可以换成内存实现
+type Chat interface {
+	Send(ctx context.Context, m *Message) error
+	MarkRead(ctx context.Context, userID, peerID uint, upTo uint) (*Conversation, error)
+	ListSince(ctx context.Context, userID uint, sinceID uint, limit int) ([]Message, error)
+	PushTyping(fromID, toID uint)
+}
+
+// WSHandler allowedOrigins 为同源之外允许发起连接的页面来源，如 http://localhost:5173
+type WSHandler struct {
+	hub            *Hub
+	chat           Chat
+	allowedOrigins []string
+}
+
+func NewWSHandler(hub *Hub, chat Chat, allowedOrigins []string) *WSHandler {
+	return &WSHandler{hub: hub, chat: chat, allowedOrigins: allowedOrigins}
+}
+
+// Ticket POST /message/wsTicket 用 access token 换一次性连接票据
+func (h *WSHandler) Ticket(c *gin.Context) {
+	userID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	username, _ := jwt.GetUsername(c)
+	ticket, err := auth.GenerateWSTicket(userID, username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, WSTicketResponse{Ticket: ticket, ExpiresIn: int(auth.WSTicketTTL.Seconds())})
+}
+
+// Connect GET /message/ws?ticket=...&since=<message_id>
+// since 为客户端已收到的最大消息 ID，连接建立后先补发之后的消息，保证断线期间不丢
+func (h *WSHandler) Connect(c *gin.Context) {
+	if !originAllowed(c.GetHeader("Origin"), c.Request.Host, h.allowedOrigins) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+		return
+	}
+	userID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var since uint64
+	if v := c.Query("since"); v != "" {
+		since, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+			return
+		}
+	}
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		ws.MaxPayloadBytes = wsMaxPayload
+		h.serve(ws, userID, uint(since))
+	}}.ServeHTTP(c.Writer, c.Request)
+}
+
+// originAllowed 浏览器跨站页面也能发起 WebSocket 握手，只放行同源和配置的来源；
+// 没有 Origin 的是非浏览器客户端，不受跨站攻击影响
+func originAllowed(origin string, host string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimRight(a, "/"), u.Scheme+"://"+u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *WSHandler) serve(ws *websocket.Conn, userID uint, since uint) {
+	defer ws.Close()
+	cl := newClient(userID)
+	h.hub.register(cl)
+	defer h.hub.unregister(cl)
+
+	go h.writeLoop(ws, cl)
+
+	if since > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), wsRequestTimeout)
+		msgs, err := h.chat.ListSince(ctx, userID, since, wsReplayLimit)
+		cancel()
+		if err != nil {
+			log.Printf("ws replay for user %d failed: %v", userID, err)
+		}
+		for i := range msgs {
+			cl.deliver(Frame{Type: FrameMessage, Message: &msgs[i]})
+		}
+	}
+
+	for {
+		_ = ws.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		var f Frame
+		if err := websocket.JSON.Receive(ws, &f); err != nil {
+			if !errors.Is(err, io.EOF) && !isClosed(cl) {
+				log.Printf("ws read for user %d: %v", userID, err)
+			}
+			return
+		}
+		if isClosed(cl) {
+			return
+		}
+		h.handleFrame(cl, f)
+	}
+}
+
+func isClosed(cl *client) bool {
+	select {
+	case <-cl.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *WSHandler) handleFrame(cl *client, f Frame) {
+	switch f.Type {
+	case FrameAck:
+		cl.ack(f.AckID)
+	case FramePing:
+		cl.enqueue(Frame{Type: FramePong})
+	case FramePong:
+	case FrameTyping:
+		if f.ToID != 0 && f.ToID != cl.userID {
+			h.chat.PushTyping(cl.userID, f.ToID)
+		}
+	case FrameSend:
+		if f.ToID == 0 || strings.TrimSpace(f.Content) == "" {
+			cl.enqueue(Frame{Type: FrameError, ClientID: f.ClientID, Error: "to_id and content are required"})
+			return
+		}
+		m := &Message{FromID: cl.userID, ToID: f.ToID, Content: f.Content}
+		ctx, cancel := context.WithTimeout(context.Background(), wsRequestTimeout)
+		err := h.chat.Send(ctx, m)
+		cancel()
+		if err != nil {
+			cl.enqueue(Frame{Type: FrameError, ClientID: f.ClientID, Error: err.Error()})
+			return
+		}
+		cl.enqueue(Frame{Type: FrameSent, ClientID: f.ClientID, Message: m})
+	case FrameRead:
+		if f.PeerID == 0 {
+			cl.enqueue(Frame{Type: FrameError, ClientID: f.ClientID, Error: "peer_id is required"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), wsRequestTimeout)
+		_, err := h.chat.MarkRead(ctx, cl.userID, f.PeerID, f.MessageID)
+		cancel()
+		if err != nil {
+			cl.enqueue(Frame{Type: FrameError, ClientID: f.ClientID, Error: err.Error()})
+		}
+	default:
+		cl.enqueue(Frame{Type: FrameError, ClientID: f.ClientID, Error: "unknown frame type"})
+	}
+}
+
+// writeLoop 每个连接唯一的写协程，同时负责心跳和超时重发
+func (h *WSHandler) writeLoop(ws *websocket.Conn, cl *client) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	retry := time.NewTicker(ackTimeout / 2)
+	defer retry.Stop()
+	defer ws.Close()
+
+	write := func(f Frame) bool {
+		_ = ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := websocket.JSON.Send(ws, f); err != nil {
+			cl.close()
+			return false
+		}
+		return true
+	}
+	for {
+		select {
+		case <-cl.closed:
+			return
+		case f := <-cl.send:
+			if !write(f) {
+				return
+			}
+		case now := <-retry.C:
+			cl.redeliver(now)
+		case <-ping.C:
+			if !write(Frame{Type: FramePing}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package message
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// memChat 内存版 Chat，行为与 Service 一致：写入后推送到 hub
+type memChat struct {
+	hub  *Hub
+	mu   sync.Mutex
+	msgs []Message
+}
+
+func (m *memChat) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	msg.ID = uint(len(m.msgs) + 1)
+	msg.CreatedAt = time.Now()
+	m.msgs = append(m.msgs, *msg)
+	m.mu.Unlock()
+	m.hub.DeliverMessage(msg)
+	return nil
+}
+
+func (m *memChat) MarkRead(ctx context.Context, userID, peerID uint, upTo uint) (*Conversation, error) {
+	m.hub.PushRead(userID, peerID, upTo)
+	return &Conversation{OwnerID: userID, PeerID: peerID, LastReadMessageID: upTo}, nil
+}
+
+func (m *memChat) ListSince(ctx context.Context, userID uint, sinceID uint, limit int) ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Message
+	for _, msg := range m.msgs {
+		if msg.ID > sinceID && (msg.FromID == userID || msg.ToID == userID) {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+func (m *memChat) PushTyping(fromID, toID uint) { m.hub.PushTyping(fromID, toID) }
+
+func newTestServer(t *testing.T) (*httptest.Server, *memChat) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(nil)
+	chat := &memChat{hub: hub}
+	r := gin.New()
+	r.GET("/message/ws", func(c *gin.Context) {
+		uid, _ := strconv.ParseUint(c.Query("uid"), 10, 64)
+		c.Set("accountID", uint(uid))
+	}, NewWSHandler(hub, chat, []string{"http://localhost"}).Connect)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, chat
+}
+
+func dial(t *testing.T, srv *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/message/ws?" + query
+	ws, err := websocket.Dial(url, "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// next 读取下一帧，跳过心跳
+func next(t *testing.T, ws *websocket.Conn) Frame {
+	t.Helper()
+	for {
+		_ = ws.SetReadDeadline(time.Now().Add(3 * time.Second))
+		var f Frame
+		if err := websocket.JSON.Receive(ws, &f); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if f.Type != FramePing {
+			return f
+		}
+	}
+}
+
+func send(t *testing.T, ws *websocket.Conn, f Frame) {
+	t.Helper()
+	if err := websocket.JSON.Send(ws, f); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+func waitOnline(t *testing.T, chat *memChat, uid uint, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(chat.hub.connections(uid)) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("user %d never got %d connections", uid, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWSChatFanOutToAllDevices(t *testing.T) {
+	srv, chat := newTestServer(t)
+	aliceA := dial(t, srv, "uid=1")
+	aliceB := dial(t, srv, "uid=1")
+	bob := dial(t, srv, "uid=2")
+	waitOnline(t, chat, 1, 2)
+	waitOnline(t, chat, 2, 1)
+
+	send(t, aliceA, Frame{Type: FrameSend, ClientID: "c1", ToID: 2, Content: "hi"})
+
+	// 发送端既收到 sent 回执，也收到 message 推送，顺序不定
+	seen := map[string]Frame{}
+	for i := 0; i < 2; i++ {
+		f := next(t, aliceA)
+		seen[f.Type] = f
+	}
+	if f := seen[FrameSent]; f.ClientID != "c1" || f.Message == nil || f.Message.ID != 1 {
+		t.Fatalf("unexpected sent frame %+v", f)
+	}
+	if f := next(t, aliceB); f.Type != FrameMessage || f.Message.Content != "hi" || f.AckID == 0 {
+		t.Fatalf("other device: unexpected frame %+v", f)
+	}
+	got := next(t, bob)
+	if got.Type != FrameMessage || got.Message.FromID != 1 || got.AckID == 0 {
+		t.Fatalf("recipient: unexpected frame %+v", got)
+	}
+	send(t, bob, Frame{Type: FrameAck, AckID: got.AckID})
+
+	send(t, bob, Frame{Type: FrameTyping, ToID: 1})
+	if f := next(t, aliceB); f.Type != FrameTyping || f.FromID != 2 {
+		t.Fatalf("expected typing frame, got %+v", f)
+	}
+
+	send(t, bob, Frame{Type: FrameRead, PeerID: 1, MessageID: got.Message.ID})
+	if f := next(t, aliceB); f.Type != FrameRead || f.FromID != 2 || f.MessageID != 1 {
+		t.Fatalf("expected read receipt, got %+v", f)
+	}
+}
+
+func TestWSRedeliversUntilAcked(t *testing.T) {
+	old := ackTimeout
+	ackTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { ackTimeout = old })
+
+	srv, chat := newTestServer(t)
+	bob := dial(t, srv, "uid=2")
+	waitOnline(t, chat, 2, 1)
+	if err := chat.Send(context.Background(), &Message{FromID: 1, ToID: 2, Content: "hello"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	first := next(t, bob)
+	again := next(t, bob)
+	if again.Type != FrameMessage || again.AckID != first.AckID || again.Message.ID != first.Message.ID {
+		t.Fatalf("expected redelivery of %+v, got %+v", first, again)
+	}
+	send(t, bob, Frame{Type: FrameAck, AckID: first.AckID})
+
+	// 确认后不再重发
+	time.Sleep(3 * ackTimeout)
+	cl := chat.hub.connections(2)[0]
+	cl.mu.Lock()
+	pending := len(cl.pending)
+	cl.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected no pending frames after ack, got %d", pending)
+	}
+}
+
+func TestWSReplaysMissedMessages(t *testing.T) {
+	srv, chat := newTestServer(t)
+	for i := 0; i < 3; i++ {
+		if err := chat.Send(context.Background(), &Message{FromID: 1, ToID: 2, Content: "m"}); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	bob := dial(t, srv, "uid=2&since=1")
+	for _, want := range []uint{2, 3} {
+		f := next(t, bob)
+		if f.Type != FrameMessage || f.Message.ID != want {
+			t.Fatalf("expected replay of message %d, got %+v", want, f)
+		}
+		send(t, bob, Frame{Type: FrameAck, AckID: f.AckID})
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"http://localhost:5173/"}
+	cases := []struct {
+		origin, host string
+		want         bool
+	}{
+		{"", "api.example.com", true},
+		{"https://app.example.com", "app.example.com", true},
+		{"http://localhost:5173", "127.0.0.1:8080", true},
+		{"https://evil.example.com", "app.example.com", false},
+		{"http://localhost:5174", "127.0.0.1:8080", false},
+		{"null", "app.example.com", false},
+	}
+	for _, tc := range cases {
+		if got := originAllowed(tc.origin, tc.host, allowed); got != tc.want {
+			t.Errorf("originAllowed(%q, %q) = %v, want %v", tc.origin, tc.host, got, tc.want)
+		}
+	}
+}
+
+func TestWSRejectsCrossSiteOrigin(t *testing.T) {
+	srv, _ := newTestServer(t)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/message/ws?uid=1"
+	if ws, err := websocket.Dial(url, "", "https://evil.example.com/"); err == nil {
+		ws.Close()
+		t.Fatal("expected handshake from a foreign origin to be rejected")
+	}
+}
@@ -67,6 +67,37 @@ func SoftJWTAuth(accountRepo *account.AccountRepository, cache *rediscache.Clien
 	}
 }
 
+// WSTicketAuth 浏览器的 WebSocket 无法带自定义 header，用 ?ticket= 传一次性票据（见 auth.GenerateWSTicket）。
+// Redis 可用时记下已用过的票据 ID，重放直接拒绝；Redis 故障时只靠 30 秒有效期兜底
+func WSTicketAuth(cache *rediscache.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing ticket"})
+			return
+		}
+		claims, err := auth.ParseWSTicket(ticket)
+		if err != nil || claims.ID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired ticket"})
+			return
+		}
+		if cache != nil {
+			cacheCtx, cancel := context.WithTimeout(c.Request.Context(), 50*time.Millisecond)
+			fresh, err := cache.SetNX(cacheCtx, cache.Key("ws:ticket:%s", claims.ID), auth.WSTicketTTL)
+			cancel()
+			if err != nil {
+				log.Printf("ws ticket: replay check unavailable: %v", err)
+			} else if !fresh {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "ticket already used"})
+				return
+			}
+		}
+		c.Set("accountID", claims.AccountID)
+		c.Set("username", claims.Username)
+		c.Next()
+	}
+}
+
 func check(c *gin.Context, claims *auth.Claims, tokenString string, accountRepo *account.AccountRepository, cache *rediscache.Client) {
 	key := cache.Key("account:%d", claims.AccountID)
 
@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"feedsystem_video_go/internal/auth"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestWSTicketAuthAcceptsTicketOnce(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", WSTicketAuth(cache), func(c *gin.Context) {
+		id, _ := GetAccountID(c)
+		if id != 7 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	get := func(ticket string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws?ticket="+ticket, nil))
+		return w.Code
+	}
+
+	ticket, err := auth.GenerateWSTicket(7, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(ticket); code != http.StatusOK {
+		t.Fatalf("expected ticket accepted, got %d", code)
+	}
+	if code := get(ticket); code != http.StatusUnauthorized {
+		t.Fatalf("expected replayed ticket rejected, got %d", code)
+	}
+
+	// access token 不能当票据用，票据也不能当 access token 用
+	access, err := auth.GenerateToken(7, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(access); code != http.StatusUnauthorized {
+		t.Fatalf("expected access token rejected, got %d", code)
+	}
+	another, _ := auth.GenerateWSTicket(7, "alice")
+	if _, err := auth.ParseToken(another); err == nil {
+		t.Fatal("ws ticket must not parse as an access token")
+	}
+	if code := get(""); code != http.StatusUnauthorized {
+		t.Fatalf("expected missing ticket rejected, got %d", code)
+	}
+}
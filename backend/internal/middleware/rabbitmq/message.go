@@ -0,0 +1,201 @@
+package rabbitmq
+
+import (
+	"time"
+)
+
+// Message 一条待投递的领域事件。业务代码在写库的同一事务里交给 outbox 落表，
+// 由 outbox relay 异步发到 Exchange/RoutingKey；EventID 同时写进消息体和 AMQP MessageId
+type Message struct {
+	EventID     string
+	Type        string // outbox 里的事件类型，投递后的本进程处理按它分发；为空时取 RoutingKey
+	Exchange    string
+	RoutingKey  string
+	AggregateID uint // 事件所属的视频/账号，便于排查
+	Payload     any
+}
+
+const (
+	// video_published/video_deleted 沿用旧 outbox 表的事件名
+	VideoPublishedType = "video_published"
+	VideoDeletedType   = "video_deleted"
+	// VideoVisibilityChangedType 已发布视频修改了可见范围
+	VideoVisibilityChangedType = "video_visibility_changed"
+	// VideoRestoredType 已发布视频从回收站恢复，和发布走同一个路由键，但不再给话题加发布分
+	VideoRestoredType = "video_restored"
+
+	timelineDeleteRK     = "video.timeline.delete"
+	timelineVisibilityRK = "video.timeline.visibility"
+)
+
+func LikeMessage(action string, userID, videoID uint) (Message, error) {
+	id, err := newEventID(16)
+	if err != nil {
+		return Message{}, err
+	}
+	rk := likeLikeRK
+	if action == "unlike" {
+		rk = likeUnlikeRK
+	}
+	return Message{
+		EventID:     id,
+		Exchange:    likeExchange,
+		RoutingKey:  rk,
+		AggregateID: videoID,
+		Payload: LikeEvent{
+			EventID:    id,
+			Action:     action,
+			UserID:     userID,
+			VideoID:    videoID,
+			OccurredAt: time.Now(),
+		},
+	}, nil
+}
+
+func PopularityMessage(videoID uint, change int64) (Message, error) {
+	id, err := newEventID(16)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		EventID:     id,
+		Exchange:    popularityExchange,
+		RoutingKey:  popularityUpdateRK,
+		AggregateID: videoID,
+		Payload: PopularityEvent{
+			EventID:    id,
+			VideoID:    videoID,
+			Change:     change,
+			OccurredAt: time.Now().UTC(),
+		},
+	}, nil
+}
+
+// CommentMessage action 为 publish 或 delete
+func CommentMessage(action string, evt CommentEvent) (Message, error) {
+	id, err := newEventID(16)
+	if err != nil {
+		return Message{}, err
+	}
+	rk := commentPublishRK
+	if action == "delete" {
+		rk = commentDeleteRK
+	}
+	evt.EventID = id
+	evt.Action = action
+	evt.OccurredAt = time.Now().UTC()
+	return Message{
+		EventID:     id,
+		Exchange:    commentExchange,
+		RoutingKey:  rk,
+		AggregateID: evt.VideoID,
+		Payload:     evt,
+	}, nil
+}
+
+func SocialMessage(action string, followerID, vloggerID uint) (Message, error) {
+	id, err := newEventID(16)
+	if err != nil {
+		return Message{}, err
+	}
+	rk := socialFollowRK
+	if action == "unfollow" {
+		rk = socialUnfollowRK
+	}
+	return Message{
+		EventID:     id,
+		Exchange:    socialExchange,
+		RoutingKey:  rk,
+		AggregateID: vloggerID,
+		Payload: SocialEvent{
+			EventID:    id,
+			Action:     action,
+			FollowerID: followerID,
+			VloggerID:  vloggerID,
+			OccurredAt: time.Now().UTC(),
+		},
+	}, nil
+}
+
+// VideoPublishedMessage 视频进入各条 feed：全站时间线和粉丝收件箱都消费它
+func VideoPublishedMessage(videoID, authorID uint, createTime time.Time, visibility string) (Message, error) {
+	return timelineMessage(VideoPublishedType, timelinePublishRK, TimelineEvent{
+		VideoID:    videoID,
+		AuthorID:   authorID,
+		CreateTime: createTime.UnixMilli(),
+		Visibility: visibility,
+	})
+}
+
+// VideoRestoredMessage 已发布视频从回收站恢复，重新进入各条 feed
+func VideoRestoredMessage(videoID, authorID uint, createTime time.Time, visibility string) (Message, error) {
+	return timelineMessage(VideoRestoredType, timelinePublishRK, TimelineEvent{
+		VideoID:    videoID,
+		AuthorID:   authorID,
+		CreateTime: createTime.UnixMilli(),
+		Visibility: visibility,
+	})
+}
+
+// VideoDeletedMessage 视频进回收站，全站时间线消费者据此移除
+func VideoDeletedMessage(videoID, authorID uint) (Message, error) {
+	return timelineMessage(VideoDeletedType, timelineDeleteRK, TimelineEvent{
+		VideoID:  videoID,
+		AuthorID: authorID,
+	})
+}
+
+// VideoVisibilityChangedMessage 已发布视频改了可见范围：全站时间线按新范围加入或移除，
+// 变为公开或粉丝可见时补写粉丝收件箱，搜索索引以数据库为准重新同步
+func VideoVisibilityChangedMessage(videoID, authorID uint, createTime time.Time, visibility string) (Message, error) {
+	return timelineMessage(VideoVisibilityChangedType, timelineVisibilityRK, TimelineEvent{
+		VideoID:    videoID,
+		AuthorID:   authorID,
+		CreateTime: createTime.UnixMilli(),
+		Visibility: visibility,
+	})
+}
+
+func timelineMessage(typ, routingKey string, evt TimelineEvent) (Message, error) {
+	id, err := newEventID(16)
+	if err != nil {
+		return Message{}, err
+	}
+	evt.EventID = id
+	evt.OccurredAt = time.Now()
+	return Message{
+		EventID:     id,
+		Type:        typ,
+		Exchange:    timelineExchange,
+		RoutingKey:  routingKey,
+		AggregateID: evt.VideoID,
+		Payload:     evt,
+	}, nil
+}
+
+// IsTimelineDelete 区分时间线 exchange 上的删除事件
+func IsTimelineDelete(routingKey string) bool {
+	return routingKey == timelineDeleteRK
+}
+
+// IsTimelineVisibilityChange 区分时间线 exchange 上的可见范围变更事件
+func IsTimelineVisibilityChange(routingKey string) bool {
+	return routingKey == timelineVisibilityRK
+}
+
+// DeclareOutboxTopology 声明 outbox relay 会投递到的交换机和队列
+func DeclareOutboxTopology(base *RabbitMQ) error {
+	for _, t := range []struct{ exchange, queue, bindingKey string }{
+		{likeExchange, likeQueue, likeBindingKey},
+		{commentExchange, commentQueue, commentBindingKey},
+		{socialExchange, socialQueue, socialBindingKey},
+		{popularityExchange, popularityQueue, popularityBindingKey},
+		{timelineExchange, timelineQueue, timelineBindingKey},
+		{transcodeExchange, transcodeQueue, transcodeBindingKey},
+	} {
+		if err := base.DeclareTopic(t.exchange, t.queue, t.bindingKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
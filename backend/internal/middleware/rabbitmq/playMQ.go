@@ -0,0 +1,65 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type PlayMQ struct {
+	*RabbitMQ
+}
+
+const (
+	playExchange   = "video.play.events"
+	playQueue      = "video.play.events"
+	playBindingKey = "video.play.*"
+
+	playReportRK = "video.play.report"
+)
+
+// PlayEvent 客户端上报的单条播放事件；Viewer 为 "u:<账号ID>" 或匿名的 "ip:<地址>"
+type PlayEvent struct {
+	EventID    string    `json:"event_id"`
+	VideoID    uint      `json:"video_id"`
+	Viewer     string    `json:"viewer"`
+	Type       string    `json:"type"`
+	Progress   float64   `json:"progress"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// PlayBatchEvent 一次 /video/reportPlay 请求对应一条消息
+type PlayBatchEvent struct {
+	EventID    string      `json:"event_id"`
+	Events     []PlayEvent `json:"events"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+func NewPlayMQ(base *RabbitMQ) (*PlayMQ, error) {
+	if base == nil {
+		return nil, errors.New("rabbitmq base is nil")
+	}
+	if err := base.DeclareTopic(playExchange, playQueue, playBindingKey); err != nil {
+		return nil, err
+	}
+	return &PlayMQ{RabbitMQ: base}, nil
+}
+
+func (p *PlayMQ) Report(ctx context.Context, events []PlayEvent) error {
+	if p == nil || p.RabbitMQ == nil {
+		return errors.New("play mq is not initialized")
+	}
+	if len(events) == 0 {
+		return errors.New("events are required")
+	}
+	id, err := newEventID(16)
+	if err != nil {
+		return err
+	}
+	batch := PlayBatchEvent{
+		EventID:    id,
+		Events:     events,
+		OccurredAt: time.Now().UTC(),
+	}
+	return p.PublishJSON(ctx, playExchange, playReportRK, batch)
+}
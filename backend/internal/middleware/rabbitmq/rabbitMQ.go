@@ -112,11 +112,32 @@ func (r *RabbitMQ) PublishJSON(ctx context.Context, exchange string, routingKey
 	if err != nil {
 		return err
 	}
+	return r.PublishRaw(ctx, exchange, routingKey, "", b)
+}
+
+// PublishMessage 直接投递一条领域事件，不经过 outbox
+func (r *RabbitMQ) PublishMessage(ctx context.Context, m Message) error {
+	b, err := json.Marshal(m.Payload)
+	if err != nil {
+		return err
+	}
+	return r.PublishRaw(ctx, m.Exchange, m.RoutingKey, m.EventID, b)
+}
+
+// PublishRaw 投递已序列化的 JSON 消息体；messageID 非空时写入 AMQP MessageId，消费端据此去重
+func (r *RabbitMQ) PublishRaw(ctx context.Context, exchange string, routingKey string, messageID string, body []byte) error {
+	if r == nil || r.Ch == nil {
+		return errors.New("rabbitmq is not initialized")
+	}
+	if exchange == "" || routingKey == "" {
+		return errors.New("exchange and routingKey are required")
+	}
 	return r.Ch.PublishWithContext(ctx, exchange, routingKey, false, false, amqp.Publishing{
 		ContentType:  "application/json",
 		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
 		Timestamp:    time.Now(),
-		Body:         b,
+		Body:         body,
 	})
 }
 
@@ -0,0 +1,103 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// SearchMQ 把视频发布/删除、账号资料变更广播给每个 API 实例，各实例据此更新自己的内存索引
+type SearchMQ struct {
+	*RabbitMQ
+}
+
+const (
+	searchExchange          = "video.search.events"
+	searchVideoBindingKey   = "video.search.*"
+	searchAccountBindingKey = "account.search.*"
+
+	searchIndexRK   = "video.search.index"
+	searchDeleteRK  = "video.search.delete"
+	searchAccountRK = "account.search.update"
+
+	SearchActionIndex   = "index"
+	SearchActionDelete  = "delete"
+	SearchActionAccount = "account"
+)
+
+type SearchEvent struct {
+	EventID    string    `json:"event_id"`
+	Action     string    `json:"action"`
+	VideoID    uint      `json:"video_id,omitempty"`
+	AccountID  uint      `json:"account_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func NewSearchMQ(base *RabbitMQ) (*SearchMQ, error) {
+	if base == nil || base.Ch == nil {
+		return nil, errors.New("rabbitmq base is nil")
+	}
+	if err := base.Ch.ExchangeDeclare(searchExchange, "topic", true, false, false, false, nil); err != nil {
+		return nil, err
+	}
+	return &SearchMQ{RabbitMQ: base}, nil
+}
+
+func (s *SearchMQ) Index(ctx context.Context, videoID uint) error {
+	if videoID == 0 {
+		return errors.New("videoID is required")
+	}
+	return s.publish(ctx, searchIndexRK, SearchEvent{Action: SearchActionIndex, VideoID: videoID})
+}
+
+func (s *SearchMQ) Delete(ctx context.Context, videoID uint) error {
+	if videoID == 0 {
+		return errors.New("videoID is required")
+	}
+	return s.publish(ctx, searchDeleteRK, SearchEvent{Action: SearchActionDelete, VideoID: videoID})
+}
+
+func (s *SearchMQ) AccountChanged(ctx context.Context, accountID uint) error {
+	if accountID == 0 {
+		return errors.New("accountID is required")
+	}
+	return s.publish(ctx, searchAccountRK, SearchEvent{Action: SearchActionAccount, AccountID: accountID})
+}
+
+func (s *SearchMQ) publish(ctx context.Context, routingKey string, evt SearchEvent) error {
+	if s == nil || s.RabbitMQ == nil {
+		return errors.New("search mq is not initialized")
+	}
+	id, err := newEventID(16)
+	if err != nil {
+		return err
+	}
+	evt.EventID = id
+	evt.OccurredAt = time.Now()
+	return s.PublishJSON(ctx, searchExchange, routingKey, evt)
+}
+
+func (s *SearchMQ) SubscribeVideos() (<-chan amqp.Delivery, error) {
+	return s.subscribe(searchVideoBindingKey)
+}
+
+func (s *SearchMQ) SubscribeAccounts() (<-chan amqp.Delivery, error) {
+	return s.subscribe(searchAccountBindingKey)
+}
+
+// subscribe 为当前实例声明一个独占、自动删除的匿名队列；索引可由数据库重建，丢消息可以接受，因此自动 ack
+func (s *SearchMQ) subscribe(bindingKey string) (<-chan amqp.Delivery, error) {
+	if s == nil || s.RabbitMQ == nil || s.Ch == nil {
+		return nil, errors.New("search mq is not initialized")
+	}
+	q, err := s.Ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Ch.QueueBind(q.Name, bindingKey, searchExchange, false, nil); err != nil {
+		return nil, err
+	}
+	return s.Ch.Consume(q.Name, "", true, true, false, false, nil)
+}
@@ -20,7 +20,9 @@ const (
 type TimelineEvent struct {
 	EventID    string    `json:"event_id"`
 	VideoID    uint      `json:"video_id"`
+	AuthorID   uint      `json:"author_id,omitempty"`
 	CreateTime int64     `json:"create_time"`
+	Visibility string    `json:"visibility,omitempty"` // 旧版本事件没有该字段，按 public 处理
 	OccurredAt time.Time `json:"occurred_at"`
 }
 
@@ -34,22 +36,16 @@ func NewTimelineMQ(base *RabbitMQ) (*TimelineMQ, error) {
 	return &TimelineMQ{RabbitMQ: base}, nil
 }
 
-func (t *TimelineMQ) PublishVideo(ctx context.Context, videoID uint, createTime time.Time) error {
+func (t *TimelineMQ) PublishVideo(ctx context.Context, videoID, authorID uint, createTime time.Time, visibility string) error {
 	if t == nil || t.RabbitMQ == nil {
 		return errors.New("timeline mq is not initialized")
 	}
 	if videoID == 0 {
 		return errors.New("videoID are required")
 	}
-	id, err := newEventID(16)
+	m, err := VideoPublishedMessage(videoID, authorID, createTime, visibility)
 	if err != nil {
 		return err
 	}
-	timeline := TimelineEvent{
-		EventID:    id,
-		VideoID:    videoID,
-		CreateTime: createTime.UnixMilli(),
-		OccurredAt: time.Now(),
-	}
-	return t.PublishJSON(ctx, timelineExchange, timelinePublishRK, timeline)
+	return t.PublishMessage(ctx, m)
 }
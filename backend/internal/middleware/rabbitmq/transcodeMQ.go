@@ -0,0 +1,42 @@
+package rabbitmq
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	transcodeExchange   = "video.transcode.events"
+	transcodeQueue      = "video.transcode.events"
+	transcodeBindingKey = "video.transcode.*"
+
+	transcodeRequestRK = "video.transcode.request"
+)
+
+type TranscodeEvent struct {
+	EventID    string    `json:"event_id"`
+	VideoID    uint      `json:"video_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// TranscodeRequestMessage 转码请求，和视频状态变为 pending 在同一事务写入 outbox
+func TranscodeRequestMessage(videoID uint) (Message, error) {
+	if videoID == 0 {
+		return Message{}, errors.New("videoID is required")
+	}
+	id, err := newEventID(16)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		EventID:     id,
+		Exchange:    transcodeExchange,
+		RoutingKey:  transcodeRequestRK,
+		AggregateID: videoID,
+		Payload: TranscodeEvent{
+			EventID:    id,
+			VideoID:    videoID,
+			OccurredAt: time.Now().UTC(),
+		},
+	}, nil
+}
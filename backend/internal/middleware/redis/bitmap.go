@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// SetBits 在同一个 pipeline 里把 offsets 置 1 并设置过期时间
+func (c *Client) SetBits(ctx context.Context, key string, offsets []int64, ttl time.Duration) error {
+	if c == nil || c.rdb == nil {
+		return errors.New("redis client not initialized")
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	for _, off := range offsets {
+		pipe.SetBit(ctx, key, off, 1)
+	}
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetBits 读取多个 key 上的同一组 offsets，结果为 [key][offset]。
+// 每个 key 只发一条 BITFIELD ... GET u1，所有 key 在同一个 pipeline 里；
+// 服务端不支持 BITFIELD 时退回逐个 GETBIT
+func (c *Client) GetBits(ctx context.Context, keys []string, offsets []int64) ([][]bool, error) {
+	if c == nil || c.rdb == nil {
+		return nil, errors.New("redis client not initialized")
+	}
+	if len(keys) == 0 || len(offsets) == 0 {
+		return make([][]bool, len(keys)), nil
+	}
+	res, err := c.bitField(ctx, keys, offsets)
+	if err != nil && strings.HasPrefix(err.Error(), "ERR unknown command") {
+		return c.getBitsEach(ctx, keys, offsets)
+	}
+	return res, err
+}
+
+func (c *Client) bitField(ctx context.Context, keys []string, offsets []int64) ([][]bool, error) {
+	args := make([]interface{}, 0, len(offsets)*3)
+	for _, off := range offsets {
+		args = append(args, "GET", "u1", off)
+	}
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.IntSliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.BitField(ctx, key, args...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	res := make([][]bool, len(keys))
+	for i, cmd := range cmds {
+		vals := cmd.Val()
+		res[i] = make([]bool, len(offsets))
+		for j := range res[i] {
+			res[i][j] = j < len(vals) && vals[j] == 1
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) getBitsEach(ctx context.Context, keys []string, offsets []int64) ([][]bool, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make([][]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = make([]*redis.IntCmd, len(offsets))
+		for j, off := range offsets {
+			cmds[i][j] = pipe.GetBit(ctx, key, off)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	res := make([][]bool, len(keys))
+	for i := range keys {
+		res[i] = make([]bool, len(offsets))
+		for j, cmd := range cmds[i] {
+			res[i][j] = cmd.Val() == 1
+		}
+	}
+	return res, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"time"
+
+	redis "github.com/redis/go-redis/v9"
 )
 
 func (c *Client) GetBytes(ctx context.Context, key string) ([]byte, error) {
@@ -33,3 +35,34 @@ func (c *Client) MGet(cacheCtx context.Context, cacheKeys ...string) ([]interfac
 	}
 	return c.rdb.MGet(cacheCtx, cacheKeys...).Result()
 }
+
+// SetNX 仅当 key 不存在时写入，返回是否写入成功；用于幂等去重
+func (c *Client) SetNX(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if c == nil || c.rdb == nil {
+		return false, errors.New("redis client not initialized")
+	}
+	return c.rdb.SetNX(ctx, key, 1, ttl).Result()
+}
+
+// SetNXMulti 在同一个 pipeline 里对多个 key 做 SetNX，返回每个 key 是否写入成功
+func (c *Client) SetNXMulti(ctx context.Context, keys []string, ttl time.Duration) ([]bool, error) {
+	if c == nil || c.rdb == nil {
+		return nil, errors.New("redis client not initialized")
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.SetNX(ctx, key, 1, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	res := make([]bool, len(keys))
+	for i, cmd := range cmds {
+		res[i] = cmd.Val()
+	}
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// PFAddMulti 单次 pipeline 向多个 HyperLogLog 写入元素，并刷新过期时间（ttl<=0 不过期）
+func (c *Client) PFAddMulti(ctx context.Context, members map[string][]string, ttl time.Duration) error {
+	if c == nil || c.rdb == nil {
+		return errors.New("redis client not initialized")
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	for key, vals := range members {
+		if len(vals) == 0 {
+			continue
+		}
+		args := make([]interface{}, len(vals))
+		for i, v := range vals {
+			args[i] = v
+		}
+		pipe.PFAdd(ctx, key, args...)
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PFCountEach 分别返回每个 key 的基数估计
+func (c *Client) PFCountEach(ctx context.Context, keys []string) ([]int64, error) {
+	if c == nil || c.rdb == nil {
+		return nil, errors.New("redis client not initialized")
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.PFCount(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	res := make([]int64, len(keys))
+	for i, cmd := range cmds {
+		res[i] = cmd.Val()
+	}
+	return res, nil
+}
@@ -0,0 +1,29 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+func (c *Client) Publish(ctx context.Context, channel string, payload []byte) error {
+	if c == nil || c.rdb == nil {
+		return errors.New("redis client not initialized")
+	}
+	return c.rdb.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe 返回的 PubSub 由调用方负责 Close
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*redis.PubSub, error) {
+	if c == nil || c.rdb == nil {
+		return nil, errors.New("redis client not initialized")
+	}
+	ps := c.rdb.Subscribe(ctx, channels...)
+	// 等待订阅确认，保证返回后发布的消息不会丢
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	return ps, nil
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SAdd 写入集合并刷新过期时间（ttl<=0 不过期）
+func (c *Client) SAdd(ctx context.Context, key string, ttl time.Duration, members ...string) error {
+	if c == nil || c.rdb == nil {
+		return errors.New("redis client not initialized")
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	pipe := c.rdb.Pipeline()
+	pipe.SAdd(ctx, key, args...)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
+	if c == nil || c.rdb == nil {
+		return nil, errors.New("redis client not initialized")
+	}
+	return c.rdb.SMembers(ctx, key).Result()
+}
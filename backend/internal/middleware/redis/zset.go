@@ -79,3 +79,109 @@ func (c *Client) ZRevRangeByScore(ctx context.Context, key string, max, min stri
 		Count:  count,
 	}).Result()
 }
+
+func (c *Client) ZRevRangeByScoreWithScores(ctx context.Context, key string, max, min string, offset, count int64) ([]redis.Z, error) {
+	if c == nil || c.rdb == nil {
+		return nil, errors.New("redis client not initialized")
+	}
+	return c.rdb.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Max:    max,
+		Min:    min,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+}
+
+func (c *Client) ZRem(ctx context.Context, key string, members ...string) error {
+	if c == nil || c.rdb == nil {
+		return nil
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	return c.rdb.ZRem(ctx, key, args...).Err()
+}
+
+// 只写入已存在的 ZSET，并裁剪到 maxLen；用于写扩散时跳过尚未构建的收件箱
+var zaddIfExistsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+  return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[1], ARGV[2])
+local maxLen = tonumber(ARGV[3])
+if maxLen > 0 then
+  redis.call("ZREMRANGEBYRANK", KEYS[1], 0, -maxLen - 1)
+end
+return 1
+`)
+
+// ZAddIfExistsMulti 对多个 key 批量执行 zaddIfExistsScript（单次 pipeline）
+func (c *Client) ZAddIfExistsMulti(ctx context.Context, keys []string, score float64, member string, maxLen int64) error {
+	if c == nil || c.rdb == nil {
+		return nil
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := zaddIfExistsScript.Load(ctx, c.rdb).Err(); err != nil {
+		return err
+	}
+	pipe := c.rdb.Pipeline()
+	for _, key := range keys {
+		zaddIfExistsScript.EvalSha(ctx, pipe, []string{key}, score, member, maxLen)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *Client) ZCount(ctx context.Context, key string, min, max string) (int64, error) {
+	if c == nil || c.rdb == nil {
+		return 0, errors.New("redis client not initialized")
+	}
+	return c.rdb.ZCount(ctx, key, min, max).Result()
+}
+
+func (c *Client) ZRemRangeByScore(ctx context.Context, key string, min, max string) error {
+	if c == nil || c.rdb == nil {
+		return nil
+	}
+	return c.rdb.ZRemRangeByScore(ctx, key, min, max).Err()
+}
+
+// ZIncrByMulti 在同一个 ZSET 里给多个成员加同样的分数，并刷新过期时间
+func (c *Client) ZIncrByMulti(ctx context.Context, key string, members []string, score float64, ttl time.Duration) error {
+	if c == nil || c.rdb == nil {
+		return errors.New("redis client not initialized")
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	for _, m := range members {
+		pipe.ZIncrBy(ctx, key, score, m)
+	}
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ZScore 成员不存在时返回 ok=false
+func (c *Client) ZScore(ctx context.Context, key string, member string) (float64, bool, error) {
+	if c == nil || c.rdb == nil {
+		return 0, false, errors.New("redis client not initialized")
+	}
+	score, err := c.rdb.ZScore(ctx, key, member).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return score, true, nil
+}
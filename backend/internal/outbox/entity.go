@@ -0,0 +1,33 @@
+package outbox
+
+import "time"
+
+// 事件状态：pending 等待投递（含退避中的重试），dead 超过最大重试次数，留表人工排查。
+// 投递成功且本进程的后续处理都成功的行直接删除
+const (
+	StatusPending = "pending"
+	StatusDead    = "dead"
+)
+
+// Event 事务性 outbox 的一行：业务写库时在同一事务里插入，relay 异步投递到 Exchange/RoutingKey。
+// Payload 是消息体 JSON，Type 决定投递成功后本进程要做的后续处理；
+// Published 表示已投递到 MQ、只剩后续处理失败待重试，重试时不再重复投递
+type Event struct {
+	ID            uint64    `gorm:"primaryKey"`
+	EventID       string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Type          string    `gorm:"type:varchar(64);not null"`
+	AggregateID   uint      `gorm:"not null;default:0;index"`
+	Exchange      string    `gorm:"type:varchar(128);not null"`
+	RoutingKey    string    `gorm:"type:varchar(128);not null"`
+	Payload       []byte    `gorm:"type:json;not null"`
+	Published     bool      `gorm:"not null;default:false"`
+	Status        string    `gorm:"type:varchar(16);not null;default:pending;index:idx_outbox_status_next,priority:1"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_status_next,priority:2"`
+	LastError     string    `gorm:"type:varchar(512)"`
+	CreatedAt     time.Time
+}
+
+func (Event) TableName() string {
+	return "outbox_events"
+}
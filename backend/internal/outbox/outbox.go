@@ -0,0 +1,45 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+
+	"gorm.io/gorm"
+)
+
+// Enqueue 在调用方的事务里写入待投递事件；事务回滚时事件一起消失，提交后由 Relay 保证至少投递一次
+func Enqueue(tx *gorm.DB, msgs ...rabbitmq.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	events := make([]Event, 0, len(msgs))
+	for _, m := range msgs {
+		if m.EventID == "" || m.Exchange == "" || m.RoutingKey == "" {
+			return errors.New("outbox message requires event id, exchange and routing key")
+		}
+		body, err := json.Marshal(m.Payload)
+		if err != nil {
+			return err
+		}
+		typ := m.Type
+		if typ == "" {
+			typ = m.RoutingKey
+		}
+		events = append(events, Event{
+			EventID:       m.EventID,
+			Type:          typ,
+			AggregateID:   m.AggregateID,
+			Exchange:      m.Exchange,
+			RoutingKey:    m.RoutingKey,
+			Payload:       body,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	return tx.Create(&events).Error
+}
@@ -0,0 +1,224 @@
+package outbox
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// metrics 通过 /debug/vars 暴露：claimed/published/failed/handler_failed/dead 为累计次数，
+// pending/dead_total/lag_seconds 为定期刷新的积压情况
+var metrics = expvar.NewMap("outbox")
+
+// Publisher 由 rabbitmq.RabbitMQ 实现
+type Publisher interface {
+	PublishRaw(ctx context.Context, exchange string, routingKey string, messageID string, body []byte) error
+}
+
+// Handler 事件投递成功后在本进程执行的后续处理，如更新搜索索引。
+// 返回错误时事件按退避重试，重试会重新执行该类型的全部 Handler，所以 Handler 必须幂等
+type Handler func(ctx context.Context, e *Event) error
+
+// Relay 把 outbox_events 投递到 MQ。多实例同时运行时用 FOR UPDATE SKIP LOCKED 分批认领，
+// 认领时把 next_attempt_at 推后一个租期，进程中途退出的批次过期后会被其他实例重新认领。
+// 投递或后续处理失败按指数退避重试，超过 maxAttempts 标记为 dead。保证至少一次，消费端需要按 event_id 去重
+type Relay struct {
+	db          *gorm.DB
+	pub         Publisher
+	handlers    map[string][]Handler
+	batchSize   int
+	interval    time.Duration
+	lease       time.Duration
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	now         func() time.Time
+}
+
+func NewRelay(db *gorm.DB, pub Publisher) *Relay {
+	return &Relay{
+		db:          db,
+		pub:         pub,
+		handlers:    make(map[string][]Handler),
+		batchSize:   100,
+		interval:    time.Second,
+		lease:       30 * time.Second,
+		maxAttempts: 10,
+		baseBackoff: time.Second,
+		maxBackoff:  10 * time.Minute,
+		now:         time.Now,
+	}
+}
+
+// Handle 注册某类事件投递成功后的处理，需在 Run 之前调用
+func (r *Relay) Handle(eventType string, h Handler) {
+	r.handlers[eventType] = append(r.handlers[eventType], h)
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	gaugeTicker := time.NewTicker(30 * time.Second)
+	defer gaugeTicker.Stop()
+	r.refreshGauges(ctx)
+	for {
+		n, err := r.relayOnce(ctx)
+		if err != nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		if n >= r.batchSize {
+			// 还有积压，不等待直接处理下一批
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-gaugeTicker.C:
+			r.refreshGauges(ctx)
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// relayOnce 认领并投递一批，返回认领到的条数
+func (r *Relay) relayOnce(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	metrics.Add("claimed", int64(len(events)))
+
+	done := make([]uint64, 0, len(events))
+	for i := range events {
+		e := &events[i]
+		if !e.Published {
+			if err := r.pub.PublishRaw(ctx, e.Exchange, e.RoutingKey, e.EventID, e.Payload); err != nil {
+				r.fail(ctx, e, err)
+				continue
+			}
+			metrics.Add("published", 1)
+			e.Published = true
+		}
+		if err := r.handle(ctx, e); err != nil {
+			r.fail(ctx, e, err)
+			continue
+		}
+		done = append(done, e.ID)
+	}
+	if len(done) > 0 {
+		if err := r.db.WithContext(ctx).Where("id IN ?", done).Delete(&Event{}).Error; err != nil {
+			// 删除失败的行租期过后会再投一次，由消费端去重
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// handle 依次执行事件的后续处理，遇到第一个错误就停止
+func (r *Relay) handle(ctx context.Context, e *Event) error {
+	for _, h := range r.handlers[e.Type] {
+		if err := h(ctx, e); err != nil {
+			metrics.Add("handler_failed", 1)
+			return fmt.Errorf("handle %s: %w", e.Type, err)
+		}
+	}
+	return nil
+}
+
+// claim 锁住一批到期的事件并推后 next_attempt_at，事务提交后释放行锁，投递在事务外进行
+func (r *Relay) claim(ctx context.Context) ([]Event, error) {
+	var events []Event
+	now := r.now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(r.batchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		ids := make([]uint64, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+		}
+		return tx.Model(&Event{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(r.lease)).Error
+	})
+	return events, err
+}
+
+func (r *Relay) fail(ctx context.Context, e *Event, cause error) {
+	attempts := e.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"published":  e.Published,
+		"last_error": truncate(cause.Error(), 512),
+	}
+	if attempts >= r.maxAttempts {
+		updates["status"] = StatusDead
+		metrics.Add("dead", 1)
+		log.Printf("outbox relay: event %s (%s) dead after %d attempts: %v", e.EventID, e.Type, attempts, cause)
+	} else {
+		updates["next_attempt_at"] = r.now().Add(backoff(attempts, r.baseBackoff, r.maxBackoff))
+		metrics.Add("failed", 1)
+	}
+	if err := r.db.WithContext(ctx).Model(&Event{}).Where("id = ?", e.ID).Updates(updates).Error; err != nil {
+		log.Printf("outbox relay: record failure of event %s failed: %v", e.EventID, err)
+	}
+}
+
+// backoff 第 n 次失败后的等待时间：base * 2^(n-1)，不超过 max
+func backoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return d
+}
+
+func (r *Relay) refreshGauges(ctx context.Context) {
+	var stats struct {
+		Pending int64
+		Dead    int64
+		Oldest  *time.Time
+	}
+	if err := r.db.WithContext(ctx).Model(&Event{}).
+		Select("SUM(status = ?) AS pending, SUM(status = ?) AS dead, MIN(CASE WHEN status = ? THEN created_at END) AS oldest",
+			StatusPending, StatusDead, StatusPending).
+		Scan(&stats).Error; err != nil {
+		return
+	}
+	setGauge("pending", stats.Pending)
+	setGauge("dead_total", stats.Dead)
+	var lag int64
+	if stats.Oldest != nil {
+		lag = int64(r.now().Sub(*stats.Oldest).Seconds())
+	}
+	setGauge("lag_seconds", lag)
+}
+
+func setGauge(name string, v int64) {
+	g := new(expvar.Int)
+	g.Set(v)
+	metrics.Set(name, g)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+)
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, time.Minute},
+		{100, time.Minute},
+	}
+	for _, c := range cases {
+		if got := backoff(c.attempts, time.Second, time.Minute); got != c.want {
+			t.Errorf("backoff(%d) = %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestTruncateKeepsValidUTF8(t *testing.T) {
+	if got := truncate("连接失败", 4); got != "连" {
+		t.Fatalf("truncate = %q", got)
+	}
+	if got := truncate("ok", 4); got != "ok" {
+		t.Fatalf("truncate = %q", got)
+	}
+}
+
+func TestEnqueueRejectsIncompleteMessage(t *testing.T) {
+	if err := Enqueue(nil, rabbitmq.Message{EventID: "e1", Exchange: "x"}); err == nil {
+		t.Fatal("expected error for message without routing key")
+	}
+}
+
+type recordingPublisher struct {
+	sent []string
+}
+
+func (p *recordingPublisher) PublishRaw(ctx context.Context, exchange string, routingKey string, messageID string, body []byte) error {
+	p.sent = append(p.sent, messageID)
+	return nil
+}
+
+func TestRelayRetriesFailedHandlerWithoutRepublishing(t *testing.T) {
+	db := dbtest.Open(t, &Event{})
+	ctx := context.Background()
+	if err := Enqueue(db, rabbitmq.Message{EventID: "e1", Type: "video_deleted", Exchange: "x", RoutingKey: "rk", Payload: map[string]int{"video_id": 7}}); err != nil {
+		t.Fatal(err)
+	}
+	pub := &recordingPublisher{}
+	r := NewRelay(db, pub)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	calls := 0
+	r.Handle("video_deleted", func(ctx context.Context, e *Event) error {
+		calls++
+		if calls == 1 {
+			return errors.New("redis down")
+		}
+		return nil
+	})
+
+	if _, err := r.relayOnce(ctx); err != nil {
+		t.Fatalf("relayOnce: %v", err)
+	}
+	var e Event
+	if err := db.First(&e).Error; err != nil {
+		t.Fatalf("event should stay after handler failure: %v", err)
+	}
+	if !e.Published || e.Status != StatusPending || e.Attempts != 1 {
+		t.Fatalf("event = published %v status %s attempts %d", e.Published, e.Status, e.Attempts)
+	}
+
+	// 退避到期后只重跑 handler，不再投递
+	now = e.NextAttemptAt
+	if _, err := r.relayOnce(ctx); err != nil {
+		t.Fatalf("relayOnce: %v", err)
+	}
+	if calls != 2 || len(pub.sent) != 1 {
+		t.Fatalf("calls = %d, published %d times", calls, len(pub.sent))
+	}
+	var n int64
+	db.Model(&Event{}).Count(&n)
+	if n != 0 {
+		t.Fatalf("event should be deleted after handler succeeds, %d left", n)
+	}
+}
+
+// SQLite 没有行锁，SKIP LOCKED 的多实例认领只能在 MySQL 上验证
+func TestConcurrentRelaysClaimDisjointBatchesOnMySQL(t *testing.T) {
+	db := dbtest.OpenMySQL(t, &Event{})
+	ctx := context.Background()
+	const total = 40
+	for i := 0; i < total; i++ {
+		m := rabbitmq.Message{EventID: fmt.Sprintf("e%d", i), Type: "t", Exchange: "x", RoutingKey: "rk", Payload: i}
+		if err := Enqueue(db, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	claimed := map[uint64]int{}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		r := NewRelay(db, &recordingPublisher{})
+		r.batchSize = 5
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				events, err := r.claim(ctx)
+				if err != nil {
+					t.Errorf("claim: %v", err)
+					return
+				}
+				if len(events) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, e := range events {
+					claimed[e.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != total {
+		t.Fatalf("claimed %d distinct events, want %d", len(claimed), total)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Fatalf("event %d claimed %d times", id, n)
+		}
+	}
+}
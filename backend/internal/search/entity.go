@@ -0,0 +1,48 @@
+package search
+
+import "feedsystem_video_go/internal/video"
+
+const (
+	maxSearchLimit    = 50
+	maxQueryRuneCount = 100
+)
+
+type SearchVideosRequest struct {
+	Query    string `json:"q"`
+	Tag      string `json:"tag,omitempty"`       // 只看带该 #话题 的视频
+	AuthorID uint   `json:"author_id,omitempty"` // 只看该作者的视频
+	Limit    int    `json:"limit"`
+	Cursor   string `json:"cursor,omitempty"`
+}
+
+type SearchVideosResponse struct {
+	VideoList  []video.Video `json:"video_list"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	HasMore    bool          `json:"has_more"`
+}
+
+const (
+	defaultUserSearchLimit = 10
+	maxUserSearchLimit     = 20
+	maxUserQueryRuneCount  = 32
+	// 先按匹配度取这么多候选，再查关注关系重排
+	userCandidatePoolSize = 200
+)
+
+type SearchUsersRequest struct {
+	Query string `json:"q"`
+	Limit int    `json:"limit"`
+}
+
+type UserSearchItem struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	IsFollowing bool   `json:"is_following"`
+	IsMutual    bool   `json:"is_mutual"`
+}
+
+type SearchUsersResponse struct {
+	Users []UserSearchItem `json:"users"`
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"net/http"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	service *SearchService
+}
+
+func NewSearchHandler(service *SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+func (h *SearchHandler) SearchVideos(c *gin.Context) {
+	var req SearchVideosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.service.SearchVideos(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type UserSearchHandler struct {
+	service *UserSearchService
+}
+
+func NewUserSearchHandler(service *UserSearchService) *UserSearchHandler {
+	return &UserSearchHandler{service: service}
+}
+
+// SearchUsers 软鉴权：未登录时不做关注关系加权
+func (h *UserSearchHandler) SearchUsers(c *gin.Context) {
+	var req SearchUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	viewerID, err := jwt.GetAccountID(c)
+	if err != nil {
+		viewerID = 0
+	}
+	resp, err := h.service.SearchUsers(c.Request.Context(), viewerID, req)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+)
+
+// Document 一个视频在索引里的内容；Tags 为从标题和简介中提取的 #话题
+type Document struct {
+	ID          uint
+	AuthorID    uint
+	Title       string
+	Description string
+	Tags        []string
+	CreateTime  time.Time
+}
+
+// Query 全文检索条件；Tag、AuthorID 为精确过滤，After 为上一页最后一条
+type Query struct {
+	Text     string
+	Tag      string
+	AuthorID uint
+	Limit    int
+	After    *Cursor
+}
+
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+// Cursor 结果按 (Score desc, ID desc) 排序，游标记录上一页最后一条的位置
+type Cursor struct {
+	Score float64
+	ID    uint
+}
+
+// Index 搜索索引抽象：默认使用进程内的 MemoryIndex，也可以换成外部搜索引擎的适配实现
+type Index interface {
+	Upsert(ctx context.Context, doc Document) error
+	Delete(ctx context.Context, id uint) error
+	Search(ctx context.Context, q Query) ([]Hit, error)
+}
+
+// after 判断 h 是否排在游标之后
+func (c *Cursor) after(h Hit) bool {
+	if c == nil {
+		return true
+	}
+	if h.Score != c.Score {
+		return h.Score < c.Score
+	}
+	return h.ID < c.ID
+}
+
+func EncodeCursor(h Hit) string {
+	raw := strconv.FormatFloat(h.Score, 'g', -1, 64) + ":" + strconv.FormatUint(uint64(h.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", apierror.ErrValidation)
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	score, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return nil, invalid
+	}
+	sc, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n == 0 {
+		return nil, invalid
+	}
+	return &Cursor{Score: sc, ID: uint(n)}, nil
+}
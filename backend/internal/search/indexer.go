@@ -0,0 +1,135 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/video"
+)
+
+const rebuildBatchSize = 500
+
+// Indexer 负责让索引跟上数据库：启动时全量重建，之后按发布/删除事件增量更新。
+// 配置了 SearchMQ 时事件广播给所有实例，否则只更新本进程的索引
+type Indexer struct {
+	index Index
+	repo  *SearchRepository
+	mq    *rabbitmq.SearchMQ
+}
+
+func NewIndexer(index Index, repo *SearchRepository, mq *rabbitmq.SearchMQ) *Indexer {
+	return &Indexer{index: index, repo: repo, mq: mq}
+}
+
+func documentOf(v *video.Video) Document {
+	return Document{
+		ID:          v.ID,
+		AuthorID:    v.AuthorID,
+		Title:       v.Title,
+		Description: v.Description,
+		Tags:        video.ExtractTags(v.Title + " " + v.Description),
+		CreateTime:  v.CreateTime,
+	}
+}
+
+// Rebuild 分批把所有已发布视频写入索引
+func (x *Indexer) Rebuild(ctx context.Context) error {
+	var afterID uint
+	total := 0
+	for {
+		videos, err := x.repo.ListReadyAfter(ctx, afterID, rebuildBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range videos {
+			if err := x.index.Upsert(ctx, documentOf(&videos[i])); err != nil {
+				return err
+			}
+		}
+		total += len(videos)
+		if len(videos) < rebuildBatchSize {
+			log.Printf("search index rebuilt: %d videos", total)
+			return nil
+		}
+		afterID = videos[len(videos)-1].ID
+	}
+}
+
+// Published 视频发布（outbox 的 video_published 投递成功）后调用
+func (x *Indexer) Published(ctx context.Context, videoID uint) error {
+	if x == nil {
+		return nil
+	}
+	if x.mq != nil {
+		if err := x.mq.Index(ctx, videoID); err == nil {
+			return nil
+		}
+	}
+	if err := x.apply(ctx, rabbitmq.SearchActionIndex, videoID); err != nil {
+		log.Printf("search index video %d failed: %v", videoID, err)
+		return err
+	}
+	return nil
+}
+
+// Deleted 视频删除后调用
+func (x *Indexer) Deleted(ctx context.Context, videoID uint) error {
+	if x == nil {
+		return nil
+	}
+	if x.mq != nil {
+		if err := x.mq.Delete(ctx, videoID); err == nil {
+			return nil
+		}
+	}
+	if err := x.apply(ctx, rabbitmq.SearchActionDelete, videoID); err != nil {
+		log.Printf("search remove video %d failed: %v", videoID, err)
+		return err
+	}
+	return nil
+}
+
+// apply 索引时以数据库为准重新读取，视频已不可见则从索引删除
+func (x *Indexer) apply(ctx context.Context, action string, videoID uint) error {
+	if action == rabbitmq.SearchActionIndex {
+		videos, err := x.repo.GetReadyByIDs(ctx, []uint{videoID})
+		if err != nil {
+			return err
+		}
+		if len(videos) == 1 {
+			return x.index.Upsert(ctx, documentOf(&videos[0]))
+		}
+	}
+	return x.index.Delete(ctx, videoID)
+}
+
+// Run 消费广播的发布/删除事件，更新本实例的索引
+func (x *Indexer) Run(ctx context.Context) error {
+	if x == nil || x.mq == nil {
+		return errors.New("search indexer is not initialized")
+	}
+	deliveries, err := x.mq.SubscribeVideos()
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("deliveries channel closed")
+			}
+			var evt rabbitmq.SearchEvent
+			if err := json.Unmarshal(d.Body, &evt); err != nil || evt.VideoID == 0 {
+				continue
+			}
+			if err := x.apply(ctx, evt.Action, evt.VideoID); err != nil {
+				log.Printf("search indexer: %s video %d failed: %v", evt.Action, evt.VideoID, err)
+			}
+		}
+	}
+}
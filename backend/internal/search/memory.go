@@ -0,0 +1,177 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// 标题命中的词频按 2 倍计，标题比简介更能代表视频内容
+	titleWeight = 2.0
+
+	defaultSearchLimit = 20
+)
+
+type memoryDoc struct {
+	authorID uint
+	tags     map[string]struct{}
+	length   float64
+	terms    []string
+}
+
+// MemoryIndex 进程内倒排索引，按 BM25（标题加权）打分，多个查询词之间为 AND；
+// 不依赖外部服务，可直接嵌入进程和测试，重启后由 Indexer 从数据库重建
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[uint]*memoryDoc
+	postings map[string]map[uint]float64
+	totalLen float64
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{docs: make(map[uint]*memoryDoc), postings: make(map[string]map[uint]float64)}
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+func (m *MemoryIndex) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+func (m *MemoryIndex) Upsert(_ context.Context, doc Document) error {
+	if doc.ID == 0 {
+		return nil
+	}
+	tf := make(map[string]float64)
+	var length float64
+	for _, t := range tokenize(doc.Title, false) {
+		tf[t] += titleWeight
+		length += titleWeight
+	}
+	for _, t := range tokenize(doc.Description, false) {
+		tf[t]++
+		length++
+	}
+	entry := &memoryDoc{authorID: doc.AuthorID, tags: make(map[string]struct{}, len(doc.Tags)), length: length}
+	for _, tag := range doc.Tags {
+		entry.tags[normalizeTag(tag)] = struct{}{}
+	}
+	for t := range tf {
+		entry.terms = append(entry.terms, t)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(doc.ID)
+	for t, f := range tf {
+		list := m.postings[t]
+		if list == nil {
+			list = make(map[uint]float64)
+			m.postings[t] = list
+		}
+		list[doc.ID] = f
+	}
+	m.docs[doc.ID] = entry
+	m.totalLen += length
+	return nil
+}
+
+func (m *MemoryIndex) Delete(_ context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(id)
+	return nil
+}
+
+func (m *MemoryIndex) removeLocked(id uint) {
+	entry := m.docs[id]
+	if entry == nil {
+		return
+	}
+	for _, t := range entry.terms {
+		delete(m.postings[t], id)
+		if len(m.postings[t]) == 0 {
+			delete(m.postings, t)
+		}
+	}
+	m.totalLen -= entry.length
+	delete(m.docs, id)
+}
+
+func (m *MemoryIndex) Search(_ context.Context, q Query) ([]Hit, error) {
+	terms := queryTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	tag := normalizeTag(q.Tag)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lists := make([]map[uint]float64, len(terms))
+	for i, t := range terms {
+		lists[i] = m.postings[t]
+		if len(lists[i]) == 0 {
+			return nil, nil
+		}
+	}
+	// 从最短的倒排表出发，逐个检查其余词是否都命中
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+	n := float64(len(m.docs))
+	avgLen := m.totalLen / n
+	idf := make([]float64, len(lists))
+	for i, list := range lists {
+		df := float64(len(list))
+		idf[i] = math.Log(1 + (n-df+0.5)/(df+0.5))
+	}
+
+	var hits []Hit
+next:
+	for id := range lists[0] {
+		doc := m.docs[id]
+		if q.AuthorID != 0 && doc.authorID != q.AuthorID {
+			continue
+		}
+		if tag != "" {
+			if _, ok := doc.tags[tag]; !ok {
+				continue
+			}
+		}
+		var score float64
+		for i, list := range lists {
+			f, ok := list[id]
+			if !ok {
+				continue next
+			}
+			norm := 1 - bm25B + bm25B*doc.length/avgLen
+			score += idf[i] * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+		h := Hit{ID: id, Score: score}
+		if q.After.after(h) {
+			hits = append(hits, h)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"feedsystem_video_go/internal/apierror"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Go语言 #Golang_Tips 入门!", false)
+	want := []string{"go", "语", "言", "语言", "golang_tips", "入", "门", "入门"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("index tokens = %v, want %v", got, want)
+	}
+	if got := queryTerms("短视频 短视频 猫"); !reflect.DeepEqual(got, []string{"短视", "视频", "猫"}) {
+		t.Fatalf("query terms = %v", got)
+	}
+}
+
+func ids(hits []Hit) []uint {
+	out := make([]uint, len(hits))
+	for i, h := range hits {
+		out[i] = h.ID
+	}
+	return out
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	idx := NewMemoryIndex()
+	docs := []Document{
+		{ID: 1, AuthorID: 10, Title: "猫咪短视频合集", Tags: []string{"萌宠"}},
+		{ID: 2, AuthorID: 11, Title: "日常 vlog", Description: "路边遇到的猫咪", Tags: []string{"vlog"}},
+		{ID: 3, AuthorID: 10, Title: "狗狗短视频", Tags: []string{"萌宠"}},
+		{ID: 4, AuthorID: 12, Title: "Cooking with Cats", Description: "cats in the kitchen"},
+	}
+	for _, d := range docs {
+		if err := idx.Upsert(ctx, d); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+
+	hits, err := idx.Search(ctx, Query{Text: "猫咪"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if !reflect.DeepEqual(ids(hits), []uint{1, 2}) {
+		t.Fatalf("猫咪 hits = %v, want title match first", ids(hits))
+	}
+	if hits, _ := idx.Search(ctx, Query{Text: "短视频", Tag: "#萌宠", AuthorID: 10}); len(hits) != 2 {
+		t.Fatalf("filtered hits = %v", ids(hits))
+	}
+	if hits, _ := idx.Search(ctx, Query{Text: "猫咪 vlog"}); !reflect.DeepEqual(ids(hits), []uint{2}) {
+		t.Fatalf("AND hits = %v", ids(hits))
+	}
+	if hits, _ := idx.Search(ctx, Query{Text: "CATS"}); !reflect.DeepEqual(ids(hits), []uint{4}) {
+		t.Fatalf("case-insensitive hits = %v", ids(hits))
+	}
+
+	first, _ := idx.Search(ctx, Query{Text: "短视频", Limit: 1})
+	c, err := DecodeCursor(EncodeCursor(first[0]))
+	if err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	second, _ := idx.Search(ctx, Query{Text: "短视频", Limit: 1, After: c})
+	if len(first) != 1 || len(second) != 1 || first[0].ID == second[0].ID {
+		t.Fatalf("paging returned %v then %v", ids(first), ids(second))
+	}
+
+	_ = idx.Delete(ctx, 1)
+	_ = idx.Upsert(ctx, Document{ID: 2, Title: "日常 vlog"})
+	if hits, _ := idx.Search(ctx, Query{Text: "猫咪"}); len(hits) != 0 {
+		t.Fatalf("stale hits after delete/update: %v", ids(hits))
+	}
+	if idx.Len() != 3 {
+		t.Fatalf("len = %d, want 3", idx.Len())
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"!!", "bm90LWEtY3Vyc29y", EncodeCursor(Hit{Score: 1})} {
+		if _, err := DecodeCursor(s); !errors.Is(err, apierror.ErrValidation) {
+			t.Fatalf("DecodeCursor(%q) err = %v", s, err)
+		}
+	}
+}
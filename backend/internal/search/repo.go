@@ -0,0 +1,44 @@
+package search
+
+import (
+	"context"
+
+	"feedsystem_video_go/internal/video"
+
+	"gorm.io/gorm"
+)
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// GetReadyByIDs 按 ID 取已发布的公开视频，顺序不保证
+func (r *SearchRepository) GetReadyByIDs(ctx context.Context, ids []uint) ([]video.Video, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var videos []video.Video
+	if err := r.db.WithContext(ctx).
+		Where("id IN ? AND status = ? AND visibility = ?", ids, video.VideoStatusReady, video.VisibilityPublic).
+		Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// ListReadyAfter 按 ID 升序分批扫描已发布的公开视频，用于重建索引
+func (r *SearchRepository) ListReadyAfter(ctx context.Context, afterID uint, limit int) ([]video.Video, error) {
+	var videos []video.Video
+	if err := r.db.WithContext(ctx).
+		Where("id > ? AND status = ? AND visibility = ?", afterID, video.VideoStatusReady, video.VisibilityPublic).
+		Order("id ASC").
+		Limit(limit).
+		Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
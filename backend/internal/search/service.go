@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/video"
+)
+
+type SearchService struct {
+	index Index
+	repo  *SearchRepository
+}
+
+func NewSearchService(index Index, repo *SearchRepository) *SearchService {
+	return &SearchService{index: index, repo: repo}
+}
+
+// SearchVideos 检索后按相关度顺序回表取视频；索引里残留的已删除视频顺手清掉
+func (s *SearchService) SearchVideos(ctx context.Context, req SearchVideosRequest) (SearchVideosResponse, error) {
+	text := strings.TrimSpace(req.Query)
+	if text == "" {
+		return SearchVideosResponse{}, fmt.Errorf("%w: q is required", apierror.ErrValidation)
+	}
+	if utf8.RuneCountInString(text) > maxQueryRuneCount {
+		return SearchVideosResponse{}, fmt.Errorf("%w: q must be at most %d characters", apierror.ErrValidation, maxQueryRuneCount)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+	var after *Cursor
+	if req.Cursor != "" {
+		c, err := DecodeCursor(req.Cursor)
+		if err != nil {
+			return SearchVideosResponse{}, err
+		}
+		after = c
+	}
+
+	hits, err := s.index.Search(ctx, Query{Text: text, Tag: req.Tag, AuthorID: req.AuthorID, Limit: limit + 1, After: after})
+	if err != nil {
+		return SearchVideosResponse{}, err
+	}
+	resp := SearchVideosResponse{VideoList: []video.Video{}}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		resp.HasMore = true
+		resp.NextCursor = EncodeCursor(hits[len(hits)-1])
+	}
+	if len(hits) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	videos, err := s.repo.GetReadyByIDs(ctx, ids)
+	if err != nil {
+		return SearchVideosResponse{}, err
+	}
+	byID := make(map[uint]video.Video, len(videos))
+	for _, v := range videos {
+		byID[v.ID] = v
+	}
+	for _, id := range ids {
+		v, ok := byID[id]
+		if !ok {
+			_ = s.index.Delete(ctx, id)
+			continue
+		}
+		resp.VideoList = append(resp.VideoList, v)
+	}
+	return resp, nil
+}
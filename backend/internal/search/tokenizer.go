@@ -0,0 +1,74 @@
+package search
+
+import "unicode"
+
+// 查询最多取这么多个词，避免超长查询拖慢检索
+const maxQueryTerms = 32
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// tokenize 以空格分词的文字按连续字母、数字、下划线切词并转小写（与 #话题 的 [\p{L}\p{N}_] 一致）；
+// 中日韩文字没有分隔符，索引时切成单字和相邻二元组，查询时只用二元组（单字查询用单字），
+// 这样“短视频”能命中“短视频”而不会被“视”“频”单字淹没
+func tokenize(text string, query bool) []string {
+	var (
+		tokens []string
+		word   []rune
+		run    []rune
+	)
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushRun := func() {
+		if len(run) == 0 {
+			return
+		}
+		if !query || len(run) == 1 {
+			for _, r := range run {
+				tokens = append(tokens, string(r))
+			}
+		}
+		for i := 1; i < len(run); i++ {
+			tokens = append(tokens, string(run[i-1:i+1]))
+		}
+		run = run[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_':
+			flushRun()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushRun()
+		}
+	}
+	flushWord()
+	flushRun()
+	return tokens
+}
+
+// queryTerms 查询分词并去重
+func queryTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range tokenize(text, true) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		terms = append(terms, t)
+		if len(terms) == maxQueryTerms {
+			break
+		}
+	}
+	return terms
+}
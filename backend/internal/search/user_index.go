@@ -0,0 +1,283 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 用户名匹配方式，分数越高越靠前
+const (
+	userMatchBio    = 20
+	userMatchFuzzy  = 40
+	userMatchPrefix = 60
+	userMatchExact  = 100
+
+	// 单个前缀展开的候选上限，避免一个字母就把全部用户扫一遍
+	maxPrefixExpand = 1000
+)
+
+type UserDoc struct {
+	ID        uint
+	Username  string
+	AvatarURL string
+	Bio       string
+}
+
+type UserMatch struct {
+	ID    uint
+	Score int
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	ids      map[uint]struct{} // 用户名（小写）恰好在此结束的账号
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[rune]*trieNode)}
+}
+
+// UserIndex 进程内的用户搜索索引：用户名放在字典树里支持前缀和模糊（编辑距离）匹配，简介按词建倒排
+type UserIndex struct {
+	mu    sync.RWMutex
+	root  *trieNode
+	users map[uint]*UserDoc
+	bio   map[string]map[uint]struct{}
+}
+
+func NewUserIndex() *UserIndex {
+	return &UserIndex{root: newTrieNode(), users: make(map[uint]*UserDoc), bio: make(map[string]map[uint]struct{})}
+}
+
+func foldRunes(s string) []rune {
+	return []rune(strings.ToLower(strings.TrimSpace(s)))
+}
+
+func (x *UserIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.users)
+}
+
+// Get 返回索引里的账号快照，搜索结果直接用它渲染，不再回表
+func (x *UserIndex) Get(id uint) (UserDoc, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	doc, ok := x.users[id]
+	if !ok {
+		return UserDoc{}, false
+	}
+	return *doc, true
+}
+
+func (x *UserIndex) Upsert(doc UserDoc) {
+	if doc.ID == 0 {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(doc.ID)
+
+	node := x.root
+	for _, r := range foldRunes(doc.Username) {
+		child := node.children[r]
+		if child == nil {
+			child = newTrieNode()
+			node.children[r] = child
+		}
+		node = child
+	}
+	if node.ids == nil {
+		node.ids = make(map[uint]struct{})
+	}
+	node.ids[doc.ID] = struct{}{}
+
+	for _, t := range tokenize(doc.Bio, false) {
+		set := x.bio[t]
+		if set == nil {
+			set = make(map[uint]struct{})
+			x.bio[t] = set
+		}
+		set[doc.ID] = struct{}{}
+	}
+	x.users[doc.ID] = &doc
+}
+
+func (x *UserIndex) Delete(id uint) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(id)
+}
+
+func (x *UserIndex) removeLocked(id uint) {
+	old := x.users[id]
+	if old == nil {
+		return
+	}
+	// 沿路径删掉账号，并回收不再有用户的分支
+	name := foldRunes(old.Username)
+	path := make([]*trieNode, 0, len(name)+1)
+	node := x.root
+	path = append(path, node)
+	for _, r := range name {
+		node = node.children[r]
+		if node == nil {
+			break
+		}
+		path = append(path, node)
+	}
+	if node != nil {
+		delete(node.ids, id)
+		for i := len(path) - 1; i > 0; i-- {
+			n := path[i]
+			if len(n.ids) > 0 || len(n.children) > 0 {
+				break
+			}
+			delete(path[i-1].children, name[i-1])
+		}
+	}
+	for _, t := range tokenize(old.Bio, false) {
+		delete(x.bio[t], id)
+		if len(x.bio[t]) == 0 {
+			delete(x.bio, t)
+		}
+	}
+	delete(x.users, id)
+}
+
+// collect 按层序展开子树，短用户名先被收集
+func collect(start *trieNode, limit int, visit func(id uint)) {
+	queue := []*trieNode{start}
+	seen := 0
+	for len(queue) > 0 && seen < limit {
+		n := queue[0]
+		queue = queue[1:]
+		for id := range n.ids {
+			visit(id)
+			seen++
+		}
+		for _, c := range n.children {
+			queue = append(queue, c)
+		}
+	}
+}
+
+// fuzzyDistance 查询越短允许的编辑距离越小，太短的查询不做模糊匹配
+func fuzzyDistance(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Search 返回按匹配分数排序的前 limit 个账号：完全匹配 > 前缀 > 模糊前缀 > 简介
+func (x *UserIndex) Search(query string, limit int) []UserMatch {
+	q := foldRunes(query)
+	if len(q) == 0 {
+		return nil
+	}
+	best := make(map[uint]int)
+	add := func(id uint, score int) {
+		if score > best[id] {
+			best[id] = score
+		}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	node := x.root
+	for _, r := range q {
+		if node = node.children[r]; node == nil {
+			break
+		}
+	}
+	if node != nil {
+		for id := range node.ids {
+			add(id, userMatchExact)
+		}
+		collect(node, maxPrefixExpand, func(id uint) { add(id, userMatchPrefix) })
+	}
+
+	if k := fuzzyDistance(len(q)); k > 0 {
+		// 在字典树上逐层推进编辑距离矩阵的一行（含相邻字符交换，即 OSA 距离），整行都超过 k 时剪枝；
+		// 行末 <= k 说明某个前缀与查询足够接近，整棵子树算模糊前缀命中
+		row := make([]int, len(q)+1)
+		for i := range row {
+			row[i] = i
+		}
+		var walk func(n *trieNode, r, prevR rune, prev, prevPrev []int)
+		walk = func(n *trieNode, r, prevR rune, prev, prevPrev []int) {
+			cur := make([]int, len(prev))
+			cur[0] = prev[0] + 1
+			rowMin := cur[0]
+			for i := 1; i < len(cur); i++ {
+				cost := 1
+				if q[i-1] == r {
+					cost = 0
+				}
+				cur[i] = min(cur[i-1]+1, prev[i]+1, prev[i-1]+cost)
+				if prevPrev != nil && i > 1 && q[i-1] == prevR && q[i-2] == r {
+					cur[i] = min(cur[i], prevPrev[i-2]+1)
+				}
+				rowMin = min(rowMin, cur[i])
+			}
+			if d := cur[len(cur)-1]; d <= k {
+				score := userMatchFuzzy - 10*d
+				collect(n, maxPrefixExpand, func(id uint) { add(id, score) })
+				return
+			}
+			if rowMin > k {
+				return
+			}
+			for cr, c := range n.children {
+				walk(c, cr, r, cur, prev)
+			}
+		}
+		for r, c := range x.root.children {
+			walk(c, r, 0, row, nil)
+		}
+	}
+
+	if terms := queryTerms(string(q)); len(terms) > 0 {
+		first := x.bio[terms[0]]
+	bio:
+		for id := range first {
+			for _, t := range terms[1:] {
+				if _, ok := x.bio[t][id]; !ok {
+					continue bio
+				}
+			}
+			add(id, userMatchBio)
+		}
+	}
+
+	matches := make([]UserMatch, 0, len(best))
+	for id, score := range best {
+		matches = append(matches, UserMatch{ID: id, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return x.lessLocked(matches[i], matches[j])
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// lessLocked 分数高的在前，同分时用户名短的在前，再按 ID
+func (x *UserIndex) lessLocked(a, b UserMatch) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	la, lb := len(x.users[a.ID].Username), len(x.users[b.ID].Username)
+	if la != lb {
+		return la < lb
+	}
+	return a.ID < b.ID
+}
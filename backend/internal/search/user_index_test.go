@@ -0,0 +1,81 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func matchIDs(ms []UserMatch) []uint {
+	out := make([]uint, len(ms))
+	for i, m := range ms {
+		out[i] = m.ID
+	}
+	return out
+}
+
+func newTestUserIndex() *UserIndex {
+	x := NewUserIndex()
+	x.Upsert(UserDoc{ID: 1, Username: "alice"})
+	x.Upsert(UserDoc{ID: 2, Username: "alicia_w"})
+	x.Upsert(UserDoc{ID: 3, Username: "Bob", Bio: "爱拍猫咪的摄影师"})
+	x.Upsert(UserDoc{ID: 4, Username: "alise"})
+	x.Upsert(UserDoc{ID: 5, Username: "小明"})
+	return x
+}
+
+func TestUserIndexPrefixAndFuzzy(t *testing.T) {
+	x := newTestUserIndex()
+
+	if got := matchIDs(x.Search("ali", 10)); !reflect.DeepEqual(got, []uint{1, 4, 2}) {
+		t.Fatalf("prefix ali = %v", got)
+	}
+	// 完全匹配排第一，alise 只差一个字母算模糊命中
+	if got := matchIDs(x.Search("Alice", 10)); !reflect.DeepEqual(got, []uint{1, 4, 2}) {
+		t.Fatalf("alice = %v", got)
+	}
+	if got := matchIDs(x.Search("alcie", 10)); len(got) == 0 || got[0] != 1 {
+		t.Fatalf("typo alcie = %v", got)
+	}
+	if got := matchIDs(x.Search("bo", 10)); !reflect.DeepEqual(got, []uint{3}) {
+		t.Fatalf("short prefix bo = %v", got)
+	}
+	if got := matchIDs(x.Search("猫咪", 10)); !reflect.DeepEqual(got, []uint{3}) {
+		t.Fatalf("bio 猫咪 = %v", got)
+	}
+	if got := matchIDs(x.Search("小", 10)); !reflect.DeepEqual(got, []uint{5}) {
+		t.Fatalf("cjk prefix = %v", got)
+	}
+}
+
+func TestUserIndexRename(t *testing.T) {
+	x := newTestUserIndex()
+	x.Upsert(UserDoc{ID: 1, Username: "zoe", Bio: "新简介"})
+	for _, id := range matchIDs(x.Search("alice", 10)) {
+		if id == 1 {
+			t.Fatalf("old username still matches")
+		}
+	}
+	if got := matchIDs(x.Search("zo", 10)); !reflect.DeepEqual(got, []uint{1}) {
+		t.Fatalf("new username = %v", got)
+	}
+	if doc, ok := x.Get(1); !ok || doc.Bio != "新简介" {
+		t.Fatalf("snapshot not updated: %+v", doc)
+	}
+	x.Delete(3)
+	if got := x.Search("猫咪", 10); len(got) != 0 {
+		t.Fatalf("deleted account still matches bio: %v", got)
+	}
+	if x.Len() != 4 {
+		t.Fatalf("len = %d, want 4", x.Len())
+	}
+}
+
+func TestRankUsersByRelation(t *testing.T) {
+	matches := []UserMatch{{ID: 1, Score: 100}, {ID: 2, Score: 60}, {ID: 3, Score: 40}, {ID: 4, Score: 20}}
+	following := map[uint]bool{3: true, 4: true}
+	followedBy := map[uint]bool{4: true, 1: true}
+	got := matchIDs(rankUsers(matches, following, followedBy))
+	if want := []uint{4, 3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranked = %v, want %v", got, want)
+	}
+}
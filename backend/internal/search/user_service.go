@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"feedsystem_video_go/internal/account"
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/social"
+
+	"gorm.io/gorm"
+)
+
+type UserSearchService struct {
+	index  *UserIndex
+	social *social.SocialRepository
+}
+
+func NewUserSearchService(index *UserIndex, socialRepo *social.SocialRepository) *UserSearchService {
+	return &UserSearchService{index: index, social: socialRepo}
+}
+
+// relationTier 互关 > 已关注 > 其他
+func relationTier(following, followedBy bool) int {
+	switch {
+	case following && followedBy:
+		return 2
+	case following:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// rankUsers 按关注关系分层，层内保持索引给出的匹配度顺序
+func rankUsers(matches []UserMatch, following, followedBy map[uint]bool) []UserMatch {
+	ranked := append([]UserMatch(nil), matches...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i].ID, ranked[j].ID
+		return relationTier(following[a], followedBy[a]) > relationTier(following[b], followedBy[b])
+	})
+	return ranked
+}
+
+// SearchUsers 用户名前缀/模糊和简介检索；登录用户关注的人、互关的人排在前面
+func (s *UserSearchService) SearchUsers(ctx context.Context, viewerID uint, req SearchUsersRequest) (SearchUsersResponse, error) {
+	q := strings.TrimSpace(req.Query)
+	if q == "" {
+		return SearchUsersResponse{}, fmt.Errorf("%w: q is required", apierror.ErrValidation)
+	}
+	if utf8.RuneCountInString(q) > maxUserQueryRuneCount {
+		return SearchUsersResponse{}, fmt.Errorf("%w: q must be at most %d characters", apierror.ErrValidation, maxUserQueryRuneCount)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultUserSearchLimit
+	}
+	limit = min(limit, maxUserSearchLimit)
+
+	matches := s.index.Search(q, userCandidatePoolSize)
+	following := map[uint]bool{}
+	followedBy := map[uint]bool{}
+	if viewerID != 0 && s.social != nil && len(matches) > 0 {
+		ids := make([]uint, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		out, in, err := s.social.ListRelations(ctx, viewerID, ids)
+		if err != nil {
+			// 关系查询失败不影响搜索，退化为纯匹配度排序
+			log.Printf("user search: list relations failed: %v", err)
+		} else {
+			following, followedBy = out, in
+		}
+	}
+
+	resp := SearchUsersResponse{Users: []UserSearchItem{}}
+	for _, m := range rankUsers(matches, following, followedBy) {
+		if len(resp.Users) == limit {
+			break
+		}
+		doc, ok := s.index.Get(m.ID)
+		if !ok {
+			continue
+		}
+		resp.Users = append(resp.Users, UserSearchItem{
+			ID:          doc.ID,
+			Username:    doc.Username,
+			AvatarURL:   doc.AvatarURL,
+			Bio:         doc.Bio,
+			IsFollowing: following[doc.ID],
+			IsMutual:    following[doc.ID] && followedBy[doc.ID],
+		})
+	}
+	return resp, nil
+}
+
+// UserIndexer 启动时从 AccountRepository.FindAll 重建用户索引，之后随注册、改名、改资料增量更新
+type UserIndexer struct {
+	index    *UserIndex
+	accounts *account.AccountRepository
+	mq       *rabbitmq.SearchMQ
+}
+
+func NewUserIndexer(index *UserIndex, accounts *account.AccountRepository, mq *rabbitmq.SearchMQ) *UserIndexer {
+	return &UserIndexer{index: index, accounts: accounts, mq: mq}
+}
+
+func userDocOf(a *account.Account) UserDoc {
+	return UserDoc{ID: a.ID, Username: a.Username, AvatarURL: a.AvatarURL, Bio: a.Bio}
+}
+
+func (x *UserIndexer) Rebuild(ctx context.Context) error {
+	accounts, err := x.accounts.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		x.index.Upsert(userDocOf(a))
+	}
+	log.Printf("user index rebuilt: %d accounts", len(accounts))
+	return nil
+}
+
+// AccountChanged 实现 account.ProfileSync
+func (x *UserIndexer) AccountChanged(ctx context.Context, accountID uint) {
+	if x == nil {
+		return
+	}
+	if x.mq != nil {
+		if err := x.mq.AccountChanged(ctx, accountID); err == nil {
+			return
+		}
+	}
+	if err := x.apply(ctx, accountID); err != nil {
+		log.Printf("user index account %d failed: %v", accountID, err)
+	}
+}
+
+func (x *UserIndexer) apply(ctx context.Context, accountID uint) error {
+	a, err := x.accounts.FindByID(ctx, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		x.index.Delete(accountID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	x.index.Upsert(userDocOf(a))
+	return nil
+}
+
+// Run 消费广播的账号变更事件，更新本实例的用户索引
+func (x *UserIndexer) Run(ctx context.Context) error {
+	if x == nil || x.mq == nil {
+		return errors.New("user indexer is not initialized")
+	}
+	deliveries, err := x.mq.SubscribeAccounts()
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("deliveries channel closed")
+			}
+			var evt rabbitmq.SearchEvent
+			if err := json.Unmarshal(d.Body, &evt); err != nil || evt.AccountID == 0 {
+				continue
+			}
+			if err := x.apply(ctx, evt.AccountID); err != nil {
+				log.Printf("user indexer: account %d failed: %v", evt.AccountID, err)
+			}
+		}
+	}
+}
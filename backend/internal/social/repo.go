@@ -3,6 +3,8 @@ package social
 import (
 	"context"
 	"feedsystem_video_go/internal/account"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/outbox"
 
 	"gorm.io/gorm"
 )
@@ -15,14 +17,26 @@ func NewSocialRepository(db *gorm.DB) *SocialRepository {
 	return &SocialRepository{db: db}
 }
 
-func (r *SocialRepository) Follow(ctx context.Context, social *Social) error {
-	return r.db.WithContext(ctx).Create(social).Error
+// Follow msgs 在同一事务里写入 outbox
+func (r *SocialRepository) Follow(ctx context.Context, social *Social, msgs ...rabbitmq.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(social).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, msgs...)
+	})
 }
 
-func (r *SocialRepository) Unfollow(ctx context.Context, social *Social) error {
-	return r.db.WithContext(ctx).
-		Where("follower_id = ? AND vlogger_id = ?", social.FollowerID, social.VloggerID).
-		Delete(&Social{}).Error
+// Unfollow 没有关注关系时不写事件
+func (r *SocialRepository) Unfollow(ctx context.Context, social *Social, msgs ...rabbitmq.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("follower_id = ? AND vlogger_id = ?", social.FollowerID, social.VloggerID).
+			Delete(&Social{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return outbox.Enqueue(tx, msgs...)
+	})
 }
 
 func (r *SocialRepository) GetAllFollowers(ctx context.Context, VloggerID uint) ([]*account.Account, error) {
@@ -107,3 +121,76 @@ func (r *SocialRepository) CountVloggers(ctx context.Context, followerID uint) (
 	}
 	return count, nil
 }
+
+func (r *SocialRepository) ListVloggerIDs(ctx context.Context, followerID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).
+		Model(&Social{}).
+		Where("follower_id = ?", followerID).
+		Pluck("vlogger_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListRelations 返回 ids 中 accountID 关注了的人和关注了 accountID 的人
+func (r *SocialRepository) ListRelations(ctx context.Context, accountID uint, ids []uint) (map[uint]bool, map[uint]bool, error) {
+	following := make(map[uint]bool)
+	followedBy := make(map[uint]bool)
+	if accountID == 0 || len(ids) == 0 {
+		return following, followedBy, nil
+	}
+	var out []uint
+	if err := r.db.WithContext(ctx).
+		Model(&Social{}).
+		Where("follower_id = ? AND vlogger_id IN ?", accountID, ids).
+		Pluck("vlogger_id", &out).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, id := range out {
+		following[id] = true
+	}
+	var in []uint
+	if err := r.db.WithContext(ctx).
+		Model(&Social{}).
+		Where("vlogger_id = ? AND follower_id IN ?", accountID, ids).
+		Pluck("follower_id", &in).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, id := range in {
+		followedBy[id] = true
+	}
+	return following, followedBy, nil
+}
+
+// ListFollowerIDs 按 follower_id 升序分页拉取粉丝 ID，afterID 为上一页最后一个 ID
+func (r *SocialRepository) ListFollowerIDs(ctx context.Context, vloggerID uint, afterID uint, limit int) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).
+		Model(&Social{}).
+		Where("vlogger_id = ? AND follower_id > ?", vloggerID, afterID).
+		Order("follower_id ASC").
+		Limit(limit).
+		Pluck("follower_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// FilterByFollowerCount 返回 vloggerIDs 中粉丝数 >= minFollowers 的作者
+func (r *SocialRepository) FilterByFollowerCount(ctx context.Context, vloggerIDs []uint, minFollowers int64) ([]uint, error) {
+	var ids []uint
+	if len(vloggerIDs) == 0 {
+		return ids, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&Social{}).
+		Select("vlogger_id").
+		Where("vlogger_id IN ?", vloggerIDs).
+		Group("vlogger_id").
+		Having("COUNT(*) >= ?", minFollowers).
+		Pluck("vlogger_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
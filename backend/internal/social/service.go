@@ -5,16 +5,32 @@ import (
 	"errors"
 	"feedsystem_video_go/internal/account"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"log"
 )
 
+// InboxInvalidator 由 feed.Inbox 实现；feed 依赖 social，这里用接口避免循环引用
+type InboxInvalidator interface {
+	Invalidate(ctx context.Context, accountID uint) error
+}
+
 type SocialService struct {
 	repo        *SocialRepository
 	accountrepo *account.AccountRepository
-	socialMQ    *rabbitmq.SocialMQ
+	inbox       InboxInvalidator
 }
 
-func NewSocialService(repo *SocialRepository, accountrepo *account.AccountRepository, socialMQ *rabbitmq.SocialMQ) *SocialService {
-	return &SocialService{repo: repo, accountrepo: accountrepo, socialMQ: socialMQ}
+func NewSocialService(repo *SocialRepository, accountrepo *account.AccountRepository, inbox InboxInvalidator) *SocialService {
+	return &SocialService{repo: repo, accountrepo: accountrepo, inbox: inbox}
+}
+
+// invalidateInbox 关注关系变化后同步作废收件箱，MQ 不可用时也不会读到旧的关注列表；worker 收到事件后会再作废一次
+func (s *SocialService) invalidateInbox(ctx context.Context, followerID uint) {
+	if s.inbox == nil {
+		return
+	}
+	if err := s.inbox.Invalidate(ctx, followerID); err != nil {
+		log.Printf("social: invalidate inbox of %d failed: %v", followerID, err)
+	}
 }
 
 func (s *SocialService) Follow(ctx context.Context, social *Social) error {
@@ -36,10 +52,16 @@ func (s *SocialService) Follow(ctx context.Context, social *Social) error {
 	if isFollowed {
 		return errors.New("already followed")
 	}
-	if s.socialMQ != nil {
-		s.socialMQ.Follow(ctx, social.FollowerID, social.VloggerID)
+	// 关系和事件同一事务落库：social worker 据此作废收件箱、记新增粉丝，通知服务发关注提醒
+	msg, err := rabbitmq.SocialMessage("follow", social.FollowerID, social.VloggerID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Follow(ctx, social, msg); err != nil {
+		return err
 	}
-	return s.repo.Follow(ctx, social)
+	s.invalidateInbox(ctx, social.FollowerID)
+	return nil
 }
 
 func (s *SocialService) Unfollow(ctx context.Context, social *Social) error {
@@ -58,10 +80,15 @@ func (s *SocialService) Unfollow(ctx context.Context, social *Social) error {
 	if !isFollowed {
 		return errors.New("not followed")
 	}
-	if s.socialMQ != nil {
-		s.socialMQ.UnFollow(ctx, social.FollowerID, social.VloggerID)
+	msg, err := rabbitmq.SocialMessage("unfollow", social.FollowerID, social.VloggerID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Unfollow(ctx, social, msg); err != nil {
+		return err
 	}
-	return s.repo.Unfollow(ctx, social)
+	s.invalidateInbox(ctx, social.FollowerID)
+	return nil
 }
 
 func (s *SocialService) GetAllFollowers(ctx context.Context, VloggerID uint) ([]*account.Account, error) {
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redirectPresignTTL = 15 * time.Minute
+
+// 头像对所有人公开；视频、封面和 HLS 要由 Authorizer 按所属视频放行；其他目录（如分片上传的 tmp/）不对外
+const publicPrefix = "avatars/"
+
+var guardedPrefixes = []string{"videos/", "covers/", "hls/"}
+
+// Authorizer 判断请求能否读取受保护的 key，由 video 包按所属视频的状态和可见范围实现。
+// viewerAccountID 为 0 表示未登录，shareToken 取自 ?share_token=
+type Authorizer interface {
+	CanServe(ctx context.Context, key string, viewerAccountID uint, shareToken string) (bool, error)
+}
+
+// ServeHandler 挂在 /static/*key 上：本地存储直接回源（支持 Range），远端存储 302 到预签名地址。
+// 无权读取和不存在一样返回 404；auth 为 nil 时只提供头像
+func ServeHandler(store Store, auth Authorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := cleanKey(c.Param("key"))
+		if !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		allowed, err := canServe(c, key, auth)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		if _, local := store.(*LocalStore); !local {
+			u, err := store.PresignedURL(c.Request.Context(), key, redirectPresignTTL)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.Redirect(http.StatusFound, u)
+			return
+		}
+
+		rc, info, err := store.Get(c.Request.Context(), key)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer rc.Close()
+		c.Header("Content-Type", info.ContentType)
+		if rs, ok := rc.(io.ReadSeeker); ok {
+			http.ServeContent(c.Writer, c.Request, key, info.ModTime, rs)
+			return
+		}
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, rc)
+	}
+}
+
+func canServe(c *gin.Context, key string, auth Authorizer) (bool, error) {
+	if strings.HasPrefix(key, publicPrefix) {
+		return true, nil
+	}
+	for _, prefix := range guardedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			if auth == nil {
+				return false, nil
+			}
+			return auth.CanServe(c.Request.Context(), key, c.GetUint("accountID"), c.Query("share_token"))
+		}
+	}
+	return false, nil
+}
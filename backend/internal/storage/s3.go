@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3TimeFormat     = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+	s3MaxPresignTime = 7 * 24 * time.Hour
+)
+
+type S3Options struct {
+	Endpoint  string // 如 http://minio:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// BaseURL 公开访问前缀（CDN 等），为空时使用 endpoint/bucket
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// S3Store S3 兼容存储（AWS S3 / MinIO 等），path-style 访问，SigV4 签名
+type S3Store struct {
+	endpoint *url.URL
+	region   string
+	bucket   string
+	ak       string
+	sk       string
+	baseURL  string
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, errors.New("s3 access key and secret key are required")
+	}
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", opts.Endpoint)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	region := opts.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	s := &S3Store{
+		endpoint: u,
+		region:   region,
+		bucket:   opts.Bucket,
+		ak:       opts.AccessKey,
+		sk:       opts.SecretKey,
+		baseURL:  opts.BaseURL,
+		client:   client,
+		now:      time.Now,
+	}
+	if s.baseURL == "" {
+		s.baseURL = u.String() + "/" + opts.Bucket
+	}
+	return s, nil
+}
+
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	k, ok := cleanKey(key)
+	if !ok {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	u := *s.endpoint
+	u.Path = u.Path + "/" + s.bucket + "/" + k
+	u.RawPath = s3EscapePath(u.Path)
+	return &u, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		// S3 PUT 需要 Content-Length，长度未知时先落临时文件
+		tmp, err := os.CreateTemp("", "s3-put-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		n, err := io.Copy(tmp, r)
+		if err != nil {
+			return err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r, size = tmp, n
+	}
+	if contentType == "" {
+		contentType = ContentTypeByKey(key)
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), io.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp, key)
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s3Error(resp, key); err != nil {
+		resp.Body.Close()
+		return nil, nil, err
+	}
+	return resp.Body, infoFromHeader(key, resp), nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := s3Error(resp, key); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := s3Error(resp, key); err != nil {
+		return nil, err
+	}
+	return infoFromHeader(key, resp), nil
+}
+
+// PresignedURL 生成 query-string 签名的 GET 地址
+func (s *S3Store) PresignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if expires <= 0 || expires > s3MaxPresignTime {
+		return "", fmt.Errorf("presign expires must be in (0, %s]", s3MaxPresignTime)
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	return s.presign(u, s.now().UTC(), expires), nil
+}
+
+func (s *S3Store) presign(u *url.URL, now time.Time, expires time.Duration) string {
+	amzDate := now.Format(s3TimeFormat)
+	scope := s.scope(now)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", s3Algorithm)
+	q.Set("X-Amz-Credential", s.ak+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalQuery := s3CanonicalQuery(q)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedBody,
+	}, "\n")
+	signature := s.sign(now, canonicalRequest)
+	signed := *u
+	signed.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+func (s *S3Store) URL(key string) string {
+	if key == "" {
+		return strings.TrimRight(s.baseURL, "/") + "/"
+	}
+	return joinURL(s.baseURL, key)
+}
+
+func (s *S3Store) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+	s.signHeaders(req, s.now().UTC())
+	return s.client.Do(req)
+}
+
+// signHeaders 按 SigV4 给请求加 Authorization；签 host、content-type、range 和全部 x-amz-* 头，
+// body 摘要取 X-Amz-Content-Sha256
+func (s *S3Store) signHeaders(req *http.Request, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = req.Header.Get(name)
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	signature := s.sign(now, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.ak, s.scope(now), signedHeaders, signature))
+}
+
+func (s *S3Store) scope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s *S3Store) sign(t time.Time, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3TimeFormat),
+		s.scope(t),
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.sk), t.Format(s3DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath 按 SigV4 规则逐段编码，保留 "/"
+func s3EscapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = s3Escape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3Error(resp *http.Response, key string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s %s: status %d: %s", resp.Request.Method, key, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func infoFromHeader(key string, resp *http.Response) *ObjectInfo {
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+	return info
+}
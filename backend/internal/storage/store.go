@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"feedsystem_video_go/internal/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Store 对象存储抽象；key 统一使用 "/" 分隔的相对路径，如 videos/1/20240101/xxx.mp4
+type Store interface {
+	// Put size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// PresignedURL 生成带过期时间的临时访问地址
+	PresignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+	// URL 公开访问地址，写入 play_url/cover_url 等字段
+	URL(key string) string
+}
+
+func NewFromConfig(cfg *config.StorageConfig) (Store, error) {
+	if cfg == nil {
+		return nil, errors.New("storage config is nil")
+	}
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.Root, cfg.BaseURL)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.Endpoint,
+			Region:    cfg.Region,
+			Bucket:    cfg.Bucket,
+			AccessKey: cfg.AccessKey,
+			SecretKey: cfg.SecretKey,
+			BaseURL:   cfg.BaseURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// KeyFromURL 把 Store.URL 生成的地址还原成 key；兼容旧版 http://host/static/... 地址
+func KeyFromURL(s Store, rawURL string) (string, bool) {
+	if s == nil || rawURL == "" {
+		return "", false
+	}
+	prefix := s.URL("")
+	if prefix != "" && strings.HasPrefix(rawURL, prefix) {
+		key := strings.TrimPrefix(rawURL, prefix)
+		if i := strings.IndexAny(key, "?#"); i >= 0 {
+			key = key[:i]
+		}
+		return cleanKey(key)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	p := path.Clean(u.Path)
+	if !strings.HasPrefix(p, "/static/") {
+		return "", false
+	}
+	return cleanKey(strings.TrimPrefix(p, "/static/"))
+}
+
+// cleanKey 拒绝越出根目录的 key
+func cleanKey(key string) (string, bool) {
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" || key == "." {
+		return "", false
+	}
+	return key, true
+}
+
+func ContentTypeByKey(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	case ".mp4":
+		return "video/mp4"
+	}
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
@@ -0,0 +1,43 @@
+package transcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const fakeVariantPlaylist = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:6.0,\nseg_000.ts\n#EXT-X-ENDLIST\n"
+
+// FakeTranscoder 不调用 ffmpeg，只写出占位 playlist 和切片；用于测试和本地无 ffmpeg 环境
+type FakeTranscoder struct {
+	Err error
+
+	mu    sync.Mutex
+	Calls []string
+}
+
+func (t *FakeTranscoder) Transcode(ctx context.Context, src string, outDir string, ladder []Rendition) (*Result, error) {
+	t.mu.Lock()
+	t.Calls = append(t.Calls, src)
+	t.mu.Unlock()
+	if t.Err != nil {
+		return nil, t.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, r := range ladder {
+		dir := filepath.Join(outDir, r.Name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "seg_000.ts"), []byte(r.Name), 0o644); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(fakeVariantPlaylist), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return writeMaster(outDir, ladder)
+}
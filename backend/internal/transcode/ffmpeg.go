@@ -0,0 +1,77 @@
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// FFmpegTranscoder 调用本地 ffmpeg 可执行文件，每档码率单独切片
+type FFmpegTranscoder struct {
+	Binary         string
+	SegmentSeconds int
+}
+
+func NewFFmpegTranscoder(binary string) (*FFmpegTranscoder, error) {
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	return &FFmpegTranscoder{Binary: path, SegmentSeconds: 6}, nil
+}
+
+func (t *FFmpegTranscoder) Transcode(ctx context.Context, src string, outDir string, ladder []Rendition) (*Result, error) {
+	if t == nil || t.Binary == "" {
+		return nil, errors.New("ffmpeg transcoder is not initialized")
+	}
+	if len(ladder) == 0 {
+		return nil, errors.New("empty rendition ladder")
+	}
+	if _, err := os.Stat(src); err != nil {
+		return nil, fmt.Errorf("source not found: %w", err)
+	}
+	for _, r := range ladder {
+		dir := filepath.Join(outDir, r.Name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		cmd := exec.CommandContext(ctx, t.Binary, t.args(src, dir, r)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("ffmpeg %s failed: %w: %s", r.Name, err, tail(out, 512))
+		}
+	}
+	return writeMaster(outDir, ladder)
+}
+
+func (t *FFmpegTranscoder) args(src, dir string, r Rendition) []string {
+	seg := t.SegmentSeconds
+	if seg <= 0 {
+		seg = 6
+	}
+	return []string{
+		"-y", "-hide_banner", "-loglevel", "error",
+		"-i", src,
+		"-vf", fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease,pad=ceil(iw/2)*2:ceil(ih/2)*2", r.Width, r.Height),
+		"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main",
+		"-b:v", r.VideoBitrate, "-maxrate", r.VideoBitrate, "-bufsize", r.VideoBitrate,
+		"-c:a", "aac", "-b:a", r.AudioBitrate, "-ac", "2",
+		"-hls_time", strconv.Itoa(seg),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%03d.ts"),
+		filepath.Join(dir, "index.m3u8"),
+	}
+}
+
+func tail(b []byte, n int) string {
+	if len(b) > n {
+		b = b[len(b)-n:]
+	}
+	return string(b)
+}
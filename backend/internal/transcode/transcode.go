@@ -0,0 +1,71 @@
+package transcode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const MasterPlaylistName = "master.m3u8"
+
+// Rendition 一档 HLS 输出（分辨率 + 码率）
+type Rendition struct {
+	Name         string
+	Width        int
+	Height       int
+	VideoBitrate string // ffmpeg 码率写法，如 "800k"
+	AudioBitrate string
+	Bandwidth    int // 写入 master playlist 的 BANDWIDTH（bit/s）
+}
+
+var DefaultLadder = []Rendition{
+	{Name: "360p", Width: 640, Height: 360, VideoBitrate: "800k", AudioBitrate: "96k", Bandwidth: 900000},
+	{Name: "720p", Width: 1280, Height: 720, VideoBitrate: "2800k", AudioBitrate: "128k", Bandwidth: 3000000},
+	{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: "5000k", AudioBitrate: "192k", Bandwidth: 5400000},
+}
+
+// Result 转码产物，路径均相对 outDir
+type Result struct {
+	MasterPlaylist string
+	Renditions     []string
+}
+
+// Transcoder 把源文件转成多码率 HLS，写入 outDir
+type Transcoder interface {
+	Transcode(ctx context.Context, src string, outDir string, ladder []Rendition) (*Result, error)
+}
+
+func variantPlaylist(r Rendition) string {
+	return filepath.ToSlash(filepath.Join(r.Name, "index.m3u8"))
+}
+
+// WriteMasterPlaylist 按 ladder 写 master playlist，每档指向 <name>/index.m3u8
+func WriteMasterPlaylist(w io.Writer, ladder []Rendition) error {
+	if _, err := io.WriteString(w, "#EXTM3U\n#EXT-X-VERSION:3\n"); err != nil {
+		return err
+	}
+	for _, r := range ladder {
+		if _, err := fmt.Fprintf(w, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s\n", r.Bandwidth, r.Width, r.Height, variantPlaylist(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMaster(outDir string, ladder []Rendition) (*Result, error) {
+	f, err := os.Create(filepath.Join(outDir, MasterPlaylistName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := WriteMasterPlaylist(f, ladder); err != nil {
+		return nil, err
+	}
+	res := &Result{MasterPlaylist: MasterPlaylistName}
+	for _, r := range ladder {
+		res.Renditions = append(res.Renditions, variantPlaylist(r))
+	}
+	return res, nil
+}
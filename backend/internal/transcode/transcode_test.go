@@ -0,0 +1,69 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMasterPlaylist(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMasterPlaylist(&buf, DefaultLadder); err != nil {
+		t.Fatalf("write master: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "#EXTM3U\n") {
+		t.Fatalf("missing #EXTM3U header: %q", out)
+	}
+	for _, r := range DefaultLadder {
+		if !strings.Contains(out, r.Name+"/index.m3u8") {
+			t.Fatalf("master playlist missing rendition %s: %q", r.Name, out)
+		}
+	}
+	if got := strings.Count(out, "#EXT-X-STREAM-INF"); got != len(DefaultLadder) {
+		t.Fatalf("expected %d variants, got %d", len(DefaultLadder), got)
+	}
+}
+
+func TestFakeTranscoderWritesRenditions(t *testing.T) {
+	outDir := t.TempDir()
+	tr := &FakeTranscoder{}
+	res, err := tr.Transcode(context.Background(), "src.mp4", outDir, DefaultLadder)
+	if err != nil {
+		t.Fatalf("transcode: %v", err)
+	}
+	if res.MasterPlaylist != MasterPlaylistName {
+		t.Fatalf("unexpected master name %q", res.MasterPlaylist)
+	}
+	paths := append([]string{res.MasterPlaylist}, res.Renditions...)
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(p))); err != nil {
+			t.Fatalf("expected %s to exist: %v", p, err)
+		}
+	}
+	if len(tr.Calls) != 1 || tr.Calls[0] != "src.mp4" {
+		t.Fatalf("unexpected calls %v", tr.Calls)
+	}
+}
+
+func TestFakeTranscoderError(t *testing.T) {
+	want := errors.New("boom")
+	tr := &FakeTranscoder{Err: want}
+	if _, err := tr.Transcode(context.Background(), "src.mp4", t.TempDir(), DefaultLadder); !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	tr := &FFmpegTranscoder{Binary: "ffmpeg"}
+	args := strings.Join(tr.args("in.mp4", "out/360p", DefaultLadder[0]), " ")
+	for _, want := range []string{"-i in.mp4", "-b:v 800k", "-hls_time 6", "-hls_playlist_type vod"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("args missing %q: %s", want, args)
+		}
+	}
+}
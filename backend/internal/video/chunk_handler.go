@@ -1,18 +1,20 @@
 package video
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"time"
 
 	"feedsystem_video_go/internal/middleware/jwt"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,10 +25,15 @@ var errChunkCacheUnavailable = errors.New("chunk upload requires redis")
 
 type ChunkUploadHandler struct {
 	cache *rediscache.Client
+	store storage.Store
 }
 
-func NewChunkUploadHandler(cache *rediscache.Client) *ChunkUploadHandler {
-	return &ChunkUploadHandler{cache: cache}
+func NewChunkUploadHandler(cache *rediscache.Client, store storage.Store) *ChunkUploadHandler {
+	return &ChunkUploadHandler{cache: cache, store: store}
+}
+
+func chunkKey(uploadID string, index int) string {
+	return path.Join("tmp", uploadID, strconv.Itoa(index))
 }
 
 func (h *ChunkUploadHandler) sessionKey(uploadID string) string {
@@ -196,26 +203,12 @@ func (h *ChunkUploadHandler) UploadChunk(c *gin.Context) {
 		return
 	}
 
-	tmpDir := filepath.Join(".run", "uploads", "tmp", req.UploadID)
-	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create temp dir"})
-		return
-	}
-
-	chunkPath := filepath.Join(tmpDir, fmt.Sprintf("%d", req.ChunkIndex))
 	if _, seekErr := chunkFile.Seek(0, io.SeekStart); seekErr != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read chunk"})
 		return
 	}
 
-	dst, err := os.Create(chunkPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save chunk"})
-		return
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, chunkFile); err != nil {
+	if err := h.store.Put(c.Request.Context(), chunkKey(req.UploadID, req.ChunkIndex), chunkFile, f.Size, "application/octet-stream"); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save chunk"})
 		return
 	}
@@ -312,62 +305,92 @@ func (h *ChunkUploadHandler) CompleteChunkUpload(c *gin.Context) {
 		return
 	}
 
-	date := time.Now().Format("20060102")
-	relDir := filepath.Join("videos", fmt.Sprintf("%d", accountID), date)
-	root := filepath.Join(".run", "uploads")
-	absDir := filepath.Join(root, relDir)
-	if err := os.MkdirAll(absDir, 0o755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create output dir"})
-		return
+	ctx := c.Request.Context()
+	var total int64
+	for i := 0; i < session.TotalChunks; i++ {
+		info, err := h.store.Stat(ctx, chunkKey(req.UploadID, i))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("chunk %d missing", i)})
+			return
+		}
+		total += info.Size
 	}
 
+	date := time.Now().Format("20060102")
 	filename, err := randHex(16)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate filename"})
 		return
 	}
-	finalPath := filepath.Join(absDir, filename+".mp4")
+	key := path.Join("videos", fmt.Sprintf("%d", accountID), date, filename+".mp4")
 
-	finalFile, err := os.Create(finalPath)
+	merged := &chunkReader{ctx: ctx, store: h.store, uploadID: req.UploadID, total: session.TotalChunks}
+	err = h.store.Put(ctx, key, merged, total, "video/mp4")
+	merged.Close()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create final file"})
+		_ = h.store.Delete(ctx, key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge chunks"})
 		return
 	}
-	defer finalFile.Close()
 
-	tmpDir := filepath.Join(".run", "uploads", "tmp", req.UploadID)
+	// Clean up temp chunks
 	for i := 0; i < session.TotalChunks; i++ {
-		chunkPath := filepath.Join(tmpDir, fmt.Sprintf("%d", i))
-		cf, err := os.Open(chunkPath)
-		if err != nil {
-			finalFile.Close()
-			os.Remove(finalPath)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("chunk %d missing", i)})
-			return
-		}
-		_, err = io.Copy(finalFile, cf)
-		cf.Close()
-		if err != nil {
-			finalFile.Close()
-			os.Remove(finalPath)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge chunks"})
-			return
-		}
+		_ = h.store.Delete(ctx, chunkKey(req.UploadID, i))
 	}
-	finalFile.Close()
-
-	// Clean up temp chunks
-	_ = os.RemoveAll(tmpDir)
 
 	// Clean up Redis session
-	_ = h.cache.Del(c.Request.Context(), h.sessionKey(req.UploadID))
-	_ = h.cache.Del(c.Request.Context(), h.hashKey(accountID, session.FileHash))
+	_ = h.cache.Del(ctx, h.sessionKey(req.UploadID))
+	_ = h.cache.Del(ctx, h.hashKey(accountID, session.FileHash))
 
-	urlPath := fmt.Sprintf("/static/videos/%d/%s/%s.mp4", accountID, date, filename)
-	playURL := buildAbsoluteURL(c, urlPath)
+	playURL := h.store.URL(key)
 
 	c.JSON(http.StatusOK, gin.H{
 		"url":      playURL,
 		"play_url": playURL,
 	})
 }
+
+// chunkReader 按序依次读取各分片，合并时不用把整个文件放进内存
+type chunkReader struct {
+	ctx      context.Context
+	store    storage.Store
+	uploadID string
+	total    int
+	next     int
+	cur      io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if r.next >= r.total {
+				return 0, io.EOF
+			}
+			rc, _, err := r.store.Get(r.ctx, chunkKey(r.uploadID, r.next))
+			if err != nil {
+				return 0, fmt.Errorf("chunk %d: %w", r.next, err)
+			}
+			r.cur = rc
+			r.next++
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur != nil {
+		err := r.cur.Close()
+		r.cur = nil
+		return err
+	}
+	return nil
+}
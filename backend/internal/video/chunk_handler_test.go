@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/storage"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
@@ -38,7 +39,6 @@ func setupTestEnv(t *testing.T) (*ChunkUploadHandler, func()) {
 		goredis.NewClient(&goredis.Options{Addr: mr.Addr()}),
 		"",
 	)
-	handler := NewChunkUploadHandler(client)
 
 	origDir, _ := os.Getwd()
 	tmpDir, err := os.MkdirTemp("", "chunk-upload-test-*")
@@ -48,6 +48,11 @@ func setupTestEnv(t *testing.T) (*ChunkUploadHandler, func()) {
 	if err := os.Chdir(tmpDir); err != nil {
 		t.Fatalf("chdir: %v", err)
 	}
+	store, err := storage.NewLocalStore(filepath.Join(".run", "uploads"), "/static")
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	handler := NewChunkUploadHandler(client, store)
 
 	cleanup := func() {
 		os.Chdir(origDir)
@@ -174,11 +179,11 @@ func completeUpload(t *testing.T, h *ChunkUploadHandler, uploadID string) map[st
 }
 
 // readMergedFile extracts the file path from the response URL and reads it.
-// URL format: http://localhost/static/videos/<accountID>/<date>/<name>.mp4
+// URL format: /static/videos/<accountID>/<date>/<name>.mp4
 func readMergedFile(t *testing.T, resp map[string]interface{}) []byte {
 	t.Helper()
 	urlStr := resp["url"].(string)
-	idx := len("http://localhost/static/")
+	idx := len("/static/")
 	fsPath := filepath.Join(".run", "uploads", urlStr[idx:])
 	data, err := os.ReadFile(fsPath)
 	if err != nil {
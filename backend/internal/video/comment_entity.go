@@ -2,24 +2,83 @@ package video
 
 import "time"
 
+// 楼中楼：顶层评论 ParentID=RootID=0；回复的 RootID 指向所在顶层评论，ParentID 指向直接回复的评论
 type Comment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Username   string    `gorm:"index" json:"username"`
+	VideoID    uint      `gorm:"index;index:idx_comments_video_root_id,priority:1" json:"video_id"`
+	AuthorID   uint      `gorm:"index" json:"author_id"`
+	ParentID   uint      `gorm:"not null;default:0;index" json:"parent_id"`
+	RootID     uint      `gorm:"not null;default:0;index:idx_comments_video_root_id,priority:2" json:"root_id"`
+	ReplyCount int64     `gorm:"not null;default:0" json:"reply_count"`
+	LikesCount int64     `gorm:"not null;default:0" json:"likes_count"`
+	Deleted    bool      `gorm:"not null;default:false" json:"deleted"`
+	Content    string    `gorm:"type:text" json:"content"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	IsLiked    bool      `gorm:"-" json:"is_liked"`
+	Pinned     bool      `gorm:"-" json:"pinned,omitempty"`
+}
+
+type CommentLike struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"index" json:"username"`
-	VideoID   uint      `gorm:"index" json:"video_id"`
-	AuthorID  uint      `gorm:"index" json:"author_id"`
-	Content   string    `gorm:"type:text" json:"content"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	CommentID uint      `gorm:"uniqueIndex:idx_comment_like_comment_account;not null" json:"comment_id"`
+	AccountID uint      `gorm:"uniqueIndex:idx_comment_like_comment_account;not null" json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
+const (
+	CommentSortLatest = "latest"
+	CommentSortHot    = "hot"
+)
+
 type PublishCommentRequest struct {
-	VideoID uint   `json:"video_id"`
-	Content string `json:"content"`
+	VideoID  uint   `json:"video_id"`
+	ParentID uint   `json:"parent_id,omitempty"`
+	Content  string `json:"content"`
+	// ShareToken 评论 unlisted 视频时必传，下同
+	ShareToken string `json:"share_token,omitempty"`
 }
 
+// Cascade=true 时连同所有下级回复一起删除；否则有回复的评论只做墓碑处理
 type DeleteCommentRequest struct {
 	CommentID uint `json:"comment_id"`
+	Cascade   bool `json:"cascade,omitempty"`
+}
+
+type ListTopCommentsRequest struct {
+	VideoID    uint   `json:"video_id"`
+	Cursor     uint   `json:"cursor,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+type ListRepliesRequest struct {
+	RootID     uint   `json:"root_id"`
+	Cursor     uint   `json:"cursor,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+type ListCommentsResponse struct {
+	Comments   []Comment `json:"comments"`
+	NextCursor uint      `json:"next_cursor"`
+	HasMore    bool      `json:"has_more"`
 }
 
 type GetAllCommentsRequest struct {
 	VideoID uint `json:"video_id"`
+	// Sort 为 hot、latest 或空（按发布时间正序），都只返回顶层评论
+	Sort       string `json:"sort,omitempty"`
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+type CommentLikeRequest struct {
+	CommentID  uint   `json:"comment_id"`
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+// CommentID 为 0 表示取消置顶
+type PinCommentRequest struct {
+	VideoID   uint `json:"video_id"`
+	CommentID uint `json:"comment_id"`
 }
@@ -44,9 +44,10 @@ func (h *CommentHandler) PublishComment(c *gin.Context) {
 		Username: user.Username,
 		VideoID:  req.VideoID,
 		AuthorID: authorId,
+		ParentID: req.ParentID,
 		Content:  req.Content,
 	}
-	if err := h.service.Publish(c.Request.Context(), comment); err != nil {
+	if err := h.service.Publish(c.Request.Context(), comment, req.ShareToken); err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
@@ -68,7 +69,7 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "comment_id is required"})
 		return
 	}
-	if err := h.service.Delete(c.Request.Context(), req.CommentID, accountID); err != nil {
+	if err := h.service.Delete(c.Request.Context(), req.CommentID, accountID, req.Cascade); err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
@@ -86,7 +87,8 @@ func (h *CommentHandler) GetAllComments(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "video_id is required"})
 		return
 	}
-	comments, err := h.service.GetAll(c.Request.Context(), req.VideoID)
+	viewerID, _ := jwt.GetAccountID(c)
+	comments, err := h.service.GetAll(c.Request.Context(), req.VideoID, req.Sort, viewerID, req.ShareToken)
 	if err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
@@ -96,3 +98,107 @@ func (h *CommentHandler) GetAllComments(c *gin.Context) {
 	}
 	c.JSON(200, comments)
 }
+
+func (h *CommentHandler) ListTopComments(c *gin.Context) {
+	var req ListTopCommentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if req.VideoID == 0 {
+		c.JSON(400, gin.H{"error": "video_id is required"})
+		return
+	}
+	viewerID, _ := jwt.GetAccountID(c)
+	resp, err := h.service.ListTopLevel(c.Request.Context(), req.VideoID, req.Cursor, req.Limit, viewerID, req.ShareToken)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, resp)
+}
+
+func (h *CommentHandler) ListReplies(c *gin.Context) {
+	var req ListRepliesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if req.RootID == 0 {
+		c.JSON(400, gin.H{"error": "root_id is required"})
+		return
+	}
+	viewerID, _ := jwt.GetAccountID(c)
+	resp, err := h.service.ListReplies(c.Request.Context(), req.RootID, req.Cursor, req.Limit, viewerID, req.ShareToken)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, resp)
+}
+
+func (h *CommentHandler) LikeComment(c *gin.Context) {
+	var req CommentLikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if req.CommentID == 0 {
+		c.JSON(400, gin.H{"error": "comment_id is required"})
+		return
+	}
+	accountID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.Like(c.Request.Context(), req.CommentID, accountID, req.ShareToken); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "comment liked successfully"})
+}
+
+func (h *CommentHandler) UnlikeComment(c *gin.Context) {
+	var req CommentLikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if req.CommentID == 0 {
+		c.JSON(400, gin.H{"error": "comment_id is required"})
+		return
+	}
+	accountID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.Unlike(c.Request.Context(), req.CommentID, accountID, req.ShareToken); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "comment unliked successfully"})
+}
+
+func (h *CommentHandler) PinComment(c *gin.Context) {
+	var req PinCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if req.VideoID == 0 {
+		c.JSON(400, gin.H{"error": "video_id is required"})
+		return
+	}
+	accountID, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.Pin(c.Request.Context(), req.VideoID, req.CommentID, accountID); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "comment pin updated successfully"})
+}
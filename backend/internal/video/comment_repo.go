@@ -2,6 +2,13 @@ package video
 
 import (
 	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/outbox"
 
 	"gorm.io/gorm"
 )
@@ -14,21 +21,242 @@ func NewCommentRepository(db *gorm.DB) *CommentRepository {
 	return &CommentRepository{db: db}
 }
 
+// CreateComment 回复会同时累加所在顶层评论的 reply_count
 func (r *CommentRepository) CreateComment(ctx context.Context, comment *Comment) error {
-	return r.db.WithContext(ctx).Create(comment).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createComment(tx, comment)
+	})
+}
+
+func createComment(tx *gorm.DB, comment *Comment) error {
+	if err := tx.Create(comment).Error; err != nil {
+		return err
+	}
+	if comment.RootID == 0 {
+		return nil
+	}
+	return changeReplyCount(tx, comment.RootID, 1)
+}
+
+func changeReplyCount(tx *gorm.DB, rootID uint, delta int64) error {
+	return tx.Model(&Comment{}).Where("id = ?", rootID).
+		UpdateColumn("reply_count", gorm.Expr("GREATEST(reply_count + ?, 0)", delta)).Error
+}
+
+// cleanupCommentRefs 清理被删评论的点赞记录和置顶
+func cleanupCommentRefs(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&CommentLike{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Video{}).Where("pinned_comment_id IN ?", ids).
+		UpdateColumn("pinned_comment_id", 0).Error
+}
+
+func deleteComment(tx *gorm.DB, comment *Comment) error {
+	if err := tx.Delete(comment).Error; err != nil {
+		return err
+	}
+	if err := cleanupCommentRefs(tx, []uint{comment.ID}); err != nil {
+		return err
+	}
+	if comment.RootID == 0 {
+		return nil
+	}
+	return changeReplyCount(tx, comment.RootID, -1)
+}
+
+// RemoveComment cascade 时删除整棵子树；否则有下级回复的评论做墓碑（清空内容保留楼层），没有回复的直接删除。
+// 墓碑在最后一条下级回复删除后一并清理。
+// msgs 在同一事务里写入 outbox
+func (r *CommentRepository) RemoveComment(ctx context.Context, comment *Comment, cascade bool, msgs ...rabbitmq.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := removeComment(tx, comment, cascade); err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, msgs...)
+	})
+}
+
+func removeComment(tx *gorm.DB, comment *Comment, cascade bool) error {
+	if cascade {
+		return deleteSubtree(tx, comment)
+	}
+	var children int64
+	if err := tx.Model(&Comment{}).Where("parent_id = ?", comment.ID).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		if err := tx.Model(&Comment{}).Where("id = ?", comment.ID).
+			Updates(map[string]interface{}{"deleted": true, "content": ""}).Error; err != nil {
+			return err
+		}
+		return cleanupCommentRefs(tx, []uint{comment.ID})
+	}
+	if err := deleteComment(tx, comment); err != nil {
+		return err
+	}
+	return pruneTombstones(tx, comment.ParentID)
+}
+
+// pruneTombstones 删除回复后沿 parent_id 向上清理已经没有下级回复的墓碑
+func pruneTombstones(tx *gorm.DB, parentID uint) error {
+	for parentID != 0 {
+		var parent Comment
+		err := tx.Where("id = ? AND deleted = ?", parentID, true).Take(&parent).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var children int64
+		if err := tx.Model(&Comment{}).Where("parent_id = ?", parent.ID).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return nil
+		}
+		if err := deleteComment(tx, &parent); err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
 }
 
-func (r *CommentRepository) DeleteComment(ctx context.Context, comment *Comment) error {
-	return r.db.WithContext(ctx).Delete(comment).Error
+func deleteSubtree(tx *gorm.DB, comment *Comment) error {
+	if comment.RootID == 0 {
+		// 顶层评论：整楼删除
+		var ids []uint
+		if err := tx.Model(&Comment{}).Where("root_id = ?", comment.ID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		ids = append(ids, comment.ID)
+		if err := tx.Where("id IN ?", ids).Delete(&Comment{}).Error; err != nil {
+			return err
+		}
+		return cleanupCommentRefs(tx, ids)
+	}
+
+	// 楼内回复：在同一楼里沿 parent_id 找出所有下级
+	var replies []Comment
+	if err := tx.Select("id", "parent_id").Where("root_id = ?", comment.RootID).Find(&replies).Error; err != nil {
+		return err
+	}
+	children := make(map[uint][]uint, len(replies))
+	for _, c := range replies {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+	}
+	ids := []uint{comment.ID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	res := tx.Where("id IN ?", ids).Delete(&Comment{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if err := cleanupCommentRefs(tx, ids); err != nil {
+		return err
+	}
+	if err := changeReplyCount(tx, comment.RootID, -res.RowsAffected); err != nil {
+		return err
+	}
+	return pruneTombstones(tx, comment.ParentID)
 }
 
-func (r *CommentRepository) GetAllComments(ctx context.Context, videoID uint) ([]Comment, error) {
+// ListTopLevel 顶层评论按 id 倒序，cursor 为上一页最后一条的 id；excludeID 非 0 时跳过该评论（置顶评论单独展示）
+func (r *CommentRepository) ListTopLevel(ctx context.Context, videoID uint, cursor uint, limit int, excludeID uint) ([]Comment, error) {
 	var comments []Comment
-	err := r.db.WithContext(ctx).
-		Where("video_id = ?", videoID).
-		Order("created_at asc").
-		Limit(200).
-		Find(&comments).Error
+	query := r.db.WithContext(ctx).
+		Where("video_id = ? AND root_id = 0", videoID)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&comments).Error
+	return comments, err
+}
+
+// ListReplies 楼内回复按时间正序展开，cursor 为上一页最后一条的 id
+func (r *CommentRepository) ListReplies(ctx context.Context, rootID uint, cursor uint, limit int) ([]Comment, error) {
+	var comments []Comment
+	query := r.db.WithContext(ctx).
+		Where("root_id = ?", rootID)
+	if cursor > 0 {
+		query = query.Where("id > ?", cursor)
+	}
+	err := query.Order("id asc").Limit(limit).Find(&comments).Error
+	return comments, err
+}
+
+// getAllLimit 全量评论接口最多返回的条数
+const getAllLimit = 200
+
+// hotCommentScore 点赞数随时间衰减：(likes+1) / (小时数+2)^1.5，小时数向下取整
+func hotCommentScore(c *Comment, now time.Time) float64 {
+	hours := math.Floor(now.Sub(c.CreatedAt).Hours())
+	if hours < 0 {
+		hours = 0
+	}
+	return float64(c.LikesCount+1) / math.Pow(hours+2, 1.5)
+}
+
+// listHotTopLevel 热度排序只看顶层评论。衰减在 Go 里算，不依赖数据库方言：
+// 候选取点赞最多和最新的顶层评论各 limit 条，合并后按热度、id 倒序取前 limit 条
+func (r *CommentRepository) listHotTopLevel(ctx context.Context, videoID uint, limit int, now time.Time) ([]Comment, error) {
+	var byLikes, byTime []Comment
+	base := func() *gorm.DB {
+		return r.db.WithContext(ctx).Where("video_id = ? AND parent_id = 0", videoID)
+	}
+	if err := base().Order("likes_count desc, id desc").Limit(limit).Find(&byLikes).Error; err != nil {
+		return nil, err
+	}
+	if err := base().Order("id desc").Limit(limit).Find(&byTime).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[uint]bool, len(byLikes)+len(byTime))
+	comments := make([]Comment, 0, len(byLikes)+len(byTime))
+	for _, c := range append(byLikes, byTime...) {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		comments = append(comments, c)
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		si, sj := hotCommentScore(&comments[i], now), hotCommentScore(&comments[j], now)
+		if si != sj {
+			return si > sj
+		}
+		return comments[i].ID > comments[j].ID
+	})
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+// GetAllComments 各种排序都只返回顶层评论，回复走 ListReplies：
+// hot 按衰减后的热度，latest 按时间倒序，默认（sort 为空）按时间正序
+func (r *CommentRepository) GetAllComments(ctx context.Context, videoID uint, sort string) ([]Comment, error) {
+	if sort == CommentSortHot {
+		return r.listHotTopLevel(ctx, videoID, getAllLimit, time.Now())
+	}
+	var comments []Comment
+	query := r.db.WithContext(ctx).
+		Where("video_id = ? AND root_id = 0", videoID)
+	switch sort {
+	case CommentSortLatest:
+		query = query.Order("created_at desc, id desc")
+	default:
+		query = query.Order("created_at asc, id asc")
+	}
+	err := query.Limit(getAllLimit).Find(&comments).Error
 	return comments, err
 }
 
@@ -53,3 +281,59 @@ func (r *CommentRepository) GetByID(ctx context.Context, id uint) (*Comment, err
 	}
 	return &comment, nil
 }
+
+// LikeComment 幂等点赞：重复点赞不报错，也不会重复计数
+func (r *CommentRepository) LikeComment(ctx context.Context, commentID, accountID uint) (created bool, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&CommentLike{CommentID: commentID, AccountID: accountID}).Error; err != nil {
+			if isDupKey(err) {
+				return nil
+			}
+			return err
+		}
+		created = true
+		return tx.Model(&Comment{}).Where("id = ?", commentID).
+			UpdateColumn("likes_count", gorm.Expr("likes_count + 1")).Error
+	})
+	return created, err
+}
+
+// UnlikeComment 幂等取消点赞
+func (r *CommentRepository) UnlikeComment(ctx context.Context, commentID, accountID uint) (deleted bool, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("comment_id = ? AND account_id = ?", commentID, accountID).Delete(&CommentLike{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		deleted = true
+		return tx.Model(&Comment{}).Where("id = ?", commentID).
+			UpdateColumn("likes_count", gorm.Expr("GREATEST(likes_count - 1, 0)")).Error
+	})
+	return deleted, err
+}
+
+func (r *CommentRepository) BatchGetLiked(ctx context.Context, commentIDs []uint, accountID uint) (map[uint]bool, error) {
+	likeMap := make(map[uint]bool)
+	if len(commentIDs) == 0 || accountID == 0 {
+		return likeMap, nil
+	}
+	var likes []CommentLike
+	err := r.db.WithContext(ctx).Model(&CommentLike{}).
+		Where("comment_id IN ? AND account_id = ?", commentIDs, accountID).
+		Find(&likes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, like := range likes {
+		likeMap[like.CommentID] = true
+	}
+	return likeMap, nil
+}
+
+func (r *CommentRepository) SetPinned(ctx context.Context, videoID, commentID uint) error {
+	return r.db.WithContext(ctx).Model(&Video{}).Where("id = ?", videoID).
+		UpdateColumn("pinned_comment_id", commentID).Error
+}
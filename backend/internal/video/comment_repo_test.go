@@ -0,0 +1,355 @@
+package video
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/social"
+
+	"gorm.io/gorm"
+)
+
+func newCommentRepo(t *testing.T) (*CommentRepository, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, &Video{}, &Comment{}, &CommentLike{}, &outbox.Event{})
+	return NewCommentRepository(db), db
+}
+
+func addComment(t *testing.T, r *CommentRepository, parent *Comment) *Comment {
+	t.Helper()
+	c := &Comment{VideoID: 1, AuthorID: 1, Content: "c"}
+	if parent != nil {
+		c.ParentID = parent.ID
+		c.RootID = parent.RootID
+		if c.RootID == 0 {
+			c.RootID = parent.ID
+		}
+	}
+	if err := r.CreateComment(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func loadComment(t *testing.T, db *gorm.DB, id uint) *Comment {
+	t.Helper()
+	var c Comment
+	if err := db.Where("id = ?", id).Limit(1).Find(&c).Error; err != nil {
+		t.Fatal(err)
+	}
+	if c.ID == 0 {
+		return nil
+	}
+	return &c
+}
+
+func TestRemoveCommentTombstonesParentWithReplies(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	root := addComment(t, r, nil)
+	reply := addComment(t, r, root)
+
+	if err := r.RemoveComment(ctx, root, false); err != nil {
+		t.Fatal(err)
+	}
+	got := loadComment(t, db, root.ID)
+	if got == nil || !got.Deleted || got.Content != "" || got.ReplyCount != 1 {
+		t.Fatalf("expected tombstoned root keeping its reply, got %+v", got)
+	}
+
+	// 删掉最后一条回复后墓碑一并清理
+	if err := r.RemoveComment(ctx, reply, false); err != nil {
+		t.Fatal(err)
+	}
+	if loadComment(t, db, root.ID) != nil || loadComment(t, db, reply.ID) != nil {
+		t.Fatal("tombstone should be removed after its last reply")
+	}
+}
+
+func TestRemoveCommentPrunesTombstoneChain(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	root := addComment(t, r, nil)
+	a := addComment(t, r, root)
+	b := addComment(t, r, a)
+	sibling := addComment(t, r, root)
+
+	if err := r.RemoveComment(ctx, a, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadComment(t, db, a.ID); got == nil || !got.Deleted {
+		t.Fatalf("expected reply a tombstoned, got %+v", got)
+	}
+	if err := r.RemoveComment(ctx, b, false); err != nil {
+		t.Fatal(err)
+	}
+	if loadComment(t, db, a.ID) != nil {
+		t.Fatal("tombstoned reply should be pruned with its last child")
+	}
+	// 顶层评论没被删，不受影响；楼内只剩 sibling
+	got := loadComment(t, db, root.ID)
+	if got == nil || got.Deleted || got.ReplyCount != 1 {
+		t.Fatalf("unexpected root %+v", got)
+	}
+	if loadComment(t, db, sibling.ID) == nil {
+		t.Fatal("sibling reply should be kept")
+	}
+}
+
+func TestRemoveCommentCascadeDeletesSubtreeAndRefs(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	if err := db.Create(&Video{ID: 1, AuthorID: 1, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	root := addComment(t, r, nil)
+	a := addComment(t, r, root)
+	b := addComment(t, r, a)
+	keep := addComment(t, r, root)
+	if _, err := r.LikeComment(ctx, b.ID, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetPinned(ctx, 1, a.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.RemoveComment(ctx, a, true); err != nil {
+		t.Fatal(err)
+	}
+	if loadComment(t, db, a.ID) != nil || loadComment(t, db, b.ID) != nil {
+		t.Fatal("subtree should be deleted")
+	}
+	if loadComment(t, db, keep.ID) == nil {
+		t.Fatal("other replies should be kept")
+	}
+	if got := loadComment(t, db, root.ID); got.ReplyCount != 1 {
+		t.Fatalf("expected reply_count 1, got %d", got.ReplyCount)
+	}
+	var likes int64
+	db.Model(&CommentLike{}).Count(&likes)
+	var v Video
+	db.First(&v, 1)
+	if likes != 0 || v.PinnedCommentID != 0 {
+		t.Fatalf("expected likes and pin cleaned up, likes=%d pinned=%d", likes, v.PinnedCommentID)
+	}
+
+	// 整楼删除
+	if err := r.RemoveComment(ctx, root, true); err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	db.Model(&Comment{}).Count(&left)
+	if left != 0 {
+		t.Fatalf("expected thread deleted, %d comments left", left)
+	}
+}
+
+func TestGetAllPutsPinnedCommentFirst(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	if err := db.Create(&Video{ID: 1, AuthorID: 9, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now().Add(-time.Hour)
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		c := &Comment{VideoID: 1, AuthorID: 1, Content: "c", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := r.CreateComment(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, c.ID)
+	}
+	s := NewCommentService(r, NewVideoRepository(db), nil, nil)
+	if err := s.Pin(ctx, 1, ids[1], 9); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		sort string
+		want []uint
+	}{
+		{"", []uint{ids[1], ids[0], ids[2]}},
+		{CommentSortLatest, []uint{ids[1], ids[2], ids[0]}},
+	}
+	for _, tc := range cases {
+		got, err := s.GetAll(ctx, 1, tc.sort, 0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tc.want) || !got[0].Pinned {
+			t.Fatalf("sort %q: unexpected comments %+v", tc.sort, got)
+		}
+		for i, c := range got {
+			if c.ID != tc.want[i] {
+				t.Fatalf("sort %q: position %d = %d, want %d", tc.sort, i, c.ID, tc.want[i])
+			}
+		}
+	}
+
+	if err := s.Pin(ctx, 1, ids[0], 2); err == nil {
+		t.Fatal("only the video author can pin")
+	}
+}
+
+func TestGetAllReturnsTopLevelOnlyForEverySort(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	if err := db.Create(&Video{ID: 1, AuthorID: 9, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	top := &Comment{VideoID: 1, AuthorID: 1, Content: "top"}
+	if err := r.CreateComment(ctx, top); err != nil {
+		t.Fatal(err)
+	}
+	reply := &Comment{VideoID: 1, AuthorID: 2, Content: "reply", ParentID: top.ID, RootID: top.ID}
+	if err := r.CreateComment(ctx, reply); err != nil {
+		t.Fatal(err)
+	}
+	for _, sort := range []string{"", CommentSortLatest, CommentSortHot} {
+		got, err := r.GetAllComments(ctx, 1, sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].ID != top.ID {
+			t.Fatalf("sort %q: expected only the top-level comment, got %+v", sort, got)
+		}
+	}
+}
+
+func TestGetAllHotRanksTopLevelByDecayedLikes(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	if err := db.Create(&Video{ID: 1, AuthorID: 9, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	seed := func(likes int64, age time.Duration, parent *Comment) *Comment {
+		c := &Comment{VideoID: 1, AuthorID: 1, Content: "c", LikesCount: likes, CreatedAt: now.Add(-age)}
+		if parent != nil {
+			c.ParentID, c.RootID = parent.ID, parent.ID
+		}
+		if err := r.CreateComment(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	// 11/50^1.5≈0.031，2/3^1.5≈0.385，1/2^1.5≈0.354
+	old := seed(10, 48*time.Hour, nil)
+	fresh := seed(1, time.Hour+time.Minute, nil)
+	newest := seed(0, 0, nil)
+	// 回复点赞再多也不进顶层热度列表
+	seed(100, 0, old)
+
+	s := NewCommentService(r, NewVideoRepository(db), nil, nil)
+	got, err := s.GetAll(ctx, 1, CommentSortHot, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint{fresh.ID, newest.ID, old.ID}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d top-level comments, got %+v", len(want), got)
+	}
+	for i, c := range got {
+		if c.ID != want[i] {
+			t.Fatalf("position %d = %d, want %d", i, c.ID, want[i])
+		}
+	}
+
+	// 置顶评论仍然排第一
+	if err := s.Pin(ctx, 1, old.ID, 9); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = s.GetAll(ctx, 1, CommentSortHot, 0, ""); len(got) != 3 || got[0].ID != old.ID || !got[0].Pinned {
+		t.Fatalf("pinned comment should lead the hot list, got %+v", got)
+	}
+}
+
+func TestListTopLevelKeepsPinnedOnFirstPageOnly(t *testing.T) {
+	r, db := newCommentRepo(t)
+	ctx := context.Background()
+	if err := db.Create(&Video{ID: 1, AuthorID: 9, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		ids = append(ids, addComment(t, r, nil).ID)
+	}
+	s := NewCommentService(r, NewVideoRepository(db), nil, nil)
+	// 置顶一条本来会落在第二页的评论
+	if err := s.Pin(ctx, 1, ids[1], 9); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.ListTopLevel(ctx, 1, 0, 2, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Comments) != 3 || first.Comments[0].ID != ids[1] || !first.Comments[0].Pinned ||
+		first.Comments[1].ID != ids[4] || first.Comments[2].ID != ids[3] || first.NextCursor != ids[3] {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	second, err := s.ListTopLevel(ctx, 1, first.NextCursor, 2, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Comments) != 2 || second.Comments[0].ID != ids[2] || second.Comments[1].ID != ids[0] || second.HasMore {
+		t.Fatalf("pinned comment should not repeat on later pages, got %+v", second)
+	}
+}
+
+func TestCommentPathsRespectVideoVisibility(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &Comment{}, &CommentLike{}, &social.Social{}, &outbox.Event{})
+	r := NewCommentRepository(db)
+	ctx := context.Background()
+	const viewer = 9
+	for _, v := range []*Video{
+		{ID: 1, AuthorID: 1, Status: VideoStatusReady, Visibility: VisibilityPrivate},
+		{ID: 2, AuthorID: 1, Status: VideoStatusReady, Visibility: VisibilityUnlisted, ShareToken: "secret"},
+		{ID: 3, AuthorID: 1, Status: VideoStatusProcessing, Visibility: VisibilityPublic},
+	} {
+		v.Title, v.Username, v.PlayURL, v.CoverURL = "t", "u", "p", "c"
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewCommentService(r, NewVideoRepository(db), social.NewSocialRepository(db), nil)
+
+	// 作者在自己的私密视频下评论，其他人读写都看不到
+	own := &Comment{VideoID: 1, AuthorID: 1, Content: "mine"}
+	if err := s.Publish(ctx, own, ""); err != nil {
+		t.Fatalf("author comment on own private video: %v", err)
+	}
+	if err := s.Publish(ctx, &Comment{VideoID: 1, AuthorID: viewer, Content: "x"}, ""); err == nil {
+		t.Fatal("expected comment on private video to fail")
+	}
+	if _, err := s.GetAll(ctx, 1, "", viewer, ""); err == nil {
+		t.Fatal("expected listAll on private video to fail")
+	}
+	if _, err := s.ListTopLevel(ctx, 1, 0, 10, viewer, ""); err == nil {
+		t.Fatal("expected listTop on private video to fail")
+	}
+	if _, err := s.ListReplies(ctx, own.ID, 0, 10, viewer, ""); err == nil {
+		t.Fatal("expected listReplies on private video to fail")
+	}
+	if err := s.Like(ctx, own.ID, viewer, ""); err == nil {
+		t.Fatal("expected comment like on private video to fail")
+	}
+	if err := s.Unlike(ctx, own.ID, viewer, ""); err == nil {
+		t.Fatal("expected comment unlike on private video to fail")
+	}
+
+	// unlisted 凭分享 token 可以评论
+	if err := s.Publish(ctx, &Comment{VideoID: 2, AuthorID: viewer, Content: "x"}, "wrong"); err == nil {
+		t.Fatal("expected comment on unlisted video with wrong token to fail")
+	}
+	if err := s.Publish(ctx, &Comment{VideoID: 2, AuthorID: viewer, Content: "x"}, "secret"); err != nil {
+		t.Fatalf("comment on unlisted video with token: %v", err)
+	}
+	// 转码中的视频不能评论
+	if err := s.Publish(ctx, &Comment{VideoID: 3, AuthorID: viewer, Content: "x"}, ""); err == nil {
+		t.Fatal("expected comment on processing video to fail")
+	}
+}
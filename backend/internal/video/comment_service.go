@@ -6,8 +6,9 @@ import (
 	"feedsystem_video_go/internal/apierror"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
-	"log"
-	"regexp"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/social"
+	"fmt"
 	"strings"
 
 	"gorm.io/gorm"
@@ -16,16 +17,34 @@ import (
 type CommentService struct {
 	repo            *CommentRepository
 	VideoRepository *VideoRepository
+	socialRepo      *social.SocialRepository
 	cache           *rediscache.Client
-	commentMQ       *rabbitmq.CommentMQ
-	popularityMQ    *rabbitmq.PopularityMQ
 }
 
-func NewCommentService(repo *CommentRepository, videoRepo *VideoRepository, cache *rediscache.Client, commentMQ *rabbitmq.CommentMQ, popularityMQ *rabbitmq.PopularityMQ) *CommentService {
-	return &CommentService{repo: repo, VideoRepository: videoRepo, cache: cache, commentMQ: commentMQ, popularityMQ: popularityMQ}
+func NewCommentService(repo *CommentRepository, videoRepo *VideoRepository, socialRepo *social.SocialRepository, cache *rediscache.Client) *CommentService {
+	return &CommentService{repo: repo, VideoRepository: videoRepo, socialRepo: socialRepo, cache: cache}
 }
 
-func (s *CommentService) Publish(ctx context.Context, comment *Comment) error {
+// visibleVideo 读取评论所在的视频，与点赞一样要求已就绪且观看者看得到；看不到时与视频不存在一样报错
+func (s *CommentService) visibleVideo(ctx context.Context, videoID uint, viewerID uint, shareToken string) (*Video, error) {
+	video, err := s.VideoRepository.GetByID(ctx, videoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("video not found")
+		}
+		return nil, err
+	}
+	visible, err := canInteract(ctx, s.socialRepo, video, viewerID, shareToken)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("video not found")
+	}
+	return video, nil
+}
+
+func (s *CommentService) Publish(ctx context.Context, comment *Comment, shareToken string) error {
 	if comment == nil {
 		return errors.New("comment is nil")
 	}
@@ -38,59 +57,75 @@ func (s *CommentService) Publish(ctx context.Context, comment *Comment) error {
 		return errors.New("content is required")
 	}
 
-	exists, err := s.VideoRepository.IsExist(ctx, comment.VideoID)
-	if err != nil {
+	if _, err := s.visibleVideo(ctx, comment.VideoID, comment.AuthorID, shareToken); err != nil {
 		return err
 	}
-	if !exists {
-		return errors.New("video not found")
+	if err := s.resolveParent(ctx, comment); err != nil {
+		return err
 	}
 
-	mysqlEnqueued := false
-	redisEnqueued := false
-	if s.commentMQ != nil {
-		if err := s.commentMQ.Publish(ctx, comment.Username, comment.VideoID, comment.AuthorID, comment.Content); err == nil {
-			mysqlEnqueued = true
+	// 评论和热度在同一事务里落库并写入 outbox：comment worker 记创作者指标，通知和热榜各自消费
+	if err := s.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&Video{}, comment.VideoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("video not found")
+			}
+			return err
 		}
-	}
-	if s.popularityMQ != nil {
-		if err := s.popularityMQ.Update(ctx, comment.VideoID, 1); err == nil {
-			redisEnqueued = true
+		if err := createComment(tx, comment); err != nil {
+			return err
 		}
-	}
-	if mysqlEnqueued && redisEnqueued {
-		s.notifyMentions(ctx, comment)
-		return nil
-	}
-
-	// Fallback: direct MySQL write when comment MQ publish fails.
-	if !mysqlEnqueued {
-		if err := s.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			if err := tx.Select("id").First(&Video{}, comment.VideoID).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.New("video not found")
-				}
-				return err
-			}
-			if err := tx.Create(comment).Error; err != nil {
-				return err
-			}
-			return tx.Model(&Video{}).Where("id = ?", comment.VideoID).
-				UpdateColumn("popularity", gorm.Expr("popularity + 1")).Error
-		}); err != nil {
+		if err := tx.Model(&Video{}).Where("id = ?", comment.VideoID).
+			UpdateColumn("popularity", gorm.Expr("popularity + 1")).Error; err != nil {
+			return err
+		}
+		commentMsg, err := rabbitmq.CommentMessage("publish", rabbitmq.CommentEvent{
+			CommentID: comment.ID,
+			Username:  comment.Username,
+			VideoID:   comment.VideoID,
+			AuthorID:  comment.AuthorID,
+			ParentID:  comment.ParentID,
+			RootID:    comment.RootID,
+			Content:   comment.Content,
+		})
+		if err != nil {
 			return err
 		}
+		popularityMsg, err := rabbitmq.PopularityMessage(comment.VideoID, 1)
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, commentMsg, popularityMsg)
+	}); err != nil {
+		return err
 	}
+	return nil
+}
 
-	// Fallback: direct Redis update when popularity MQ publish fails.
-	if !redisEnqueued {
-		UpdatePopularityCache(ctx, s.cache, comment.VideoID, 1)
+// resolveParent 校验被回复的评论并补齐 RootID
+func (s *CommentService) resolveParent(ctx context.Context, comment *Comment) error {
+	comment.RootID = 0
+	if comment.ParentID == 0 {
+		return nil
+	}
+	parent, err := s.repo.GetByID(ctx, comment.ParentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil || parent.Deleted {
+		return errors.New("parent comment not found")
+	}
+	if parent.VideoID != comment.VideoID {
+		return fmt.Errorf("%w: parent comment belongs to another video", apierror.ErrValidation)
+	}
+	comment.RootID = parent.RootID
+	if comment.RootID == 0 {
+		comment.RootID = parent.ID
 	}
-	s.notifyMentions(ctx, comment)
 	return nil
 }
 
-func (s *CommentService) Delete(ctx context.Context, commentID uint, accountID uint) error {
+func (s *CommentService) Delete(ctx context.Context, commentID uint, accountID uint, cascade bool) error {
 	comment, err := s.repo.GetByID(ctx, commentID)
 	if err != nil {
 		return err
@@ -101,58 +136,223 @@ func (s *CommentService) Delete(ctx context.Context, commentID uint, accountID u
 	if comment.AuthorID != accountID {
 		return apierror.ErrUnauthorized
 	}
-	if s.commentMQ != nil {
-		if err := s.commentMQ.Delete(ctx, commentID); err == nil {
-			return nil
+	msg, err := rabbitmq.CommentMessage("delete", rabbitmq.CommentEvent{
+		CommentID: commentID,
+		VideoID:   comment.VideoID,
+		AuthorID:  comment.AuthorID,
+		Cascade:   cascade,
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.RemoveComment(ctx, comment, cascade, msg)
+}
+
+func normalizeCommentLimit(limit int) int {
+	if limit <= 0 {
+		return 20
+	}
+	if limit > 50 {
+		return 50
+	}
+	return limit
+}
+
+// ListTopLevel 置顶评论只出现在第一页的第一位，各页的常规列表都不再包含它
+func (s *CommentService) ListTopLevel(ctx context.Context, videoID uint, cursor uint, limit int, viewerID uint, shareToken string) (ListCommentsResponse, error) {
+	video, err := s.visibleVideo(ctx, videoID, viewerID, shareToken)
+	if err != nil {
+		return ListCommentsResponse{}, err
+	}
+	limit = normalizeCommentLimit(limit)
+	comments, err := s.repo.ListTopLevel(ctx, videoID, cursor, limit+1, video.PinnedCommentID)
+	if err != nil {
+		return ListCommentsResponse{}, err
+	}
+	resp := buildCommentPage(comments, limit)
+	if cursor == 0 && video.PinnedCommentID != 0 {
+		pinned, err := s.repo.GetByID(ctx, video.PinnedCommentID)
+		if err != nil {
+			return ListCommentsResponse{}, err
+		}
+		resp.Comments = withPinnedFirst(resp.Comments, pinned)
+	}
+	if err := s.fillLiked(ctx, resp.Comments, viewerID); err != nil {
+		return ListCommentsResponse{}, err
+	}
+	return resp, nil
+}
+
+func (s *CommentService) ListReplies(ctx context.Context, rootID uint, cursor uint, limit int, viewerID uint, shareToken string) (ListCommentsResponse, error) {
+	root, err := s.repo.GetByID(ctx, rootID)
+	if err != nil {
+		return ListCommentsResponse{}, err
+	}
+	if root == nil || root.RootID != 0 {
+		return ListCommentsResponse{}, errors.New("root comment not found")
+	}
+	if _, err := s.visibleVideo(ctx, root.VideoID, viewerID, shareToken); err != nil {
+		return ListCommentsResponse{}, err
+	}
+	limit = normalizeCommentLimit(limit)
+	comments, err := s.repo.ListReplies(ctx, rootID, cursor, limit+1)
+	if err != nil {
+		return ListCommentsResponse{}, err
+	}
+	resp := buildCommentPage(comments, limit)
+	if err := s.fillLiked(ctx, resp.Comments, viewerID); err != nil {
+		return ListCommentsResponse{}, err
+	}
+	return resp, nil
+}
+
+// buildCommentPage 多查一条用于判断 has_more
+func buildCommentPage(comments []Comment, limit int) ListCommentsResponse {
+	resp := ListCommentsResponse{Comments: comments}
+	if len(comments) > limit {
+		resp.Comments = comments[:limit]
+		resp.HasMore = true
+	}
+	if resp.Comments == nil {
+		resp.Comments = []Comment{}
+	}
+	if n := len(resp.Comments); n > 0 {
+		resp.NextCursor = resp.Comments[n-1].ID
+	}
+	return resp
+}
+
+func (s *CommentService) GetAll(ctx context.Context, videoID uint, sort string, viewerID uint, shareToken string) ([]Comment, error) {
+	switch sort {
+	case "", CommentSortLatest, CommentSortHot:
+	default:
+		return nil, fmt.Errorf("%w: sort must be hot or latest", apierror.ErrValidation)
+	}
+	video, err := s.visibleVideo(ctx, videoID, viewerID, shareToken)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.repo.GetAllComments(ctx, videoID, sort)
+	if err != nil {
+		return nil, err
+	}
+	var pinned *Comment
+	if video.PinnedCommentID != 0 {
+		pinned, err = s.repo.GetByID(ctx, video.PinnedCommentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	comments = withPinnedFirst(comments, pinned)
+	if err := s.fillLiked(ctx, comments, viewerID); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// withPinnedFirst 置顶评论固定放在第一位，并从原列表中去重
+func withPinnedFirst(comments []Comment, pinned *Comment) []Comment {
+	if pinned == nil || pinned.Deleted {
+		return comments
+	}
+	out := make([]Comment, 0, len(comments)+1)
+	p := *pinned
+	p.Pinned = true
+	out = append(out, p)
+	for _, c := range comments {
+		if c.ID != pinned.ID {
+			out = append(out, c)
 		}
 	}
-	return s.repo.DeleteComment(ctx, comment)
+	return out
+}
+
+// fillLiked 批量填充当前用户的点赞状态，未登录时全部为 false
+func (s *CommentService) fillLiked(ctx context.Context, comments []Comment, viewerID uint) error {
+	if viewerID == 0 || len(comments) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	liked, err := s.repo.BatchGetLiked(ctx, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].IsLiked = liked[comments[i].ID]
+	}
+	return nil
 }
 
-func (s *CommentService) GetAll(ctx context.Context, videoID uint) ([]Comment, error) {
-	exists, err := s.VideoRepository.IsExist(ctx, videoID)
+func (s *CommentService) likableComment(ctx context.Context, commentID uint) (*Comment, error) {
+	comment, err := s.repo.GetByID(ctx, commentID)
 	if err != nil {
 		return nil, err
 	}
-	if !exists {
-		return nil, errors.New("video not found")
+	if comment == nil || comment.Deleted {
+		return nil, errors.New("comment not found")
 	}
-	return s.repo.GetAllComments(ctx, videoID)
+	return comment, nil
 }
 
-var mentionRegex = regexp.MustCompile(`@(\w+)`)
+// visibleComment 评论存在且所在视频对 accountID 可见
+func (s *CommentService) visibleComment(ctx context.Context, commentID uint, accountID uint, shareToken string) error {
+	comment, err := s.likableComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	_, err = s.visibleVideo(ctx, comment.VideoID, accountID, shareToken)
+	return err
+}
 
-func (s *CommentService) notifyMentions(ctx context.Context, comment *Comment) {
-	matches := mentionRegex.FindAllStringSubmatch(comment.Content, -1)
-	if len(matches) == 0 {
-		return
+// Like 重复点赞直接返回成功
+func (s *CommentService) Like(ctx context.Context, commentID uint, accountID uint, shareToken string) error {
+	if err := s.visibleComment(ctx, commentID, accountID, shareToken); err != nil {
+		return err
 	}
-	seen := make(map[string]bool)
-	for _, m := range matches {
-		username := m[1]
-		if seen[username] || username == comment.Username {
-			continue
+	_, err := s.repo.LikeComment(ctx, commentID, accountID)
+	return err
+}
+
+func (s *CommentService) Unlike(ctx context.Context, commentID uint, accountID uint, shareToken string) error {
+	if err := s.visibleComment(ctx, commentID, accountID, shareToken); err != nil {
+		return err
+	}
+	_, err := s.repo.UnlikeComment(ctx, commentID, accountID)
+	return err
+}
+
+// Pin 只有视频作者可以置顶；commentID 为 0 表示取消置顶
+func (s *CommentService) Pin(ctx context.Context, videoID uint, commentID uint, accountID uint) error {
+	video, err := s.VideoRepository.GetByID(ctx, videoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("video not found")
 		}
-		seen[username] = true
-		var accID uint
-		if err := s.repo.db.WithContext(ctx).Table("accounts").Where("username = ?", username).Select("id").Scan(&accID).Error; err != nil || accID == 0 {
-			continue
+		return err
+	}
+	if video.AuthorID != accountID {
+		return apierror.ErrUnauthorized
+	}
+	if commentID != 0 {
+		comment, err := s.likableComment(ctx, commentID)
+		if err != nil {
+			return err
 		}
-		notif := struct {
-			RecipientID uint
-			SenderID    uint
-			Type        string
-			TargetID    uint
-			Content     string
-		}{
-			RecipientID: accID,
-			SenderID:    comment.AuthorID,
-			Type:        "mention",
-			TargetID:    comment.VideoID,
-			Content:     comment.Username + " 在评论中提到了你",
+		if comment.VideoID != videoID {
+			return fmt.Errorf("%w: comment belongs to another video", apierror.ErrValidation)
 		}
-		if err := s.repo.db.WithContext(ctx).Table("notifications").Create(&notif).Error; err != nil {
-			log.Printf("create mention notification failed: %v", err)
+		if comment.RootID != 0 {
+			return fmt.Errorf("%w: only top-level comments can be pinned", apierror.ErrValidation)
 		}
 	}
+	if err := s.repo.SetPinned(ctx, videoID, commentID); err != nil {
+		return err
+	}
+	if s.cache != nil {
+		_ = s.cache.Del(context.Background(), s.cache.Key("video:detail:id=%d", videoID))
+	}
+	return nil
 }
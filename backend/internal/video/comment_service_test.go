@@ -0,0 +1,57 @@
+package video
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildCommentPage(t *testing.T) {
+	comments := []Comment{{ID: 9}, {ID: 7}, {ID: 5}}
+
+	page := buildCommentPage(comments, 2)
+	if !page.HasMore || len(page.Comments) != 2 || page.NextCursor != 7 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+
+	page = buildCommentPage(comments, 3)
+	if page.HasMore || len(page.Comments) != 3 || page.NextCursor != 5 {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	page = buildCommentPage(nil, 3)
+	if page.Comments == nil || page.HasMore || page.NextCursor != 0 {
+		t.Fatalf("unexpected empty page %+v", page)
+	}
+}
+
+func TestWithPinnedFirst(t *testing.T) {
+	comments := []Comment{{ID: 3}, {ID: 2}, {ID: 1}}
+
+	got := withPinnedFirst(comments, &Comment{ID: 2})
+	if len(got) != 3 || got[0].ID != 2 || !got[0].Pinned || got[1].ID != 3 || got[2].ID != 1 {
+		t.Fatalf("unexpected order %+v", got)
+	}
+
+	got = withPinnedFirst(comments, &Comment{ID: 9})
+	if len(got) != 4 || got[0].ID != 9 {
+		t.Fatalf("pinned comment outside the list should be prepended, got %+v", got)
+	}
+
+	if got = withPinnedFirst(comments, &Comment{ID: 2, Deleted: true}); len(got) != 3 || got[0].ID != 3 {
+		t.Fatalf("deleted pinned comment should be ignored, got %+v", got)
+	}
+}
+
+func TestHotCommentScoreDecaysByWholeHours(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := &Comment{LikesCount: 7, CreatedAt: now.Add(-(2*time.Hour + 59*time.Minute))}
+	// 2 小时 59 分按 2 小时算：8 / 4^1.5 = 1
+	if got := hotCommentScore(c, now); got != 1 {
+		t.Fatalf("score = %v, want 1", got)
+	}
+	// 时钟偏差导致的未来时间按 0 小时算
+	future := &Comment{LikesCount: 1, CreatedAt: now.Add(time.Minute)}
+	if got, want := hotCommentScore(future, now), hotCommentScore(&Comment{LikesCount: 1, CreatedAt: now}, now); got != want {
+		t.Fatalf("future comment score = %v, want %v", got, want)
+	}
+}
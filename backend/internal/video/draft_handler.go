@@ -0,0 +1,83 @@
+package video
+
+import (
+	"net/http"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (vh *VideoHandler) ListDrafts(c *gin.Context) {
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	videos, err := vh.service.ListDrafts(c.Request.Context(), authorId)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if videos == nil {
+		videos = []Video{}
+	}
+	c.JSON(http.StatusOK, videos)
+}
+
+func (vh *VideoHandler) UpdateDraft(c *gin.Context) {
+	var req UpdateDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	video, err := vh.service.UpdateDraft(c.Request.Context(), authorId, req)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, video)
+}
+
+func (vh *VideoHandler) PublishDraft(c *gin.Context) {
+	var req DraftIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	video, err := vh.service.PublishDraft(c.Request.Context(), authorId, req.ID)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, video)
+}
+
+func (vh *VideoHandler) CancelDraft(c *gin.Context) {
+	var req DraftIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := vh.service.CancelDraft(c.Request.Context(), authorId, req.ID); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "draft cancelled"})
+}
@@ -0,0 +1,63 @@
+package video
+
+import (
+	"context"
+	"log"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+const schedulerBatchSize = 100
+
+// Scheduler 定时扫描到点的 scheduled 视频并发布。多实例部署时每轮先抢 Redis 锁，
+// 避免所有实例一起扫表；真正防止重复发布的是 goLive 的状态条件更新，Redis 不可用时照常运行
+type Scheduler struct {
+	service  *VideoService
+	cache    *rediscache.Client
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewScheduler(service *VideoService, cache *rediscache.Client) *Scheduler {
+	return &Scheduler{service: service, cache: cache, interval: 5 * time.Second, now: time.Now}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	if s.cache != nil {
+		lockKey := s.cache.Key("lock:video:scheduler")
+		token, locked, err := s.cache.Lock(ctx, lockKey, s.interval)
+		if err == nil && !locked {
+			return
+		}
+		if locked {
+			defer func() { _ = s.cache.Unlock(context.Background(), lockKey, token) }()
+		}
+	}
+	for {
+		n, err := s.service.PublishDue(ctx, s.now(), schedulerBatchSize)
+		if err != nil {
+			log.Printf("video scheduler: list due videos failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("video scheduler: published %d scheduled videos", n)
+		}
+		if n < schedulerBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+)
+
+// 定时发布最多提前 30 天
+const maxScheduleAhead = 30 * 24 * time.Hour
+
+func validatePublishAt(at time.Time, now time.Time) error {
+	if !at.After(now) {
+		return fmt.Errorf("%w: publish_at must be in the future", apierror.ErrValidation)
+	}
+	if at.Sub(now) > maxScheduleAhead {
+		return fmt.Errorf("%w: publish_at must be within %d days", apierror.ErrValidation, int(maxScheduleAhead/(24*time.Hour)))
+	}
+	return nil
+}
+
+var errNotDraft = fmt.Errorf("%w: video is not a draft or scheduled video", apierror.ErrValidation)
+
+// ownDraft 取作者自己草稿箱里的视频
+func (vs *VideoService) ownDraft(ctx context.Context, id uint, authorID uint) (*Video, error) {
+	video, err := vs.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if video.AuthorID != authorID {
+		return nil, apierror.ErrUnauthorized
+	}
+	if !video.IsUnpublished() {
+		return nil, errNotDraft
+	}
+	return video, nil
+}
+
+// ListDrafts 作者的草稿和定时发布视频，最近创建的在前
+func (vs *VideoService) ListDrafts(ctx context.Context, authorID uint) ([]Video, error) {
+	return vs.repo.ListDrafts(ctx, authorID)
+}
+
+// UpdateDraft 编辑草稿或定时视频；标题、简介变化时重建话题
+func (vs *VideoService) UpdateDraft(ctx context.Context, authorID uint, req UpdateDraftRequest) (*Video, error) {
+	video, err := vs.ownDraft(ctx, req.ID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	retag := false
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: title is required", apierror.ErrValidation)
+		}
+		retag = retag || title != video.Title
+		video.Title = title
+	}
+	if req.Description != nil {
+		retag = retag || *req.Description != video.Description
+		video.Description = *req.Description
+	}
+	if req.CoverURL != nil {
+		cover := strings.TrimSpace(*req.CoverURL)
+		if cover == "" {
+			return nil, fmt.Errorf("%w: cover url is required", apierror.ErrValidation)
+		}
+		video.CoverURL = cover
+	}
+	if req.Visibility != nil {
+		visibility, err := NormalizeVisibility(*req.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case visibility == VisibilityUnlisted && video.ShareToken == "":
+			if video.ShareToken, err = randHex(16); err != nil {
+				return nil, err
+			}
+		case visibility != VisibilityUnlisted:
+			video.ShareToken = ""
+		}
+		video.Visibility = visibility
+	}
+	if req.PublishAt != nil {
+		if *req.PublishAt == 0 {
+			video.Status = VideoStatusDraft
+			video.PublishAt = nil
+		} else {
+			at := time.Unix(*req.PublishAt, 0)
+			if err := validatePublishAt(at, time.Now()); err != nil {
+				return nil, err
+			}
+			video.Status = VideoStatusScheduled
+			video.PublishAt = &at
+		}
+	}
+	ok, err := vs.repo.UpdateDraft(ctx, video, retag)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 编辑期间被定时任务发布了
+		return nil, errNotDraft
+	}
+	vs.invalidateDetail(video.ID)
+	return video, nil
+}
+
+// PublishDraft 立即发布草稿或定时视频
+func (vs *VideoService) PublishDraft(ctx context.Context, authorID uint, id uint) (*Video, error) {
+	video, err := vs.ownDraft(ctx, id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	live, err := vs.goLive(ctx, video, VideoStatusDraft, VideoStatusScheduled)
+	if err != nil {
+		return nil, err
+	}
+	if !live {
+		return nil, errNotDraft
+	}
+	return video, nil
+}
+
+// CancelDraft 丢弃草稿或取消定时发布的视频；只想推迟发布用 UpdateDraft 把 publish_at 置 0
+func (vs *VideoService) CancelDraft(ctx context.Context, authorID uint, id uint) error {
+	if _, err := vs.ownDraft(ctx, id, authorID); err != nil {
+		return err
+	}
+	ok, err := vs.repo.DeleteDraft(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotDraft
+	}
+	vs.invalidateDetail(id)
+	return nil
+}
+
+// PublishDue 发布所有到点的定时视频，返回本轮发布的数量
+func (vs *VideoService) PublishDue(ctx context.Context, now time.Time, limit int) (int, error) {
+	due, err := vs.repo.ListDueScheduled(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	published := 0
+	for i := range due {
+		live, err := vs.goLive(ctx, &due[i], VideoStatusScheduled)
+		if err != nil {
+			log.Printf("scheduled publish failed: video=%d err=%v", due[i].ID, err)
+			continue
+		}
+		if live {
+			published++
+		}
+	}
+	return published, nil
+}
+
+// goLive 草稿或定时视频正式发布：发布时间记为当前时间，之后和直接发布一样经 outbox 进入转码或各条 feed。
+// 按原状态做条件更新，多个实例同时触发同一个视频时只有一个返回 true
+func (vs *VideoService) goLive(ctx context.Context, video *Video, from ...string) (bool, error) {
+	status := vs.liveStatus()
+	now := time.Now()
+	live, err := vs.repo.MarkLive(ctx, video, status, now, from)
+	if err != nil || !live {
+		return live, err
+	}
+	video.Status = status
+	video.CreateTime = now
+	video.PublishAt = nil
+	vs.invalidateDetail(video.ID)
+	return true, nil
+}
+
+func (vs *VideoService) invalidateDetail(id uint) {
+	if vs.cache == nil {
+		return
+	}
+	_ = vs.cache.Del(context.Background(), vs.cache.Key("video:detail:id=%d", id))
+}
@@ -0,0 +1,92 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/dbtest"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/outbox"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestValidatePublishAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		at time.Time
+		ok bool
+	}{
+		{now.Add(time.Minute), true},
+		{now.Add(maxScheduleAhead), true},
+		{now, false},
+		{now.Add(-time.Hour), false},
+		{now.Add(maxScheduleAhead + time.Second), false},
+	}
+	for _, c := range cases {
+		err := validatePublishAt(c.at, now)
+		if c.ok && err != nil {
+			t.Errorf("publish_at %v: unexpected error %v", c.at, err)
+		}
+		if !c.ok && !errors.Is(err, apierror.ErrValidation) {
+			t.Errorf("publish_at %v: expected validation error, got %v", c.at, err)
+		}
+	}
+}
+
+func TestSchedulerSkipsTickWhenAnotherInstanceHoldsLock(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+
+	lockKey := cache.Key("lock:video:scheduler")
+	if _, ok, err := cache.Lock(ctx, lockKey, time.Minute); err != nil || !ok {
+		t.Fatalf("take lock: ok=%v err=%v", ok, err)
+	}
+	// service 为 nil：只要真的去扫表就会 panic
+	s := NewScheduler(nil, cache)
+	s.tick(ctx)
+}
+
+func TestPublishAndGoLiveEnqueueTranscodeThroughOutbox(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &Tag{}, &VideoTag{}, &outbox.Event{})
+	// 开启转码时请求经 outbox 投递，不直接发 MQ
+	vs := NewVideoService(NewVideoRepository(db), nil, true, nil)
+	ctx := context.Background()
+
+	v := &Video{AuthorID: 1, Username: "u", Title: "t", PlayURL: "/static/videos/1/a.mp4", CoverURL: "c"}
+	if err := vs.Publish(ctx, v); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	draft := &Video{AuthorID: 1, Username: "u", Title: "d", PlayURL: "/static/videos/1/b.mp4", CoverURL: "c", Status: VideoStatusDraft}
+	if err := vs.Publish(ctx, draft); err != nil {
+		t.Fatalf("publish draft: %v", err)
+	}
+	if _, err := vs.PublishDraft(ctx, 1, draft.ID); err != nil {
+		t.Fatalf("publish draft: %v", err)
+	}
+
+	var events []outbox.Event
+	db.Order("id").Find(&events)
+	if len(events) != 2 {
+		t.Fatalf("expected two transcode requests, got %d", len(events))
+	}
+	for i, want := range []uint{v.ID, draft.ID} {
+		if events[i].AggregateID != want || events[i].RoutingKey != "video.transcode.request" {
+			t.Errorf("event %d = %s for video %d, want transcode request for %d", i, events[i].RoutingKey, events[i].AggregateID, want)
+		}
+	}
+	var got Video
+	db.First(&got, draft.ID)
+	if got.Status != VideoStatusPending {
+		t.Fatalf("expected pending, got %s", got.Status)
+	}
+}
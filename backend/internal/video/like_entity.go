@@ -10,5 +10,6 @@ type Like struct {
 }
 
 type LikeRequest struct {
-	VideoID uint `json:"video_id"`
+	VideoID    uint   `json:"video_id"`
+	ShareToken string `json:"share_token,omitempty"` // 给 unlisted 视频点赞时必传
 }
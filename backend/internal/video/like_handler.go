@@ -36,7 +36,7 @@ func (lh *LikeHandler) Like(c *gin.Context) {
 		VideoID:   req.VideoID,
 		AccountID: accountID,
 	}
-	if err := lh.service.Like(c.Request.Context(), like); err != nil {
+	if err := lh.service.Like(c.Request.Context(), like, req.ShareToken); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
@@ -2,9 +2,8 @@ package video
 
 import (
 	"context"
-	"errors"
+	"feedsystem_video_go/internal/social"
 
-	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 )
 
@@ -26,31 +25,6 @@ func (r *LikeRepository) Unlike(ctx context.Context, like *Like) error {
 		Delete(&Like{}).Error
 }
 
-func (r *LikeRepository) LikeIgnoreDuplicate(ctx context.Context, like *Like) (created bool, err error) {
-	if like == nil || like.VideoID == 0 || like.AccountID == 0 {
-		return false, nil
-	}
-	err = r.db.WithContext(ctx).Create(like).Error
-	if err == nil {
-		return true, nil
-	}
-	var mysqlErr *mysql.MySQLError
-	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-		return false, nil
-	}
-	return false, err
-}
-
-func (r *LikeRepository) DeleteByVideoAndAccount(ctx context.Context, videoID, accountID uint) (deleted bool, err error) {
-	if videoID == 0 || accountID == 0 {
-		return false, nil
-	}
-	res := r.db.WithContext(ctx).
-		Where("video_id = ? AND account_id = ?", videoID, accountID).
-		Delete(&Like{})
-	return res.RowsAffected > 0, res.Error
-}
-
 func (r *LikeRepository) IsLiked(ctx context.Context, videoID, accountID uint) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&Like{}).
@@ -88,10 +62,15 @@ func (r *LikeRepository) ListLikedVideos(ctx context.Context, accountID uint) ([
 	if accountID == 0 {
 		return videos, nil
 	}
+	// 和 feed 一样只列已就绪且当前还看得到的视频：自己的、公开的、已关注作者的粉丝可见视频
+	following := r.db.Model(&social.Social{}).Select("1").
+		Where("follower_id = ? AND vlogger_id = videos.author_id", accountID)
 	err := r.db.WithContext(ctx).
 		Model(&Video{}).
 		Joins("JOIN likes ON likes.video_id = videos.id").
-		Where("likes.account_id = ?", accountID).
+		Where("likes.account_id = ? AND videos.status = ?", accountID, VideoStatusReady).
+		Where("videos.author_id = ? OR videos.visibility = ? OR (videos.visibility = ? AND EXISTS (?))",
+			accountID, VisibilityPublic, VisibilityFollowers, following).
 		Order("likes.created_at desc").
 		Limit(200).
 		Find(&videos).Error
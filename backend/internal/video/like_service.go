@@ -5,6 +5,8 @@ import (
 	"errors"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/social"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -12,15 +14,14 @@ import (
 )
 
 type LikeService struct {
-	repo         *LikeRepository
-	VideoRepo    *VideoRepository
-	cache        *rediscache.Client
-	likeMQ       *rabbitmq.LikeMQ
-	popularityMQ *rabbitmq.PopularityMQ
+	repo       *LikeRepository
+	VideoRepo  *VideoRepository
+	socialRepo *social.SocialRepository
+	cache      *rediscache.Client
 }
 
-func NewLikeService(repo *LikeRepository, videoRepo *VideoRepository, cache *rediscache.Client, likeMQ *rabbitmq.LikeMQ, popularityMQ *rabbitmq.PopularityMQ) *LikeService {
-	return &LikeService{repo: repo, VideoRepo: videoRepo, cache: cache, likeMQ: likeMQ, popularityMQ: popularityMQ}
+func NewLikeService(repo *LikeRepository, videoRepo *VideoRepository, socialRepo *social.SocialRepository, cache *rediscache.Client) *LikeService {
+	return &LikeService{repo: repo, VideoRepo: videoRepo, socialRepo: socialRepo, cache: cache}
 }
 
 func isDupKey(err error) bool {
@@ -28,7 +29,9 @@ func isDupKey(err error) bool {
 	return errors.As(err, &me) && me.Number == 1062
 }
 
-func (s *LikeService) Like(ctx context.Context, like *Like) error {
+// Like 点赞和计数在同一事务里落库，同时写入 outbox：like worker 记创作者指标和通知，
+// popularity worker 更新热榜。只能给已就绪且自己看得到的视频点赞，看不到时与视频不存在一样报错
+func (s *LikeService) Like(ctx context.Context, like *Like, shareToken string) error {
 	if like == nil {
 		return errors.New("like is nil")
 	}
@@ -36,73 +39,38 @@ func (s *LikeService) Like(ctx context.Context, like *Like) error {
 		return errors.New("video_id and account_id are required")
 	}
 
-	if s.VideoRepo != nil {
-		ok, err := s.VideoRepo.IsExist(ctx, like.VideoID)
+	like.CreatedAt = time.Now()
+	return s.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var video Video
+		if err := tx.Select("id", "author_id", "status", "visibility", "share_token").First(&video, like.VideoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("video not found")
+			}
+			return err
+		}
+		visible, err := canInteract(ctx, s.socialRepo, &video, like.AccountID, shareToken)
 		if err != nil {
 			return err
 		}
-		if !ok {
+		if !visible {
 			return errors.New("video not found")
 		}
-	}
-
-	isLiked, err := s.repo.IsLiked(ctx, like.VideoID, like.AccountID)
-	if err != nil {
-		return err
-	}
-	if isLiked {
-		return errors.New("user has liked this video")
-	}
-
-	like.CreatedAt = time.Now()
-	mysqlEnqueued := false
-	redisEnqueued := false
-	if s.likeMQ != nil {
-		if err := s.likeMQ.Like(ctx, like.AccountID, like.VideoID); err == nil {
-			mysqlEnqueued = true
+		if err := tx.Create(like).Error; err != nil {
+			if isDupKey(err) {
+				return errors.New("user has liked this video")
+			}
+			return err
 		}
-	}
-	if s.popularityMQ != nil {
-		if err := s.popularityMQ.Update(ctx, like.VideoID, 1); err == nil {
-			redisEnqueued = true
+		if err := tx.Model(&Video{}).Where("id = ?", like.VideoID).
+			UpdateColumn("likes_count", gorm.Expr("likes_count + 1")).Error; err != nil {
+			return err
 		}
-	}
-	if mysqlEnqueued && redisEnqueued {
-		return nil
-	}
-
-	// Fallback: direct MySQL write when like MQ publish fails.
-	if !mysqlEnqueued {
-		err := s.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			if err := tx.Select("id").First(&Video{}, like.VideoID).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.New("video not found")
-				}
-				return err
-			}
-			if err := tx.Create(like).Error; err != nil {
-				if isDupKey(err) {
-					return errors.New("user has liked this video")
-				}
-				return err
-			}
-			if err := tx.Model(&Video{}).Where("id = ?", like.VideoID).
-				UpdateColumn("likes_count", gorm.Expr("likes_count + 1")).Error; err != nil {
-				return err
-			}
-			return tx.Model(&Video{}).Where("id = ?", like.VideoID).
-				UpdateColumn("popularity", gorm.Expr("popularity + 1")).Error
-		})
-		if err != nil {
+		if err := tx.Model(&Video{}).Where("id = ?", like.VideoID).
+			UpdateColumn("popularity", gorm.Expr("popularity + 1")).Error; err != nil {
 			return err
 		}
-	}
-
-	// Fallback: direct Redis update when popularity MQ publish fails.
-	if !redisEnqueued {
-		UpdatePopularityCache(ctx, s.cache, like.VideoID, 1)
-	}
-	return nil
+		return enqueueLikeEvents(tx, "like", like, 1)
+	})
 }
 
 func (s *LikeService) Unlike(ctx context.Context, like *Like) error {
@@ -113,68 +81,42 @@ func (s *LikeService) Unlike(ctx context.Context, like *Like) error {
 		return errors.New("video_id and account_id are required")
 	}
 
-	if s.VideoRepo != nil {
-		ok, err := s.VideoRepo.IsExist(ctx, like.VideoID)
-		if err != nil {
+	return s.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&Video{}, like.VideoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("video not found")
+			}
 			return err
 		}
-		if !ok {
-			return errors.New("video not found")
+		del := tx.Where("video_id = ? AND account_id = ?", like.VideoID, like.AccountID).Delete(&Like{})
+		if del.Error != nil {
+			return del.Error
 		}
-	}
-
-	isLiked, err := s.repo.IsLiked(ctx, like.VideoID, like.AccountID)
-	if err != nil {
-		return err
-	}
-	if !isLiked {
-		return errors.New("user has not liked this video")
-	}
-
-	mysqlEnqueued := false
-	redisEnqueued := false
-	if s.likeMQ != nil {
-		if err := s.likeMQ.Unlike(ctx, like.AccountID, like.VideoID); err == nil {
-			mysqlEnqueued = true
+		if del.RowsAffected == 0 {
+			return errors.New("user has not liked this video")
 		}
-	}
-	if s.popularityMQ != nil {
-		if err := s.popularityMQ.Update(ctx, like.VideoID, -1); err == nil {
-			redisEnqueued = true
+		if err := tx.Model(&Video{}).Where("id = ?", like.VideoID).
+			UpdateColumn("likes_count", gorm.Expr("GREATEST(likes_count - 1, 0)")).Error; err != nil {
+			return err
 		}
-	}
-	if mysqlEnqueued && redisEnqueued {
-		return nil
-	}
-
-	// Fallback: direct MySQL write when like MQ publish fails.
-	if !mysqlEnqueued {
-		err := s.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			del := tx.Where("video_id = ? AND account_id = ?", like.VideoID, like.AccountID).Delete(&Like{})
-			if del.Error != nil {
-				return del.Error
-			}
-			if del.RowsAffected == 0 {
-				return errors.New("user has not liked this video")
-			}
-
-			if err := tx.Model(&Video{}).Where("id = ?", like.VideoID).
-				UpdateColumn("likes_count", gorm.Expr("GREATEST(likes_count - 1, 0)")).Error; err != nil {
-				return err
-			}
-			return tx.Model(&Video{}).Where("id = ?", like.VideoID).
-				UpdateColumn("popularity", gorm.Expr("GREATEST(popularity - 1, 0)")).Error
-		})
-		if err != nil {
+		if err := tx.Model(&Video{}).Where("id = ?", like.VideoID).
+			UpdateColumn("popularity", gorm.Expr("GREATEST(popularity - 1, 0)")).Error; err != nil {
 			return err
 		}
-	}
+		return enqueueLikeEvents(tx, "unlike", like, -1)
+	})
+}
 
-	// Fallback: direct Redis update when popularity MQ publish fails.
-	if !redisEnqueued {
-		UpdatePopularityCache(ctx, s.cache, like.VideoID, -1)
+func enqueueLikeEvents(tx *gorm.DB, action string, like *Like, change int64) error {
+	likeMsg, err := rabbitmq.LikeMessage(action, like.AccountID, like.VideoID)
+	if err != nil {
+		return err
+	}
+	popularityMsg, err := rabbitmq.PopularityMessage(like.VideoID, change)
+	if err != nil {
+		return err
 	}
-	return nil
+	return outbox.Enqueue(tx, likeMsg, popularityMsg)
 }
 
 func (s *LikeService) IsLiked(ctx context.Context, videoID, accountID uint) (bool, error) {
@@ -0,0 +1,74 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/social"
+)
+
+func TestLikeRespectsVisibilityAndStatus(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &Like{}, &social.Social{}, &outbox.Event{})
+	ctx := context.Background()
+	const viewer = 9
+	videos := []*Video{
+		{ID: 1, AuthorID: 1, Status: VideoStatusReady, Visibility: VisibilityPublic},
+		{ID: 2, AuthorID: 1, Status: VideoStatusReady, Visibility: VisibilityPrivate},
+		{ID: 3, AuthorID: 2, Status: VideoStatusReady, Visibility: VisibilityFollowers},
+		{ID: 4, AuthorID: 1, Status: VideoStatusReady, Visibility: VisibilityFollowers},
+		{ID: 5, AuthorID: 1, Status: VideoStatusPending, Visibility: VisibilityPublic},
+		{ID: 6, AuthorID: 1, Status: VideoStatusReady, Visibility: VisibilityUnlisted, ShareToken: "secret"},
+		{ID: 7, AuthorID: viewer, Status: VideoStatusReady, Visibility: VisibilityPrivate},
+	}
+	for _, v := range videos {
+		v.Title, v.Username, v.PlayURL, v.CoverURL = "t", "u", "p", "c"
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// viewer 只关注了作者 2
+	if err := db.Create(&social.Social{FollowerID: viewer, VloggerID: 2}).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewLikeService(NewLikeRepository(db), NewVideoRepository(db), social.NewSocialRepository(db), nil)
+
+	for id, token := range map[uint]string{1: "", 3: "", 6: "secret", 7: ""} {
+		if err := svc.Like(ctx, &Like{VideoID: id, AccountID: viewer}, token); err != nil {
+			t.Fatalf("like visible video %d: %v", id, err)
+		}
+	}
+	for id, token := range map[uint]string{2: "", 4: "", 5: "", 6: "wrong"} {
+		if err := svc.Like(ctx, &Like{VideoID: id, AccountID: 8}, token); err == nil {
+			t.Fatalf("expected like of hidden video %d to fail", id)
+		}
+	}
+
+	// 点赞后视频变为私密，已点赞列表里不再出现
+	for _, id := range []uint{2, 5} {
+		if err := db.Create(&Like{VideoID: id, AccountID: viewer}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Model(&Video{}).Where("id = ?", 1).Update("visibility", VisibilityPrivate).Error; err != nil {
+		t.Fatal(err)
+	}
+	liked, err := svc.ListLikedVideos(ctx, viewer)
+	if err != nil {
+		t.Fatalf("list liked: %v", err)
+	}
+	got := map[uint]bool{}
+	for _, v := range liked {
+		got[v.ID] = true
+	}
+	if len(got) != 2 || !got[3] || !got[7] {
+		t.Fatalf("liked videos = %v, want 3 and 7", got)
+	}
+	b, _ := json.Marshal(videos[5])
+	if strings.Contains(string(b), "secret") {
+		t.Fatalf("share token leaked in %s", b)
+	}
+}
@@ -0,0 +1,57 @@
+package video
+
+import "time"
+
+const (
+	// 开始播放，计一次播放
+	PlayEventStart = "start"
+	// 离开视频时上报看到的位置（progress 为 0~1），计一次结束
+	PlayEventProgress = "progress"
+	// 播放到结尾，progress 视为 1，计一次结束和一次完播
+	PlayEventComplete = "complete"
+
+	MaxPlayEventBatch = 50
+)
+
+// VideoPlayStat 播放统计，由 PlayWorker 周期性累加；平均观看比例 = WatchProgressSum / EndedCount
+type VideoPlayStat struct {
+	VideoID          uint      `gorm:"primaryKey;autoIncrement:false" json:"video_id"`
+	PlayCount        int64     `gorm:"not null;default:0" json:"play_count"`
+	CompleteCount    int64     `gorm:"not null;default:0" json:"complete_count"`
+	EndedCount       int64     `gorm:"not null;default:0" json:"ended_count"`
+	WatchProgressSum float64   `gorm:"not null;default:0" json:"-"`
+	UniqueViewers    int64     `gorm:"not null;default:0" json:"unique_viewers"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// AvgWatchPercent 平均观看比例（0~100）
+func (s *VideoPlayStat) AvgWatchPercent() float64 {
+	if s == nil || s.EndedCount == 0 {
+		return 0
+	}
+	return s.WatchProgressSum / float64(s.EndedCount) * 100
+}
+
+type PlayEventRequest struct {
+	EventID  string  `json:"event_id"` // 客户端生成，用于幂等去重
+	VideoID  uint    `json:"video_id"`
+	Type     string  `json:"type"`
+	Progress float64 `json:"progress"`
+}
+
+type ReportPlayRequest struct {
+	Events []PlayEventRequest `json:"events"`
+}
+
+type ReportPlayResponse struct {
+	Accepted   int `json:"accepted"`
+	Duplicated int `json:"duplicated"`
+}
+
+// PlayDelta 一个刷新周期内单个视频的增量
+type PlayDelta struct {
+	Plays       int64
+	Completes   int64
+	Ended       int64
+	ProgressSum float64
+}
@@ -0,0 +1,37 @@
+package video
+
+import (
+	"fmt"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PlayHandler struct {
+	service *PlayService
+}
+
+func NewPlayHandler(service *PlayService) *PlayHandler {
+	return &PlayHandler{service: service}
+}
+
+// ReportPlay 批量上报播放事件；登录用户按账号计独立观众，匿名按 IP
+func (h *PlayHandler) ReportPlay(c *gin.Context) {
+	var req ReportPlayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	viewer := "ip:" + c.ClientIP()
+	if accountID, err := jwt.GetAccountID(c); err == nil && accountID != 0 {
+		viewer = fmt.Sprintf("u:%d", accountID)
+	}
+	resp, err := h.service.Report(c.Request.Context(), viewer, req.Events)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, resp)
+}
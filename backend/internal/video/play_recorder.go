@@ -0,0 +1,149 @@
+package video
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+const (
+	// 一次播放计入热榜窗口的分数，点赞为 1
+	PlayPopularityWeight = 0.1
+	// 同一观众对同一视频在这段时间内只计一次热度，与热榜的 60 分钟窗口一致
+	playPopularityWindow = time.Hour
+	// 每个视频独立观众的 HyperLogLog 保留时间，过期后从 0 重新估计（MySQL 里取 GREATEST 不回退）
+	playViewersTTL = 30 * 24 * time.Hour
+)
+
+// PlayAggregator 在内存里合并播放事件，按视频累计增量和观众
+type PlayAggregator struct {
+	deltas  map[uint]*PlayDelta
+	viewers map[uint]map[string]struct{}
+}
+
+func NewPlayAggregator() *PlayAggregator {
+	return &PlayAggregator{deltas: make(map[uint]*PlayDelta), viewers: make(map[uint]map[string]struct{})}
+}
+
+func (a *PlayAggregator) Add(videoID uint, viewer, typ string, progress float64) {
+	if videoID == 0 {
+		return
+	}
+	d := a.deltas[videoID]
+	if d == nil {
+		d = &PlayDelta{}
+		a.deltas[videoID] = d
+	}
+	switch typ {
+	case PlayEventStart:
+		d.Plays++
+		if viewer != "" {
+			if a.viewers[videoID] == nil {
+				a.viewers[videoID] = make(map[string]struct{})
+			}
+			a.viewers[videoID][viewer] = struct{}{}
+		}
+	case PlayEventProgress:
+		d.Ended++
+		d.ProgressSum += min(max(progress, 0), 1)
+	case PlayEventComplete:
+		d.Ended++
+		d.Completes++
+		d.ProgressSum++
+	}
+}
+
+func (a *PlayAggregator) Len() int {
+	return len(a.deltas)
+}
+
+func (a *PlayAggregator) Deltas() map[uint]*PlayDelta {
+	return a.deltas
+}
+
+// PlayRecorder 把聚合结果写入 HyperLogLog、MySQL 和热榜窗口
+type PlayRecorder struct {
+	repo  *PlayRepository
+	cache *rediscache.Client
+}
+
+func NewPlayRecorder(repo *PlayRepository, cache *rediscache.Client) *PlayRecorder {
+	return &PlayRecorder{repo: repo, cache: cache}
+}
+
+func (r *PlayRecorder) viewersKey(videoID uint) string {
+	return r.cache.Key("video:viewers:%d", videoID)
+}
+
+// Record 先写 HLL 拿到最新基数，再在一个事务里落库，最后累加热榜；落库失败时调用方保留聚合结果重试
+func (r *PlayRecorder) Record(ctx context.Context, agg *PlayAggregator) error {
+	if agg == nil || agg.Len() == 0 {
+		return nil
+	}
+	uniques := make(map[uint]int64)
+	if r.cache != nil && len(agg.viewers) > 0 {
+		ids := make([]uint, 0, len(agg.viewers))
+		members := make(map[string][]string, len(agg.viewers))
+		for id, set := range agg.viewers {
+			ids = append(ids, id)
+			vals := make([]string, 0, len(set))
+			for v := range set {
+				vals = append(vals, v)
+			}
+			members[r.viewersKey(id)] = vals
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if err := r.cache.PFAddMulti(ctx, members, playViewersTTL); err != nil {
+			return err
+		}
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = r.viewersKey(id)
+		}
+		counts, err := r.cache.PFCountEach(ctx, keys)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids {
+			uniques[id] = counts[i]
+		}
+	}
+
+	if err := r.repo.ApplyDeltas(ctx, agg.deltas, uniques); err != nil {
+		return err
+	}
+	r.addPopularity(ctx, agg)
+	return nil
+}
+
+// addPopularity 每个观众在窗口内只给同一视频加一次热度，重复开播只计播放数
+func (r *PlayRecorder) addPopularity(ctx context.Context, agg *PlayAggregator) {
+	if r.cache == nil {
+		return
+	}
+	for id, set := range agg.viewers {
+		keys := make([]string, 0, len(set))
+		for v := range set {
+			keys = append(keys, r.cache.Key("play:popularity:%d:%s", id, v))
+		}
+		fresh, err := r.cache.SetNXMulti(ctx, keys, playPopularityWindow)
+		if err != nil {
+			log.Printf("play popularity dedup unavailable: %v", err)
+			continue
+		}
+		n := 0
+		for _, ok := range fresh {
+			if ok {
+				n++
+			}
+		}
+		if n > 0 {
+			if err := AddPopularityScore(ctx, r.cache, id, float64(n)*PlayPopularityWeight); err != nil {
+				log.Printf("play popularity of video %d lost: %v", id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/dbtest"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestPlayAggregatorMergesEvents(t *testing.T) {
+	agg := NewPlayAggregator()
+	agg.Add(1, "u:1", PlayEventStart, 0)
+	agg.Add(1, "u:1", PlayEventStart, 0)
+	agg.Add(1, "ip:10.0.0.1", PlayEventStart, 0)
+	agg.Add(1, "u:1", PlayEventProgress, 0.5)
+	agg.Add(1, "u:1", PlayEventProgress, 3) // 越界按 1 计
+	agg.Add(1, "ip:10.0.0.1", PlayEventComplete, 0)
+	agg.Add(2, "u:2", PlayEventProgress, 0.25)
+	agg.Add(0, "u:3", PlayEventStart, 0)
+
+	if agg.Len() != 2 {
+		t.Fatalf("expected 2 videos, got %d", agg.Len())
+	}
+	d := agg.Deltas()[1]
+	if d.Plays != 3 || d.Ended != 3 || d.Completes != 1 || d.ProgressSum != 2.5 {
+		t.Fatalf("unexpected delta %+v", d)
+	}
+	if len(agg.viewers[1]) != 2 {
+		t.Fatalf("expected 2 distinct viewers, got %d", len(agg.viewers[1]))
+	}
+	if d := agg.Deltas()[2]; d.Plays != 0 || d.Ended != 1 || d.ProgressSum != 0.25 {
+		t.Fatalf("unexpected delta %+v", d)
+	}
+
+	stat := &VideoPlayStat{EndedCount: 4, WatchProgressSum: 3}
+	if stat.AvgWatchPercent() != 75 {
+		t.Fatalf("expected 75%%, got %v", stat.AvgWatchPercent())
+	}
+}
+
+func TestValidatePlayEvent(t *testing.T) {
+	ok := PlayEventRequest{EventID: "e1", VideoID: 1, Type: PlayEventProgress, Progress: 0.3}
+	if err := validatePlayEvent(ok); err != nil {
+		t.Fatalf("expected valid event, got %v", err)
+	}
+	bad := []PlayEventRequest{
+		{VideoID: 1, Type: PlayEventStart},
+		{EventID: "e1", Type: PlayEventStart},
+		{EventID: "e1", VideoID: 1, Type: "pause"},
+		{EventID: "e1", VideoID: 1, Type: PlayEventProgress, Progress: 1.5},
+	}
+	for _, e := range bad {
+		if err := validatePlayEvent(e); !errors.Is(err, apierror.ErrValidation) {
+			t.Fatalf("expected validation error for %+v, got %v", e, err)
+		}
+	}
+}
+
+func TestPlayRecorderCountsPopularityOncePerViewer(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+	recorder := NewPlayRecorder(NewPlayRepository(dbtest.Open(t, &Video{})), cache)
+
+	for _, viewers := range [][]string{{"u:1", "u:1"}, {"u:1"}, {"u:2"}} {
+		agg := NewPlayAggregator()
+		for _, v := range viewers {
+			agg.Add(9, v, PlayEventStart, 0)
+		}
+		if err := recorder.Record(ctx, agg); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	windowKey := cache.Key("hot:video:1m:%s", time.Now().UTC().Truncate(time.Minute).Format("200601021504"))
+	score, _, err := cache.ZScore(ctx, windowKey, "9")
+	if err != nil {
+		t.Fatalf("zscore: %v", err)
+	}
+	// u:1 开播三次只计一次，u:2 再计一次
+	if want := 2 * PlayPopularityWeight; score < want-1e-9 || score > want+1e-9 {
+		t.Fatalf("popularity = %v, want %v", score, want)
+	}
+}
+
+func TestPlayReportReleasesDedupWhenRecordFails(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	db := dbtest.Open(t, &Video{})
+	sqlDB, _ := db.DB()
+	_ = sqlDB.Close()
+	svc := NewPlayService(NewPlayRecorder(NewPlayRepository(db), cache), cache, nil)
+
+	events := []PlayEventRequest{{EventID: "e1", VideoID: 1, Type: PlayEventStart}}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Report(context.Background(), "u:1", events); err == nil {
+			t.Fatalf("attempt %d: expected record error", i)
+		}
+		if mr.Exists(cache.Key("play:event:%s:%s", "u:1", "e1")) {
+			t.Fatalf("attempt %d: dedup key should be released after failure", i)
+		}
+	}
+}
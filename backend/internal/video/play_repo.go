@@ -0,0 +1,68 @@
+package video
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PlayRepository struct {
+	db *gorm.DB
+}
+
+func NewPlayRepository(db *gorm.DB) *PlayRepository {
+	return &PlayRepository{db: db}
+}
+
+// ApplyDeltas 在一个事务里累加 videos.play_count 与 video_play_stats；
+// uniques 为 HyperLogLog 的最新基数，直接覆盖。已删除的视频直接跳过
+func (r *PlayRepository) ApplyDeltas(ctx context.Context, deltas map[uint]*PlayDelta, uniques map[uint]int64) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&Video{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		for _, id := range existing {
+			d := deltas[id]
+			if d.Plays > 0 {
+				if err := tx.Model(&Video{}).Where("id = ?", id).
+					UpdateColumn("play_count", gorm.Expr("play_count + ?", d.Plays)).Error; err != nil {
+					return err
+				}
+			}
+			stat := VideoPlayStat{
+				VideoID:          id,
+				PlayCount:        d.Plays,
+				CompleteCount:    d.Completes,
+				EndedCount:       d.Ended,
+				WatchProgressSum: d.ProgressSum,
+				UniqueViewers:    uniques[id],
+			}
+			updates := map[string]interface{}{
+				"play_count":         gorm.Expr("play_count + ?", d.Plays),
+				"complete_count":     gorm.Expr("complete_count + ?", d.Completes),
+				"ended_count":        gorm.Expr("ended_count + ?", d.Ended),
+				"watch_progress_sum": gorm.Expr("watch_progress_sum + ?", d.ProgressSum),
+				"updated_at":         gorm.Expr("NOW()"),
+			}
+			if u, ok := uniques[id]; ok {
+				updates["unique_viewers"] = gorm.Expr("GREATEST(unique_viewers, ?)", u)
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "video_id"}},
+				DoUpdates: clause.Assignments(updates),
+			}).Create(&stat).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
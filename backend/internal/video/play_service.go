@@ -0,0 +1,118 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+// 客户端事件 ID 的去重窗口，超过后重复上报会被再次计数
+const playEventDedupTTL = 24 * time.Hour
+
+type PlayService struct {
+	recorder *PlayRecorder
+	cache    *rediscache.Client
+	playMQ   *rabbitmq.PlayMQ
+}
+
+func NewPlayService(recorder *PlayRecorder, cache *rediscache.Client, playMQ *rabbitmq.PlayMQ) *PlayService {
+	return &PlayService{recorder: recorder, cache: cache, playMQ: playMQ}
+}
+
+func validatePlayEvent(e PlayEventRequest) error {
+	if e.EventID == "" || len(e.EventID) > 64 {
+		return fmt.Errorf("%w: event_id is required and must be at most 64 chars", apierror.ErrValidation)
+	}
+	if e.VideoID == 0 {
+		return fmt.Errorf("%w: video_id is required", apierror.ErrValidation)
+	}
+	switch e.Type {
+	case PlayEventStart, PlayEventProgress, PlayEventComplete:
+	default:
+		return fmt.Errorf("%w: type must be start, progress or complete", apierror.ErrValidation)
+	}
+	if e.Progress < 0 || e.Progress > 1 {
+		return fmt.Errorf("%w: progress must be between 0 and 1", apierror.ErrValidation)
+	}
+	return nil
+}
+
+// Report 校验并按客户端事件 ID 去重后整批投递到 MQ；MQ 不可用时直接聚合落库
+func (s *PlayService) Report(ctx context.Context, viewer string, events []PlayEventRequest) (ReportPlayResponse, error) {
+	if len(events) == 0 || len(events) > MaxPlayEventBatch {
+		return ReportPlayResponse{}, fmt.Errorf("%w: events must contain 1-%d items", apierror.ErrValidation, MaxPlayEventBatch)
+	}
+	for _, e := range events {
+		if err := validatePlayEvent(e); err != nil {
+			return ReportPlayResponse{}, err
+		}
+	}
+
+	var resp ReportPlayResponse
+	now := time.Now().UTC()
+	accepted := make([]rabbitmq.PlayEvent, 0, len(events))
+	// claimed 记录本次写入的去重 key，投递和落库都失败时释放，客户端重试不会被当成重复
+	var claimed []string
+	for _, e := range events {
+		if s.cache != nil {
+			key := s.cache.Key("play:event:%s:%s", viewer, e.EventID)
+			fresh, err := s.cache.SetNX(ctx, key, playEventDedupTTL)
+			switch {
+			case err != nil:
+				log.Printf("play event dedup unavailable: %v", err)
+			case !fresh:
+				resp.Duplicated++
+				continue
+			default:
+				claimed = append(claimed, key)
+			}
+		}
+		accepted = append(accepted, rabbitmq.PlayEvent{
+			EventID:    e.EventID,
+			VideoID:    e.VideoID,
+			Viewer:     viewer,
+			Type:       e.Type,
+			Progress:   e.Progress,
+			OccurredAt: now,
+		})
+	}
+	resp.Accepted = len(accepted)
+	if len(accepted) == 0 {
+		return resp, nil
+	}
+
+	if s.playMQ != nil {
+		if err := s.playMQ.Report(ctx, accepted); err == nil {
+			return resp, nil
+		}
+	}
+
+	// Fallback: MQ 不可用时同步落库
+	agg := NewPlayAggregator()
+	for _, e := range accepted {
+		agg.Add(e.VideoID, e.Viewer, e.Type, e.Progress)
+	}
+	if err := s.recorder.Record(ctx, agg); err != nil {
+		s.releaseDedup(claimed)
+		return ReportPlayResponse{}, err
+	}
+	return resp, nil
+}
+
+func (s *PlayService) releaseDedup(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	for _, key := range keys {
+		if err := s.cache.Del(ctx, key); err != nil {
+			log.Printf("release play event dedup %s failed: %v", key, err)
+		}
+	}
+}
@@ -8,10 +8,16 @@ import (
 	rediscache "feedsystem_video_go/internal/middleware/redis"
 )
 
-// 更新视频流行度缓存
-func UpdatePopularityCache(ctx context.Context, cache *rediscache.Client, id uint, change int64) {
-	if cache == nil || id == 0 || change == 0 {
-		return
+// UpdatePopularityCache 更新视频流行度缓存，写热榜窗口失败时返回错误，消费者据此重试
+func UpdatePopularityCache(ctx context.Context, cache *rediscache.Client, id uint, change int64) error {
+	return AddPopularityScore(ctx, cache, id, float64(change))
+}
+
+// AddPopularityScore 累加到当前分钟的热榜窗口，并同步给视频的话题加分；播放等低权重事件按小数计分。
+// 只有热榜窗口的写入失败才返回错误；话题加分尽力而为，失败重试会让视频热度重复累加
+func AddPopularityScore(ctx context.Context, cache *rediscache.Client, id uint, score float64) error {
+	if cache == nil || id == 0 || score == 0 {
+		return nil
 	}
 
 	_ = cache.Del(context.Background(), cache.Key("video:detail:id=%d", id))
@@ -23,6 +29,10 @@ func UpdatePopularityCache(ctx context.Context, cache *rediscache.Client, id uin
 	opCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
 	defer cancel()
 
-	_ = cache.ZincrBy(opCtx, windowKey, member, float64(change))
+	if err := cache.ZincrBy(opCtx, windowKey, member, score); err != nil {
+		return err
+	}
 	_ = cache.Expire(opCtx, windowKey, 2*time.Hour)
+	addTagScore(opCtx, cache, id, score, now)
+	return nil
 }
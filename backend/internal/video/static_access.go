@@ -0,0 +1,85 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"feedsystem_video_go/internal/social"
+	"feedsystem_video_go/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// StaticAccess 实现 storage.Authorizer：/static 下的源文件、封面和 HLS 分片按引用它们的视频放行
+type StaticAccess struct {
+	repo       *VideoRepository
+	socialRepo *social.SocialRepository
+	store      storage.Store
+}
+
+func NewStaticAccess(repo *VideoRepository, socialRepo *social.SocialRepository, store storage.Store) *StaticAccess {
+	return &StaticAccess{repo: repo, socialRepo: socialRepo, store: store}
+}
+
+// CanServe 任一引用该文件的视频对观看者可见即可：视频须已就绪（作者本人除外），再按可见范围判断。
+// hls/<id>/ 只属于视频 id；videos/<uid>/、covers/<uid>/ 只看作者 uid 名下的视频，
+// 还没被任何视频引用的文件（上传后尚未发布）只给上传者本人
+func (a *StaticAccess) CanServe(ctx context.Context, key string, viewerAccountID uint, shareToken string) (bool, error) {
+	dir, id, ok := keyOwner(key)
+	if !ok {
+		return false, nil
+	}
+	var videos []Video
+	if dir == "hls" {
+		v, err := a.repo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		videos = []Video{*v}
+	} else {
+		var err error
+		if videos, err = a.repo.ListReferencingKey(ctx, a.store, key, id); err != nil {
+			return false, err
+		}
+		if len(videos) == 0 {
+			return viewerAccountID != 0 && viewerAccountID == id, nil
+		}
+	}
+	for i := range videos {
+		v := &videos[i]
+		if v.Status != VideoStatusReady && (viewerAccountID == 0 || viewerAccountID != v.AuthorID) {
+			continue
+		}
+		visible, err := canSee(ctx, a.socialRepo, v, viewerAccountID, shareToken)
+		if err != nil {
+			return false, err
+		}
+		if visible {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keyOwner 解析 videos/<作者>/...、covers/<作者>/...、hls/<视频>/... 的第二段
+func keyOwner(key string) (dir string, id uint, ok bool) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 3 || parts[2] == "" {
+		return "", 0, false
+	}
+	switch parts[0] {
+	case "videos", "covers", "hls":
+	default:
+		return "", 0, false
+	}
+	n, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || n == 0 {
+		return "", 0, false
+	}
+	return parts[0], uint(n), true
+}
@@ -0,0 +1,72 @@
+package video
+
+import (
+	"context"
+	"testing"
+
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/social"
+	"feedsystem_video_go/internal/storage"
+)
+
+func TestStaticAccessFollowsVideoVisibility(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &social.Social{})
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	videos := []*Video{
+		{ID: 1, AuthorID: 5, Status: VideoStatusReady, Visibility: VisibilityPublic,
+			PlayURL: store.URL("hls/1/master.m3u8"), CoverURL: store.URL("covers/5/a.jpg"), SourceURL: store.URL("videos/5/a.mp4")},
+		{ID: 2, AuthorID: 5, Status: VideoStatusReady, Visibility: VisibilityPrivate,
+			PlayURL: store.URL("hls/2/master.m3u8"), CoverURL: store.URL("covers/5/b.jpg"), SourceURL: store.URL("videos/5/b.mp4")},
+		{ID: 3, AuthorID: 5, Status: VideoStatusProcessing, Visibility: VisibilityPublic,
+			PlayURL: store.URL("videos/5/c.mp4"), CoverURL: "http://old-host/static/covers/5/c.jpg"},
+		{ID: 4, AuthorID: 5, Status: VideoStatusReady, Visibility: VisibilityUnlisted, ShareToken: "secret",
+			PlayURL: store.URL("hls/4/master.m3u8"), CoverURL: store.URL("covers/5/d.jpg")},
+	}
+	for _, v := range videos {
+		v.Title, v.Username = "t", "u"
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := NewStaticAccess(NewVideoRepository(db), social.NewSocialRepository(db), store)
+
+	cases := []struct {
+		key    string
+		viewer uint
+		token  string
+		want   bool
+	}{
+		{"hls/1/720p/seg0.ts", 0, "", true},
+		{"covers/5/a.jpg", 0, "", true},
+		{"videos/5/a.mp4", 0, "", true},
+		{"hls/2/master.m3u8", 0, "", false},
+		{"hls/2/master.m3u8", 7, "", false},
+		{"hls/2/master.m3u8", 5, "", true},
+		{"covers/5/b.jpg", 7, "", false},
+		// 转码中只有作者能看，旧版带域名的地址也能对上
+		{"videos/5/c.mp4", 0, "", false},
+		{"covers/5/c.jpg", 5, "", true},
+		{"hls/4/master.m3u8", 0, "", false},
+		{"hls/4/master.m3u8", 0, "secret", true},
+		// 还没被视频引用的上传只给上传者
+		{"covers/5/new.jpg", 0, "", false},
+		{"covers/5/new.jpg", 5, "", true},
+		// 别人名下目录里的同名文件不算这个视频的
+		{"covers/6/a.jpg", 0, "", false},
+		{"hls/9/master.m3u8", 0, "", false},
+		{"covers/x/a.jpg", 0, "", false},
+	}
+	for _, c := range cases {
+		got, err := a.CanServe(ctx, c.key, c.viewer, c.token)
+		if err != nil {
+			t.Fatalf("CanServe(%s): %v", c.key, err)
+		}
+		if got != c.want {
+			t.Errorf("CanServe(%s, viewer %d, token %q) = %v, want %v", c.key, c.viewer, c.token, got, c.want)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package video
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+)
 
 type Tag struct {
 	ID   uint   `gorm:"primaryKey" json:"id"`
@@ -13,6 +16,14 @@ type VideoTag struct {
 	TagID   uint `gorm:"index;not null"`
 }
 
+// TagFollow 用户关注的话题，关注流可以合并这些话题下的新视频
+type TagFollow struct {
+	ID        uint      `gorm:"primaryKey"`
+	AccountID uint      `gorm:"not null;uniqueIndex:idx_tag_follow_account_tag"`
+	TagID     uint      `gorm:"not null;index;uniqueIndex:idx_tag_follow_account_tag"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
 var tagRegex = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
 
 func ExtractTags(text string) []string {
@@ -28,3 +39,58 @@ func ExtractTags(text string) []string {
 	}
 	return tags
 }
+
+const (
+	defaultTrendingWindow = 60 // 分钟
+	maxTrendingWindow     = 120
+	defaultTrendingLimit  = 20
+	maxTrendingLimit      = 50
+
+	// 每个用户最多关注的话题数，关注流按话题回源时 IN 列表不会过长
+	MaxFollowedTags = 200
+)
+
+type TrendingTagsRequest struct {
+	Window int `json:"window"` // 统计最近多少分钟，默认 60，最大 120
+	Limit  int `json:"limit"`
+}
+
+type TrendingTag struct {
+	ID    uint    `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+type TrendingTagsResponse struct {
+	Tags   []TrendingTag `json:"tags"`
+	Window int           `json:"window"`
+	AsOf   int64         `json:"as_of"` // 统计截止的分钟（unix 秒）
+}
+
+type TagDetailRequest struct {
+	TagName string `json:"tag_name"`
+}
+
+// TagStats 只统计已发布的视频
+type TagStats struct {
+	VideoCount int64 `json:"video_count"`
+	PlayCount  int64 `json:"play_count"`
+	LikesCount int64 `json:"likes_count"`
+}
+
+type TagDetailResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	TagStats
+	FollowerCount int64   `json:"follower_count"`
+	IsFollowing   bool    `json:"is_following"`
+	TrendScore    float64 `json:"trend_score"` // 最近 60 分钟的热度
+}
+
+type TagFollowRequest struct {
+	TagName string `json:"tag_name"`
+}
+
+type ListFollowedTagsResponse struct {
+	Tags []Tag `json:"tags"`
+}
@@ -0,0 +1,121 @@
+package video
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+func (r *TagRepository) GetByName(ctx context.Context, name string) (*Tag, error) {
+	var tag Tag
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepository) GetByIDs(ctx context.Context, ids []uint) ([]Tag, error) {
+	var tags []Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) ListTagIDsByVideo(ctx context.Context, videoID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&VideoTag{}).
+		Where("video_id = ?", videoID).
+		Pluck("tag_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Stats 汇总话题下已发布公开视频的数量、播放和点赞
+func (r *TagRepository) Stats(ctx context.Context, tagID uint) (TagStats, error) {
+	var stats TagStats
+	err := r.db.WithContext(ctx).Model(&VideoTag{}).
+		Select("COUNT(*) AS video_count, COALESCE(SUM(videos.play_count), 0) AS play_count, COALESCE(SUM(videos.likes_count), 0) AS likes_count").
+		Joins("JOIN videos ON videos.id = video_tags.video_id").
+		Where("video_tags.tag_id = ? AND videos.status = ? AND videos.visibility = ? AND videos.deleted_at IS NULL", tagID, VideoStatusReady, VisibilityPublic).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// Follow 已关注时返回 false
+func (r *TagRepository) Follow(ctx context.Context, accountID, tagID uint) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&TagFollow{AccountID: accountID, TagID: tagID})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Unfollow 未关注时返回 false
+func (r *TagRepository) Unfollow(ctx context.Context, accountID, tagID uint) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("account_id = ? AND tag_id = ?", accountID, tagID).
+		Delete(&TagFollow{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *TagRepository) IsFollowing(ctx context.Context, accountID, tagID uint) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&TagFollow{}).
+		Where("account_id = ? AND tag_id = ?", accountID, tagID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *TagRepository) ListFollowedTagIDs(ctx context.Context, accountID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&TagFollow{}).
+		Where("account_id = ?", accountID).
+		Order("id DESC").
+		Pluck("tag_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListFollowedTags 按关注时间倒序
+func (r *TagRepository) ListFollowedTags(ctx context.Context, accountID uint) ([]Tag, error) {
+	var tags []Tag
+	if err := r.db.WithContext(ctx).Model(&Tag{}).
+		Joins("JOIN tag_follows ON tag_follows.tag_id = tags.id").
+		Where("tag_follows.account_id = ?", accountID).
+		Order("tag_follows.id DESC").
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) CountFollowers(ctx context.Context, tagID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&TagFollow{}).Where("tag_id = ?", tagID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *TagRepository) CountFollowedTags(ctx context.Context, accountID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&TagFollow{}).Where("account_id = ?", accountID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
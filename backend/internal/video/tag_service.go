@@ -0,0 +1,262 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+const (
+	followedTagsTTL         = 7 * 24 * time.Hour
+	followedTagsPlaceholder = "0"
+)
+
+func followedTagsKey(cache *rediscache.Client, accountID uint) string {
+	if cache == nil {
+		return ""
+	}
+	return cache.Key("tag:follow:%d", accountID)
+}
+
+type TagService struct {
+	repo  *TagRepository
+	cache *rediscache.Client
+	now   func() time.Time
+}
+
+func NewTagService(repo *TagRepository, cache *rediscache.Client) *TagService {
+	return &TagService{repo: repo, cache: cache, now: time.Now}
+}
+
+// Published 视频发布（outbox 的 video_published 投递成功）后调用：
+// 记下视频的话题集合供后续互动加分，并给每个话题加一次发布分。
+// 事件至少投递一次，发布分用 SETNX 标记保证每个视频只加一次
+func (s *TagService) Published(ctx context.Context, videoID uint) error {
+	if s == nil || s.cache == nil {
+		return nil
+	}
+	ids, err := s.repo.ListTagIDsByVideo(ctx, videoID)
+	if err != nil {
+		log.Printf("tag trending: list tags of video %d failed: %v", videoID, err)
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	members := make([]string, len(ids))
+	for i, id := range ids {
+		members[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	if err := s.cache.SAdd(ctx, videoTagsKey(s.cache, videoID), videoTagsTTL, members...); err != nil {
+		log.Printf("tag trending: cache tags of video %d failed: %v", videoID, err)
+		return err
+	}
+	guard := tagPublishedKey(s.cache, videoID)
+	first, err := s.cache.SetNX(ctx, guard, videoTagsTTL)
+	if err != nil {
+		return err
+	}
+	if !first {
+		return nil
+	}
+	if err := s.cache.ZIncrByMulti(ctx, tagWindowKey(s.cache, s.now()), members, TagPublishWeight, tagWindowTTL); err != nil {
+		// 没加上分，去掉标记让重试还能加
+		_ = s.cache.Del(ctx, guard)
+		return err
+	}
+	return nil
+}
+
+// mergedWindow 合并最近 window 个分钟桶，结果缓存一分钟，同一分钟内的请求复用
+func (s *TagService) mergedWindow(ctx context.Context, asOf time.Time, window int) string {
+	dest := s.cache.Key("hot:tag:merge:%dm:%s", window, asOf.Format("200601021504"))
+	if exists, _ := s.cache.Exists(ctx, dest); exists {
+		return dest
+	}
+	keys := make([]string, 0, window)
+	for i := 0; i < window; i++ {
+		keys = append(keys, tagWindowKey(s.cache, asOf.Add(-time.Duration(i)*time.Minute)))
+	}
+	_ = s.cache.ZUnionStore(ctx, dest, keys, "SUM")
+	_ = s.cache.Expire(ctx, dest, time.Minute)
+	return dest
+}
+
+// Trending 按滑动窗口内的热度返回话题排行；取消点赞等负分抵消后 <= 0 的话题不上榜
+func (s *TagService) Trending(ctx context.Context, req TrendingTagsRequest) (TrendingTagsResponse, error) {
+	window := req.Window
+	if window <= 0 {
+		window = defaultTrendingWindow
+	}
+	if window > maxTrendingWindow {
+		return TrendingTagsResponse{}, fmt.Errorf("%w: window must be at most %d minutes", apierror.ErrValidation, maxTrendingWindow)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultTrendingLimit
+	}
+	limit = min(limit, maxTrendingLimit)
+
+	asOf := s.now().UTC().Truncate(time.Minute)
+	resp := TrendingTagsResponse{Tags: []TrendingTag{}, Window: window, AsOf: asOf.Unix()}
+	if s.cache == nil {
+		return resp, nil
+	}
+	zs, err := s.cache.ZRevRangeByScoreWithScores(ctx, s.mergedWindow(ctx, asOf, window), "+inf", "(0", 0, int64(limit))
+	if err != nil {
+		return TrendingTagsResponse{}, err
+	}
+	ids := make([]uint, 0, len(zs))
+	scores := make(map[uint]float64, len(zs))
+	for _, z := range zs {
+		member, _ := z.Member.(string)
+		if id, err := strconv.ParseUint(member, 10, 64); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+			scores[uint(id)] = z.Score
+		}
+	}
+	tags, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return TrendingTagsResponse{}, err
+	}
+	names := make(map[uint]string, len(tags))
+	for _, t := range tags {
+		names[t.ID] = t.Name
+	}
+	for _, id := range ids {
+		name, ok := names[id]
+		if !ok {
+			continue
+		}
+		resp.Tags = append(resp.Tags, TrendingTag{ID: id, Name: name, Score: scores[id]})
+	}
+	return resp, nil
+}
+
+func (s *TagService) lookup(ctx context.Context, tagName string) (*Tag, error) {
+	name := strings.TrimPrefix(strings.TrimSpace(tagName), "#")
+	if name == "" {
+		return nil, fmt.Errorf("%w: tag_name is required", apierror.ErrValidation)
+	}
+	return s.repo.GetByName(ctx, name)
+}
+
+// Detail 话题页：视频数、总播放、总点赞、关注数和最近一小时热度；viewerAccountID 为 0 表示未登录
+func (s *TagService) Detail(ctx context.Context, tagName string, viewerAccountID uint) (TagDetailResponse, error) {
+	tag, err := s.lookup(ctx, tagName)
+	if err != nil {
+		return TagDetailResponse{}, err
+	}
+	stats, err := s.repo.Stats(ctx, tag.ID)
+	if err != nil {
+		return TagDetailResponse{}, err
+	}
+	followers, err := s.repo.CountFollowers(ctx, tag.ID)
+	if err != nil {
+		return TagDetailResponse{}, err
+	}
+	resp := TagDetailResponse{ID: tag.ID, Name: tag.Name, TagStats: stats, FollowerCount: followers}
+	if viewerAccountID != 0 {
+		if resp.IsFollowing, err = s.repo.IsFollowing(ctx, viewerAccountID, tag.ID); err != nil {
+			return TagDetailResponse{}, err
+		}
+	}
+	if s.cache != nil {
+		asOf := s.now().UTC().Truncate(time.Minute)
+		if score, ok, err := s.cache.ZScore(ctx, s.mergedWindow(ctx, asOf, defaultTrendingWindow), strconv.FormatUint(uint64(tag.ID), 10)); err == nil && ok {
+			resp.TrendScore = score
+		}
+	}
+	return resp, nil
+}
+
+// Follow 关注话题，重复关注视为成功
+func (s *TagService) Follow(ctx context.Context, accountID uint, tagName string) error {
+	tag, err := s.lookup(ctx, tagName)
+	if err != nil {
+		return err
+	}
+	if following, err := s.repo.IsFollowing(ctx, accountID, tag.ID); err != nil || following {
+		return err
+	}
+	count, err := s.repo.CountFollowedTags(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if count >= MaxFollowedTags {
+		return fmt.Errorf("%w: cannot follow more than %d tags", apierror.ErrValidation, MaxFollowedTags)
+	}
+	if _, err := s.repo.Follow(ctx, accountID, tag.ID); err != nil {
+		return err
+	}
+	s.invalidateFollowed(ctx, accountID)
+	return nil
+}
+
+// Unfollow 取消关注话题，未关注视为成功
+func (s *TagService) Unfollow(ctx context.Context, accountID uint, tagName string) error {
+	tag, err := s.lookup(ctx, tagName)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.Unfollow(ctx, accountID, tag.ID); err != nil {
+		return err
+	}
+	s.invalidateFollowed(ctx, accountID)
+	return nil
+}
+
+func (s *TagService) ListFollowed(ctx context.Context, accountID uint) (ListFollowedTagsResponse, error) {
+	tags, err := s.repo.ListFollowedTags(ctx, accountID)
+	if err != nil {
+		return ListFollowedTagsResponse{}, err
+	}
+	return ListFollowedTagsResponse{Tags: tags}, nil
+}
+
+// FollowedTagIDs 关注流合并话题视频时使用，优先读缓存；
+// 集合里固定放一个占位成员 "0"，没有关注任何话题时也能命中缓存
+func (s *TagService) FollowedTagIDs(ctx context.Context, accountID uint) ([]uint, error) {
+	key := followedTagsKey(s.cache, accountID)
+	if s.cache != nil {
+		if members, err := s.cache.SMembers(ctx, key); err == nil && len(members) > 0 {
+			ids := make([]uint, 0, len(members))
+			for _, m := range members {
+				if id, err := strconv.ParseUint(m, 10, 64); err == nil && id > 0 {
+					ids = append(ids, uint(id))
+				}
+			}
+			return ids, nil
+		}
+	}
+	ids, err := s.repo.ListFollowedTagIDs(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		members := make([]string, 0, len(ids)+1)
+		members = append(members, followedTagsPlaceholder)
+		for _, id := range ids {
+			members = append(members, strconv.FormatUint(uint64(id), 10))
+		}
+		if err := s.cache.SAdd(ctx, key, followedTagsTTL, members...); err != nil {
+			log.Printf("tag follow: cache followed tags of account %d failed: %v", accountID, err)
+		}
+	}
+	return ids, nil
+}
+
+func (s *TagService) invalidateFollowed(ctx context.Context, accountID uint) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Del(ctx, followedTagsKey(s.cache, accountID)); err != nil {
+		log.Printf("tag follow: invalidate followed tags of account %d failed: %v", accountID, err)
+	}
+}
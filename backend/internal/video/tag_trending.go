@@ -0,0 +1,58 @@
+package video
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+const (
+	// 一次发布计入话题热度的分数；互动按视频热度的同样分数计入（点赞、评论 1，播放 0.1）
+	TagPublishWeight = 5
+	tagWindowTTL     = 2 * time.Hour
+	// 视频的话题集合在发布时写入 Redis，有互动就续期；长期没有互动的视频不再给话题加分
+	videoTagsTTL = 7 * 24 * time.Hour
+)
+
+func tagWindowKey(cache *rediscache.Client, minute time.Time) string {
+	return cache.Key("hot:tag:1m:%s", minute.UTC().Truncate(time.Minute).Format("200601021504"))
+}
+
+func videoTagsKey(cache *rediscache.Client, videoID uint) string {
+	return cache.Key("video:tags:%d", videoID)
+}
+
+// tagPublishedKey 视频的发布分已经加过的标记，outbox 重投时不重复加分
+func tagPublishedKey(cache *rediscache.Client, videoID uint) string {
+	return cache.Key("tag:published:%d", videoID)
+}
+
+// addTagScore 把视频的热度增量同样记到它的各个话题当前分钟的窗口上
+func addTagScore(ctx context.Context, cache *rediscache.Client, videoID uint, score float64, now time.Time) {
+	key := videoTagsKey(cache, videoID)
+	tags, err := cache.SMembers(ctx, key)
+	if err != nil || len(tags) == 0 {
+		return
+	}
+	_ = cache.ZIncrByMulti(ctx, tagWindowKey(cache, now), tags, score, tagWindowTTL)
+	_ = cache.Expire(ctx, key, videoTagsTTL)
+}
+
+// refreshVideoTags 视频改了话题后重写它的话题集合，之后的互动计入新话题
+func refreshVideoTags(ctx context.Context, cache *rediscache.Client, videoID uint, tagIDs []uint) {
+	if cache == nil {
+		return
+	}
+	key := videoTagsKey(cache, videoID)
+	_ = cache.Del(ctx, key)
+	if len(tagIDs) == 0 {
+		return
+	}
+	members := make([]string, len(tagIDs))
+	for i, id := range tagIDs {
+		members[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	_ = cache.SAdd(ctx, key, videoTagsTTL, members...)
+}
@@ -0,0 +1,70 @@
+package video
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestPopularityScoreCreditsVideoTags(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+
+	if err := cache.SAdd(ctx, videoTagsKey(cache, 7), videoTagsTTL, "1", "2"); err != nil {
+		t.Fatalf("sadd: %v", err)
+	}
+	AddPopularityScore(ctx, cache, 7, 1)
+	AddPopularityScore(ctx, cache, 7, 0.5)
+	AddPopularityScore(ctx, cache, 8, 1) // 没有话题的视频只进视频热榜
+
+	key := tagWindowKey(cache, time.Now())
+	for _, tag := range []string{"1", "2"} {
+		score, ok, err := cache.ZScore(ctx, key, tag)
+		if err != nil || !ok || score != 1.5 {
+			t.Fatalf("tag %s score = %v (ok=%v, err=%v), want 1.5", tag, score, ok, err)
+		}
+	}
+	if n, _ := mr.ZMembers(key); len(n) != 2 {
+		t.Fatalf("tag window members = %v", n)
+	}
+	if ttl := mr.TTL(videoTagsKey(cache, 7)); ttl <= 0 {
+		t.Fatalf("video tags key should keep a ttl, got %v", ttl)
+	}
+}
+
+func TestTagPublishedScoresOncePerVideo(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+
+	db := dbtest.Open(t, &VideoTag{})
+	if err := db.Create(&VideoTag{VideoID: 7, TagID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewTagService(NewTagRepository(db), cache)
+	// outbox 重投同一个 video_published
+	for i := 0; i < 2; i++ {
+		if err := svc.Published(ctx, 7); err != nil {
+			t.Fatalf("published: %v", err)
+		}
+	}
+	score, _, err := cache.ZScore(ctx, tagWindowKey(cache, time.Now()), "1")
+	if err != nil || score != TagPublishWeight {
+		t.Fatalf("tag score = %v (err=%v), want %v", score, err, TagPublishWeight)
+	}
+}
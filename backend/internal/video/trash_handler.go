@@ -0,0 +1,43 @@
+package video
+
+import (
+	"net/http"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/middleware/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (vh *VideoHandler) ListTrash(c *gin.Context) {
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	videos, err := vh.service.ListTrash(c.Request.Context(), authorId)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, videos)
+}
+
+func (vh *VideoHandler) RestoreVideo(c *gin.Context) {
+	var req RestoreVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	video, err := vh.service.Restore(c.Request.Context(), authorId, req.ID)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, video)
+}
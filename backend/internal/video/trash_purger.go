@@ -0,0 +1,203 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/storage"
+)
+
+const purgeBatchSize = 50
+
+// Purger 定时彻底清理超过保留期的回收站视频：先删存储里的文件，再删视频和依赖它的行。
+// 存储删除失败时这一行留到下一轮重试；多实例部署时靠 Redis 锁只让一个实例扫表
+type Purger struct {
+	repo     *VideoRepository
+	store    storage.Store
+	cache    *rediscache.Client
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewPurger(repo *VideoRepository, store storage.Store, cache *rediscache.Client) *Purger {
+	return &Purger{repo: repo, store: store, cache: cache, interval: time.Hour, now: time.Now}
+}
+
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.tick(ctx)
+		}
+	}
+}
+
+func (p *Purger) tick(ctx context.Context) {
+	if p.cache != nil {
+		lockKey := p.cache.Key("lock:video:purge")
+		token, locked, err := p.cache.Lock(ctx, lockKey, p.interval)
+		if err == nil && !locked {
+			return
+		}
+		if locked {
+			defer func() { _ = p.cache.Unlock(context.Background(), lockKey, token) }()
+		}
+	}
+	before := p.now().Add(-TrashRetention)
+	videos, err := p.repo.ListPurgeable(ctx, before, purgeBatchSize)
+	if err != nil {
+		log.Printf("video purger: list purgeable videos failed: %v", err)
+		return
+	}
+	purged := 0
+	for i := range videos {
+		v := &videos[i]
+		if err := p.deleteFiles(ctx, v); err != nil {
+			log.Printf("video purger: delete files of video %d failed: %v", v.ID, err)
+			continue
+		}
+		ok, err := p.repo.Purge(ctx, v.ID, before)
+		if err != nil {
+			log.Printf("video purger: purge video %d failed: %v", v.ID, err)
+			continue
+		}
+		if ok {
+			purged++
+		}
+	}
+	if purged > 0 {
+		log.Printf("video purger: purged %d videos", purged)
+	}
+}
+
+// deleteFiles 删除播放、封面和源文件；HLS 播放地址按 playlist 找出全部分片一起删。
+// 地址是发布时客户端填的，只删属于这个视频或作者目录下的 key，其他的跳过；
+// 作者可能在多个视频里复用同一个封面或源文件，还有别的视频引用的也跳过
+func (p *Purger) deleteFiles(ctx context.Context, v *Video) error {
+	if p.store == nil {
+		return nil
+	}
+	owned := ownedKeyPrefixes(v)
+	var keys []string
+	for _, u := range []string{v.PlayURL, v.CoverURL, v.SourceURL} {
+		key, ok := storage.KeyFromURL(p.store, u)
+		if !ok {
+			continue
+		}
+		if !hasAnyPrefix(key, owned) {
+			log.Printf("video purger: skip %s of video %d, not owned by it", key, v.ID)
+			continue
+		}
+		if p.repo != nil {
+			shared, err := p.repo.IsKeyReferenced(ctx, p.store, key, v.ID)
+			if err != nil {
+				return err
+			}
+			if shared {
+				log.Printf("video purger: skip %s of video %d, still used by another video", key, v.ID)
+				continue
+			}
+		}
+		if strings.HasSuffix(key, ".m3u8") {
+			hls, err := p.playlistKeys(ctx, key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, hls...)
+		}
+		keys = append(keys, key)
+	}
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		if err := p.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownedKeyPrefixes 返回视频可以删除的存储前缀：作者上传的源文件和封面、转码产出的 HLS 目录
+func ownedKeyPrefixes(v *Video) []string {
+	author := strconv.FormatUint(uint64(v.AuthorID), 10)
+	return []string{
+		path.Join("videos", author) + "/",
+		path.Join("covers", author) + "/",
+		path.Join("hls", strconv.FormatUint(uint64(v.ID), 10)) + "/",
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// playlistKeys 递归读取 playlist，返回它引用的子 playlist 和分片的 key
+func (p *Purger) playlistKeys(ctx context.Context, key string) ([]string, error) {
+	rc, _, err := p.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	body, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range playlistEntries(body, path.Dir(key)) {
+		if strings.HasSuffix(entry, ".m3u8") {
+			sub, err := p.playlistKeys(ctx, entry)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, sub...)
+		}
+		keys = append(keys, entry)
+	}
+	return keys, nil
+}
+
+// playlistEntries 解析 m3u8 里的相对地址并拼成 dir 下的 key；
+// 绝对地址和越出 dir 的路径不是这个视频的文件，忽略
+func playlistEntries(body []byte, dir string) []string {
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, "://") || strings.HasPrefix(line, "/") {
+			continue
+		}
+		if i := strings.IndexAny(line, "?#"); i >= 0 {
+			line = line[:i]
+		}
+		key := path.Join(dir, line)
+		if !strings.HasPrefix(key, dir+"/") {
+			continue
+		}
+		entries = append(entries, key)
+	}
+	return entries
+}
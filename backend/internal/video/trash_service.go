@@ -0,0 +1,100 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"feedsystem_video_go/internal/apierror"
+
+	"gorm.io/gorm"
+)
+
+// 热榜分钟窗口保留 2 小时，清理时覆盖全部窗口
+const hotWindowMinutes = 120
+
+// CleanupDeleted 处理 outbox 的 video_deleted：从全站时间线、热榜窗口、话题集合和搜索索引里移除视频。
+// 各步骤都是幂等的，任一步失败返回错误让 outbox 重试整个事件；事件到达前视频已被恢复时跳过
+func (vs *VideoService) CleanupDeleted(ctx context.Context, id uint) error {
+	if vs.repo != nil {
+		restored, err := vs.repo.IsExist(ctx, id)
+		if err != nil {
+			return err
+		}
+		if restored {
+			return nil
+		}
+	}
+	if vs.cache != nil {
+		member := strconv.FormatUint(uint64(id), 10)
+		if err := vs.cache.ZRem(ctx, vs.cache.Key("feed:global_timeline"), member); err != nil {
+			return fmt.Errorf("remove video %d from global timeline: %w", id, err)
+		}
+		now := time.Now().UTC().Truncate(time.Minute)
+		for i := 0; i < hotWindowMinutes; i++ {
+			minute := now.Add(-time.Duration(i) * time.Minute).Format("200601021504")
+			if err := vs.cache.ZRem(ctx, vs.cache.Key("hot:video:1m:%s", minute), member); err != nil {
+				return fmt.Errorf("remove video %d from hot window: %w", id, err)
+			}
+			if i < 2 {
+				if err := vs.cache.ZRem(ctx, vs.cache.Key("hot:video:merge:1m:%s", minute), member); err != nil {
+					return fmt.Errorf("remove video %d from hot window: %w", id, err)
+				}
+			}
+		}
+		if err := vs.cache.Del(ctx, videoTagsKey(vs.cache, id)); err != nil {
+			return fmt.Errorf("delete tags of video %d: %w", id, err)
+		}
+	}
+	if vs.searchSync != nil {
+		if err := vs.searchSync.Deleted(ctx, id); err != nil {
+			return err
+		}
+	}
+	InvalidateVideoCaches(ctx, vs.cache, id)
+	return nil
+}
+
+// ListTrash 回收站：保留期内删除的视频及其彻底清理时间
+func (vs *VideoService) ListTrash(ctx context.Context, authorID uint) ([]TrashedVideo, error) {
+	videos, err := vs.repo.ListTrash(ctx, authorID, time.Now().Add(-TrashRetention))
+	if err != nil {
+		return nil, err
+	}
+	trashed := make([]TrashedVideo, 0, len(videos))
+	for _, v := range videos {
+		deletedAt := v.DeletedAt.Time
+		trashed = append(trashed, TrashedVideo{Video: v, DeletedAt: deletedAt, PurgeAt: deletedAt.Add(TrashRetention)})
+	}
+	return trashed, nil
+}
+
+// Restore 从回收站恢复视频；已发布的视频经 outbox 重新进入时间线和搜索，转码被打断的重新转码
+func (vs *VideoService) Restore(ctx context.Context, authorID uint, id uint) (*Video, error) {
+	video, err := vs.repo.GetTrashed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if video.AuthorID != authorID {
+		return nil, apierror.ErrUnauthorized
+	}
+	restored, err := vs.repo.Restore(ctx, video, time.Now().Add(-TrashRetention), vs.transcodeEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, fmt.Errorf("%w: video is past the restore window", apierror.ErrValidation)
+	}
+	video.DeletedAt = gorm.DeletedAt{}
+	InvalidateVideoCaches(ctx, vs.cache, id)
+	if video.IsTranscoding() && !vs.transcodeEnabled {
+		// 转码已关闭，直接以原文件发布
+		if err := vs.repo.MarkReady(ctx, video, video.SourceURL); err != nil {
+			return nil, err
+		}
+		video.Status = VideoStatusReady
+		video.PlayURL = video.SourceURL
+	}
+	return video, nil
+}
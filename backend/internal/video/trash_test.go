@@ -0,0 +1,202 @@
+package video
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/storage"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestPlaylistEntriesKeepsOnlyFilesUnderDir(t *testing.T) {
+	body := []byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\n720p/index.m3u8?v=1\n\nhttps://cdn.example.com/x.ts\n/abs/seg.ts\n../../other/seg.ts\nseg0.ts\n")
+	got := playlistEntries(body, "hls/7")
+	want := []string{"hls/7/720p/index.m3u8", "hls/7/seg0.ts"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestPurgerDeletesHLSTreeAndSourceFiles(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"hls/7/master.m3u8":     "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\n720p/index.m3u8\n",
+		"hls/7/720p/index.m3u8": "#EXTM3U\n#EXTINF:4,\nseg0.ts\n#EXTINF:4,\nseg1.ts\n",
+		"hls/7/720p/seg0.ts":    "a",
+		"hls/7/720p/seg1.ts":    "b",
+		"videos/7/source.mp4":   "src",
+		"covers/7/cover.jpg":    "img",
+		"videos/8/keep.mp4":     "other",
+	}
+	for key, body := range files {
+		if err := store.Put(ctx, key, strings.NewReader(body), int64(len(body)), storage.ContentTypeByKey(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := NewPurger(nil, store, nil)
+	v := &Video{
+		ID:        7,
+		AuthorID:  7,
+		PlayURL:   store.URL("hls/7/master.m3u8"),
+		CoverURL:  store.URL("covers/7/cover.jpg"),
+		SourceURL: store.URL("videos/7/source.mp4"),
+	}
+	if err := p.deleteFiles(ctx, v); err != nil {
+		t.Fatalf("deleteFiles: %v", err)
+	}
+	for key := range files {
+		_, err := store.Stat(ctx, key)
+		if key == "videos/8/keep.mp4" {
+			if err != nil {
+				t.Errorf("%s should be kept: %v", key, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s should be deleted", key)
+		}
+	}
+}
+
+func TestPurgerSkipsFilesOutsideVideoAndAuthor(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 作者 7 把地址指向作者 8 和视频 8 的文件
+	files := []string{"videos/8/keep.mp4", "covers/8/keep.jpg", "hls/8/master.m3u8", "videos/7/source.mp4"}
+	for _, key := range files {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, storage.ContentTypeByKey(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := NewPurger(nil, store, nil)
+	v := &Video{
+		ID:        7,
+		AuthorID:  7,
+		PlayURL:   store.URL("hls/8/master.m3u8"),
+		CoverURL:  store.URL("covers/8/keep.jpg"),
+		SourceURL: store.URL("videos/8/keep.mp4"),
+	}
+	if err := p.deleteFiles(ctx, v); err != nil {
+		t.Fatalf("deleteFiles: %v", err)
+	}
+	for _, key := range files {
+		if _, err := store.Stat(ctx, key); err != nil {
+			t.Errorf("%s should be kept: %v", key, err)
+		}
+	}
+}
+
+func TestPurgerKeepsFilesStillUsedByAnotherVideo(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"covers/7/cover.jpg", "videos/7/a.mp4", "videos/7/b.mp4"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, storage.ContentTypeByKey(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db := dbtest.Open(t, &Video{})
+	repo := NewVideoRepository(db)
+	purged := &Video{AuthorID: 7, Title: "a", PlayURL: store.URL("videos/7/a.mp4"), CoverURL: store.URL("covers/7/cover.jpg")}
+	// 复用了同一个封面，自己也在回收站里还没到清理时间
+	sibling := &Video{AuthorID: 7, Title: "b", PlayURL: store.URL("videos/7/b.mp4"), CoverURL: store.URL("covers/7/cover.jpg")}
+	for _, v := range []*Video{purged, sibling} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(sibling).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewPurger(repo, store, nil).deleteFiles(ctx, purged); err != nil {
+		t.Fatalf("deleteFiles: %v", err)
+	}
+	if _, err := store.Stat(ctx, "videos/7/a.mp4"); err == nil {
+		t.Error("videos/7/a.mp4 should be deleted")
+	}
+	for _, key := range []string{"covers/7/cover.jpg", "videos/7/b.mp4"} {
+		if _, err := store.Stat(ctx, key); err != nil {
+			t.Errorf("%s should be kept: %v", key, err)
+		}
+	}
+}
+
+func TestRestoreEnqueuesRestoredInsteadOfPublished(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t, &Video{}, &outbox.Event{})
+	repo := NewVideoRepository(db)
+	v := &Video{AuthorID: 7, Title: "a", Status: VideoStatusReady, Visibility: VisibilityPublic}
+	if err := db.Create(v).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(v).Error; err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repo.Restore(ctx, v, time.Now().Add(-time.Hour), false)
+	if err != nil || !restored {
+		t.Fatalf("restore = %v, %v", restored, err)
+	}
+	var types []string
+	db.Model(&outbox.Event{}).Pluck("type", &types)
+	// 恢复不能再走 video_published，否则话题会再加一次发布分
+	if !reflect.DeepEqual(types, []string{rabbitmq.VideoRestoredType}) {
+		t.Fatalf("outbox types = %v", types)
+	}
+}
+
+func TestCleanupDeletedRemovesVideoFromTimelineAndHotWindows(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	cache := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+
+	timeline := cache.Key("feed:global_timeline")
+	window := cache.Key("hot:video:1m:%s", time.Now().UTC().Add(-30*time.Minute).Format("200601021504"))
+	for _, key := range []string{timeline, window} {
+		if err := cache.ZAdd(ctx, key, goredis.Z{Score: 1, Member: "7"}, goredis.Z{Score: 2, Member: "8"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.SAdd(ctx, videoTagsKey(cache, 7), time.Hour, "3"); err != nil {
+		t.Fatal(err)
+	}
+
+	vs := &VideoService{cache: cache}
+	if err := vs.CleanupDeleted(ctx, 7); err != nil {
+		t.Fatalf("CleanupDeleted: %v", err)
+	}
+
+	for _, key := range []string{timeline, window} {
+		if _, ok, _ := cache.ZScore(ctx, key, "7"); ok {
+			t.Errorf("video 7 still in %s", key)
+		}
+		if _, ok, _ := cache.ZScore(ctx, key, "8"); !ok {
+			t.Errorf("video 8 removed from %s", key)
+		}
+	}
+	if mr.Exists(videoTagsKey(cache, 7)) {
+		t.Error("video tags set should be deleted")
+	}
+}
@@ -0,0 +1,28 @@
+package video
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+// VideoChangedChannel 视频元数据或可见范围变化时在这个 channel 上广播视频 ID，
+// 各 API 实例据此清掉进程内的实体缓存（FeedService 的 L1）
+func VideoChangedChannel(cache *rediscache.Client) string {
+	return cache.Key("video:changed")
+}
+
+// InvalidateVideoCaches 删除 Redis 里的详情和实体缓存并通知所有实例；
+// 关注流收件箱里只存视频 ID，实体缓存失效后粉丝翻页拿到的就是新数据
+func InvalidateVideoCaches(ctx context.Context, cache *rediscache.Client, id uint) {
+	if cache == nil {
+		return
+	}
+	_ = cache.Del(ctx, cache.Key("video:detail:id=%d", id))
+	_ = cache.Del(ctx, cache.Key("video:entity:%d", id))
+	if err := cache.Publish(ctx, VideoChangedChannel(cache), []byte(strconv.FormatUint(uint64(id), 10))); err != nil {
+		log.Printf("broadcast video %d change failed: %v", id, err)
+	}
+}
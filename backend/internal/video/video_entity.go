@@ -1,18 +1,66 @@
 package video
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 视频处理状态：上传后 pending，转码中 processing，完成 ready，失败 failed；
+// 草稿 draft 和定时发布 scheduled 还没进入发布流程，正式发布时才转为 pending/ready
+const (
+	VideoStatusPending    = "pending"
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+	VideoStatusDraft      = "draft"
+	VideoStatusScheduled  = "scheduled"
+)
+
+// UnpublishedStatuses 草稿箱里的视频状态
+var UnpublishedStatuses = []string{VideoStatusDraft, VideoStatusScheduled}
 
 type Video struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	AuthorID    uint      `gorm:"index;not null" json:"author_id"`
-	Username    string    `gorm:"type:varchar(255);not null" json:"username"`
-	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
-	Description string    `gorm:"type:varchar(255);" json:"description,omitempty"`
-	PlayURL     string    `gorm:"type:varchar(255);not null" json:"play_url"`
-	CoverURL    string    `gorm:"type:varchar(255);not null" json:"cover_url"`
-	CreateTime  time.Time `gorm:"autoCreateTime;index:idx_videos_create_time,sort:desc;index:idx_videos_popularity_time_id,priority:2,sort:desc" json:"create_time"`
-	LikesCount  int64     `gorm:"column:likes_count;not null;default:0;index:idx_videos_likes_count_id,priority:1,sort:desc" json:"likes_count"`
-	Popularity  int64     `gorm:"column:popularity;not null;default:0;index:idx_videos_popularity_time_id,priority:1,sort:desc" json:"popularity"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	AuthorID        uint       `gorm:"index;not null" json:"author_id"`
+	Username        string     `gorm:"type:varchar(255);not null" json:"username"`
+	Title           string     `gorm:"type:varchar(255);not null" json:"title"`
+	Description     string     `gorm:"type:varchar(255);" json:"description,omitempty"`
+	PlayURL         string     `gorm:"type:varchar(255);not null" json:"play_url"`
+	CoverURL        string     `gorm:"type:varchar(255);not null" json:"cover_url"`
+	SourceURL       string     `gorm:"type:varchar(255)" json:"source_url,omitempty"`
+	Status          string     `gorm:"type:varchar(20);not null;default:'ready';index" json:"status"`
+	Visibility      string     `gorm:"type:varchar(20);not null;default:'public';index" json:"visibility"`
+	ShareToken      string     `gorm:"type:varchar(32);index" json:"-"` // 仅 unlisted 视频有，切换可见范围时重置；只通过 /video/shareToken 返回给作者
+	PinnedCommentID uint       `gorm:"not null;default:0" json:"pinned_comment_id,omitempty"`
+	CreateTime      time.Time  `gorm:"autoCreateTime;index:idx_videos_create_time,sort:desc;index:idx_videos_popularity_time_id,priority:2,sort:desc" json:"create_time"`
+	LikesCount      int64      `gorm:"column:likes_count;not null;default:0;index:idx_videos_likes_count_id,priority:1,sort:desc" json:"likes_count"`
+	Popularity      int64      `gorm:"column:popularity;not null;default:0;index:idx_videos_popularity_time_id,priority:1,sort:desc" json:"popularity"`
+	PlayCount       int64      `gorm:"column:play_count;not null;default:0" json:"play_count"`
+	PublishAt       *time.Time `gorm:"index" json:"publish_at,omitempty"` // 定时发布时间，仅 scheduled 状态有值
+	ProcessingUntil *time.Time `json:"-"`                                 // 转码租约到期时间，过期前其他 worker 不会重复认领
+	// 软删除：进回收站后普通查询都看不到，TrashRetention 内可恢复，之后由 Purger 彻底清理
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// 回收站保留时间
+const TrashRetention = 30 * 24 * time.Hour
+
+// TrashedVideo 回收站里的视频
+type TrashedVideo struct {
+	Video
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// IsTranscoding 等待或正在转码
+func (v *Video) IsTranscoding() bool {
+	return v.Status == VideoStatusPending || v.Status == VideoStatusProcessing
+}
+
+// IsUnpublished 草稿或定时发布中的视频只有作者自己能看到
+func (v *Video) IsUnpublished() bool {
+	return v.Status == VideoStatusDraft || v.Status == VideoStatusScheduled
 }
 
 type PublishVideoRequest struct {
@@ -20,29 +68,82 @@ type PublishVideoRequest struct {
 	Description string `json:"description"`
 	PlayURL     string `json:"play_url"`
 	CoverURL    string `json:"cover_url"`
+	Visibility  string `json:"visibility,omitempty"` // public（默认）/ followers / private / unlisted
+	Draft       bool   `json:"draft,omitempty"`      // 只存草稿，不发布
+	PublishAt   int64  `json:"publish_at,omitempty"` // 定时发布时间（秒），为空表示立即发布
 }
 
 type DeleteVideoRequest struct {
 	ID uint `json:"id"`
 }
 
+type RestoreVideoRequest struct {
+	ID uint `json:"id"`
+}
+
 type ListByAuthorIDRequest struct {
 	AuthorID uint `json:"author_id"`
 }
 
 type GetDetailRequest struct {
+	ID         uint   `json:"id"`
+	ShareToken string `json:"share_token,omitempty"` // 打开 unlisted 视频时必传
+}
+
+type DraftIDRequest struct {
 	ID uint `json:"id"`
 }
 
+// UpdateDraftRequest 字段为空表示不修改；publish_at 传 0 表示取消定时、退回草稿
+type UpdateDraftRequest struct {
+	ID          uint    `json:"id"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	CoverURL    *string `json:"cover_url,omitempty"`
+	Visibility  *string `json:"visibility,omitempty"`
+	PublishAt   *int64  `json:"publish_at,omitempty"`
+}
+
+// UpdateVideoRequest 发布后修改元数据，字段为空表示不修改
+type UpdateVideoRequest struct {
+	ID          uint    `json:"id"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	CoverURL    *string `json:"cover_url,omitempty"`
+}
+
+type ListEditsRequest struct {
+	ID uint `json:"id"`
+}
+
+// VideoEdit 发布后的一次元数据修改，记录修改前后的值
+type VideoEdit struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	VideoID        uint      `gorm:"index;not null" json:"video_id"`
+	EditorID       uint      `gorm:"not null" json:"editor_id"`
+	OldTitle       string    `gorm:"type:varchar(255)" json:"old_title"`
+	NewTitle       string    `gorm:"type:varchar(255)" json:"new_title"`
+	OldDescription string    `gorm:"type:varchar(255)" json:"old_description"`
+	NewDescription string    `gorm:"type:varchar(255)" json:"new_description"`
+	OldCoverURL    string    `gorm:"type:varchar(255)" json:"old_cover_url"`
+	NewCoverURL    string    `gorm:"type:varchar(255)" json:"new_cover_url"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type SetVisibilityRequest struct {
+	ID         uint   `json:"id"`
+	Visibility string `json:"visibility"`
+}
+
+type ShareTokenRequest struct {
+	ID uint `json:"id"`
+}
+
+type ShareTokenResponse struct {
+	ShareToken string `json:"share_token"`
+}
+
 type UpdateLikesCountRequest struct {
 	ID         uint  `json:"id"`
 	LikesCount int64 `json:"likes_count"`
 }
-
-type OutboxMsg struct {
-	ID         uint      `gorm:"primaryKey"`
-	VideoID    uint      `gorm:"index"`
-	EventType  string    `gorm:"type:varchar(50)"`
-	CreateTime time.Time `gorm:"autoCreateTime"`
-	Status     string    `gorm:"type:varchar(50);index"`
-}
@@ -4,8 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"path"
 	"path/filepath"
 	"strings"
@@ -14,6 +14,7 @@ import (
 	"feedsystem_video_go/internal/account"
 	"feedsystem_video_go/internal/apierror"
 	"feedsystem_video_go/internal/middleware/jwt"
+	"feedsystem_video_go/internal/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,10 +22,11 @@ import (
 type VideoHandler struct {
 	service        *VideoService
 	accountService *account.AccountService
+	store          storage.Store
 }
 
-func NewVideoHandler(service *VideoService, accountService *account.AccountService) *VideoHandler {
-	return &VideoHandler{service: service, accountService: accountService}
+func NewVideoHandler(service *VideoService, accountService *account.AccountService, store storage.Store) *VideoHandler {
+	return &VideoHandler{service: service, accountService: accountService, store: store}
 }
 
 func (vh *VideoHandler) PublishVideo(c *gin.Context) {
@@ -51,8 +53,16 @@ func (vh *VideoHandler) PublishVideo(c *gin.Context) {
 		Description: req.Description,
 		PlayURL:     req.PlayURL,
 		CoverURL:    req.CoverURL,
+		Visibility:  req.Visibility,
 		CreateTime:  time.Now(),
 	}
+	if req.Draft {
+		video.Status = VideoStatusDraft
+	}
+	if req.PublishAt > 0 {
+		at := time.Unix(req.PublishAt, 0)
+		video.PublishAt = &at
+	}
 	if err := vh.service.Publish(c.Request.Context(), video); err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
@@ -86,32 +96,21 @@ func (vh *VideoHandler) UploadVideo(c *gin.Context) {
 	}
 
 	date := time.Now().Format("20060102")
-	relDir := filepath.Join("videos", fmt.Sprintf("%d", authorId), date)
-	root := filepath.Join(".run", "uploads")
-	absDir := filepath.Join(root, relDir)
-	if err := os.MkdirAll(absDir, 0o755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
 	filename, err := randHex(16)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate filename"})
 		return
 	}
-	filename = filename + ext
-	absPath := filepath.Join(absDir, filename)
-
-	if err := c.SaveUploadedFile(f, absPath); err != nil {
+	key := path.Join("videos", fmt.Sprintf("%d", authorId), date, filename+ext)
+	if err := saveUploadedFile(c, vh.store, f, key); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	urlPath := path.Join("/static", "videos", fmt.Sprintf("%d", authorId), date, filename)
-
+	playURL := vh.store.URL(key)
 	c.JSON(http.StatusOK, gin.H{
-		"url":      buildAbsoluteURL(c, urlPath),
-		"play_url": buildAbsoluteURL(c, urlPath),
+		"url":      playURL,
+		"play_url": playURL,
 	})
 }
 
@@ -143,35 +142,34 @@ func (vh *VideoHandler) UploadCover(c *gin.Context) {
 	}
 
 	date := time.Now().Format("20060102")
-	relDir := filepath.Join("covers", fmt.Sprintf("%d", authorId), date)
-	root := filepath.Join(".run", "uploads")
-	absDir := filepath.Join(root, relDir)
-	if err := os.MkdirAll(absDir, 0o755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
 	filename, err := randHex(16)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate filename"})
 		return
 	}
-	filename = filename + ext
-	absPath := filepath.Join(absDir, filename)
-
-	if err := c.SaveUploadedFile(f, absPath); err != nil {
+	key := path.Join("covers", fmt.Sprintf("%d", authorId), date, filename+ext)
+	if err := saveUploadedFile(c, vh.store, f, key); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	urlPath := path.Join("/static", "covers", fmt.Sprintf("%d", authorId), date, filename)
-
+	coverURL := vh.store.URL(key)
 	c.JSON(http.StatusOK, gin.H{
-		"url":       buildAbsoluteURL(c, urlPath),
-		"cover_url": buildAbsoluteURL(c, urlPath),
+		"url":       coverURL,
+		"cover_url": coverURL,
 	})
 }
 
+// saveUploadedFile 把表单文件写入对象存储
+func saveUploadedFile(c *gin.Context, store storage.Store, f *multipart.FileHeader, key string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return store.Put(c.Request.Context(), key, src, f.Size, storage.ContentTypeByKey(key))
+}
+
 func randHex(n int) (string, error) {
 	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
@@ -180,17 +178,6 @@ func randHex(n int) (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-func buildAbsoluteURL(c *gin.Context, p string) string {
-	scheme := "http"
-	if c.Request.TLS != nil {
-		scheme = "https"
-	}
-	if xf := c.GetHeader("X-Forwarded-Proto"); xf != "" {
-		scheme = xf
-	}
-	return fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, p)
-}
-
 func (vh *VideoHandler) DeleteVideo(c *gin.Context) {
 	var req DeleteVideoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -215,7 +202,8 @@ func (vh *VideoHandler) ListByAuthorID(c *gin.Context) {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	videos, err := vh.service.ListByAuthorID(c.Request.Context(), req.AuthorID)
+	viewerAccountID, _ := jwt.GetAccountID(c)
+	videos, err := vh.service.ListByAuthorID(c.Request.Context(), req.AuthorID, viewerAccountID)
 	if err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
@@ -232,7 +220,8 @@ func (vh *VideoHandler) GetDetail(c *gin.Context) {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	video, err := vh.service.GetDetail(c.Request.Context(), req.ID)
+	viewerAccountID, _ := jwt.GetAccountID(c)
+	video, err := vh.service.GetDetail(c.Request.Context(), req.ID, viewerAccountID, req.ShareToken)
 	if err != nil {
 		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
 		return
@@ -240,6 +229,85 @@ func (vh *VideoHandler) GetDetail(c *gin.Context) {
 	c.JSON(200, video)
 }
 
+func (vh *VideoHandler) UpdateVideo(c *gin.Context) {
+	var req UpdateVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	video, err := vh.service.Update(c.Request.Context(), authorId, req)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, video)
+}
+
+func (vh *VideoHandler) ListEdits(c *gin.Context) {
+	var req ListEditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	edits, err := vh.service.ListEdits(c.Request.Context(), authorId, req.ID)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if edits == nil {
+		edits = []VideoEdit{}
+	}
+	c.JSON(200, edits)
+}
+
+func (vh *VideoHandler) SetVisibility(c *gin.Context) {
+	var req SetVisibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	video, err := vh.service.SetVisibility(c.Request.Context(), req.ID, authorId, req.Visibility)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, video)
+}
+
+func (vh *VideoHandler) ShareToken(c *gin.Context) {
+	var req ShareTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	authorId, err := jwt.GetAccountID(c)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	token, err := vh.service.ShareToken(c.Request.Context(), req.ID, authorId)
+	if err != nil {
+		c.JSON(apierror.ClassifyHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, ShareTokenResponse{ShareToken: token})
+}
+
 func (vh *VideoHandler) UpdateLikesCount(c *gin.Context) {
 	var req UpdateLikesCountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -3,8 +3,15 @@ package video
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
+
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/storage"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type VideoRepository struct {
@@ -22,24 +29,133 @@ func (vr *VideoRepository) CreateVideo(ctx context.Context, video *Video) error
 	return nil
 }
 
-func (vr *VideoRepository) CreateMsg(ctx context.Context, Msg *OutboxMsg) error {
-	if err := vr.db.WithContext(ctx).Create(Msg).Error; err != nil {
-		return err
+// SoftDelete 视频进回收站，同一事务写入删除消息，由 outbox 异步清理时间线、热榜和搜索
+func (vr *VideoRepository) SoftDelete(ctx context.Context, video *Video) error {
+	return vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&Video{}, video.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		m, err := rabbitmq.VideoDeletedMessage(video.ID, video.AuthorID)
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, m)
+	})
+}
+
+// ListTrash 作者回收站里 since 之后删除的视频，最近删除的在前
+func (vr *VideoRepository) ListTrash(ctx context.Context, authorID uint, since time.Time) ([]Video, error) {
+	var videos []Video
+	if err := vr.db.WithContext(ctx).Unscoped().
+		Where("author_id = ? AND deleted_at IS NOT NULL AND deleted_at > ?", authorID, since).
+		Order("deleted_at desc").
+		Limit(200).
+		Find(&videos).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return videos, nil
 }
 
-func (vr *VideoRepository) DeleteVideo(ctx context.Context, id uint) error {
-	if err := vr.db.WithContext(ctx).Delete(&Video{}, id).Error; err != nil {
-		return err
+// GetTrashed 取回收站里的视频，不在回收站时返回 gorm.ErrRecordNotFound
+func (vr *VideoRepository) GetTrashed(ctx context.Context, id uint) (*Video, error) {
+	var video Video
+	if err := vr.db.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&video).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return &video, nil
 }
 
-func (vr *VideoRepository) ListByAuthorID(ctx context.Context, authorID int64) ([]Video, error) {
+// Restore 恢复 since 之后删除的视频；已发布的视频在同一事务写入恢复消息，重新进入各条 feed，
+// 转码被打断的视频在 retranscode 为 true 时同一事务重新请求转码
+func (vr *VideoRepository) Restore(ctx context.Context, video *Video, since time.Time, retranscode bool) (bool, error) {
+	restored := false
+	err := vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&Video{}).
+			Where("id = ? AND deleted_at IS NOT NULL AND deleted_at > ?", video.ID, since).
+			Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		restored = true
+		switch {
+		case video.Status == VideoStatusReady:
+			m, err := rabbitmq.VideoRestoredMessage(video.ID, video.AuthorID, video.CreateTime, video.Visibility)
+			if err != nil {
+				return err
+			}
+			return outbox.Enqueue(tx, m)
+		case video.IsTranscoding() && retranscode:
+			return enqueueTranscode(tx, video)
+		}
+		return nil
+	})
+	return restored, err
+}
+
+// ListPurgeable 删除时间早于 before、可以彻底清理的视频
+func (vr *VideoRepository) ListPurgeable(ctx context.Context, before time.Time, limit int) ([]Video, error) {
 	var videos []Video
-	if err := vr.db.WithContext(ctx).
-		Where("author_id = ?", authorID).
+	if err := vr.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at asc, id asc").
+		Limit(limit).
+		Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// Purge 彻底删除回收站里的视频及其点赞、评论、话题、播放统计和修改记录；
+// 视频已被恢复时返回 false
+func (vr *VideoRepository) Purge(ctx context.Context, id uint, before time.Time) (bool, error) {
+	purged := false
+	err := vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", id, before).
+			Delete(&Video{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		purged = true
+		commentIDs := tx.Model(&Comment{}).Select("id").Where("video_id = ?", id)
+		if err := tx.Where("comment_id IN (?)", commentIDs).Delete(&CommentLike{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&Comment{}, &Like{}, &VideoTag{}, &VideoPlayStat{}, &VideoEdit{}} {
+			if err := tx.Where("video_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// 作者本人在主页还能看到转码中和转码失败的视频
+var authorListStatuses = []string{VideoStatusReady, VideoStatusPending, VideoStatusProcessing, VideoStatusFailed}
+
+// ListByAuthorID visibilities 为空时是作者本人：不按可见范围过滤，并列出转码中和失败的视频
+func (vr *VideoRepository) ListByAuthorID(ctx context.Context, authorID int64, visibilities []string) ([]Video, error) {
+	var videos []Video
+	query := vr.db.WithContext(ctx).Where("author_id = ?", authorID)
+	if len(visibilities) > 0 {
+		query = query.Where("status = ? AND visibility IN ?", VideoStatusReady, visibilities)
+	} else {
+		query = query.Where("status IN ?", authorListStatuses)
+	}
+	if err := query.
 		Order("create_time desc").
 		Limit(200).
 		Find(&videos).Error; err != nil {
@@ -48,6 +164,50 @@ func (vr *VideoRepository) ListByAuthorID(ctx context.Context, authorID int64) (
 	return videos, nil
 }
 
+// ListReferencingKey 作者名下播放、封面或源文件地址指向 key 的视频；
+// 地址可能是 store.URL 生成的，也可能是旧版带域名的 /static/ 地址，先按后缀粗筛再用 KeyFromURL 精确比对
+func (vr *VideoRepository) ListReferencingKey(ctx context.Context, store storage.Store, key string, authorID uint) ([]Video, error) {
+	return referencingKey(vr.db.WithContext(ctx).Where("author_id = ?", authorID), store, key)
+}
+
+// IsKeyReferenced 除 exceptID 外是否还有视频（含回收站里尚未清理的）引用 key，清理文件前检查
+func (vr *VideoRepository) IsKeyReferenced(ctx context.Context, store storage.Store, key string, exceptID uint) (bool, error) {
+	videos, err := referencingKey(vr.db.WithContext(ctx).Unscoped().Where("id <> ?", exceptID), store, key)
+	return len(videos) > 0, err
+}
+
+func referencingKey(q *gorm.DB, store storage.Store, key string) ([]Video, error) {
+	pattern := "%/" + escapeLike(key)
+	var candidates []Video
+	if err := q.
+		Select("id", "author_id", "status", "visibility", "share_token", "play_url", "cover_url", "source_url").
+		Where("play_url LIKE ? ESCAPE '!' OR cover_url LIKE ? ESCAPE '!' OR source_url LIKE ? ESCAPE '!'", pattern, pattern, pattern).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	videos := candidates[:0]
+	for _, v := range candidates {
+		if referencesKey(store, &v, key) {
+			videos = append(videos, v)
+		}
+	}
+	return videos, nil
+}
+
+func referencesKey(store storage.Store, v *Video, key string) bool {
+	for _, u := range []string{v.PlayURL, v.CoverURL, v.SourceURL} {
+		if k, ok := storage.KeyFromURL(store, u); ok && k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeLike 转义 LIKE 通配符；用 ! 作转义符，MySQL 和 SQLite 写法一致
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
 func (vr *VideoRepository) GetByID(ctx context.Context, id uint) (*Video, error) {
 	var video Video
 	if err := vr.db.WithContext(ctx).First(&video, id).Error; err != nil {
@@ -56,6 +216,32 @@ func (vr *VideoRepository) GetByID(ctx context.Context, id uint) (*Video, error)
 	return &video, nil
 }
 
+// UpdateVisibility 已发布的视频在同一事务写入 video_visibility_changed。
+// 状态在更新后持锁重读，与 MarkReady 并发时两边总有一方看到对方的结果，不会漏发
+func (vr *VideoRepository) UpdateVisibility(ctx context.Context, id uint, visibility string, shareToken string) error {
+	return vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Video{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{"visibility": visibility, "share_token": shareToken}).Error; err != nil {
+			return err
+		}
+		var current Video
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "author_id", "status", "create_time").
+			First(&current, id).Error; err != nil {
+			return err
+		}
+		if current.Status != VideoStatusReady {
+			return nil
+		}
+		m, err := rabbitmq.VideoVisibilityChangedMessage(current.ID, current.AuthorID, current.CreateTime, visibility)
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, m)
+	})
+}
+
 func (vr *VideoRepository) UpdateLikesCount(ctx context.Context, id uint, likesCount int64) error {
 	if err := vr.db.WithContext(ctx).Model(&Video{}).
 		Where("id = ?", id).
@@ -85,27 +271,9 @@ func (vr *VideoRepository) UpdatePopularity(ctx context.Context, id uint, change
 	return nil
 }
 
-func (vr *VideoRepository) ChangeLikesCount(ctx context.Context, id uint, change int64) error {
-	if err := vr.db.WithContext(ctx).Model(&Video{}).
-		Where("id = ?", id).
-		UpdateColumn("likes_count", gorm.Expr("GREATEST(likes_count + ?, 0)", change)).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func (vr *VideoRepository) ChangePopularity(ctx context.Context, id uint, change int64) error {
-	if err := vr.db.WithContext(ctx).Model(&Video{}).
-		Where("id = ?", id).
-		UpdateColumn("popularity", gorm.Expr("GREATEST(popularity + ?, 0)", change)).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
 func (vr *VideoRepository) CountByAuthor(ctx context.Context, authorID uint) (int64, error) {
 	var count int64
-	if err := vr.db.WithContext(ctx).Model(&Video{}).Where("author_id = ?", authorID).Count(&count).Error; err != nil {
+	if err := vr.db.WithContext(ctx).Model(&Video{}).Where("author_id = ? AND status = ?", authorID, VideoStatusReady).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
@@ -118,3 +286,213 @@ func (vr *VideoRepository) TotalLikesByAuthor(ctx context.Context, authorID uint
 	}
 	return total, nil
 }
+
+// ListDrafts 草稿箱：草稿和定时发布的视频
+func (vr *VideoRepository) ListDrafts(ctx context.Context, authorID uint) ([]Video, error) {
+	var videos []Video
+	if err := vr.db.WithContext(ctx).
+		Where("author_id = ? AND status IN ?", authorID, UnpublishedStatuses).
+		Order("id desc").
+		Limit(200).
+		Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// ListDueScheduled 到点待发布的定时视频，先到点的先发
+func (vr *VideoRepository) ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]Video, error) {
+	var videos []Video
+	if err := vr.db.WithContext(ctx).
+		Where("status = ? AND publish_at <= ?", VideoStatusScheduled, now).
+		Order("publish_at asc, id asc").
+		Limit(limit).
+		Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// UpdateDraft 只改仍在草稿箱里的视频；retag 为 true 时按新的标题和简介重建话题
+func (vr *VideoRepository) UpdateDraft(ctx context.Context, video *Video, retag bool) (bool, error) {
+	updated := false
+	err := vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Video{}).
+			Where("id = ? AND status IN ?", video.ID, UnpublishedStatuses).
+			Updates(map[string]interface{}{
+				"title":       video.Title,
+				"description": video.Description,
+				"cover_url":   video.CoverURL,
+				"visibility":  video.Visibility,
+				"share_token": video.ShareToken,
+				"status":      video.Status,
+				"publish_at":  video.PublishAt,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		if !retag {
+			return nil
+		}
+		_, err := syncTags(tx, video)
+		return err
+	})
+	return updated, err
+}
+
+// UpdateMetadata 修改已发布视频的标题、简介和封面，同一事务里同步话题并写修改记录；
+// 返回修改后的话题 ID，retag 为 false 时为 nil
+func (vr *VideoRepository) UpdateMetadata(ctx context.Context, video *Video, edit *VideoEdit, retag bool) ([]uint, error) {
+	var tagIDs []uint
+	err := vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Video{}).
+			Where("id = ? AND status NOT IN ?", video.ID, UnpublishedStatuses).
+			Updates(map[string]interface{}{
+				"title":       video.Title,
+				"description": video.Description,
+				"cover_url":   video.CoverURL,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if retag {
+			ids, err := syncTags(tx, video)
+			if err != nil {
+				return err
+			}
+			tagIDs = ids
+		}
+		return tx.Create(edit).Error
+	})
+	return tagIDs, err
+}
+
+// ListEdits 修改记录，最近的在前
+func (vr *VideoRepository) ListEdits(ctx context.Context, videoID uint) ([]VideoEdit, error) {
+	var edits []VideoEdit
+	if err := vr.db.WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("id desc").
+		Limit(100).
+		Find(&edits).Error; err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// DeleteDraft 删除还没发布的视频及其话题关联
+func (vr *VideoRepository) DeleteDraft(ctx context.Context, id uint) (bool, error) {
+	deleted := false
+	err := vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Where("id = ? AND status IN ?", id, UnpublishedStatuses).Delete(&Video{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		deleted = true
+		return tx.Where("video_id = ?", id).Delete(&VideoTag{}).Error
+	})
+	return deleted, err
+}
+
+// MarkLive 草稿或定时视频进入发布流程；同一事务写入发布消息（ready）或转码请求（pending）
+func (vr *VideoRepository) MarkLive(ctx context.Context, video *Video, status string, at time.Time, from []string) (bool, error) {
+	live := false
+	err := vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Video{}).
+			Where("id = ? AND status IN ?", video.ID, from).
+			Updates(map[string]interface{}{"status": status, "create_time": at, "publish_at": nil})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		live = true
+		switch status {
+		case VideoStatusPending:
+			return enqueueTranscode(tx, video)
+		case VideoStatusReady:
+			return enqueuePublished(tx, video, at)
+		}
+		return nil
+	})
+	return live, err
+}
+
+// MarkProcessing 抢占转码任务并持有租约到 until。只能从 pending 或租约已过期的 processing 认领，
+// 重复投递的消息不会和正在执行的转码抢同一个输出目录；已 ready/failed 或租约未过期时返回 false
+func (vr *VideoRepository) MarkProcessing(ctx context.Context, id uint, now time.Time, until time.Time) (bool, error) {
+	res := vr.db.WithContext(ctx).Model(&Video{}).
+		Where("id = ?", id).
+		Where("status = ? OR (status = ? AND (processing_until IS NULL OR processing_until < ?))",
+			VideoStatusPending, VideoStatusProcessing, now).
+		Updates(map[string]interface{}{"status": VideoStatusProcessing, "processing_until": until})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// RenewProcessing 转码进行中续租
+func (vr *VideoRepository) RenewProcessing(ctx context.Context, id uint, until time.Time) error {
+	return vr.db.WithContext(ctx).Model(&Video{}).
+		Where("id = ? AND status = ?", id, VideoStatusProcessing).
+		Update("processing_until", until).Error
+}
+
+// MarkReady 转码完成：更新播放地址并在同一事务写入发布消息，视频此时才进入各条 feed。
+// 转码期间作者可能改了可见范围，发布消息用更新后持锁重读的 visibility
+func (vr *VideoRepository) MarkReady(ctx context.Context, video *Video, playURL string) error {
+	return vr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Video{}).
+			Where("id = ? AND status IN ?", video.ID, []string{VideoStatusPending, VideoStatusProcessing}).
+			Updates(map[string]interface{}{"status": VideoStatusReady, "play_url": playURL})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		var current Video
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("visibility").First(&current, video.ID).Error; err != nil {
+			return err
+		}
+		video.Visibility = current.Visibility
+		return enqueuePublished(tx, video, video.CreateTime)
+	})
+}
+
+func (vr *VideoRepository) MarkFailed(ctx context.Context, id uint) error {
+	return vr.db.WithContext(ctx).Model(&Video{}).
+		Where("id = ? AND status IN ?", id, []string{VideoStatusPending, VideoStatusProcessing}).
+		Update("status", VideoStatusFailed).Error
+}
+
+// enqueueTranscode 在同一事务写入转码请求，投递失败由 outbox 重试，视频不会卡在 pending
+func enqueueTranscode(tx *gorm.DB, video *Video) error {
+	m, err := rabbitmq.TranscodeRequestMessage(video.ID)
+	if err != nil {
+		return err
+	}
+	return outbox.Enqueue(tx, m)
+}
+
+// enqueuePublished 在同一事务写入发布事件，视频经 outbox 进入全站时间线、粉丝收件箱和搜索
+func enqueuePublished(tx *gorm.DB, video *Video, createTime time.Time) error {
+	m, err := rabbitmq.VideoPublishedMessage(video.ID, video.AuthorID, createTime, video.Visibility)
+	if err != nil {
+		return err
+	}
+	return outbox.Enqueue(tx, m)
+}
@@ -4,26 +4,40 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"strconv"
+	"fmt"
 	"strings"
 	"time"
 
 	"feedsystem_video_go/internal/apierror"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/social"
 
 	"gorm.io/gorm"
 )
 
 type VideoService struct {
-	repo         *VideoRepository
-	cache        *rediscache.Client
-	cacheTTL     time.Duration
-	popularityMQ *rabbitmq.PopularityMQ
+	repo             *VideoRepository
+	cache            *rediscache.Client
+	cacheTTL         time.Duration
+	transcodeEnabled bool
+	socialRepo       *social.SocialRepository
+	searchSync       SearchSync
 }
 
-func NewVideoService(repo *VideoRepository, cache *rediscache.Client, popularityMQ *rabbitmq.PopularityMQ) *VideoService {
-	return &VideoService{repo: repo, cache: cache, cacheTTL: 5 * time.Minute, popularityMQ: popularityMQ}
+// SearchSync 视频删除或可见范围变化时同步搜索索引，由 search.Indexer 实现；定义在这里避免 video 依赖 search
+type SearchSync interface {
+	Published(ctx context.Context, videoID uint) error
+	Deleted(ctx context.Context, videoID uint) error
+}
+
+func NewVideoService(repo *VideoRepository, cache *rediscache.Client, transcodeEnabled bool, socialRepo *social.SocialRepository) *VideoService {
+	return &VideoService{repo: repo, cache: cache, cacheTTL: 5 * time.Minute, transcodeEnabled: transcodeEnabled, socialRepo: socialRepo}
+}
+
+func (vs *VideoService) SetSearchSync(sync SearchSync) {
+	vs.searchSync = sync
 }
 
 func (vs *VideoService) Publish(ctx context.Context, video *Video) error {
@@ -43,38 +57,109 @@ func (vs *VideoService) Publish(ctx context.Context, video *Video) error {
 	if video.CoverURL == "" {
 		return errors.New("cover url is required")
 	}
-
-	//事务保证视频写入库和消息写入本地消息表的一致性
-	err := vs.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(video).Error; err != nil {
+	visibility, err := NormalizeVisibility(video.Visibility)
+	if err != nil {
+		return err
+	}
+	video.Visibility = visibility
+	if visibility == VisibilityUnlisted {
+		if video.ShareToken, err = randHex(16); err != nil {
 			return err
 		}
+	}
 
-		msg := OutboxMsg{
-			VideoID:    video.ID,
-			EventType:  "video_published",
-			Status:     "pending",
-			CreateTime: video.CreateTime,
+	// 开启转码时先落 pending，转码完成后再写发布消息；否则直接以原文件发布。
+	// 草稿和定时发布只落库，正式发布时再走同样的流程
+	video.SourceURL = video.PlayURL
+	switch {
+	case video.Status == VideoStatusDraft && video.PublishAt != nil:
+		return fmt.Errorf("%w: draft and publish_at cannot be used together", apierror.ErrValidation)
+	case video.PublishAt != nil:
+		if err := validatePublishAt(*video.PublishAt, time.Now()); err != nil {
+			return err
 		}
+		video.Status = VideoStatusScheduled
+	case video.Status == VideoStatusDraft:
+	default:
+		video.Status = vs.liveStatus()
+	}
 
-		if err := tx.Create(&msg).Error; err != nil {
+	//事务保证视频写入库和消息写入本地消息表的一致性；转码请求也走 outbox，不会因投递失败卡在 pending
+	return vs.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(video).Error; err != nil {
 			return err
 		}
 
-		tags := ExtractTags(video.Title + " " + video.Description)
-		for _, tagName := range tags {
-			var tag Tag
-			if err := tx.Where("name = ?", tagName).FirstOrCreate(&tag, Tag{Name: tagName}).Error; err != nil {
-				return err
-			}
-			if err := tx.Create(&VideoTag{VideoID: video.ID, TagID: tag.ID}).Error; err != nil {
-				return err
-			}
+		if err := createTags(tx, video); err != nil {
+			return err
+		}
+		switch video.Status {
+		case VideoStatusPending:
+			return enqueueTranscode(tx, video)
+		case VideoStatusReady:
+			return enqueuePublished(tx, video, video.CreateTime)
 		}
 		return nil
 	})
-	return err
+}
 
+// liveStatus 正式发布时的初始状态
+func (vs *VideoService) liveStatus() string {
+	if vs.transcodeEnabled {
+		return VideoStatusPending
+	}
+	return VideoStatusReady
+}
+
+// syncTags 按标题和简介重新提取话题，只删掉不再出现的、补上新增的，返回现在的话题 ID
+func syncTags(tx *gorm.DB, video *Video) ([]uint, error) {
+	names := ExtractTags(video.Title + " " + video.Description)
+	want := make([]uint, 0, len(names))
+	for _, tagName := range names {
+		var tag Tag
+		if err := tx.Where("name = ?", tagName).FirstOrCreate(&tag, Tag{Name: tagName}).Error; err != nil {
+			return nil, err
+		}
+		want = append(want, tag.ID)
+	}
+	var have []uint
+	if err := tx.Model(&VideoTag{}).Where("video_id = ?", video.ID).Pluck("tag_id", &have).Error; err != nil {
+		return nil, err
+	}
+	stale := tx.Where("video_id = ?", video.ID)
+	if len(want) > 0 {
+		stale = stale.Where("tag_id NOT IN ?", want)
+	}
+	if err := stale.Delete(&VideoTag{}).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[uint]bool, len(have))
+	for _, id := range have {
+		existing[id] = true
+	}
+	for _, id := range want {
+		if existing[id] {
+			continue
+		}
+		if err := tx.Create(&VideoTag{VideoID: video.ID, TagID: id}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return want, nil
+}
+
+func createTags(tx *gorm.DB, video *Video) error {
+	tags := ExtractTags(video.Title + " " + video.Description)
+	for _, tagName := range tags {
+		var tag Tag
+		if err := tx.Where("name = ?", tagName).FirstOrCreate(&tag, Tag{Name: tagName}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&VideoTag{VideoID: video.ID, TagID: tag.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (vs *VideoService) Delete(ctx context.Context, id uint, authorID uint) error {
@@ -88,25 +173,200 @@ func (vs *VideoService) Delete(ctx context.Context, id uint, authorID uint) erro
 	if video.AuthorID != authorID {
 		return apierror.ErrUnauthorized
 	}
-	if err := vs.repo.DeleteVideo(ctx, id); err != nil {
+	// 先进回收站，时间线、热榜和搜索由 outbox 的 video_deleted 异步清理（CleanupDeleted）
+	if err := vs.repo.SoftDelete(ctx, video); err != nil {
 		return err
 	}
-	if vs.cache != nil {
-		cacheKey := vs.cache.Key("video:detail:id=%d", id)
-		_ = vs.cache.Del(context.Background(), cacheKey)
-	}
+	InvalidateVideoCaches(ctx, vs.cache, id)
 	return nil
 }
 
-func (vs *VideoService) ListByAuthorID(ctx context.Context, authorID uint) ([]Video, error) {
-	videos, err := vs.repo.ListByAuthorID(ctx, int64(authorID))
+// ListByAuthorID 作者主页：作者本人看到全部，粉丝多看到粉丝可见的，其他人只看到公开的
+func (vs *VideoService) ListByAuthorID(ctx context.Context, authorID uint, viewerAccountID uint) ([]Video, error) {
+	var visibilities []string
+	if viewerAccountID == 0 || viewerAccountID != authorID {
+		visibilities = []string{VisibilityPublic}
+		isFollower, err := vs.isFollower(ctx, viewerAccountID, authorID)
+		if err != nil {
+			return nil, err
+		}
+		if isFollower {
+			visibilities = FollowingVisibilities
+		}
+	}
+	videos, err := vs.repo.ListByAuthorID(ctx, int64(authorID), visibilities)
 	if err != nil {
 		return nil, err
 	}
 	return videos, nil
 }
 
-func (vs *VideoService) GetDetail(ctx context.Context, id uint) (*Video, error) {
+func (vs *VideoService) isFollower(ctx context.Context, viewerAccountID, authorID uint) (bool, error) {
+	if viewerAccountID == 0 || vs.socialRepo == nil {
+		return false, nil
+	}
+	return vs.socialRepo.IsFollowed(ctx, &social.Social{FollowerID: viewerAccountID, VloggerID: authorID})
+}
+
+// GetDetail 无权查看时与视频不存在一样返回 404，不暴露私密视频是否存在；
+// 草稿、定时、转码中和转码失败的视频（含原始上传的 source_url）只有作者能看
+func (vs *VideoService) GetDetail(ctx context.Context, id uint, viewerAccountID uint, shareToken string) (*Video, error) {
+	video, err := vs.loadDetail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	isAuthor := viewerAccountID != 0 && viewerAccountID == video.AuthorID
+	if video.Status != VideoStatusReady && !isAuthor {
+		return nil, gorm.ErrRecordNotFound
+	}
+	visible, err := canSee(ctx, vs.socialRepo, video, viewerAccountID, shareToken)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return video, nil
+}
+
+// SetVisibility 修改可见范围：进出 unlisted 时重置分享 token；时间线、收件箱和搜索由 outbox 事件驱动更新
+func (vs *VideoService) SetVisibility(ctx context.Context, id uint, authorID uint, visibility string) (*Video, error) {
+	visibility, err := NormalizeVisibility(visibility)
+	if err != nil {
+		return nil, err
+	}
+	video, err := vs.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if video.AuthorID != authorID {
+		return nil, apierror.ErrUnauthorized
+	}
+	if video.Visibility == visibility {
+		return video, nil
+	}
+	shareToken := ""
+	if visibility == VisibilityUnlisted {
+		if shareToken, err = randHex(16); err != nil {
+			return nil, err
+		}
+	}
+	// 已发布的视频通过 outbox 通知时间线、粉丝收件箱和搜索索引按新范围更新
+	if err := vs.repo.UpdateVisibility(ctx, id, visibility, shareToken); err != nil {
+		return nil, err
+	}
+	video.Visibility = visibility
+	video.ShareToken = shareToken
+
+	InvalidateVideoCaches(context.Background(), vs.cache, id)
+	return video, nil
+}
+
+// ShareToken 只有作者能拿到 unlisted 视频的分享 token
+func (vs *VideoService) ShareToken(ctx context.Context, id uint, authorID uint) (string, error) {
+	video, err := vs.repo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if video.AuthorID != authorID {
+		return "", apierror.ErrUnauthorized
+	}
+	if video.Visibility != VisibilityUnlisted || video.ShareToken == "" {
+		return "", fmt.Errorf("%w: video is not unlisted", apierror.ErrValidation)
+	}
+	return video.ShareToken, nil
+}
+
+// Update 发布后修改标题、简介和封面（草稿用 UpdateDraft）：同步话题、记录修改历史，
+// 并让各级缓存、搜索索引和话题热度跟上
+func (vs *VideoService) Update(ctx context.Context, authorID uint, req UpdateVideoRequest) (*Video, error) {
+	video, err := vs.repo.GetByID(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if video.AuthorID != authorID {
+		return nil, apierror.ErrUnauthorized
+	}
+	if video.IsUnpublished() {
+		return nil, fmt.Errorf("%w: use /video/draft/update for drafts and scheduled videos", apierror.ErrValidation)
+	}
+	edit := &VideoEdit{
+		VideoID:        video.ID,
+		EditorID:       authorID,
+		OldTitle:       video.Title,
+		OldDescription: video.Description,
+		OldCoverURL:    video.CoverURL,
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: title is required", apierror.ErrValidation)
+		}
+		video.Title = title
+	}
+	if req.Description != nil {
+		video.Description = *req.Description
+	}
+	if req.CoverURL != nil {
+		cover := strings.TrimSpace(*req.CoverURL)
+		if cover == "" {
+			return nil, fmt.Errorf("%w: cover url is required", apierror.ErrValidation)
+		}
+		video.CoverURL = cover
+	}
+	if video.Title == edit.OldTitle && video.Description == edit.OldDescription && video.CoverURL == edit.OldCoverURL {
+		return video, nil
+	}
+	edit.NewTitle, edit.NewDescription, edit.NewCoverURL = video.Title, video.Description, video.CoverURL
+	retag := video.Title != edit.OldTitle || video.Description != edit.OldDescription
+
+	tagIDs, err := vs.repo.UpdateMetadata(ctx, video, edit, retag)
+	if err != nil {
+		return nil, err
+	}
+	InvalidateVideoCaches(context.Background(), vs.cache, video.ID)
+	if video.Status != VideoStatusReady || !video.IsPublic() {
+		return video, nil
+	}
+	if retag {
+		// 话题集合只为已发布的公开视频缓存（见 TagService.Published）
+		refreshVideoTags(ctx, vs.cache, video.ID, tagIDs)
+		if vs.searchSync != nil {
+			_ = vs.searchSync.Published(ctx, video.ID)
+		}
+	}
+	return video, nil
+}
+
+// ListEdits 修改记录只给作者看
+func (vs *VideoService) ListEdits(ctx context.Context, authorID uint, id uint) ([]VideoEdit, error) {
+	video, err := vs.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if video.AuthorID != authorID {
+		return nil, apierror.ErrUnauthorized
+	}
+	return vs.repo.ListEdits(ctx, id)
+}
+
+// loadDetail 读详情缓存，未命中时加锁回源，避免热点视频击穿
+// detailCacheEntry 详情缓存要带上 share_token 做 unlisted 校验，Video 的 JSON 里不输出它
+type detailCacheEntry struct {
+	*Video
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+func decodeDetailCache(b []byte) (*Video, error) {
+	entry := detailCacheEntry{Video: &Video{}}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	entry.Video.ShareToken = entry.ShareToken
+	return entry.Video, nil
+}
+
+func (vs *VideoService) loadDetail(ctx context.Context, id uint) (*Video, error) {
 	cacheKey := vs.cache.Key("video:detail:id=%d", id)
 
 	getCached := func() (*Video, bool) {
@@ -117,15 +377,15 @@ func (vs *VideoService) GetDetail(ctx context.Context, id uint) (*Video, error)
 		if err != nil {
 			return nil, false
 		}
-		var cached Video
-		if err := json.Unmarshal(b, &cached); err != nil {
+		cached, err := decodeDetailCache(b)
+		if err != nil {
 			return nil, false
 		}
-		return &cached, true
+		return cached, true
 	}
 
 	setCached := func(video *Video) {
-		b, err := json.Marshal(video)
+		b, err := json.Marshal(detailCacheEntry{Video: video, ShareToken: video.ShareToken})
 		if err != nil {
 			return
 		}
@@ -143,9 +403,8 @@ func (vs *VideoService) GetDetail(ctx context.Context, id uint) (*Video, error)
 		b, err := vs.cache.GetBytes(opCtx, cacheKey)
 		cancel()
 		if err == nil {
-			var cached Video
-			if err := json.Unmarshal(b, &cached); err == nil {
-				return &cached, nil
+			if cached, err := decodeDetailCache(b); err == nil {
+				return cached, nil
 			}
 		} else if rediscache.IsMiss(err) {
 			lockKey := "lock:" + cacheKey
@@ -200,31 +459,21 @@ func (vs *VideoService) UpdateLikesCount(ctx context.Context, id uint, likesCoun
 	return nil
 }
 
+// UpdatePopularity 数据库热度和热榜事件同一事务写入，热榜窗口由 popularity worker 更新
 func (vs *VideoService) UpdatePopularity(ctx context.Context, id uint, change int64) error {
-	if err := vs.repo.UpdatePopularity(ctx, id, change); err != nil {
-		return err
-	}
-
-	if vs.popularityMQ != nil {
-		if err := vs.popularityMQ.Update(ctx, id, change); err == nil {
-			return nil
+	if err := vs.repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Video{}).Where("id = ?", id).
+			Update("popularity", gorm.Expr("popularity + ?", change)).Error; err != nil {
+			return err
 		}
+		m, err := rabbitmq.PopularityMessage(id, change)
+		if err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, m)
+	}); err != nil {
+		return err
 	}
-
-	if vs.cache != nil {
-		// 1) 详情缓存：直接失效（最简单靠谱）
-		_ = vs.cache.Del(context.Background(), vs.cache.Key("video:detail:id=%d", id))
-
-		// 2) 热榜：写到“时间窗ZSET”，不要用 detail key
-		now := time.Now().UTC().Truncate(time.Minute)
-		windowKey := vs.cache.Key("hot:video:1m:%s", now.Format("200601021504"))
-		member := strconv.FormatUint(uint64(id), 10)
-
-		opCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
-		defer cancel()
-
-		_ = vs.cache.ZincrBy(opCtx, windowKey, member, float64(change))
-		_ = vs.cache.Expire(opCtx, windowKey, 2*time.Hour)
-	}
+	vs.invalidateDetail(id)
 	return nil
 }
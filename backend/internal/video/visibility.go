@@ -0,0 +1,84 @@
+package video
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/social"
+)
+
+// 视频可见范围：public 所有人；followers 仅粉丝；private 仅作者自己；
+// unlisted 不进任何列表和搜索，只能凭分享 token 打开详情
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+	VisibilityUnlisted  = "unlisted"
+)
+
+// FollowingVisibilities 关注关系下能看到的可见范围
+var FollowingVisibilities = []string{VisibilityPublic, VisibilityFollowers}
+
+// NormalizeVisibility 空值视为 public
+func NormalizeVisibility(visibility string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(visibility)); v {
+	case "":
+		return VisibilityPublic, nil
+	case VisibilityPublic, VisibilityFollowers, VisibilityPrivate, VisibilityUnlisted:
+		return v, nil
+	default:
+		return "", fmt.Errorf("%w: unknown visibility %q", apierror.ErrValidation, visibility)
+	}
+}
+
+// IsPublic 旧数据没有 visibility 字段时按 public 处理
+func (v *Video) IsPublic() bool {
+	return v.Visibility == "" || v.Visibility == VisibilityPublic
+}
+
+// NeedsFollowCheck 只有粉丝可见且观看者不是作者时才需要查关注关系
+func (v *Video) NeedsFollowCheck(viewerAccountID uint) bool {
+	return v.Visibility == VisibilityFollowers && viewerAccountID != 0 && viewerAccountID != v.AuthorID
+}
+
+// CanView 判断观看者能否看到视频；粉丝可见时 isFollower 由调用方查好传入，
+// shareToken 只对 unlisted 生效
+func (v *Video) CanView(viewerAccountID uint, isFollower bool, shareToken string) bool {
+	if viewerAccountID != 0 && viewerAccountID == v.AuthorID {
+		return true
+	}
+	switch v.Visibility {
+	case "", VisibilityPublic:
+		return true
+	case VisibilityFollowers:
+		return isFollower
+	case VisibilityUnlisted:
+		return shareToken != "" && v.ShareToken != "" &&
+			subtle.ConstantTimeCompare([]byte(shareToken), []byte(v.ShareToken)) == 1
+	default:
+		return false
+	}
+}
+
+// canSee 按可见范围判断观看者能否看到视频，粉丝可见时查关注关系；不看视频状态
+func canSee(ctx context.Context, socialRepo *social.SocialRepository, v *Video, viewerAccountID uint, shareToken string) (bool, error) {
+	isFollower := false
+	if v.NeedsFollowCheck(viewerAccountID) && socialRepo != nil {
+		var err error
+		if isFollower, err = socialRepo.IsFollowed(ctx, &social.Social{FollowerID: viewerAccountID, VloggerID: v.AuthorID}); err != nil {
+			return false, err
+		}
+	}
+	return v.CanView(viewerAccountID, isFollower, shareToken), nil
+}
+
+// canInteract 点赞、评论等互动：视频已转码完成且观看者看得到
+func canInteract(ctx context.Context, socialRepo *social.SocialRepository, v *Video, viewerAccountID uint, shareToken string) (bool, error) {
+	if v.Status != VideoStatusReady {
+		return false, nil
+	}
+	return canSee(ctx, socialRepo, v, viewerAccountID, shareToken)
+}
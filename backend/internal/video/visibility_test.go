@@ -0,0 +1,142 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"feedsystem_video_go/internal/apierror"
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/outbox"
+)
+
+func TestNormalizeVisibility(t *testing.T) {
+	if v, err := NormalizeVisibility(""); err != nil || v != VisibilityPublic {
+		t.Fatalf("expected empty visibility to default to public, got %q %v", v, err)
+	}
+	if v, err := NormalizeVisibility(" Followers "); err != nil || v != VisibilityFollowers {
+		t.Fatalf("expected followers, got %q %v", v, err)
+	}
+	if _, err := NormalizeVisibility("friends"); !errors.Is(err, apierror.ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestVideoCanView(t *testing.T) {
+	cases := []struct {
+		name       string
+		video      Video
+		viewer     uint
+		isFollower bool
+		token      string
+		want       bool
+	}{
+		{"legacy row without visibility", Video{AuthorID: 1}, 0, false, "", true},
+		{"public to anonymous", Video{AuthorID: 1, Visibility: VisibilityPublic}, 0, false, "", true},
+		{"followers to stranger", Video{AuthorID: 1, Visibility: VisibilityFollowers}, 2, false, "", false},
+		{"followers to follower", Video{AuthorID: 1, Visibility: VisibilityFollowers}, 2, true, "", true},
+		{"private to follower", Video{AuthorID: 1, Visibility: VisibilityPrivate}, 2, true, "", false},
+		{"private to author", Video{AuthorID: 1, Visibility: VisibilityPrivate}, 1, false, "", true},
+		{"unlisted without token", Video{AuthorID: 1, Visibility: VisibilityUnlisted, ShareToken: "abc"}, 2, true, "", false},
+		{"unlisted with wrong token", Video{AuthorID: 1, Visibility: VisibilityUnlisted, ShareToken: "abc"}, 0, false, "abd", false},
+		{"unlisted with token", Video{AuthorID: 1, Visibility: VisibilityUnlisted, ShareToken: "abc"}, 0, false, "abc", true},
+		{"token ignored for private", Video{AuthorID: 1, Visibility: VisibilityPrivate, ShareToken: "abc"}, 0, false, "abc", false},
+	}
+	for _, c := range cases {
+		if got := c.video.CanView(c.viewer, c.isFollower, c.token); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestDetailCacheKeepsShareToken(t *testing.T) {
+	v := &Video{ID: 3, AuthorID: 1, Visibility: VisibilityUnlisted, ShareToken: "abc"}
+	b, err := json.Marshal(detailCacheEntry{Video: v, ShareToken: v.ShareToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeDetailCache(b)
+	if err != nil || got.ID != 3 || got.ShareToken != "abc" {
+		t.Fatalf("decoded %+v %v", got, err)
+	}
+}
+
+func TestSetVisibilityEnqueuesChangeForPublishedVideos(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &outbox.Event{})
+	vs := NewVideoService(NewVideoRepository(db), nil, false, nil)
+	ctx := context.Background()
+
+	ready := &Video{AuthorID: 1, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c", Status: VideoStatusReady, Visibility: VisibilityPrivate}
+	draft := &Video{AuthorID: 1, Username: "u", Title: "d", PlayURL: "p", CoverURL: "c", Status: VideoStatusDraft, Visibility: VisibilityPrivate}
+	for _, v := range []*Video{ready, draft} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := vs.SetVisibility(ctx, v.ID, 1, VisibilityPublic); err != nil {
+			t.Fatalf("set visibility: %v", err)
+		}
+	}
+
+	var events []outbox.Event
+	db.Find(&events)
+	if len(events) != 1 || events[0].Type != rabbitmq.VideoVisibilityChangedType || events[0].AggregateID != ready.ID {
+		t.Fatalf("expected one visibility change for video %d, got %+v", ready.ID, events)
+	}
+	var evt rabbitmq.TimelineEvent
+	if err := json.Unmarshal(events[0].Payload, &evt); err != nil || evt.Visibility != VisibilityPublic || evt.AuthorID != 1 {
+		t.Fatalf("unexpected payload %s: %v", events[0].Payload, err)
+	}
+}
+
+func TestGetDetailHidesUnreadyVideosFromOthers(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &outbox.Event{})
+	vs := NewVideoService(NewVideoRepository(db), nil, false, nil)
+	ctx := context.Background()
+	for _, status := range []string{VideoStatusPending, VideoStatusProcessing, VideoStatusFailed, VideoStatusDraft} {
+		v := &Video{AuthorID: 1, Username: "u", Title: status, PlayURL: "p", CoverURL: "c", SourceURL: "s", Status: status, Visibility: VisibilityPublic}
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := vs.GetDetail(ctx, v.ID, 2, ""); err == nil {
+			t.Errorf("%s video should be hidden from other viewers", status)
+		}
+		if _, err := vs.GetDetail(ctx, v.ID, 0, ""); err == nil {
+			t.Errorf("%s video should be hidden from anonymous viewers", status)
+		}
+		if _, err := vs.GetDetail(ctx, v.ID, 1, ""); err != nil {
+			t.Errorf("author should see own %s video: %v", status, err)
+		}
+	}
+}
+
+func TestMarkReadyPublishesVisibilityChangedDuringTranscode(t *testing.T) {
+	db := dbtest.Open(t, &Video{}, &outbox.Event{})
+	repo := NewVideoRepository(db)
+	vs := NewVideoService(repo, nil, false, nil)
+	ctx := context.Background()
+
+	v := &Video{AuthorID: 1, Username: "u", Title: "t", PlayURL: "p", CoverURL: "c", Status: VideoStatusProcessing, Visibility: VisibilityPublic}
+	if err := db.Create(v).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 转码 worker 手里的是转码开始前读到的副本
+	stale := *v
+	if _, err := vs.SetVisibility(ctx, v.ID, 1, VisibilityPrivate); err != nil {
+		t.Fatalf("set visibility: %v", err)
+	}
+	if err := repo.MarkReady(ctx, &stale, "hls/1/master.m3u8"); err != nil {
+		t.Fatalf("mark ready: %v", err)
+	}
+
+	var events []outbox.Event
+	db.Find(&events)
+	if len(events) != 1 || events[0].Type != rabbitmq.VideoPublishedType {
+		t.Fatalf("expected only the publish event, got %+v", events)
+	}
+	var evt rabbitmq.TimelineEvent
+	if err := json.Unmarshal(events[0].Payload, &evt); err != nil || evt.Visibility != VisibilityPrivate {
+		t.Fatalf("publish event should carry the current visibility, got %s: %v", events[0].Payload, err)
+	}
+}
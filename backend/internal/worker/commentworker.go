@@ -4,27 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"feedsystem_video_go/internal/creator"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	"feedsystem_video_go/internal/video"
 	"log"
-	"strings"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
 )
 
 type CommentWorker struct {
-	ch       *amqp.Channel
-	comments *video.CommentRepository
-	videos   *video.VideoRepository
-	queue    string
+	ch     *amqp.Channel
+	videos *video.VideoRepository
+	stats  *creator.StatRepository
+	ledger *DBLedger
+	queue  string
 }
 
-func NewCommentWorker(ch *amqp.Channel, comments *video.CommentRepository, videos *video.VideoRepository, queue string) *CommentWorker {
-	return &CommentWorker{ch: ch, comments: comments, videos: videos, queue: queue}
+func NewCommentWorker(ch *amqp.Channel, videos *video.VideoRepository, stats *creator.StatRepository, ledger *DBLedger, queue string) *CommentWorker {
+	return &CommentWorker{ch: ch, videos: videos, stats: stats, ledger: ledger, queue: queue}
 }
 
 func (w *CommentWorker) Run(ctx context.Context) error {
-	if w == nil || w.ch == nil || w.comments == nil || w.videos == nil {
+	if w == nil || w.ch == nil || w.videos == nil || w.ledger == nil {
 		return errors.New("comment worker is not initialized")
 	}
 	if w.queue == "" {
@@ -58,7 +60,10 @@ func (w *CommentWorker) Run(ctx context.Context) error {
 }
 
 func (w *CommentWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
-	if err := w.process(ctx, d.Body); err != nil {
+	err := w.ledger.Apply(ctx, deliveryEventID(d), func(tx *gorm.DB) error {
+		return w.process(ctx, tx, d.Body)
+	})
+	if err != nil {
 		retryCount := rabbitmq.GetRetryCount(d)
 		if retryCount >= rabbitmq.MaxRetryCount {
 			log.Printf("comment worker: max retries exceeded (%d), moving to DLX: %v", retryCount, err)
@@ -72,56 +77,30 @@ func (w *CommentWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
 	_ = d.Ack(false)
 }
 
-func (w *CommentWorker) process(ctx context.Context, body []byte) error {
+func (w *CommentWorker) process(ctx context.Context, tx *gorm.DB, body []byte) error {
 	var evt rabbitmq.CommentEvent
 	if err := json.Unmarshal(body, &evt); err != nil {
 		return nil
 	}
 	switch evt.Action {
 	case "publish":
-		return w.applyPublish(ctx, &evt)
-	case "delete":
-		return w.applyDelete(ctx, &evt)
+		return w.applyPublish(ctx, tx, &evt)
 	default:
 		return nil
 	}
 }
 
-func (w *CommentWorker) applyPublish(ctx context.Context, evt *rabbitmq.CommentEvent) error {
-	if evt == nil || evt.VideoID == 0 || evt.AuthorID == 0 || strings.TrimSpace(evt.Content) == "" {
-		return nil
-	}
-
-	ok, err := w.videos.IsExist(ctx, evt.VideoID)
-	if err != nil {
-		return err
-	}
-	if !ok {
-		return nil
-	}
-
-	c := &video.Comment{
-		Username: strings.TrimSpace(evt.Username),
-		VideoID:  evt.VideoID,
-		AuthorID: evt.AuthorID,
-		Content:  strings.TrimSpace(evt.Content),
-	}
-	if err := w.comments.CreateComment(ctx, c); err != nil {
-		return err
-	}
-	return w.videos.ChangePopularity(ctx, evt.VideoID, 1)
-}
-
-func (w *CommentWorker) applyDelete(ctx context.Context, evt *rabbitmq.CommentEvent) error {
-	if evt == nil || evt.CommentID == 0 {
+// applyPublish 评论已由接口层与事件同一事务写入，这里只记创作者指标；删除事件没有后续处理
+func (w *CommentWorker) applyPublish(ctx context.Context, tx *gorm.DB, evt *rabbitmq.CommentEvent) error {
+	if evt == nil || evt.VideoID == 0 {
 		return nil
 	}
-	c, err := w.comments.GetByID(ctx, evt.CommentID)
+	v, err := lookupVideo(ctx, w.videos, evt.VideoID)
 	if err != nil {
 		return err
 	}
-	if c == nil {
+	if v == nil {
 		return nil
 	}
-	return w.comments.DeleteComment(ctx, c)
+	return recordCreatorStat(ctx, tx, w.stats, v.AuthorID, evt.VideoID, evt.OccurredAt, creator.StatDelta{Comments: 1, PopularityDelta: 1})
 }
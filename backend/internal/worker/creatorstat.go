@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"feedsystem_video_go/internal/creator"
+	"feedsystem_video_go/internal/video"
+
+	"gorm.io/gorm"
+)
+
+// lookupVideo 取视频用于拿作者 ID，视频已删除时返回 nil
+func lookupVideo(ctx context.Context, videos *video.VideoRepository, videoID uint) (*video.Video, error) {
+	v, err := videos.GetByID(ctx, videoID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return v, err
+}
+
+// recordCreatorStat 在消费台账的事务里写创作者统计，失败时整条消息回滚重试
+func recordCreatorStat(ctx context.Context, tx *gorm.DB, stats *creator.StatRepository, authorID, videoID uint, at time.Time, d creator.StatDelta) error {
+	if err := stats.WithTx(tx).Record(ctx, authorID, videoID, at, d); err != nil {
+		return fmt.Errorf("record creator stat author=%d video=%d: %w", authorID, videoID, err)
+	}
+	return nil
+}
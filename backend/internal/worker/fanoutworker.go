@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feedsystem_video_go/internal/feed"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/social"
+	"feedsystem_video_go/internal/video"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
+)
+
+const fanoutBatchSize = 500
+
+// FanoutWorker 消费视频发布和可见范围变更事件，把视频写入粉丝的关注流收件箱
+type FanoutWorker struct {
+	ch     *amqp.Channel
+	videos *video.VideoRepository
+	social *social.SocialRepository
+	inbox  *feed.Inbox
+	queue  string
+}
+
+func NewFanoutWorker(ch *amqp.Channel, videos *video.VideoRepository, social *social.SocialRepository, inbox *feed.Inbox, queue string) *FanoutWorker {
+	return &FanoutWorker{ch: ch, videos: videos, social: social, inbox: inbox, queue: queue}
+}
+
+func (w *FanoutWorker) Run(ctx context.Context) error {
+	if w == nil || w.ch == nil || w.videos == nil || w.social == nil || w.inbox == nil {
+		return errors.New("fanout worker is not initialized")
+	}
+	if w.queue == "" {
+		return errors.New("queue is required")
+	}
+
+	deliveries, err := w.ch.Consume(
+		w.queue,
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("deliveries channel closed")
+			}
+			w.handleDelivery(ctx, d)
+		}
+	}
+}
+
+func (w *FanoutWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
+	if err := w.process(ctx, d.Body); err != nil {
+		retryCount := rabbitmq.GetRetryCount(d)
+		if retryCount >= rabbitmq.MaxRetryCount {
+			log.Printf("fanout worker: max retries exceeded (%d), moving to DLX: %v", retryCount, err)
+			_ = d.Ack(false)
+			return
+		}
+		log.Printf("fanout worker: failed (retry %d/%d): %v", retryCount+1, rabbitmq.MaxRetryCount, err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+func (w *FanoutWorker) process(ctx context.Context, body []byte) error {
+	var evt rabbitmq.TimelineEvent
+	if err := json.Unmarshal(body, &evt); err != nil {
+		return nil
+	}
+	if evt.VideoID == 0 {
+		return nil
+	}
+	if evt.Visibility == video.VisibilityPrivate || evt.Visibility == video.VisibilityUnlisted {
+		// 私密和仅链接可见的视频不进粉丝的关注流
+		return nil
+	}
+
+	authorID := evt.AuthorID
+	createTime := time.UnixMilli(evt.CreateTime)
+	if authorID == 0 || evt.CreateTime == 0 {
+		// 旧版本事件不带作者信息，回表补齐
+		v, err := w.videos.GetByID(ctx, evt.VideoID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		authorID = v.AuthorID
+		createTime = v.CreateTime
+	}
+
+	followerCount, err := w.social.CountFollowers(ctx, authorID)
+	if err != nil {
+		return err
+	}
+	if followerCount >= feed.PushFollowerLimit {
+		// 大 V 不写扩散，读时拉取
+		return nil
+	}
+
+	var afterID uint
+	for {
+		followerIDs, err := w.social.ListFollowerIDs(ctx, authorID, afterID, fanoutBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(followerIDs) == 0 {
+			return nil
+		}
+		if err := w.inbox.Push(ctx, followerIDs, evt.VideoID, createTime); err != nil {
+			return err
+		}
+		if len(followerIDs) < fanoutBatchSize {
+			return nil
+		}
+		afterID = followerIDs[len(followerIDs)-1]
+	}
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// redisLedgerTTL 覆盖 MQ 重投和 outbox 租期过期重发的时间窗口
+	redisLedgerTTL = 24 * time.Hour
+	// processedEventRetention MySQL 台账保留时间，过期的由 RunLedgerPruner 清理
+	processedEventRetention = 7 * 24 * time.Hour
+	ledgerPruneBatch        = 1000
+)
+
+// ProcessedEvent 写库的消费者已处理的事件，和业务写入在同一事务里插入，(consumer, event_id) 已存在说明处理过
+type ProcessedEvent struct {
+	Consumer  string    `gorm:"type:varchar(32);primaryKey"`
+	EventID   string    `gorm:"type:varchar(64);primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (ProcessedEvent) TableName() string {
+	return "processed_events"
+}
+
+// deliveryEventID 优先取 AMQP MessageId，旧消息退回消息体里的 event_id；都没有时返回空，不做去重
+func deliveryEventID(d amqp.Delivery) string {
+	if d.MessageId != "" {
+		return d.MessageId
+	}
+	var body struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(d.Body, &body); err != nil {
+		return ""
+	}
+	return body.EventID
+}
+
+// DBLedger 给写 MySQL 的消费者去重：台账和 fn 的写入同一事务提交，重复投递时 fn 不执行
+type DBLedger struct {
+	db       *gorm.DB
+	consumer string
+}
+
+func NewDBLedger(db *gorm.DB, consumer string) *DBLedger {
+	return &DBLedger{db: db, consumer: consumer}
+}
+
+// Apply eventID 为空时只在事务里执行 fn；fn 返回错误时台账一起回滚，重投后会再次处理
+func (l *DBLedger) Apply(ctx context.Context, eventID string, fn func(tx *gorm.DB) error) error {
+	if l == nil || l.db == nil {
+		return errors.New("db ledger is not initialized")
+	}
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if eventID != "" {
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&ProcessedEvent{Consumer: l.consumer, EventID: eventID})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return nil
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// RedisLedger 给只改 Redis 的消费者去重：先用 SETNX 占位再执行，失败时删除占位让重投再来一次。
+// 占位后进程崩溃会丢这一次处理，对热度这类近似值比重复累加更可接受
+type RedisLedger struct {
+	cache    *rediscache.Client
+	consumer string
+	ttl      time.Duration
+}
+
+func NewRedisLedger(cache *rediscache.Client, consumer string) *RedisLedger {
+	return &RedisLedger{cache: cache, consumer: consumer, ttl: redisLedgerTTL}
+}
+
+func (l *RedisLedger) Apply(ctx context.Context, eventID string, fn func(ctx context.Context) error) error {
+	if l == nil || l.cache == nil || eventID == "" {
+		return fn(ctx)
+	}
+	key := l.cache.Key("consumed:%s:%s", l.consumer, eventID)
+	fresh, err := l.cache.SetNX(ctx, key, l.ttl)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return nil
+	}
+	if err := fn(ctx); err != nil {
+		if delErr := l.cache.Del(context.Background(), key); delErr != nil {
+			log.Printf("redis ledger: release %s failed: %v", key, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// RunLedgerPruner 每小时分批删除超过保留期的 processed_events，多实例同时删互不影响
+func RunLedgerPruner(ctx context.Context, db *gorm.DB) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		pruneProcessedEvents(ctx, db, time.Now().Add(-processedEventRetention))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func pruneProcessedEvents(ctx context.Context, db *gorm.DB, before time.Time) {
+	for ctx.Err() == nil {
+		res := db.WithContext(ctx).Where("created_at < ?", before).Limit(ledgerPruneBatch).Delete(&ProcessedEvent{})
+		if res.Error != nil {
+			log.Printf("ledger pruner: %v", res.Error)
+			return
+		}
+		if res.RowsAffected < ledgerPruneBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestDeliveryEventIDPrefersMessageID(t *testing.T) {
+	body := []byte(`{"event_id":"from-body","video_id":1}`)
+	if got := deliveryEventID(amqp.Delivery{MessageId: "from-header", Body: body}); got != "from-header" {
+		t.Fatalf("expected message id, got %q", got)
+	}
+	if got := deliveryEventID(amqp.Delivery{Body: body}); got != "from-body" {
+		t.Fatalf("expected body event id, got %q", got)
+	}
+	if got := deliveryEventID(amqp.Delivery{Body: []byte("not json")}); got != "" {
+		t.Fatalf("expected empty id, got %q", got)
+	}
+}
+
+func TestRedisLedgerAppliesEventOnce(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	ledger := NewRedisLedger(newTestCache(t, mr), "popularity")
+	ctx := context.Background()
+
+	applied := 0
+	fn := func(context.Context) error { applied++; return nil }
+	for i := 0; i < 3; i++ {
+		if err := ledger.Apply(ctx, "e1", fn); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+	}
+	if applied != 1 {
+		t.Fatalf("expected event applied once, got %d", applied)
+	}
+	// 没有事件 ID 的旧消息不去重
+	_ = ledger.Apply(ctx, "", fn)
+	_ = ledger.Apply(ctx, "", fn)
+	if applied != 3 {
+		t.Fatalf("expected events without id applied every time, got %d", applied)
+	}
+}
+
+func TestRedisLedgerReleasesOnFailure(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	ledger := NewRedisLedger(newTestCache(t, mr), "popularity")
+	ctx := context.Background()
+
+	if err := ledger.Apply(ctx, "e1", func(context.Context) error { return errors.New("boom") }); err == nil {
+		t.Fatal("expected error")
+	}
+	applied := false
+	if err := ledger.Apply(ctx, "e1", func(context.Context) error { applied = true; return nil }); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !applied {
+		t.Fatal("redelivery after failure should be applied")
+	}
+}
+
+func TestPopularityWorkerReturnsRedisFailure(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	w := NewPopularityWorker(nil, newTestCache(t, mr), "q")
+	body := []byte(`{"event_id":"e1","video_id":7,"change":1}`)
+
+	// 写热榜失败必须返回错误，ledger 才会释放事件、消息才会 nack 重投
+	mr.SetError("LOADING")
+	if err := w.process(context.Background(), body); err == nil {
+		t.Fatal("expected redis failure to be returned")
+	}
+	mr.SetError("")
+	if err := w.process(context.Background(), body); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+}
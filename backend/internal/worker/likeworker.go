@@ -4,27 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"feedsystem_video_go/internal/creator"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	"feedsystem_video_go/internal/video"
 	"log"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
 )
 
 type LikeWorker struct {
 	ch     *amqp.Channel
-	likes  *video.LikeRepository
 	videos *video.VideoRepository
+	stats  *creator.StatRepository
+	ledger *DBLedger
 	queue  string
 }
 
-func NewLikeWorker(ch *amqp.Channel, likes *video.LikeRepository, videos *video.VideoRepository, queue string) *LikeWorker {
-	return &LikeWorker{ch: ch, likes: likes, videos: videos, queue: queue}
+func NewLikeWorker(ch *amqp.Channel, videos *video.VideoRepository, stats *creator.StatRepository, ledger *DBLedger, queue string) *LikeWorker {
+	return &LikeWorker{ch: ch, videos: videos, stats: stats, ledger: ledger, queue: queue}
 }
 
 func (w *LikeWorker) Run(ctx context.Context) error {
-	if w == nil || w.ch == nil || w.likes == nil || w.videos == nil {
+	if w == nil || w.ch == nil || w.videos == nil || w.ledger == nil {
 		return errors.New("like worker is not initialized")
 	}
 	if w.queue == "" {
@@ -58,7 +61,10 @@ func (w *LikeWorker) Run(ctx context.Context) error {
 }
 
 func (w *LikeWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
-	if err := w.process(ctx, d.Body); err != nil {
+	err := w.ledger.Apply(ctx, deliveryEventID(d), func(tx *gorm.DB) error {
+		return w.process(ctx, tx, d.Body)
+	})
+	if err != nil {
 		retryCount := rabbitmq.GetRetryCount(d)
 		if retryCount >= rabbitmq.MaxRetryCount {
 			log.Printf("like worker: max retries exceeded (%d), moving to DLX: %v", retryCount, err)
@@ -72,7 +78,7 @@ func (w *LikeWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
 	_ = d.Ack(false)
 }
 
-func (w *LikeWorker) process(ctx context.Context, body []byte) error {
+func (w *LikeWorker) process(ctx context.Context, tx *gorm.DB, body []byte) error {
 	var evt rabbitmq.LikeEvent
 	if err := json.Unmarshal(body, &evt); err != nil {
 		// 解析事件失败，直接丢弃
@@ -84,60 +90,22 @@ func (w *LikeWorker) process(ctx context.Context, body []byte) error {
 
 	switch evt.Action {
 	case "like":
-		return w.applyLike(ctx, evt.UserID, evt.VideoID)
+		return w.applyLike(ctx, tx, evt.VideoID, evt.OccurredAt, creator.StatDelta{Likes: 1, PopularityDelta: 1})
 	case "unlike":
-		return w.applyUnlike(ctx, evt.UserID, evt.VideoID)
+		return w.applyLike(ctx, tx, evt.VideoID, evt.OccurredAt, creator.StatDelta{Likes: -1, PopularityDelta: -1})
 	default:
 		return nil
 	}
 }
 
-func (w *LikeWorker) applyLike(ctx context.Context, userID, videoID uint) error {
-	ok, err := w.videos.IsExist(ctx, videoID)
-	if err != nil {
-		return err
-	}
-	if !ok {
-		return nil
-	}
-
-	created, err := w.likes.LikeIgnoreDuplicate(ctx, &video.Like{
-		VideoID:   videoID,
-		AccountID: userID,
-		CreatedAt: time.Now(),
-	})
-	if err != nil {
-		return err
-	}
-	if !created {
-		return nil
-	}
-
-	if err := w.videos.ChangeLikesCount(ctx, videoID, 1); err != nil {
-		return err
-	}
-	return w.videos.ChangePopularity(ctx, videoID, 1)
-}
-
-func (w *LikeWorker) applyUnlike(ctx context.Context, userID, videoID uint) error {
-	ok, err := w.videos.IsExist(ctx, videoID)
-	if err != nil {
-		return err
-	}
-	if !ok {
-		return nil
-	}
-
-	deleted, err := w.likes.DeleteByVideoAndAccount(ctx, videoID, userID)
+// applyLike 点赞和计数已由接口层与事件同一事务写入，这里只记创作者指标
+func (w *LikeWorker) applyLike(ctx context.Context, tx *gorm.DB, videoID uint, at time.Time, d creator.StatDelta) error {
+	v, err := lookupVideo(ctx, w.videos, videoID)
 	if err != nil {
 		return err
 	}
-	if !deleted {
+	if v == nil {
 		return nil
 	}
-
-	if err := w.videos.ChangeLikesCount(ctx, videoID, -1); err != nil {
-		return err
-	}
-	return w.videos.ChangePopularity(ctx, videoID, -1)
+	return recordCreatorStat(ctx, tx, w.stats, v.AuthorID, videoID, at, d)
 }
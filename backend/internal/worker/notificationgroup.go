@@ -0,0 +1,186 @@
+package worker
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// notificationBucket 同一桶内的同类通知合并为一行
+	notificationBucket = 24 * time.Hour
+	maxRecentActors    = 3
+)
+
+// aggregatedActions 可聚合的通知类型及其文案
+var aggregatedActions = map[string]string{
+	"like":    "点赞了你的视频",
+	"comment": "评论了你的视频",
+	"follow":  "关注了你",
+}
+
+type NotificationActor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// NotificationActors 最近几位触发者，按时间倒序，以 JSON 存储
+type NotificationActors []NotificationActor
+
+func (a NotificationActors) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]NotificationActor(a))
+	return string(b), err
+}
+
+func (a *NotificationActors) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type %T for NotificationActors", value)
+	}
+	if len(b) == 0 {
+		*a = nil
+		return nil
+	}
+	return json.Unmarshal(b, (*[]NotificationActor)(a))
+}
+
+// groupKey 关注按接收者聚合，点赞/评论按接收者+视频聚合
+func groupKey(n *Notification, at time.Time) string {
+	target := n.TargetID
+	if n.Type == "follow" {
+		target = 0
+	}
+	return fmt.Sprintf("%s:%d:%d:%d", n.Type, n.RecipientID, target, at.Unix()/int64(notificationBucket/time.Second))
+}
+
+// mergeActors 把 actor 放到最前面并截断；actor 已在列表中时返回 false，不重复计数
+func mergeActors(actors NotificationActors, actor NotificationActor) (NotificationActors, bool) {
+	out := make(NotificationActors, 0, maxRecentActors)
+	out = append(out, actor)
+	isNew := true
+	for _, a := range actors {
+		if a.ID == actor.ID {
+			isNew = false
+			continue
+		}
+		if len(out) < maxRecentActors {
+			out = append(out, a)
+		}
+	}
+	return out, isNew
+}
+
+func aggregatedContent(action string, count int64) string {
+	if count <= 1 {
+		return action
+	}
+	return fmt.Sprintf("等 %d 人%s", count, action)
+}
+
+// errGroupRace 并发插入同一组时另一方先插入成功
+var errGroupRace = errors.New("notification group inserted concurrently")
+
+// saveNotification 可聚合类型合并进桶内未读的那一行，其余直接插入；组已读后再来的事件另起一组。
+// 写入后 n 即为要推送的完整视图
+func saveNotification(ctx context.Context, db *gorm.DB, n *Notification, now time.Time) error {
+	now = now.Truncate(time.Microsecond)
+	action, ok := aggregatedActions[n.Type]
+	if !ok {
+		n.UpdatedAt = now
+		return db.WithContext(ctx).Create(n).Error
+	}
+
+	var username string
+	if err := db.WithContext(ctx).Table("accounts").Where("id = ?", n.SenderID).Select("username").Scan(&username).Error; err != nil {
+		return err
+	}
+	actor := NotificationActor{ID: n.SenderID, Username: username}
+	key := groupKey(n, now)
+
+	// 并发插入同一组时唯一索引冲突，重来一次就会走合并分支
+	for attempt := 0; ; attempt++ {
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return mergeNotification(tx, n, key, action, actor, now)
+		})
+		if errors.Is(err, errGroupRace) && attempt == 0 {
+			continue
+		}
+		return err
+	}
+}
+
+func mergeNotification(tx *gorm.DB, n *Notification, key string, action string, actor NotificationActor, now time.Time) error {
+	var group Notification
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("group_key = ?", key).First(&group).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && group.IsRead {
+		// 已读的组不再合并：清掉它的 group_key，新事件另起一组
+		if err := tx.Model(&Notification{}).Where("id = ?", group.ID).Update("group_key", nil).Error; err != nil {
+			return err
+		}
+	}
+	if err != nil || group.IsRead {
+		return insertGroup(tx, n, key, action, actor, now)
+	}
+	actors, isNew := mergeActors(group.RecentActors, actor)
+	if isNew {
+		group.ActorCount++
+	}
+	group.RecentActors = actors
+	group.SenderID = n.SenderID
+	if n.Type == "follow" {
+		group.TargetID = n.TargetID
+	}
+	group.Content = aggregatedContent(action, group.ActorCount)
+	group.UpdatedAt = now
+	if err := tx.Model(&Notification{}).Where("id = ?", group.ID).Updates(map[string]interface{}{
+		"actor_count":   group.ActorCount,
+		"recent_actors": group.RecentActors,
+		"sender_id":     group.SenderID,
+		"target_id":     group.TargetID,
+		"content":       group.Content,
+		"updated_at":    now,
+	}).Error; err != nil {
+		return err
+	}
+	*n = group
+	return nil
+}
+
+// insertGroup 新建一组；同一 group_key 已被其他事务插入时返回 errGroupRace
+func insertGroup(tx *gorm.DB, n *Notification, key string, action string, actor NotificationActor, now time.Time) error {
+	n.ID = 0
+	n.GroupKey = &key
+	n.ActorCount = 1
+	n.RecentActors = NotificationActors{actor}
+	n.Content = action
+	n.IsRead = false
+	n.UpdatedAt = now
+	res := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "group_key"}}, DoNothing: true}).Create(n)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errGroupRace
+	}
+	return nil
+}
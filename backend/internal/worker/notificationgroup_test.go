@@ -0,0 +1,218 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+
+	"gorm.io/gorm"
+)
+
+func TestMergeActorsKeepsMostRecentFirst(t *testing.T) {
+	var actors NotificationActors
+	for _, id := range []uint{1, 2, 3, 4} {
+		var isNew bool
+		actors, isNew = mergeActors(actors, NotificationActor{ID: id})
+		if !isNew {
+			t.Fatalf("actor %d should be new", id)
+		}
+	}
+	if len(actors) != maxRecentActors || actors[0].ID != 4 || actors[2].ID != 2 {
+		t.Fatalf("unexpected actors %+v", actors)
+	}
+	actors, isNew := mergeActors(actors, NotificationActor{ID: 3})
+	if isNew || actors[0].ID != 3 || len(actors) != maxRecentActors {
+		t.Fatalf("repeat actor should move to front without counting, got %+v %v", actors, isNew)
+	}
+	if got := aggregatedContent("点赞了你的视频", 13); got != "等 13 人点赞了你的视频" {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func newNotificationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := dbtest.Open(t, &Notification{})
+	if err := db.Exec("CREATE TABLE accounts (id INTEGER PRIMARY KEY, username TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 20; i++ {
+		db.Exec("INSERT INTO accounts (id, username) VALUES (?, ?)", i, "user"+string(rune('a'+i-1)))
+	}
+	return db
+}
+
+func like(sender uint) *Notification {
+	return &Notification{RecipientID: 100, SenderID: sender, Type: "like", TargetID: 7, Content: "点赞了你的视频"}
+}
+
+func loadNotifications(t *testing.T, db *gorm.DB) []Notification {
+	t.Helper()
+	var rows []Notification
+	if err := db.Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestSaveNotificationMergesIntoUnreadGroupWithinBucket(t *testing.T) {
+	db := newNotificationDB(t)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	for i, sender := range []uint{1, 2, 3, 4, 2} {
+		n := like(sender)
+		if err := saveNotification(ctx, db, n, now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		// 推送的是合并后的完整视图
+		if n.ID == 0 || n.RecentActors[0].ID != sender {
+			t.Fatalf("unexpected pushed view %+v", n)
+		}
+	}
+	rows := loadNotifications(t, db)
+	if len(rows) != 1 {
+		t.Fatalf("expected one grouped row, got %d", len(rows))
+	}
+	g := rows[0]
+	// 重复的触发者不重复计数，只移到最前
+	if g.ActorCount != 4 || g.Content != "等 4 人点赞了你的视频" || g.IsRead || g.SenderID != 2 {
+		t.Fatalf("unexpected group %+v", g)
+	}
+	if len(g.RecentActors) != maxRecentActors || g.RecentActors[0].ID != 2 || g.RecentActors[1].ID != 4 || g.RecentActors[0].Username != "userb" {
+		t.Fatalf("unexpected recent actors %+v", g.RecentActors)
+	}
+	if !g.UpdatedAt.Equal(now.Add(4 * time.Minute)) {
+		t.Fatalf("updated_at = %v", g.UpdatedAt)
+	}
+
+	// 其他视频、其他类型各自成组
+	other := like(1)
+	other.TargetID = 8
+	if err := saveNotification(ctx, db, other, now); err != nil {
+		t.Fatal(err)
+	}
+	follow := &Notification{RecipientID: 100, SenderID: 5, Type: "follow", TargetID: 5}
+	if err := saveNotification(ctx, db, follow, now); err != nil {
+		t.Fatal(err)
+	}
+	if rows := loadNotifications(t, db); len(rows) != 3 {
+		t.Fatalf("expected three groups, got %d", len(rows))
+	}
+}
+
+func TestSaveNotificationStartsNewGroupAfterBucketOrRead(t *testing.T) {
+	db := newNotificationDB(t)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	if err := saveNotification(ctx, db, like(1), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveNotification(ctx, db, like(2), now.Add(notificationBucket)); err != nil {
+		t.Fatal(err)
+	}
+	rows := loadNotifications(t, db)
+	if len(rows) != 2 || rows[0].ActorCount != 1 || rows[1].ActorCount != 1 {
+		t.Fatalf("next bucket should start a new group, got %+v", rows)
+	}
+
+	// 用户读过之后再来的点赞另起一组，已读的那一行保持原样
+	if err := db.Model(&Notification{}).Where("id = ?", rows[1].ID).Update("is_read", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	n := like(3)
+	if err := saveNotification(ctx, db, n, now.Add(notificationBucket+time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	rows = loadNotifications(t, db)
+	if len(rows) != 3 {
+		t.Fatalf("expected a new group after read, got %d rows", len(rows))
+	}
+	read, fresh := rows[1], rows[2]
+	if !read.IsRead || read.ActorCount != 1 || read.GroupKey != nil {
+		t.Fatalf("read group should be detached and untouched, got %+v", read)
+	}
+	if fresh.ID != n.ID || fresh.IsRead || fresh.ActorCount != 1 || fresh.GroupKey == nil {
+		t.Fatalf("unexpected new group %+v", fresh)
+	}
+	// 新组之后继续合并
+	if err := saveNotification(ctx, db, like(4), now.Add(notificationBucket+2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if rows = loadNotifications(t, db); len(rows) != 3 || rows[2].ActorCount != 2 {
+		t.Fatalf("expected merge into the new group, got %+v", rows)
+	}
+}
+
+func TestSaveNotificationConcurrentInsertsShareOneGroup(t *testing.T) {
+	db := newNotificationDB(t)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	const senders = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, senders)
+	for i := 1; i <= senders; i++ {
+		wg.Add(1)
+		go func(sender uint) {
+			defer wg.Done()
+			errs <- saveNotification(ctx, db, like(sender), now)
+		}(uint(i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent save: %v", err)
+		}
+	}
+	rows := loadNotifications(t, db)
+	if len(rows) != 1 || rows[0].ActorCount != senders {
+		t.Fatalf("expected one group counting %d actors, got %+v", senders, rows)
+	}
+}
+
+func TestInsertGroupReportsLostRace(t *testing.T) {
+	db := newNotificationDB(t)
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	key := groupKey(like(1), now)
+	// 另一个事务在加锁查询之后抢先插入了同一组
+	if err := insertGroup(db, like(9), key, "点赞了你的视频", NotificationActor{ID: 9}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := insertGroup(db, like(1), key, "点赞了你的视频", NotificationActor{ID: 1}, now); !errors.Is(err, errGroupRace) {
+		t.Fatalf("expected errGroupRace, got %v", err)
+	}
+	// saveNotification 重来一次走合并分支
+	if err := saveNotification(context.Background(), db, like(1), now); err != nil {
+		t.Fatal(err)
+	}
+	if rows := loadNotifications(t, db); len(rows) != 1 || rows[0].ActorCount != 2 {
+		t.Fatalf("expected merge into the existing group, got %+v", rows)
+	}
+}
+
+func TestBackfillNotificationTimesFillsNullColumns(t *testing.T) {
+	db := newNotificationDB(t)
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	// 旧版本直接插表的 mention 只写了 updated_at，更早的数据只有 created_at
+	db.Exec("INSERT INTO notifications (recipient_id, sender_id, type, created_at) VALUES (100, 1, 'comment', ?)", created)
+	db.Exec("INSERT INTO notifications (recipient_id, sender_id, type, updated_at) VALUES (100, 2, 'mention', ?)", created)
+	if err := BackfillNotificationTimes(context.Background(), db); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	var nulls int64
+	db.Model(&Notification{}).Where("updated_at IS NULL OR created_at IS NULL").Count(&nulls)
+	if nulls != 0 {
+		t.Fatalf("%d rows still have NULL times", nulls)
+	}
+	for _, n := range loadNotifications(t, db) {
+		if !n.UpdatedAt.Equal(created) || !n.CreatedAt.Equal(created) {
+			t.Fatalf("row %d times = %v / %v, want %v", n.ID, n.CreatedAt, n.UpdatedAt, created)
+		}
+	}
+}
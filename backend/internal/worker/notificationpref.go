@@ -0,0 +1,250 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DeliveryInApp  = "in_app" // 入库并实时推送
+	DeliverySilent = "silent" // 只入库，不推送 SSE
+	DeliveryDrop   = "drop"   // 不生成通知
+
+	MuteSender = "sender"
+	MuteVideo  = "video"
+)
+
+// notificationTypes 允许设置偏好的通知类型
+var notificationTypes = map[string]bool{"like": true, "comment": true, "reply": true, "follow": true, "mention": true}
+
+// NotificationPreference 每个账号每种通知类型一行，没有记录时按 in_app 处理
+type NotificationPreference struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AccountID uint      `gorm:"uniqueIndex:idx_notification_pref_account_type;not null" json:"account_id"`
+	Type      string    `gorm:"type:varchar(50);uniqueIndex:idx_notification_pref_account_type;not null" json:"type"`
+	Delivery  string    `gorm:"type:varchar(20);not null" json:"delivery"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationMute 屏蔽某个用户或某个视频产生的通知
+type NotificationMute struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AccountID uint      `gorm:"uniqueIndex:idx_notification_mute;not null" json:"account_id"`
+	Kind      string    `gorm:"type:varchar(20);uniqueIndex:idx_notification_mute;not null" json:"kind"`
+	TargetID  uint      `gorm:"uniqueIndex:idx_notification_mute;not null" json:"target_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationSettings 一个账号的全部偏好，整体缓存在 Redis
+type NotificationSettings struct {
+	Delivery     map[string]string `json:"delivery"`
+	MutedSenders []uint            `json:"muted_senders"`
+	MutedVideos  []uint            `json:"muted_videos"`
+}
+
+// Decide 返回该通知的投递方式：屏蔽优先，其次按类型偏好，默认 in_app
+func (s *NotificationSettings) Decide(n *Notification) string {
+	if s == nil {
+		return DeliveryInApp
+	}
+	for _, id := range s.MutedSenders {
+		if id == n.SenderID {
+			return DeliveryDrop
+		}
+	}
+	if n.Type != "follow" {
+		for _, id := range s.MutedVideos {
+			if id == n.TargetID {
+				return DeliveryDrop
+			}
+		}
+	}
+	if d, ok := s.Delivery[n.Type]; ok {
+		return d
+	}
+	return DeliveryInApp
+}
+
+const preferenceCacheTTL = 10 * time.Minute
+
+// PreferenceStore 读偏好时先查 Redis，未命中再查 MySQL 并回填；写入后删除缓存
+type PreferenceStore struct {
+	db    *gorm.DB
+	cache *rediscache.Client
+}
+
+func NewPreferenceStore(db *gorm.DB, cache *rediscache.Client) *PreferenceStore {
+	return &PreferenceStore{db: db, cache: cache}
+}
+
+func (p *PreferenceStore) cacheKey(accountID uint) string {
+	return p.cache.Key("notification:prefs:%d", accountID)
+}
+
+func (p *PreferenceStore) Load(ctx context.Context, accountID uint) (*NotificationSettings, error) {
+	if p.cache != nil {
+		opCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		b, err := p.cache.GetBytes(opCtx, p.cacheKey(accountID))
+		cancel()
+		if err == nil {
+			var s NotificationSettings
+			if err := json.Unmarshal(b, &s); err == nil {
+				return &s, nil
+			}
+		}
+	}
+
+	s := &NotificationSettings{Delivery: map[string]string{}, MutedSenders: []uint{}, MutedVideos: []uint{}}
+	var prefs []NotificationPreference
+	if err := p.db.WithContext(ctx).Where("account_id = ?", accountID).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	for _, pref := range prefs {
+		s.Delivery[pref.Type] = pref.Delivery
+	}
+	var mutes []NotificationMute
+	if err := p.db.WithContext(ctx).Where("account_id = ?", accountID).Order("id asc").Find(&mutes).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range mutes {
+		switch m.Kind {
+		case MuteSender:
+			s.MutedSenders = append(s.MutedSenders, m.TargetID)
+		case MuteVideo:
+			s.MutedVideos = append(s.MutedVideos, m.TargetID)
+		}
+	}
+
+	// 没有任何设置的账号也缓存，避免每个事件都查库
+	if p.cache != nil {
+		if b, err := json.Marshal(s); err == nil {
+			opCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			_ = p.cache.SetBytes(opCtx, p.cacheKey(accountID), b, preferenceCacheTTL)
+			cancel()
+		}
+	}
+	return s, nil
+}
+
+func (p *PreferenceStore) invalidate(accountID uint) {
+	if p.cache != nil {
+		_ = p.cache.Del(context.Background(), p.cacheKey(accountID))
+	}
+}
+
+func (p *PreferenceStore) SetDelivery(ctx context.Context, accountID uint, typ string, delivery string) error {
+	if !notificationTypes[typ] {
+		return errors.New("unknown notification type")
+	}
+	switch delivery {
+	case DeliveryInApp, DeliverySilent, DeliveryDrop:
+	default:
+		return errors.New("delivery must be in_app, silent or drop")
+	}
+	pref := NotificationPreference{AccountID: accountID, Type: typ, Delivery: delivery}
+	if err := p.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"delivery", "updated_at"}),
+	}).Create(&pref).Error; err != nil {
+		return err
+	}
+	p.invalidate(accountID)
+	return nil
+}
+
+func (p *PreferenceStore) SetMute(ctx context.Context, accountID uint, kind string, targetID uint, muted bool) error {
+	if kind != MuteSender && kind != MuteVideo {
+		return errors.New("kind must be sender or video")
+	}
+	if targetID == 0 {
+		return errors.New("target_id is required")
+	}
+	var err error
+	if muted {
+		err = p.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&NotificationMute{AccountID: accountID, Kind: kind, TargetID: targetID}).Error
+	} else {
+		err = p.db.WithContext(ctx).
+			Where("account_id = ? AND kind = ? AND target_id = ?", accountID, kind, targetID).
+			Delete(&NotificationMute{}).Error
+	}
+	if err != nil {
+		return err
+	}
+	p.invalidate(accountID)
+	return nil
+}
+
+func (p *PreferenceStore) GetHandler(c *gin.Context) {
+	userID, ok := sseAccountID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid account"})
+		return
+	}
+	s, err := p.Load(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+func (p *PreferenceStore) SetHandler(c *gin.Context) {
+	userID, ok := sseAccountID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid account"})
+		return
+	}
+	var req struct {
+		Type     string `json:"type"`
+		Delivery string `json:"delivery"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := p.SetDelivery(c.Request.Context(), userID, req.Type, req.Delivery); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
+func (p *PreferenceStore) muteHandler(muted bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := sseAccountID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid account"})
+			return
+		}
+		var req struct {
+			Kind     string `json:"kind"`
+			TargetID uint   `json:"target_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := p.SetMute(c.Request.Context(), userID, req.Kind, req.TargetID, muted); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	}
+}
+
+func (p *PreferenceStore) RegisterRoutes(group *gin.RouterGroup) {
+	group.POST("/preferences", p.GetHandler)
+	group.POST("/setPreference", p.SetHandler)
+	group.POST("/mute", p.muteHandler(true))
+	group.POST("/unmute", p.muteHandler(false))
+}
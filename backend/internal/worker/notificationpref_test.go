@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestNotificationSettingsDecide(t *testing.T) {
+	s := &NotificationSettings{
+		Delivery:     map[string]string{"like": DeliverySilent, "comment": DeliveryDrop},
+		MutedSenders: []uint{9},
+		MutedVideos:  []uint{100},
+	}
+	cases := []struct {
+		n    Notification
+		want string
+	}{
+		{Notification{Type: "like", SenderID: 1, TargetID: 5}, DeliverySilent},
+		{Notification{Type: "comment", SenderID: 1, TargetID: 5}, DeliveryDrop},
+		{Notification{Type: "reply", SenderID: 1, TargetID: 5}, DeliveryInApp},
+		{Notification{Type: "reply", SenderID: 9, TargetID: 5}, DeliveryDrop},
+		{Notification{Type: "reply", SenderID: 1, TargetID: 100}, DeliveryDrop},
+		// 关注通知的 TargetID 是用户 ID，不受视频屏蔽影响
+		{Notification{Type: "follow", SenderID: 100, TargetID: 100}, DeliveryInApp},
+	}
+	for _, tc := range cases {
+		if got := s.Decide(&tc.n); got != tc.want {
+			t.Fatalf("%+v: expected %s, got %s", tc.n, tc.want, got)
+		}
+	}
+	if got := (*NotificationSettings)(nil).Decide(&Notification{Type: "like"}); got != DeliveryInApp {
+		t.Fatalf("nil settings should deliver in app, got %s", got)
+	}
+}
+
+func TestPreferenceStoreServesFromCache(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := newTestCache(t, mr)
+
+	b, _ := json.Marshal(NotificationSettings{Delivery: map[string]string{"like": DeliveryDrop}})
+	if err := cache.SetBytes(context.Background(), "v1:notification:prefs:3", b, time.Minute); err != nil {
+		t.Fatalf("seed cache: %v", err)
+	}
+	// db 为 nil：命中缓存时不能访问 MySQL
+	store := NewPreferenceStore(nil, cache)
+	s, err := store.Load(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if s.Decide(&Notification{Type: "like"}) != DeliveryDrop {
+		t.Fatalf("expected cached preference, got %+v", s)
+	}
+}
+
+func TestMentionNotificationsFollowPreferences(t *testing.T) {
+	db := newNotificationDB(t)
+	if err := db.AutoMigrate(&NotificationPreference{}, &NotificationMute{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE videos (id INTEGER PRIMARY KEY, author_id INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("INSERT INTO videos (id, author_id) VALUES (7, 1)")
+	// userb 关掉了 mention，userc 屏蔽了视频 7，只有 userd 收到
+	db.Create(&NotificationPreference{AccountID: 2, Type: "mention", Delivery: DeliveryDrop})
+	db.Create(&NotificationMute{AccountID: 3, Kind: MuteVideo, TargetID: 7})
+
+	w := NewNotificationWorker(nil, db, "q", nil, NewPreferenceStore(db, nil))
+	body, _ := json.Marshal(map[string]any{"author_id": 5, "username": "usere", "video_id": 7, "content": "@userb @userc @userd @userd @usere"})
+	if err := w.process(context.Background(), amqp.Delivery{RoutingKey: "comment.publish", Body: body}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	var got []Notification
+	db.Where("type = ?", "mention").Find(&got)
+	if len(got) != 1 || got[0].RecipientID != 4 || got[0].SenderID != 5 {
+		t.Fatalf("mention notifications = %+v", got)
+	}
+	var comments int64
+	db.Model(&Notification{}).Where("type = ? AND recipient_id = ?", "comment", 1).Count(&comments)
+	if comments != 1 {
+		t.Fatalf("video author should still get the comment notification, got %d", comments)
+	}
+}
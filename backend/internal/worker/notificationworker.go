@@ -6,21 +6,28 @@ import (
 	"errors"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	"log"
+	"regexp"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"gorm.io/gorm"
 )
 
+// Notification like/comment/follow 按 GroupKey 聚合成一行：SenderID 为最近一位触发者，
+// ActorCount 为时间桶内的累计人数；reply/mention 等不聚合的通知 GroupKey 为空
 type Notification struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	RecipientID uint      `gorm:"index;not null" json:"recipient_id"`
-	SenderID    uint      `gorm:"not null" json:"sender_id"`
-	Type        string    `gorm:"type:varchar(50);not null" json:"type"`
-	TargetID    uint      `json:"target_id"`
-	Content     string    `gorm:"type:varchar(255)" json:"content"`
-	IsRead      bool      `gorm:"default:false" json:"is_read"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID           uint               `gorm:"primaryKey" json:"id"`
+	RecipientID  uint               `gorm:"index;index:idx_notification_recipient_updated,priority:1;not null" json:"recipient_id"`
+	SenderID     uint               `gorm:"not null" json:"sender_id"`
+	Type         string             `gorm:"type:varchar(50);not null" json:"type"`
+	TargetID     uint               `json:"target_id"`
+	Content      string             `gorm:"type:varchar(255)" json:"content"`
+	GroupKey     *string            `gorm:"type:varchar(128);uniqueIndex" json:"-"`
+	ActorCount   int64              `gorm:"not null;default:1" json:"actor_count"`
+	RecentActors NotificationActors `gorm:"type:varchar(512)" json:"recent_actors,omitempty"`
+	IsRead       bool               `gorm:"default:false" json:"is_read"`
+	CreatedAt    time.Time          `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time          `gorm:"precision:6;index:idx_notification_recipient_updated,priority:2" json:"updated_at"`
 }
 
 type NotificationWorker struct {
@@ -28,14 +35,15 @@ type NotificationWorker struct {
 	db    *gorm.DB
 	queue string
 	hub   NotificationHub
+	prefs *PreferenceStore
 }
 
 type NotificationHub interface {
 	Push(userID uint, n *Notification)
 }
 
-func NewNotificationWorker(ch *amqp.Channel, db *gorm.DB, queue string, hub NotificationHub) *NotificationWorker {
-	return &NotificationWorker{ch: ch, db: db, queue: queue, hub: hub}
+func NewNotificationWorker(ch *amqp.Channel, db *gorm.DB, queue string, hub NotificationHub, prefs *PreferenceStore) *NotificationWorker {
+	return &NotificationWorker{ch: ch, db: db, queue: queue, hub: hub, prefs: prefs}
 }
 
 func (w *NotificationWorker) Run(ctx context.Context) error {
@@ -45,7 +53,10 @@ func (w *NotificationWorker) Run(ctx context.Context) error {
 	if w.queue == "" {
 		return errors.New("queue is required")
 	}
-	if err := w.db.WithContext(ctx).AutoMigrate(&Notification{}); err != nil {
+	if err := w.db.WithContext(ctx).AutoMigrate(&Notification{}, &NotificationPreference{}, &NotificationMute{}); err != nil {
+		return err
+	}
+	if err := BackfillNotificationTimes(ctx, w.db); err != nil {
 		return err
 	}
 	deliveries, err := w.ch.Consume(w.queue, "", false, false, false, false, nil)
@@ -65,6 +76,19 @@ func (w *NotificationWorker) Run(ctx context.Context) error {
 	}
 }
 
+// BackfillNotificationTimes 补齐旧数据缺失的时间：SSE 补发和通知列表都按 updated_at 排序，
+// NULL 的行会排错或永远补发不到。只更新为 NULL 的行，每次启动执行也无副作用
+func BackfillNotificationTimes(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).Model(&Notification{}).
+		Where("updated_at IS NULL AND created_at IS NOT NULL").
+		UpdateColumn("updated_at", gorm.Expr("created_at")).Error; err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Model(&Notification{}).
+		Where("created_at IS NULL AND updated_at IS NOT NULL").
+		UpdateColumn("created_at", gorm.Expr("updated_at")).Error
+}
+
 func (w *NotificationWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
 	retryCount := rabbitmq.GetRetryCount(d)
 	if err := w.process(ctx, d); err != nil {
@@ -88,6 +112,7 @@ func (w *NotificationWorker) process(ctx context.Context, d amqp.Delivery) error
 	routingKey := d.RoutingKey
 
 	var notif *Notification
+	var mentions []*Notification
 
 	switch {
 	case routingKey == "like.like":
@@ -118,6 +143,22 @@ func (w *NotificationWorker) process(ctx context.Context, d amqp.Delivery) error
 		if evt.AuthorID == 0 || evt.VideoID == 0 {
 			return nil
 		}
+		var err error
+		if mentions, err = w.mentionNotifications(ctx, &evt); err != nil {
+			return err
+		}
+		if evt.ParentID != 0 {
+			// 回复通知被回复的人，不再通知视频作者
+			var parentAuthorID uint
+			if err := w.db.WithContext(ctx).Table("comments").Where("id = ?", evt.ParentID).Select("author_id").Scan(&parentAuthorID).Error; err != nil {
+				return err
+			}
+			if parentAuthorID == 0 || parentAuthorID == evt.AuthorID {
+				break
+			}
+			notif = &Notification{RecipientID: parentAuthorID, SenderID: evt.AuthorID, Type: "reply", TargetID: evt.VideoID, Content: "回复了你的评论"}
+			break
+		}
 		var authorID uint
 		if err := w.db.WithContext(ctx).Model(&struct {
 			ID       uint
@@ -126,7 +167,7 @@ func (w *NotificationWorker) process(ctx context.Context, d amqp.Delivery) error
 			return err
 		}
 		if authorID == 0 || authorID == evt.AuthorID {
-			return nil
+			break
 		}
 		notif = &Notification{RecipientID: authorID, SenderID: evt.AuthorID, Type: "comment", TargetID: evt.VideoID, Content: "评论了你的视频"}
 
@@ -141,14 +182,61 @@ func (w *NotificationWorker) process(ctx context.Context, d amqp.Delivery) error
 		notif = &Notification{RecipientID: evt.VloggerID, SenderID: evt.FollowerID, Type: "follow", TargetID: evt.FollowerID, Content: "关注了你"}
 	}
 
-	if notif == nil {
+	if notif != nil {
+		if err := w.deliver(ctx, notif); err != nil {
+			return err
+		}
+	}
+	for _, n := range mentions {
+		if err := w.deliver(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver 按接收者的偏好决定丢弃、只入库还是入库并推送
+func (w *NotificationWorker) deliver(ctx context.Context, notif *Notification) error {
+	delivery := DeliveryInApp
+	if w.prefs != nil {
+		settings, err := w.prefs.Load(ctx, notif.RecipientID)
+		if err != nil {
+			return err
+		}
+		delivery = settings.Decide(notif)
+	}
+	if delivery == DeliveryDrop {
 		return nil
 	}
-	if err := w.db.WithContext(ctx).Create(notif).Error; err != nil {
+	if err := saveNotification(ctx, w.db, notif, time.Now()); err != nil {
 		return err
 	}
-	if w.hub != nil {
+	if w.hub != nil && delivery == DeliveryInApp {
 		w.hub.Push(notif.RecipientID, notif)
 	}
 	return nil
 }
+
+var mentionRegex = regexp.MustCompile(`@(\w+)`)
+
+// mentionNotifications 评论里 @ 到的每个用户一条 mention 通知，不通知自己
+func (w *NotificationWorker) mentionNotifications(ctx context.Context, evt *rabbitmq.CommentEvent) ([]*Notification, error) {
+	var notifs []*Notification
+	seen := make(map[string]bool)
+	for _, m := range mentionRegex.FindAllStringSubmatch(evt.Content, -1) {
+		username := m[1]
+		if seen[username] || username == evt.Username {
+			continue
+		}
+		seen[username] = true
+		var accountID uint
+		if err := w.db.WithContext(ctx).Table("accounts").Where("username = ?", username).Select("id").Scan(&accountID).Error; err != nil {
+			return nil, err
+		}
+		if accountID == 0 || accountID == evt.AuthorID {
+			continue
+		}
+		notifs = append(notifs, &Notification{RecipientID: accountID, SenderID: evt.AuthorID, Type: "mention", TargetID: evt.VideoID, Content: "在评论中提到了你"})
+	}
+	return notifs, nil
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+)
+
+// NotificationBus 跨实例分发通知：任意实例 Publish，所有实例收到后推给本地连接
+type NotificationBus interface {
+	Publish(ctx context.Context, userID uint, n *Notification) error
+	// Subscribe 阻塞直到 ctx 结束或订阅断开
+	Subscribe(ctx context.Context, deliver func(userID uint, n *Notification)) error
+}
+
+type busEnvelope struct {
+	UserID       uint          `json:"user_id"`
+	Notification *Notification `json:"notification"`
+}
+
+// MemoryBus 进程内实现，适用于单实例部署和测试
+type MemoryBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]func(userID uint, n *Notification)
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[int]func(userID uint, n *Notification))}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, userID uint, n *Notification) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, deliver := range b.subs {
+		deliver(userID, n)
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, deliver func(userID uint, n *Notification)) error {
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	b.subs[id] = deliver
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.subs, id)
+	b.mu.Unlock()
+	return ctx.Err()
+}
+
+// RedisBus 基于 Redis pub/sub，所有 API 实例订阅同一个 channel
+type RedisBus struct {
+	cache   *rediscache.Client
+	channel string
+}
+
+func NewRedisBus(cache *rediscache.Client) *RedisBus {
+	return &RedisBus{cache: cache, channel: cache.Key("notification:bus")}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, userID uint, n *Notification) error {
+	payload, err := json.Marshal(busEnvelope{UserID: userID, Notification: n})
+	if err != nil {
+		return err
+	}
+	return b.cache.Publish(ctx, b.channel, payload)
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, deliver func(userID uint, n *Notification)) error {
+	ps, err := b.cache.Subscribe(ctx, b.channel)
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+	msgs := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-msgs:
+			if !ok {
+				return errors.New("notification bus subscription closed")
+			}
+			var env busEnvelope
+			if err := json.Unmarshal([]byte(m.Payload), &env); err != nil || env.UserID == 0 || env.Notification == nil {
+				continue
+			}
+			deliver(env.UserID, env.Notification)
+		}
+	}
+}
+
+var (
+	_ NotificationBus = (*MemoryBus)(nil)
+	_ NotificationBus = (*RedisBus)(nil)
+)
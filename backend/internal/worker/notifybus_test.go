@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestCache(t *testing.T, mr *miniredis.Miniredis) *rediscache.Client {
+	t.Helper()
+	c := rediscache.NewClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), "v1:")
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func waitSubscribers(t *testing.T, mr *miniredis.Miniredis, channel string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers on %s", n, channel)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSSEHubDeliversAcrossInstances(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cacheA, cacheB := newTestCache(t, mr), newTestCache(t, mr)
+	hubA := NewSSEHub(nil, NewRedisBus(cacheA), NewRedisPresence(cacheA, "a"))
+	hubB := NewSSEHub(nil, NewRedisBus(cacheB), NewRedisPresence(cacheB, "b"))
+	go hubA.Run(ctx)
+	go hubB.Run(ctx)
+	waitSubscribers(t, mr, "v1:notification:bus", 2)
+
+	// 用户连在 B 上，通知由 A 上的 worker 产生
+	sub := hubB.Subscribe(7)
+	hubA.Push(7, &Notification{ID: 1, RecipientID: 7, Type: "like"})
+	select {
+	case n := <-sub.C:
+		if n.ID != 1 || n.Type != "like" {
+			t.Fatalf("unexpected notification %+v", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("notification was not delivered across instances")
+	}
+
+	online, err := hubA.presence.Online(ctx, 7)
+	if err != nil || !online {
+		t.Fatalf("expected user 7 online from instance a, got %v %v", online, err)
+	}
+	hubB.Unsubscribe(7, sub)
+	if online, _ := hubA.presence.Online(ctx, 7); online {
+		t.Fatalf("expected user 7 offline after disconnect")
+	}
+}
+
+func TestRedisPresenceCountsConnectionsPerInstance(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	defer mr.Close()
+	cache := newTestCache(t, mr)
+	a := NewRedisPresence(cache, "a")
+	b := NewRedisPresence(cache, "b")
+	ctx := context.Background()
+
+	a.Connect(1)
+	a.Connect(1)
+	b.Connect(1)
+	a.Disconnect(1)
+	if online, _ := a.Online(ctx, 1); !online {
+		t.Fatalf("expected online while connections remain")
+	}
+	a.Disconnect(1)
+	if online, _ := a.Online(ctx, 1); !online {
+		t.Fatalf("expected online while instance b still holds a connection")
+	}
+	b.Disconnect(1)
+	if online, _ := a.Online(ctx, 1); online {
+		t.Fatalf("expected offline after all instances disconnect")
+	}
+	if users := a.LocalUsers(); len(users) != 0 {
+		t.Fatalf("expected no local users, got %v", users)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
 	"feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/search"
 	"feedsystem_video_go/internal/video"
 	"fmt"
 	"log"
@@ -14,36 +16,59 @@ import (
 	"gorm.io/gorm"
 )
 
-func StartOutboxPoller(db *gorm.DB, tmq *rabbitmq.TimelineMQ) {
-	if db == nil || tmq == nil || tmq.RabbitMQ == nil || tmq.Ch == nil {
-		log.Printf("Outbox poller disabled: timeline mq is not initialized")
+// StartOutboxRelay 在 API 进程里投递 outbox_events；多实例可以同时运行，靠行锁分批认领。
+// 视频发布/恢复/删除/可见范围变更投递成功后在本进程同步搜索索引、话题热度和回收站清理；
+// 这些处理失败时事件留在 outbox 里按退避重试，直到成功或变成 dead
+func StartOutboxRelay(db *gorm.DB, rmq *rabbitmq.RabbitMQ, indexer *search.Indexer, tags *video.TagService, videos *video.VideoService) {
+	if db == nil || rmq == nil || rmq.Ch == nil {
+		log.Printf("Outbox relay disabled: rabbitmq is not initialized")
+		return
+	}
+	if err := rabbitmq.DeclareOutboxTopology(rmq); err != nil {
+		log.Printf("Outbox relay disabled: declare topology failed: %v", err)
 		return
 	}
 
-	go func() {
-		for {
-			var messages []video.OutboxMsg
-
-			err := db.Where("status = ?", "pending").Order("create_time ASC").Limit(100).Find(&messages).Error
-
-			if err != nil || len(messages) == 0 {
-				time.Sleep(1 * time.Second)
-				continue
-			}
-
-			for _, msg := range messages {
-				err := tmq.PublishVideo(context.Background(), msg.VideoID, msg.CreateTime)
-
-				if err == nil {
-					if err := db.Delete(&msg).Error; err != nil {
-						log.Printf("删除 outbox 消息失败: id=%d, err=%v", msg.ID, err)
-					}
-				} else {
-					log.Printf("投递MQ失败: VideoID: %d, err: %v", msg.VideoID, err)
-				}
-			}
+	relay := outbox.NewRelay(db, rmq)
+	relay.Handle(rabbitmq.VideoPublishedType, func(ctx context.Context, e *outbox.Event) error {
+		var evt rabbitmq.TimelineEvent
+		if err := json.Unmarshal(e.Payload, &evt); err != nil {
+			// 坏消息重试也不会成功，直接丢弃
+			return nil
 		}
-	}()
+		if err := indexer.Published(ctx, evt.VideoID); err != nil {
+			return err
+		}
+		// 话题加分按视频去重，重投不会重复计分
+		if evt.Visibility == video.VisibilityPublic {
+			return tags.Published(ctx, evt.VideoID)
+		}
+		return nil
+	})
+	relay.Handle(rabbitmq.VideoRestoredType, func(ctx context.Context, e *outbox.Event) error {
+		var evt rabbitmq.TimelineEvent
+		if err := json.Unmarshal(e.Payload, &evt); err != nil {
+			return nil
+		}
+		// 恢复只重建索引，发布分在第一次发布时已经加过
+		return indexer.Published(ctx, evt.VideoID)
+	})
+	relay.Handle(rabbitmq.VideoVisibilityChangedType, func(ctx context.Context, e *outbox.Event) error {
+		var evt rabbitmq.TimelineEvent
+		if err := json.Unmarshal(e.Payload, &evt); err != nil {
+			return nil
+		}
+		// 索引以数据库为准：变为公开时写入，否则删除
+		return indexer.Published(ctx, evt.VideoID)
+	})
+	relay.Handle(rabbitmq.VideoDeletedType, func(ctx context.Context, e *outbox.Event) error {
+		var evt rabbitmq.TimelineEvent
+		if err := json.Unmarshal(e.Payload, &evt); err != nil {
+			return nil
+		}
+		return videos.CleanupDeleted(ctx, evt.VideoID)
+	})
+	go relay.Run(context.Background())
 }
 
 func StartConsumer(tmq *rabbitmq.TimelineMQ, queueName string, redisClient *redis.Client) {
@@ -82,6 +107,24 @@ func StartConsumer(tmq *rabbitmq.TimelineMQ, queueName string, redisClient *redi
 				continue
 			}
 
+			// 删除，或已发布视频改成了非公开：从全站时间线移除
+			if rabbitmq.IsTimelineDelete(msg.RoutingKey) ||
+				(rabbitmq.IsTimelineVisibilityChange(msg.RoutingKey) && event.Visibility != video.VisibilityPublic) {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				if err := redisClient.ZRem(ctx, redisClient.Key("feed:global_timeline"), fmt.Sprintf("%d", event.VideoID)); err != nil {
+					log.Printf("ZRem失败")
+				}
+				cancel()
+				msg.Ack(false)
+				continue
+			}
+
+			if event.Visibility != "" && event.Visibility != video.VisibilityPublic {
+				// 全站时间线只放公开视频
+				msg.Ack(false)
+				continue
+			}
+
 			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 			timelineKey := redisClient.Key("feed:global_timeline")
 			err = redisClient.ZAdd(ctx, timelineKey, oredis.Z{
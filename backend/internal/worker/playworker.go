@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/video"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	playFlushInterval = 10 * time.Second
+	// 攒到这么多条未确认消息就提前刷新，需小于 PlayPrefetch，否则会卡住
+	playFlushBatch = 40
+	// PlayPrefetch PlayWorker 独占通道的 Qos prefetch
+	PlayPrefetch = 64
+)
+
+// PlayWorker 在内存里聚合播放事件，定时或攒够一批后统一落库；
+// 消息在落库成功后才批量 ack，落库失败时保留聚合结果并在下一轮重试。
+// 批量 ack 会确认通道上此前的所有消息，所以 ch 不能与其他 worker 共用
+type PlayWorker struct {
+	ch       *amqp.Channel
+	recorder *video.PlayRecorder
+	queue    string
+
+	agg     *video.PlayAggregator
+	pending int
+	lastTag uint64
+}
+
+func NewPlayWorker(ch *amqp.Channel, recorder *video.PlayRecorder, queue string) *PlayWorker {
+	return &PlayWorker{ch: ch, recorder: recorder, queue: queue, agg: video.NewPlayAggregator()}
+}
+
+func (w *PlayWorker) Run(ctx context.Context) error {
+	if w == nil || w.ch == nil || w.recorder == nil {
+		return errors.New("play worker is not initialized")
+	}
+	if w.queue == "" {
+		return errors.New("queue is required")
+	}
+
+	deliveries, err := w.ch.Consume(
+		w.queue,
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(playFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			w.flush(flushCtx)
+			cancel()
+			return ctx.Err()
+		case <-ticker.C:
+			w.flush(ctx)
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("deliveries channel closed")
+			}
+			w.handleDelivery(d)
+			if w.pending >= playFlushBatch {
+				w.flush(ctx)
+			}
+		}
+	}
+}
+
+func (w *PlayWorker) handleDelivery(d amqp.Delivery) {
+	var batch rabbitmq.PlayBatchEvent
+	if err := json.Unmarshal(d.Body, &batch); err != nil {
+		// 解析事件失败，直接丢弃
+		_ = d.Ack(false)
+		return
+	}
+	for _, e := range batch.Events {
+		w.agg.Add(e.VideoID, e.Viewer, e.Type, e.Progress)
+	}
+	w.pending++
+	w.lastTag = d.DeliveryTag
+}
+
+func (w *PlayWorker) flush(ctx context.Context) {
+	if w.pending == 0 {
+		return
+	}
+	if err := w.recorder.Record(ctx, w.agg); err != nil {
+		log.Printf("play worker: flush %d messages failed, will retry: %v", w.pending, err)
+		return
+	}
+	if err := w.ch.Ack(w.lastTag, true); err != nil {
+		log.Printf("play worker: ack failed: %v", err)
+	}
+	w.agg = video.NewPlayAggregator()
+	w.pending = 0
+}
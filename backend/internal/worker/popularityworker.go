@@ -13,13 +13,14 @@ import (
 )
 
 type PopularityWorker struct {
-	ch    *amqp.Channel
-	cache *rediscache.Client
-	queue string
+	ch     *amqp.Channel
+	cache  *rediscache.Client
+	ledger *RedisLedger
+	queue  string
 }
 
 func NewPopularityWorker(ch *amqp.Channel, cache *rediscache.Client, queue string) *PopularityWorker {
-	return &PopularityWorker{ch: ch, cache: cache, queue: queue}
+	return &PopularityWorker{ch: ch, cache: cache, ledger: NewRedisLedger(cache, "popularity"), queue: queue}
 }
 
 func (w *PopularityWorker) Run(ctx context.Context) error {
@@ -57,7 +58,10 @@ func (w *PopularityWorker) Run(ctx context.Context) error {
 }
 
 func (w *PopularityWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
-	if err := w.process(ctx, d.Body); err != nil {
+	err := w.ledger.Apply(ctx, deliveryEventID(d), func(ctx context.Context) error {
+		return w.process(ctx, d.Body)
+	})
+	if err != nil {
 		retryCount := rabbitmq.GetRetryCount(d)
 		if retryCount >= rabbitmq.MaxRetryCount {
 			log.Printf("popularity worker: max retries exceeded (%d), moving to DLX: %v", retryCount, err)
@@ -79,6 +83,5 @@ func (w *PopularityWorker) process(ctx context.Context, body []byte) error {
 	if evt.VideoID == 0 || evt.Change == 0 {
 		return nil
 	}
-	video.UpdatePopularityCache(ctx, w.cache, evt.VideoID, evt.Change)
-	return nil
+	return video.UpdatePopularityCache(ctx, w.cache, evt.VideoID, evt.Change)
 }
@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// Presence 记录用户在哪些实例上有长连接
+type Presence interface {
+	Connect(userID uint)
+	Disconnect(userID uint)
+	Online(ctx context.Context, userID uint) (bool, error)
+}
+
+// localPresence 本实例每个用户的连接数
+type localPresence struct {
+	mu     sync.Mutex
+	counts map[uint]int
+}
+
+// add 返回变化后的连接数
+func (p *localPresence) add(userID uint, delta int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := p.counts[userID] + delta
+	if n <= 0 {
+		delete(p.counts, userID)
+		return 0
+	}
+	p.counts[userID] = n
+	return n
+}
+
+func (p *localPresence) users() []uint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]uint, 0, len(p.counts))
+	for uid := range p.counts {
+		out = append(out, uid)
+	}
+	return out
+}
+
+type MemoryPresence struct {
+	local localPresence
+}
+
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{local: localPresence{counts: make(map[uint]int)}}
+}
+
+func (p *MemoryPresence) Connect(userID uint)    { p.local.add(userID, 1) }
+func (p *MemoryPresence) Disconnect(userID uint) { p.local.add(userID, -1) }
+
+func (p *MemoryPresence) Online(ctx context.Context, userID uint) (bool, error) {
+	p.local.mu.Lock()
+	defer p.local.mu.Unlock()
+	return p.local.counts[userID] > 0, nil
+}
+
+// LocalUsers 本实例当前在线的用户
+func (p *MemoryPresence) LocalUsers() []uint { return p.local.users() }
+
+// RedisPresence 每个用户一个 zset：member 为实例 ID，score 为过期时间（毫秒）。
+// 实例定期续期本地在线用户，实例宕机后记录在 ttl 内自然失效
+type RedisPresence struct {
+	cache      *rediscache.Client
+	instanceID string
+	ttl        time.Duration
+	local      localPresence
+}
+
+const (
+	defaultPresenceTTL = 90 * time.Second
+	presenceOpTimeout  = 200 * time.Millisecond
+)
+
+func NewRedisPresence(cache *rediscache.Client, instanceID string) *RedisPresence {
+	return &RedisPresence{
+		cache:      cache,
+		instanceID: instanceID,
+		ttl:        defaultPresenceTTL,
+		local:      localPresence{counts: make(map[uint]int)},
+	}
+}
+
+func (p *RedisPresence) key(userID uint) string {
+	return p.cache.Key("presence:user:%d", userID)
+}
+
+func (p *RedisPresence) touch(ctx context.Context, userID uint) error {
+	expireAt := time.Now().Add(p.ttl).UnixMilli()
+	key := p.key(userID)
+	if err := p.cache.ZAdd(ctx, key, redis.Z{Score: float64(expireAt), Member: p.instanceID}); err != nil {
+		return err
+	}
+	_ = p.cache.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	return p.cache.Expire(ctx, key, p.ttl)
+}
+
+func (p *RedisPresence) Connect(userID uint) {
+	if p.local.add(userID, 1) != 1 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), presenceOpTimeout)
+	defer cancel()
+	_ = p.touch(ctx, userID)
+}
+
+func (p *RedisPresence) Disconnect(userID uint) {
+	if p.local.add(userID, -1) != 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), presenceOpTimeout)
+	defer cancel()
+	_ = p.cache.ZRem(ctx, p.key(userID), p.instanceID)
+}
+
+func (p *RedisPresence) Online(ctx context.Context, userID uint) (bool, error) {
+	n, err := p.cache.ZCount(ctx, p.key(userID), strconv.FormatInt(time.Now().UnixMilli(), 10), "+inf")
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// LocalUsers 本实例当前在线的用户
+func (p *RedisPresence) LocalUsers() []uint { return p.local.users() }
+
+// Run 定期为本地在线用户续期，阻塞直到 ctx 结束
+func (p *RedisPresence) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, uid := range p.local.users() {
+				opCtx, cancel := context.WithTimeout(ctx, presenceOpTimeout)
+				_ = p.touch(opCtx, uid)
+				cancel()
+			}
+		}
+	}
+}
+
+// NewInstanceID 主机名加随机后缀，同一主机上的多个进程也能区分
+func NewInstanceID() string {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "instance"
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+var (
+	_ Presence = (*MemoryPresence)(nil)
+	_ Presence = (*RedisPresence)(nil)
+)
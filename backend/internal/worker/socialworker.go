@@ -4,26 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"feedsystem_video_go/internal/creator"
+	"feedsystem_video_go/internal/feed"
 	"feedsystem_video_go/internal/middleware/rabbitmq"
-	"feedsystem_video_go/internal/social"
 	"log"
 
-	"github.com/go-sql-driver/mysql"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
 )
 
 type SocialWorker struct {
-	ch    *amqp.Channel
-	repo  *social.SocialRepository
-	queue string
+	ch     *amqp.Channel
+	inbox  *feed.Inbox
+	stats  *creator.StatRepository
+	ledger *DBLedger
+	queue  string
 }
 
-func NewSocialWorker(ch *amqp.Channel, repo *social.SocialRepository, queue string) *SocialWorker {
-	return &SocialWorker{ch: ch, repo: repo, queue: queue}
+func NewSocialWorker(ch *amqp.Channel, inbox *feed.Inbox, stats *creator.StatRepository, ledger *DBLedger, queue string) *SocialWorker {
+	return &SocialWorker{ch: ch, inbox: inbox, stats: stats, ledger: ledger, queue: queue}
 }
 
 func (w *SocialWorker) Run(ctx context.Context) error {
-	if w == nil || w.ch == nil || w.repo == nil {
+	if w == nil || w.ch == nil || w.ledger == nil {
 		return errors.New("social worker is not initialized")
 	}
 	if w.queue == "" {
@@ -57,7 +60,10 @@ func (w *SocialWorker) Run(ctx context.Context) error {
 }
 
 func (w *SocialWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
-	if err := w.process(ctx, d.Body); err != nil {
+	err := w.ledger.Apply(ctx, deliveryEventID(d), func(tx *gorm.DB) error {
+		return w.process(ctx, tx, d.Body)
+	})
+	if err != nil {
 		retryCount := rabbitmq.GetRetryCount(d)
 		if retryCount >= rabbitmq.MaxRetryCount {
 			log.Printf("social worker: max retries exceeded (%d), moving to DLX: %v", retryCount, err)
@@ -71,7 +77,7 @@ func (w *SocialWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
 	_ = d.Ack(false)
 }
 
-func (w *SocialWorker) process(ctx context.Context, body []byte) error {
+func (w *SocialWorker) process(ctx context.Context, tx *gorm.DB, body []byte) error {
 	var evt rabbitmq.SocialEvent
 	if err := json.Unmarshal(body, &evt); err != nil {
 		// 解析事件失败，直接丢弃
@@ -81,26 +87,22 @@ func (w *SocialWorker) process(ctx context.Context, body []byte) error {
 		return nil
 	}
 
+	var followers int64
 	switch evt.Action {
 	case "follow":
-		err := w.repo.Follow(ctx, &social.Social{
-			FollowerID: evt.FollowerID,
-			VloggerID:  evt.VloggerID,
-		})
-		if err == nil {
-			return nil
-		}
-		var mysqlErr *mysql.MySQLError
-		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-			return nil
-		}
-		return err
+		followers = 1
 	case "unfollow":
-		return w.repo.Unfollow(ctx, &social.Social{
-			FollowerID: evt.FollowerID,
-			VloggerID:  evt.VloggerID,
-		})
+		followers = -1
 	default:
 		return nil
 	}
+	// 关系已由接口层与事件同一事务写入，事件只在关系真正变化时产生
+	if err := recordCreatorStat(ctx, tx, w.stats, evt.VloggerID, 0, evt.OccurredAt, creator.StatDelta{NewFollowers: followers}); err != nil {
+		return err
+	}
+	// 关注关系变化：收件箱作废，下次读取按新关注列表重建
+	if err := w.inbox.Invalidate(ctx, evt.FollowerID); err != nil {
+		log.Printf("social worker: invalidate inbox failed: %v", err)
+	}
+	return nil
 }
@@ -1,11 +1,15 @@
 package worker
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,55 +19,134 @@ import (
 	"gorm.io/gorm"
 )
 
+// SSEHub 本地只保存本实例的连接；bus 负责把通知广播到所有实例，presence 记录用户在线位置
 type SSEHub struct {
-	mu      sync.RWMutex
-	clients map[uint][]chan *Notification
-	db      *gorm.DB
+	mu       sync.RWMutex
+	clients  map[uint][]*Subscriber
+	db       *gorm.DB
+	bus      NotificationBus
+	presence Presence
 }
 
-func NewSSEHub(db *gorm.DB) *SSEHub {
-	return &SSEHub{clients: make(map[uint][]chan *Notification), db: db}
+const busPublishTimeout = time.Second
+
+// NewSSEHub bus 为 nil 时退化为只推送本实例连接
+func NewSSEHub(db *gorm.DB, bus NotificationBus, presence Presence) *SSEHub {
+	if presence == nil {
+		presence = NewMemoryPresence()
+	}
+	return &SSEHub{clients: make(map[uint][]*Subscriber), db: db, bus: bus, presence: presence}
 }
 
+// Push 经 bus 广播，由用户实际连接所在的实例投递；用户不在线时直接跳过
 func (h *SSEHub) Push(userID uint, n *Notification) {
+	if h.bus == nil {
+		h.deliverLocal(userID, n)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), busPublishTimeout)
+	defer cancel()
+	if online, err := h.presence.Online(ctx, userID); err == nil && !online {
+		return
+	}
+	if err := h.bus.Publish(ctx, userID, n); err != nil {
+		log.Printf("notification bus publish failed, delivering locally: %v", err)
+		h.deliverLocal(userID, n)
+	}
+}
+
+// Run 订阅 bus 并投递给本地连接，订阅断开后重试；阻塞直到 ctx 结束
+func (h *SSEHub) Run(ctx context.Context) {
+	if r, ok := h.presence.(interface{ Run(context.Context) }); ok {
+		go r.Run(ctx)
+	}
+	if h.bus == nil {
+		return
+	}
+	backoff := time.Second
+	for {
+		err := h.bus.Subscribe(ctx, h.deliverLocal)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("notification bus subscribe: %v (retry in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Subscriber 一条 SSE 连接；缓冲写满时关闭 Overflow，而不是静默丢弃
+type Subscriber struct {
+	C        <-chan *Notification
+	Overflow <-chan struct{}
+
+	ch       chan *Notification
+	overflow chan struct{}
+	once     sync.Once
+}
+
+const sseClientBuffer = 64
+
+func (s *Subscriber) signalOverflow() {
+	s.once.Do(func() { close(s.overflow) })
+}
+
+func (h *SSEHub) deliverLocal(userID uint, n *Notification) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	chs, ok := h.clients[userID]
+	subs, ok := h.clients[userID]
 	if !ok {
 		return
 	}
-	for _, ch := range chs {
+	for _, sub := range subs {
 		select {
-		case ch <- n:
+		case sub.ch <- n:
 		default:
+			sub.signalOverflow()
 		}
 	}
 }
 
-func (h *SSEHub) Subscribe(userID uint) chan *Notification {
-	ch := make(chan *Notification, 20)
+func (h *SSEHub) Subscribe(userID uint) *Subscriber {
+	ch := make(chan *Notification, sseClientBuffer)
+	overflow := make(chan struct{})
+	sub := &Subscriber{C: ch, Overflow: overflow, ch: ch, overflow: overflow}
 	h.mu.Lock()
-	h.clients[userID] = append(h.clients[userID], ch)
+	h.clients[userID] = append(h.clients[userID], sub)
 	h.mu.Unlock()
-	return ch
+	h.presence.Connect(userID)
+	return sub
 }
 
-func (h *SSEHub) Unsubscribe(userID uint, ch chan *Notification) {
+func (h *SSEHub) Unsubscribe(userID uint, sub *Subscriber) {
+	if h.removeClient(userID, sub) {
+		h.presence.Disconnect(userID)
+	}
+}
+
+func (h *SSEHub) removeClient(userID uint, sub *Subscriber) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	chs := h.clients[userID]
-	for i, c := range chs {
-		if c == ch {
-			chs = append(chs[:i], chs[i+1:]...)
-			if len(chs) == 0 {
+	subs := h.clients[userID]
+	for i, s := range subs {
+		if s == sub {
+			subs = append(subs[:i], subs[i+1:]...)
+			if len(subs) == 0 {
 				delete(h.clients, userID)
 			} else {
-				h.clients[userID] = chs
+				h.clients[userID] = subs
 			}
-			close(c)
-			return
+			close(s.ch)
+			return true
 		}
 	}
+	return false
 }
 
 func sseAccountID(c *gin.Context) (uint, bool) {
@@ -98,42 +181,190 @@ func (h *SSEHub) SSERequireAuth() gin.HandlerFunc {
 	}
 }
 
+const sseReplayPage = 200
+
+// eventCursor SSE 事件 id。聚合通知被更新时 ID 不变，所以用 (updated_at, id) 作为单调游标，
+// 格式为 "<updated_at 微秒>-<id>"。旧客户端带的是纯数字的 Notification.ID（legacy），按 id 补发
+type eventCursor struct {
+	at     int64
+	id     uint
+	legacy bool
+}
+
+func cursorOf(n *Notification) eventCursor {
+	if n.UpdatedAt.IsZero() {
+		return eventCursor{id: n.ID}
+	}
+	return eventCursor{at: n.UpdatedAt.UnixMicro(), id: n.ID}
+}
+
+func (c eventCursor) String() string {
+	if c.legacy {
+		return strconv.FormatUint(uint64(c.id), 10)
+	}
+	return fmt.Sprintf("%d-%d", c.at, c.id)
+}
+
+func (c eventCursor) IsZero() bool {
+	return c.at == 0 && c.id == 0
+}
+
+func (c eventCursor) after(o eventCursor) bool {
+	return c.at > o.at || (c.at == o.at && c.id > o.id)
+}
+
+func parseEventCursor(v string) (eventCursor, bool) {
+	at, id, ok := strings.Cut(v, "-")
+	if !ok {
+		nid, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return eventCursor{}, false
+		}
+		return eventCursor{id: uint(nid), legacy: true}, true
+	}
+	micros, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return eventCursor{}, false
+	}
+	nid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return eventCursor{}, false
+	}
+	return eventCursor{at: micros, id: uint(nid)}, true
+}
+
+// lastEventID 浏览器 EventSource 重连时自动带 Last-Event-ID；手动重连可用 ?last_event_id=。
+// 没带时返回零值，格式不对时返回 false
+func lastEventID(c *gin.Context) (eventCursor, bool) {
+	v := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if v == "" {
+		v = strings.TrimSpace(c.Query("last_event_id"))
+	}
+	if v == "" {
+		return eventCursor{}, true
+	}
+	return parseEventCursor(v)
+}
+
+// SSEHandler 每帧带事件 id；带 Last-Event-ID 重连时先从表里补发之后新增或更新过的通知。
+// 连接消费太慢导致缓冲写满时，发送 overflow 事件并断开，客户端重连后通过补发追上
 func (h *SSEHub) SSEHandler(c *gin.Context) {
 	userID, ok := sseAccountID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid account"})
 		return
 	}
+	lastSent, ok := lastEventID(c)
+	if !ok {
+		log.Printf("sse: user %d sent malformed Last-Event-ID (header %q, query %q)", userID, c.GetHeader("Last-Event-ID"), c.Query("last_event_id"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+		return
+	}
 
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
 	c.Writer.Header().Set("Connection", "keep-alive")
 	c.Writer.WriteHeader(http.StatusOK)
 
-	ch := h.Subscribe(userID)
-	defer h.Unsubscribe(userID, ch)
-
 	ctx := c.Request.Context()
 	flusher, _ := c.Writer.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	flush()
+
+	// 先订阅再补发，补发期间到达的通知留在缓冲里，按游标去重
+	sub := h.Subscribe(userID)
+	defer h.Unsubscribe(userID, sub)
+
+	resume := !lastSent.IsZero()
+	emit := func(n *Notification) {
+		cur := cursorOf(n)
+		b, _ := json.Marshal(n)
+		fmt.Fprintf(c.Writer, "id: %s\ndata: %s\n\n", cur, b)
+		if lastSent.legacy || cur.after(lastSent) {
+			lastSent = cur
+		}
+	}
+	writeNotification := func(n *Notification) {
+		if cursorOf(n).after(lastSent) {
+			emit(n)
+		}
+	}
+
+	if resume && lastSent.legacy && h.db != nil {
+		// 旧格式只有 Notification.ID：补发 id 之后的通知，之后改用 (updated_at, id) 游标
+		afterID := lastSent.id
+		for {
+			var missed []Notification
+			if err := h.db.WithContext(ctx).
+				Where("recipient_id = ? AND id > ?", userID, afterID).
+				Order("id asc").
+				Limit(sseReplayPage).
+				Find(&missed).Error; err != nil {
+				log.Printf("sse replay for user %d failed: %v", userID, err)
+				break
+			}
+			for i := range missed {
+				emit(&missed[i])
+				afterID = missed[i].ID
+			}
+			flush()
+			if len(missed) < sseReplayPage {
+				break
+			}
+		}
+	} else if resume && h.db != nil {
+		for {
+			var missed []Notification
+			at := time.UnixMicro(lastSent.at)
+			if err := h.db.WithContext(ctx).
+				Where("recipient_id = ? AND (updated_at > ? OR (updated_at = ? AND id > ?))", userID, at, at, lastSent.id).
+				Order("updated_at asc, id asc").
+				Limit(sseReplayPage).
+				Find(&missed).Error; err != nil {
+				log.Printf("sse replay for user %d failed: %v", userID, err)
+				break
+			}
+			for i := range missed {
+				writeNotification(&missed[i])
+			}
+			flush()
+			if len(missed) < sseReplayPage {
+				break
+			}
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case n, ok := <-ch:
+		case <-sub.Overflow:
+			// 先把缓冲里已有的发完，再告知客户端从 last_event_id 重连
+		drain:
+			for {
+				select {
+				case n := <-sub.C:
+					writeNotification(n)
+				default:
+					break drain
+				}
+			}
+			fmt.Fprintf(c.Writer, "event: overflow\ndata: {\"last_event_id\":%q}\n\n", lastSent.String())
+			flush()
+			return
+		case n, ok := <-sub.C:
 			if !ok {
 				return
 			}
-			b, _ := json.Marshal(n)
-			fmt.Fprintf(c.Writer, "data: %s\n\n", b)
-			if flusher != nil {
-				flusher.Flush()
-			}
+			writeNotification(n)
+			flush()
 		case <-time.After(30 * time.Second):
 			fmt.Fprintf(c.Writer, ": keepalive\n\n")
-			if flusher != nil {
-				flusher.Flush()
-			}
+			flush()
 		}
 	}
 }
@@ -148,7 +379,7 @@ func (h *SSEHub) ListHandler(c *gin.Context) {
 	var notifications []Notification
 	if err := h.db.WithContext(c.Request.Context()).
 		Where("recipient_id = ?", userID).
-		Order("created_at desc").
+		Order("updated_at desc, id desc").
 		Limit(50).
 		Find(&notifications).Error; err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
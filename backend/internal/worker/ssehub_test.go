@@ -0,0 +1,157 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSSEHubSignalsOverflowInsteadOfDropping(t *testing.T) {
+	hub := NewSSEHub(nil, nil, nil)
+	sub := hub.Subscribe(1)
+	defer hub.Unsubscribe(1, sub)
+
+	for i := 1; i <= sseClientBuffer; i++ {
+		hub.Push(1, &Notification{ID: uint(i)})
+	}
+	select {
+	case <-sub.Overflow:
+		t.Fatalf("overflow signalled before buffer was full")
+	default:
+	}
+	hub.Push(1, &Notification{ID: sseClientBuffer + 1})
+	select {
+	case <-sub.Overflow:
+	default:
+		t.Fatalf("expected overflow signal once buffer is full")
+	}
+}
+
+func TestSSEHandlerWritesEventIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := NewSSEHub(nil, nil, nil)
+	r := gin.New()
+	r.GET("/stream", func(c *gin.Context) { c.Set("accountID", uint(3)) }, hub.SSEHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stream", nil)
+	// 没有 db 时不补发，但 Last-Event-ID 之前的通知仍会被去重
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	req.Header.Set("Last-Event-ID", eventCursor{at: base.UnixMicro(), id: 4}.String())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for online, _ := hub.presence.Online(ctx, 3); !online; online, _ = hub.presence.Online(ctx, 3) {
+		if time.Now().After(deadline) {
+			t.Fatalf("stream never subscribed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	hub.Push(3, &Notification{ID: 4, Type: "like", UpdatedAt: base})
+	// 聚合通知 ID 不变，但 updated_at 前进后会重新推送
+	hub.Push(3, &Notification{ID: 2, Type: "follow", UpdatedAt: base.Add(time.Second)})
+
+	sc := bufio.NewScanner(resp.Body)
+	var lines []string
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == 2 {
+			break
+		}
+	}
+	want := "id: " + eventCursor{at: base.Add(time.Second).UnixMicro(), id: 2}.String()
+	if len(lines) != 2 || lines[0] != want || !strings.Contains(lines[1], `"type":"follow"`) {
+		t.Fatalf("unexpected frames %q", lines)
+	}
+}
+
+func TestEventCursorRoundTrip(t *testing.T) {
+	c := eventCursor{at: 1700000000123456, id: 42}
+	got, ok := parseEventCursor(c.String())
+	if !ok || got != c {
+		t.Fatalf("round trip: %+v %v", got, ok)
+	}
+	// 旧客户端的纯数字 Notification.ID
+	if got, ok := parseEventCursor("42"); !ok || got != (eventCursor{id: 42, legacy: true}) || got.String() != "42" {
+		t.Fatalf("expected bare id to parse as legacy cursor, got %+v %v", got, ok)
+	}
+	for _, bad := range []string{"abc", "1-x", "-5", "1.5"} {
+		if _, ok := parseEventCursor(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	if !(eventCursor{at: 1, id: 1}).after(eventCursor{at: 0, id: 9}) || (eventCursor{at: 1, id: 1}).after(eventCursor{at: 1, id: 1}) {
+		t.Fatalf("unexpected ordering")
+	}
+}
+
+func TestSSEHandlerReplaysFromLegacyNumericID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &Notification{})
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		if err := db.Create(&Notification{RecipientID: 3, SenderID: 1, Type: "like", UpdatedAt: base.Add(time.Duration(i) * time.Second)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	hub := NewSSEHub(db, nil, nil)
+	r := gin.New()
+	r.GET("/stream", func(c *gin.Context) { c.Set("accountID", uint(3)) }, hub.SSEHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	sc := bufio.NewScanner(resp.Body)
+	var ids []string
+	for sc.Scan() && len(ids) < 2 {
+		if line := sc.Text(); strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	want := []string{
+		eventCursor{at: base.Add(2 * time.Second).UnixMicro(), id: 2}.String(),
+		eventCursor{at: base.Add(3 * time.Second).UnixMicro(), id: 3}.String(),
+	}
+	if len(ids) != 2 || ids[0] != want[0] || ids[1] != want[1] {
+		t.Fatalf("replayed ids %q, want %q", ids, want)
+	}
+}
+
+func TestSSEHandlerRejectsMalformedLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := NewSSEHub(nil, nil, nil)
+	r := gin.New()
+	r.GET("/stream", func(c *gin.Context) { c.Set("accountID", uint(3)) }, hub.SSEHandler)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stream?last_event_id=oops", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,287 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	rediscache "feedsystem_video_go/internal/middleware/redis"
+	"feedsystem_video_go/internal/storage"
+	"feedsystem_video_go/internal/transcode"
+	"feedsystem_video_go/internal/video"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
+)
+
+// errTranscodeBusy 视频正被另一个 worker 转码且租约未过期，消息稍后重投
+var errTranscodeBusy = errors.New("transcode is held by another worker")
+
+// TranscodeWorker 消费转码请求，把上传的原文件切成多码率 HLS
+type TranscodeWorker struct {
+	ch         *amqp.Channel
+	videos     *video.VideoRepository
+	cache      *rediscache.Client
+	transcoder transcode.Transcoder
+	store      storage.Store
+	queue      string
+	// lease 转码租约，执行期间每 lease/3 续一次；进程退出后租约过期，重投的消息才能重新认领
+	lease time.Duration
+	// busyDelay 遇到租约未过期的视频时，等待多久再把消息放回队列
+	busyDelay time.Duration
+}
+
+func NewTranscodeWorker(ch *amqp.Channel, videos *video.VideoRepository, cache *rediscache.Client, transcoder transcode.Transcoder, store storage.Store, queue string) *TranscodeWorker {
+	return &TranscodeWorker{
+		ch:         ch,
+		videos:     videos,
+		cache:      cache,
+		transcoder: transcoder,
+		store:      store,
+		queue:      queue,
+		lease:      2 * time.Minute,
+		busyDelay:  10 * time.Second,
+	}
+}
+
+func (w *TranscodeWorker) Run(ctx context.Context) error {
+	if w == nil || w.ch == nil || w.videos == nil || w.transcoder == nil || w.store == nil {
+		return errors.New("transcode worker is not initialized")
+	}
+	if w.queue == "" {
+		return errors.New("queue is required")
+	}
+
+	deliveries, err := w.ch.Consume(
+		w.queue,
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return errors.New("deliveries channel closed")
+			}
+			w.handleDelivery(ctx, d)
+		}
+	}
+}
+
+func (w *TranscodeWorker) handleDelivery(ctx context.Context, d amqp.Delivery) {
+	if err := w.process(ctx, d.Body); err != nil {
+		if errors.Is(err, errTranscodeBusy) {
+			// 不计入重试次数：等正在执行的转码结束或租约过期
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.busyDelay):
+			}
+			_ = d.Nack(false, true)
+			return
+		}
+		retryCount := rabbitmq.GetRetryCount(d)
+		if retryCount >= rabbitmq.MaxRetryCount {
+			log.Printf("transcode worker: max retries exceeded (%d), moving to DLX: %v", retryCount, err)
+			_ = d.Ack(false)
+			return
+		}
+		log.Printf("transcode worker: failed (retry %d/%d): %v", retryCount+1, rabbitmq.MaxRetryCount, err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+func (w *TranscodeWorker) process(ctx context.Context, body []byte) error {
+	var evt rabbitmq.TranscodeEvent
+	if err := json.Unmarshal(body, &evt); err != nil {
+		return nil
+	}
+	if evt.VideoID == 0 {
+		return nil
+	}
+
+	v, err := w.videos.GetByID(ctx, evt.VideoID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	now := time.Now()
+	ok, err := w.videos.MarkProcessing(ctx, v.ID, now, now.Add(w.lease))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if v.IsTranscoding() {
+			return errTranscodeBusy
+		}
+		// 已经 ready/failed，重复投递直接丢弃
+		return nil
+	}
+	w.invalidateDetail(v.ID)
+
+	stopRenew := w.renewLease(ctx, v.ID)
+	playURL, err := w.transcode(ctx, v)
+	stopRenew()
+	if err != nil {
+		var perm *permanentError
+		if !errors.As(err, &perm) {
+			return err
+		}
+		// 源文件缺失、ffmpeg 报错之类重试也不会成功，直接标记 failed
+		log.Printf("transcode worker: video %d failed: %v", v.ID, err)
+		if err := w.videos.MarkFailed(ctx, v.ID); err != nil {
+			return err
+		}
+		w.invalidateDetail(v.ID)
+		return nil
+	}
+
+	if err := w.videos.MarkReady(ctx, v, playURL); err != nil {
+		return err
+	}
+	w.invalidateDetail(v.ID)
+	return nil
+}
+
+// renewLease 转码期间定期续租，返回的函数停止续租
+func (w *TranscodeWorker) renewLease(ctx context.Context, id uint) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(w.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.videos.RenewProcessing(ctx, id, time.Now().Add(w.lease)); err != nil {
+					log.Printf("transcode worker: renew lease of video %d failed: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (w *TranscodeWorker) transcode(ctx context.Context, v *video.Video) (string, error) {
+	sourceURL := v.SourceURL
+	if sourceURL == "" {
+		sourceURL = v.PlayURL
+	}
+	srcKey, ok := storage.KeyFromURL(w.store, sourceURL)
+	if !ok {
+		return "", &permanentError{fmt.Errorf("source %q is not in storage", sourceURL)}
+	}
+
+	workDir, err := os.MkdirTemp("", fmt.Sprintf("transcode-%d-*", v.ID))
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	src := filepath.Join(workDir, "source"+path.Ext(srcKey))
+	if err := w.download(ctx, srcKey, src); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", &permanentError{err}
+		}
+		return "", err
+	}
+
+	outDir := filepath.Join(workDir, "out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return "", err
+	}
+	res, err := w.transcoder.Transcode(ctx, src, outDir, transcode.DefaultLadder)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return "", &permanentError{err}
+	}
+
+	prefix := path.Join("hls", strconv.FormatUint(uint64(v.ID), 10))
+	if err := w.upload(ctx, outDir, prefix); err != nil {
+		return "", err
+	}
+	return w.store.URL(path.Join(prefix, res.MasterPlaylist)), nil
+}
+
+func (w *TranscodeWorker) download(ctx context.Context, key string, dst string) error {
+	rc, _, err := w.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// upload 把输出目录整体写入存储，playlist 里的相对路径保持不变
+func (w *TranscodeWorker) upload(ctx context.Context, dir string, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		return w.store.Put(ctx, key, f, fi.Size(), storage.ContentTypeByKey(key))
+	})
+}
+
+func (w *TranscodeWorker) invalidateDetail(videoID uint) {
+	if w.cache == nil {
+		return
+	}
+	_ = w.cache.Del(context.Background(), w.cache.Key("video:detail:id=%d", videoID))
+}
@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"feedsystem_video_go/internal/dbtest"
+	"feedsystem_video_go/internal/middleware/rabbitmq"
+	"feedsystem_video_go/internal/outbox"
+	"feedsystem_video_go/internal/storage"
+	"feedsystem_video_go/internal/transcode"
+	"feedsystem_video_go/internal/video"
+
+	"gorm.io/gorm"
+)
+
+type transcodeFixture struct {
+	db     *gorm.DB
+	store  *storage.LocalStore
+	fake   *transcode.FakeTranscoder
+	worker *TranscodeWorker
+}
+
+func newTranscodeFixture(t *testing.T) *transcodeFixture {
+	t.Helper()
+	db := dbtest.Open(t, &video.Video{}, &outbox.Event{})
+	store, err := storage.NewLocalStore(t.TempDir(), "/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &transcode.FakeTranscoder{}
+	return &transcodeFixture{
+		db:     db,
+		store:  store,
+		fake:   fake,
+		worker: NewTranscodeWorker(nil, video.NewVideoRepository(db), nil, fake, store, "q"),
+	}
+}
+
+// seed 写入一个待转码视频；withSource 为 false 时存储里没有源文件
+func (f *transcodeFixture) seed(t *testing.T, status string, withSource bool) *video.Video {
+	t.Helper()
+	key := "videos/1/source.mp4"
+	if withSource {
+		if err := f.store.Put(context.Background(), key, strings.NewReader("src"), 3, "video/mp4"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := &video.Video{AuthorID: 1, Username: "u", Title: "t", CoverURL: "c", Status: status, SourceURL: f.store.URL(key), PlayURL: f.store.URL(key)}
+	if err := f.db.Create(v).Error; err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func (f *transcodeFixture) process(t *testing.T, videoID uint) error {
+	t.Helper()
+	body, _ := json.Marshal(rabbitmq.TranscodeEvent{VideoID: videoID})
+	return f.worker.process(context.Background(), body)
+}
+
+func (f *transcodeFixture) reload(t *testing.T, id uint) (*video.Video, int64) {
+	t.Helper()
+	var v video.Video
+	if err := f.db.First(&v, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	var events int64
+	f.db.Model(&outbox.Event{}).Where("type = ?", rabbitmq.VideoPublishedType).Count(&events)
+	return &v, events
+}
+
+func TestTranscodeWorkerMovesPendingVideoToReady(t *testing.T) {
+	f := newTranscodeFixture(t)
+	v := f.seed(t, video.VideoStatusPending, true)
+
+	if err := f.process(t, v.ID); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	got, events := f.reload(t, v.ID)
+	if got.Status != video.VideoStatusReady {
+		t.Fatalf("expected ready, got %s", got.Status)
+	}
+	if got.PlayURL != f.store.URL("hls/1/"+transcode.MasterPlaylistName) {
+		t.Fatalf("unexpected play url %s", got.PlayURL)
+	}
+	if _, err := f.store.Stat(context.Background(), "hls/1/"+transcode.MasterPlaylistName); err != nil {
+		t.Fatalf("master playlist not uploaded: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("expected one published event, got %d", events)
+	}
+
+	// 重复投递：已 ready，不再转码也不重复发布
+	if err := f.process(t, v.ID); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if _, events := f.reload(t, v.ID); events != 1 || len(f.fake.Calls) != 1 {
+		t.Fatalf("redelivery should be a no-op, events=%d calls=%d", events, len(f.fake.Calls))
+	}
+}
+
+func TestTranscodeWorkerResumesProcessingVideo(t *testing.T) {
+	f := newTranscodeFixture(t)
+	// 上一次处理到一半进程退出，消息重投时视频停在 processing
+	v := f.seed(t, video.VideoStatusProcessing, true)
+
+	if err := f.process(t, v.ID); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if got, _ := f.reload(t, v.ID); got.Status != video.VideoStatusReady {
+		t.Fatalf("expected ready, got %s", got.Status)
+	}
+}
+
+func TestTranscodeWorkerSkipsVideoLeasedByAnotherWorker(t *testing.T) {
+	f := newTranscodeFixture(t)
+	v := f.seed(t, video.VideoStatusProcessing, true)
+	until := time.Now().Add(time.Minute)
+	f.db.Model(&video.Video{}).Where("id = ?", v.ID).Update("processing_until", until)
+
+	// 租约未过期：重复投递不能和正在执行的转码抢同一个输出目录
+	if err := f.process(t, v.ID); !errors.Is(err, errTranscodeBusy) {
+		t.Fatalf("expected errTranscodeBusy, got %v", err)
+	}
+	if len(f.fake.Calls) != 0 {
+		t.Fatalf("transcoder should not run while leased, calls=%v", f.fake.Calls)
+	}
+
+	// 持有租约的 worker 退出后租约过期，重投的消息重新认领
+	f.db.Model(&video.Video{}).Where("id = ?", v.ID).Update("processing_until", time.Now().Add(-time.Second))
+	if err := f.process(t, v.ID); err != nil {
+		t.Fatalf("process after lease expiry: %v", err)
+	}
+	if got, _ := f.reload(t, v.ID); got.Status != video.VideoStatusReady {
+		t.Fatalf("expected ready, got %s", got.Status)
+	}
+}
+
+func TestTranscodeWorkerMarksFailedOnPermanentErrors(t *testing.T) {
+	t.Run("transcoder error", func(t *testing.T) {
+		f := newTranscodeFixture(t)
+		f.fake.Err = errors.New("invalid data found when processing input")
+		v := f.seed(t, video.VideoStatusPending, true)
+		if err := f.process(t, v.ID); err != nil {
+			t.Fatalf("permanent failure should not be retried: %v", err)
+		}
+		got, events := f.reload(t, v.ID)
+		if got.Status != video.VideoStatusFailed || events != 0 {
+			t.Fatalf("expected failed without events, got %s events=%d", got.Status, events)
+		}
+	})
+	t.Run("missing source", func(t *testing.T) {
+		f := newTranscodeFixture(t)
+		v := f.seed(t, video.VideoStatusPending, false)
+		if err := f.process(t, v.ID); err != nil {
+			t.Fatalf("permanent failure should not be retried: %v", err)
+		}
+		if got, _ := f.reload(t, v.ID); got.Status != video.VideoStatusFailed {
+			t.Fatalf("expected failed, got %s", got.Status)
+		}
+		if len(f.fake.Calls) != 0 {
+			t.Fatalf("transcoder should not run without source, calls=%v", f.fake.Calls)
+		}
+	})
+}
+
+func TestTranscodeWorkerRetriesTransientErrors(t *testing.T) {
+	f := newTranscodeFixture(t)
+	v := f.seed(t, video.VideoStatusPending, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body, _ := json.Marshal(rabbitmq.TranscodeEvent{VideoID: v.ID})
+	if err := f.worker.process(ctx, body); err == nil {
+		t.Fatal("expected error so the delivery is requeued")
+	}
+	// 取消不算永久失败，不会标记 failed，重投后继续
+	if got, _ := f.reload(t, v.ID); got.Status == video.VideoStatusFailed {
+		t.Fatal("transient error must not mark the video failed")
+	}
+	if err := f.process(t, v.ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got, _ := f.reload(t, v.ID); got.Status != video.VideoStatusReady {
+		t.Fatalf("expected ready after retry, got %s", got.Status)
+	}
+}
@@ -17,6 +17,14 @@ type ApiErrorBody = { error?: string }
 
 const API_BASE = (import.meta.env.VITE_API_BASE as string | undefined) ?? '/api'
 
+// wsUrl 把 API 路径转成 ws(s):// 绝对地址，浏览器 WebSocket 不能带 header，一次性票据走 query
+export function wsUrl(path: string, query: Record<string, string | number> = {}) {
+  const url = new URL(`${API_BASE}${path}`, window.location.href)
+  url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:'
+  for (const [k, v] of Object.entries(query)) url.searchParams.set(k, String(v))
+  return url.toString()
+}
+
 let isRefreshing = false
 let refreshPromise: Promise<string | null> | null = null
 
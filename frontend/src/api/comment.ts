@@ -1,16 +1,44 @@
 import { postJson } from './client'
 import { normalizeCommentList } from './normalize'
-import type { Comment, MessageResponse } from './types'
+import type { Comment, CommentSort, ListCommentsResponse, MessageResponse } from './types'
 
-export async function listAll(videoId: number) {
-  const comments = await postJson<Comment[] | null>('/comment/listAll', { video_id: videoId })
+// unlisted 视频需带上分享链接里的 share_token
+export async function listAll(videoId: number, sort?: CommentSort, shareToken?: string) {
+  const comments = await postJson<Comment[] | null>('/comment/listAll', { video_id: videoId, sort, share_token: shareToken })
   return normalizeCommentList(comments)
 }
 
-export function publish(videoId: number, content: string) {
-  return postJson<MessageResponse>('/comment/publish', { video_id: videoId, content }, { authRequired: true })
+export async function listTop(videoId: number, cursor = 0, limit = 20) {
+  const res = await postJson<ListCommentsResponse>('/comment/listTop', { video_id: videoId, cursor, limit })
+  return { ...res, comments: normalizeCommentList(res.comments) }
 }
 
-export function remove(commentId: number) {
-  return postJson<MessageResponse>('/comment/delete', { comment_id: commentId }, { authRequired: true })
+export async function listReplies(rootId: number, cursor = 0, limit = 20) {
+  const res = await postJson<ListCommentsResponse>('/comment/listReplies', { root_id: rootId, cursor, limit })
+  return { ...res, comments: normalizeCommentList(res.comments) }
+}
+
+export function publish(videoId: number, content: string, parentId = 0, shareToken?: string) {
+  return postJson<MessageResponse>(
+    '/comment/publish',
+    { video_id: videoId, content, parent_id: parentId || undefined, share_token: shareToken },
+    { authRequired: true },
+  )
+}
+
+export function remove(commentId: number, cascade = false) {
+  return postJson<MessageResponse>('/comment/delete', { comment_id: commentId, cascade }, { authRequired: true })
+}
+
+export function like(commentId: number) {
+  return postJson<MessageResponse>('/comment/like', { comment_id: commentId }, { authRequired: true })
+}
+
+export function unlike(commentId: number) {
+  return postJson<MessageResponse>('/comment/unlike', { comment_id: commentId }, { authRequired: true })
+}
+
+// commentId 为 0 表示取消置顶
+export function pin(videoId: number, commentId: number) {
+  return postJson<MessageResponse>('/comment/pin', { video_id: videoId, comment_id: commentId }, { authRequired: true })
 }
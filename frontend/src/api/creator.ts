@@ -0,0 +1,19 @@
+import { postJson } from './client'
+import { listOrEmpty } from './normalize'
+import type { GetCreatorStatsResponse, StatGranularity } from './types'
+
+export type CreatorStatsQuery = {
+  videoId?: number
+  granularity?: StatGranularity
+  from?: string // YYYY-MM-DD
+  to?: string // YYYY-MM-DD
+}
+
+export async function getCreatorStats(query: CreatorStatsQuery = {}) {
+  const res = await postJson<GetCreatorStatsResponse>(
+    '/creator/stats',
+    { video_id: query.videoId ?? 0, granularity: query.granularity, from: query.from, to: query.to },
+    { authRequired: true },
+  )
+  return { ...res, points: listOrEmpty(res.points) }
+}
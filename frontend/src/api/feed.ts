@@ -1,6 +1,13 @@
 import { postJson } from './client'
 import { normalizeFeedVideoList } from './normalize'
-import type { ListByFollowingResponse, ListByPopularityResponse, ListLatestResponse, ListLikesCountResponse } from './types'
+import type {
+  ListByFollowingResponse,
+  ListByPopularityResponse,
+  ListByTagResponse,
+  ListForYouResponse,
+  ListLatestResponse,
+  ListLikesCountResponse,
+} from './types'
 
 export async function listLatest(input: { limit: number; latest_time: number }) {
   const res = await postJson<ListLatestResponse>('/feed/listLatest', input)
@@ -17,12 +24,29 @@ export async function listLikesCount(input: { limit: number; likes_count_before?
   return { ...res, video_list: normalizeFeedVideoList(res.video_list) }
 }
 
-export async function listByPopularity(input: { limit: number; as_of: number; offset: number }) {
+// cursor 优先；as_of + offset 只为兼容保留
+export async function listByPopularity(input: { limit: number; as_of: number; offset: number; cursor?: string }) {
   const res = await postJson<ListByPopularityResponse>('/feed/listByPopularity', input)
   return { ...res, video_list: normalizeFeedVideoList(res.video_list) }
 }
 
-export async function listByFollowing(input: { limit: number; latest_time: number }) {
+// include_tags 翻页时需与第一页保持一致
+export async function listByFollowing(input: { limit: number; latest_time: number; cursor?: string; include_tags?: boolean }) {
   const res = await postJson<ListByFollowingResponse>('/feed/listByFollowing', input, { authRequired: true })
   return { ...res, video_list: normalizeFeedVideoList(res.video_list) }
 }
+
+// cursor 为空时开启新的推荐会话
+export async function listForYou(input: { limit: number; cursor?: string }) {
+  const res = await postJson<ListForYouResponse>('/feed/listForYou', input)
+  return { ...res, video_list: normalizeFeedVideoList(res.video_list) }
+}
+
+export async function listByTag(input: { tag_name: string; limit: number; cursor?: string }) {
+  const res = await postJson<ListByTagResponse>('/feed/listByTag', input)
+  return { ...res, video_list: normalizeFeedVideoList(res.video_list) }
+}
+
+export async function reportImpressions(input: { video_ids: number[] }) {
+  return postJson<{ message: string }>('/feed/reportImpressions', input, { authRequired: true })
+}
@@ -1,8 +1,10 @@
 import { postJson } from './client'
 import type { IsLikedResponse, MessageResponse, Video } from './types'
 
-export function like(videoId: number) {
-  return postJson<MessageResponse>('/like/like', { video_id: videoId }, { authRequired: true })
+// unlisted 视频需带上分享链接里的 share_token
+export function like(videoId: number, shareToken?: string) {
+  const body = shareToken ? { video_id: videoId, share_token: shareToken } : { video_id: videoId }
+  return postJson<MessageResponse>('/like/like', body, { authRequired: true })
 }
 
 export function unlike(videoId: number) {
@@ -1,10 +1,30 @@
-import { postJson } from './client'
-import type { DirectMessage, ListMessagesResponse } from './types'
+import { postJson, wsUrl } from './client'
+import type { Conversation, DirectMessage, ListConversationsResponse, ListMessagesResponse, WSTicketResponse } from './types'
 
 export function sendMessage(toId: number, content: string) {
   return postJson<DirectMessage>('/message/send', { to_id: toId, content }, { authRequired: true })
 }
 
-export function listMessages(peerId: number) {
-  return postJson<ListMessagesResponse>('/message/list', { peer_id: peerId }, { authRequired: true })
+export function listMessages(peerId: number, cursor = 0, limit = 50) {
+  return postJson<ListMessagesResponse>('/message/list', { peer_id: peerId, cursor, limit }, { authRequired: true })
+}
+
+export function listConversations(cursor = 0, limit = 20) {
+  return postJson<ListConversationsResponse>('/message/conversations', { cursor, limit }, { authRequired: true })
+}
+
+// messageId 为 0 表示全部已读
+export function markRead(peerId: number, messageId = 0) {
+  return postJson<Conversation>('/message/markRead', { peer_id: peerId, message_id: messageId }, { authRequired: true })
+}
+
+export function unreadCount() {
+  return postJson<{ unread: number }>('/message/unreadCount', {}, { authRequired: true })
+}
+
+// since 为本地已收到的最大消息 ID，重连时服务端会补发之后的消息；收到 message 帧后需回 ack。
+// 每次建连先换一张一次性票据，access token 不出现在 URL 上
+export async function openChatSocket(since = 0) {
+  const { ticket } = await postJson<WSTicketResponse>('/message/wsTicket', {}, { authRequired: true })
+  return new WebSocket(wsUrl('/message/ws', { ticket, since }))
 }
@@ -0,0 +1,27 @@
+import { postJson } from './client'
+import { listOrEmpty } from './normalize'
+import type { SearchUsersResponse, SearchVideosResponse } from './types'
+
+export type SearchVideosQuery = {
+  q: string
+  tag?: string
+  authorId?: number
+  limit?: number
+  cursor?: string
+}
+
+export async function searchVideos(query: SearchVideosQuery) {
+  const res = await postJson<SearchVideosResponse>('/search/videos', {
+    q: query.q,
+    tag: query.tag,
+    author_id: query.authorId,
+    limit: query.limit ?? 20,
+    cursor: query.cursor,
+  })
+  return { ...res, video_list: listOrEmpty(res.video_list) }
+}
+
+export async function searchUsers(q: string, limit = 10) {
+  const res = await postJson<SearchUsersResponse>('/search/users', { q, limit })
+  return { ...res, users: listOrEmpty(res.users) }
+}
@@ -0,0 +1,26 @@
+import { postJson } from './client'
+import { listOrEmpty } from './normalize'
+import type { ListFollowedTagsResponse, MessageResponse, TagDetailResponse, TrendingTagsResponse } from './types'
+
+// window 为统计的分钟数，默认 60
+export async function getTrendingTags(input: { window?: number; limit?: number } = {}) {
+  const res = await postJson<TrendingTagsResponse>('/tag/trending', input)
+  return { ...res, tags: listOrEmpty(res.tags) }
+}
+
+export function getTagDetail(tagName: string) {
+  return postJson<TagDetailResponse>('/tag/detail', { tag_name: tagName })
+}
+
+export function followTag(tagName: string) {
+  return postJson<MessageResponse>('/tag/follow', { tag_name: tagName }, { authRequired: true })
+}
+
+export function unfollowTag(tagName: string) {
+  return postJson<MessageResponse>('/tag/unfollow', { tag_name: tagName }, { authRequired: true })
+}
+
+export async function listFollowedTags() {
+  const res = await postJson<ListFollowedTagsResponse>('/tag/listFollowed', {}, { authRequired: true })
+  return { ...res, tags: listOrEmpty(res.tags) }
+}
@@ -11,8 +11,47 @@ export type DirectMessage = {
 
 export type ListMessagesResponse = {
   messages: DirectMessage[]
+  next_cursor: number
+  has_more: boolean
+  peer_last_read_id: number
+}
+
+export type ChatFrame = {
+  type: 'send' | 'sent' | 'message' | 'typing' | 'read' | 'ack' | 'ping' | 'pong' | 'error'
+  ack_id?: number
+  client_id?: string
+  to_id?: number
+  from_id?: number
+  peer_id?: number
+  message_id?: number
+  content?: string
+  message?: DirectMessage
+  error?: string
+}
+
+export type Conversation = {
+  id: number
+  owner_id: number
+  peer_id: number
+  peer_username?: string
+  peer_avatar_url?: string
+  last_message_id: number
+  last_message_from_id: number
+  last_message: string
+  last_message_at: string
+  unread_count: number
+  last_read_message_id: number
+  updated_at: string
+}
+
+export type ListConversationsResponse = {
+  conversations: Conversation[]
+  next_cursor: number
+  has_more: boolean
 }
 
+export type WSTicketResponse = { ticket: string; expires_in: number }
+
 export type TokenResponse = { token: string; refresh_token?: string; account_id?: number; username?: string }
 
 export type Account = {
@@ -22,6 +61,8 @@ export type Account = {
   bio?: string
 }
 
+export type VideoVisibility = 'public' | 'followers' | 'private' | 'unlisted'
+
 export type Video = {
   id: number
   author_id: number
@@ -30,19 +71,58 @@ export type Video = {
   description?: string
   play_url: string
   cover_url: string
+  source_url?: string
+  status?: 'pending' | 'processing' | 'ready' | 'failed' | 'draft' | 'scheduled'
+  publish_at?: string
+  visibility?: VideoVisibility
+  pinned_comment_id?: number
   create_time: string
   likes_count: number
 }
 
+// 回收站里的视频，purge_at 之后彻底删除
+export type TrashedVideo = Video & {
+  deleted_at: string
+  purge_at: string
+}
+
+export type VideoEdit = {
+  id: number
+  video_id: number
+  editor_id: number
+  old_title: string
+  new_title: string
+  old_description: string
+  new_description: string
+  old_cover_url: string
+  new_cover_url: string
+  created_at: string
+}
+
 export type Comment = {
   id: number
   username: string
   video_id: number
   author_id: number
+  parent_id?: number
+  root_id?: number
+  reply_count?: number
+  deleted?: boolean
+  likes_count?: number
+  is_liked?: boolean
+  pinned?: boolean
   content: string
   created_at: string
 }
 
+export type CommentSort = 'hot' | 'latest'
+
+export type ListCommentsResponse = {
+  comments: Comment[]
+  next_cursor: number
+  has_more: boolean
+}
+
 export type FeedAuthor = {
   id: number
   username: string
@@ -57,9 +137,13 @@ export type FeedVideoItem = {
   cover_url: string
   create_time: number
   likes_count: number
+  play_count?: number
   is_liked: boolean
+  source?: ForYouSource
 }
 
+export type ForYouSource = 'following' | 'tag' | 'hot' | 'fresh'
+
 export type ListLatestResponse = {
   video_list: FeedVideoItem[]
   next_time: number
@@ -77,6 +161,7 @@ export type ListByPopularityResponse = {
   video_list: FeedVideoItem[]
   as_of: number
   next_offset: number
+  next_cursor?: string
   has_more: boolean
   next_latest_popularity?: number
   next_latest_before?: string
@@ -86,9 +171,73 @@ export type ListByPopularityResponse = {
 export type ListByFollowingResponse = {
   video_list: FeedVideoItem[]
   next_time: number
+  next_cursor?: string
+  has_more: boolean
+}
+
+export type ListByTagResponse = {
+  video_list: FeedVideoItem[]
+  next_cursor?: string
   has_more: boolean
 }
 
+export type TrendingTag = {
+  id: number
+  name: string
+  score: number
+}
+
+export type TrendingTagsResponse = {
+  tags: TrendingTag[]
+  window: number
+  as_of: number
+}
+
+export type TagDetailResponse = {
+  id: number
+  name: string
+  video_count: number
+  play_count: number
+  likes_count: number
+  follower_count: number
+  is_following: boolean
+  trend_score: number
+}
+
+export type FollowedTag = {
+  id: number
+  name: string
+}
+
+export type ListFollowedTagsResponse = {
+  tags: FollowedTag[]
+}
+
+export type ListForYouResponse = {
+  video_list: FeedVideoItem[]
+  next_cursor?: string
+  has_more: boolean
+}
+
+export type SearchVideosResponse = {
+  video_list: Video[]
+  next_cursor?: string
+  has_more: boolean
+}
+
+export type UserSearchItem = {
+  id: number
+  username: string
+  avatar_url?: string
+  bio?: string
+  is_following: boolean
+  is_mutual: boolean
+}
+
+export type SearchUsersResponse = {
+  users: UserSearchItem[]
+}
+
 export type IsLikedResponse = {
   is_liked: boolean
 }
@@ -100,3 +249,20 @@ export type GetAllFollowersResponse = {
 export type GetAllVloggersResponse = {
   vloggers: Account[]
 }
+
+export type StatGranularity = 'hour' | 'day'
+
+export type CreatorStatPoint = {
+  bucket_start: number
+  likes: number
+  comments: number
+  new_followers: number
+  popularity_delta: number
+}
+
+export type GetCreatorStatsResponse = {
+  video_id: number
+  granularity: StatGranularity
+  points: CreatorStatPoint[]
+  totals: Omit<CreatorStatPoint, 'bucket_start'>
+}
@@ -1,11 +1,78 @@
 import { postForm, postJson } from './client'
 import { normalizeVideoList } from './normalize'
-import type { Video } from './types'
+import type { MessageResponse, TrashedVideo, Video, VideoEdit, VideoVisibility } from './types'
 
-export function publishVideo(input: { title: string; description: string; play_url: string; cover_url: string }) {
+export function publishVideo(input: {
+  title: string
+  description: string
+  play_url: string
+  cover_url: string
+  visibility?: VideoVisibility
+  draft?: boolean
+  publish_at?: number // 定时发布（秒）
+}) {
   return postJson<Video>('/video/publish', input, { authRequired: true })
 }
 
+export async function listDrafts() {
+  const videos = await postJson<Video[] | null>('/video/draft/list', {}, { authRequired: true })
+  return normalizeVideoList(videos)
+}
+
+// 只传要改的字段；publish_at 传 0 取消定时、退回草稿
+export function updateDraft(input: {
+  id: number
+  title?: string
+  description?: string
+  cover_url?: string
+  visibility?: VideoVisibility
+  publish_at?: number
+}) {
+  return postJson<Video>('/video/draft/update', input, { authRequired: true })
+}
+
+export function publishDraft(id: number) {
+  return postJson<Video>('/video/draft/publish', { id }, { authRequired: true })
+}
+
+export function cancelDraft(id: number) {
+  return postJson<MessageResponse>('/video/draft/cancel', { id }, { authRequired: true })
+}
+
+// 发布后修改元数据，只传要改的字段
+export function updateVideo(input: { id: number; title?: string; description?: string; cover_url?: string }) {
+  return postJson<Video>('/video/update', input, { authRequired: true })
+}
+
+export async function listEditHistory(id: number) {
+  const edits = await postJson<VideoEdit[] | null>('/video/editHistory', { id }, { authRequired: true })
+  return edits ?? []
+}
+
+// 删除进回收站，30 天内可恢复
+export function deleteVideo(id: number) {
+  return postJson<MessageResponse>('/video/delete', { id }, { authRequired: true })
+}
+
+export async function listTrash() {
+  const videos = await postJson<TrashedVideo[] | null>('/video/trash/list', {}, { authRequired: true })
+  return videos ?? []
+}
+
+export function restoreVideo(id: number) {
+  return postJson<Video>('/video/trash/restore', { id }, { authRequired: true })
+}
+
+// 切换到 unlisted 后用 getShareToken 取新的分享 token
+export function setVisibility(id: number, visibility: VideoVisibility) {
+  return postJson<Video>('/video/setVisibility', { id, visibility }, { authRequired: true })
+}
+
+// 仅作者可调用
+export function getShareToken(id: number) {
+  return postJson<{ share_token: string }>('/video/shareToken', { id }, { authRequired: true })
+}
+
 export type UploadResponse = { url: string; play_url?: string; cover_url?: string }
 
 export function uploadVideo(file: File) {
@@ -25,8 +92,16 @@ export async function listByAuthorId(authorId: number) {
   return normalizeVideoList(videos)
 }
 
-export function getDetail(id: number) {
-  return postJson<Video>('/video/getDetail', { id })
+export function getDetail(id: number, shareToken?: string) {
+  return postJson<Video>('/video/getDetail', shareToken ? { id, share_token: shareToken } : { id })
+}
+
+export type PlayEventType = 'start' | 'progress' | 'complete'
+
+export type PlayEvent = { event_id: string; video_id: number; type: PlayEventType; progress: number }
+
+export function reportPlay(events: PlayEvent[]) {
+  return postJson<{ accepted: number; duplicated: number }>('/video/reportPlay', { events })
 }
 
 // --- Chunk Upload API ---
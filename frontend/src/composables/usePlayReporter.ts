@@ -0,0 +1,59 @@
+import * as videoApi from '../api/video'
+import type { PlayEvent, PlayEventType } from '../api/video'
+
+// 播放事件攒批上报：切到视频时 start，离开时 progress（看到的比例），循环播放绕回开头时 complete
+export function usePlayReporter() {
+  const queue: PlayEvent[] = []
+  let timer: ReturnType<typeof setTimeout> | null = null
+  let current: { id: number; el: HTMLVideoElement; maxProgress: number; completed: boolean; onTime: () => void } | null = null
+
+  function push(video_id: number, type: PlayEventType, progress: number) {
+    queue.push({ event_id: crypto.randomUUID(), video_id, type, progress: Math.min(Math.max(progress, 0), 1) })
+    if (queue.length >= 20) void flush()
+    else if (!timer) timer = setTimeout(() => void flush(), 10000)
+  }
+
+  async function flush() {
+    if (timer) {
+      clearTimeout(timer)
+      timer = null
+    }
+    if (queue.length === 0) return
+    const events = queue.splice(0, queue.length)
+    try {
+      await videoApi.reportPlay(events)
+    } catch {
+      // 统计上报失败不影响播放
+    }
+  }
+
+  function end() {
+    if (!current) return
+    const { id, el, maxProgress, completed, onTime } = current
+    el.removeEventListener('timeupdate', onTime)
+    if (!completed) push(id, 'progress', maxProgress)
+    current = null
+  }
+
+  function begin(id: number, el: HTMLVideoElement) {
+    if (current?.id === id) return
+    end()
+    const state = {
+      id, el, maxProgress: 0, completed: false,
+      onTime: () => {
+        if (!el.duration || !Number.isFinite(el.duration)) return
+        const p = el.currentTime / el.duration
+        if (!state.completed && state.maxProgress > 0.9 && p < state.maxProgress - 0.5) {
+          state.completed = true
+          push(id, 'complete', 1)
+        }
+        state.maxProgress = Math.max(state.maxProgress, p)
+      },
+    }
+    el.addEventListener('timeupdate', state.onTime)
+    current = state
+    push(id, 'start', 0)
+  }
+
+  return { begin, end, flush }
+}
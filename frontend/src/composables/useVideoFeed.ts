@@ -93,6 +93,35 @@ export function useVideoFeed() {
     }
   }
 
+  // 曝光按批上报，服务端据此在各个列表里跳过已看过的视频
+  const pendingImpressions = new Set<number>()
+  let impressionTimer: ReturnType<typeof setTimeout> | null = null
+
+  async function flushImpressions() {
+    if (impressionTimer) {
+      clearTimeout(impressionTimer)
+      impressionTimer = null
+    }
+    if (!auth.isLoggedIn || pendingImpressions.size === 0) return
+    const video_ids = [...pendingImpressions]
+    pendingImpressions.clear()
+    try {
+      await feedApi.reportImpressions({ video_ids })
+    } catch {
+      // 上报失败只影响去重效果，不打扰用户
+    }
+  }
+
+  function reportImpression(item: FeedVideoItem | null | undefined) {
+    if (!item || !auth.isLoggedIn) return
+    pendingImpressions.add(item.id)
+    if (pendingImpressions.size >= 10) {
+      void flushImpressions()
+    } else if (!impressionTimer) {
+      impressionTimer = setTimeout(() => void flushImpressions(), 5000)
+    }
+  }
+
   async function ensureTabLoaded() {
     if (tab.value === 'recommend' && recommend.items.length === 0) await loadRecommend(true)
     if (tab.value === 'hot' && hot.items.length === 0) await loadHot(true)
@@ -108,5 +137,9 @@ export function useVideoFeed() {
     if (tab.value === 'following' && following.hasMore) await loadFollowing(false)
   }
 
-  return { tab, recommend, hot, following, currentState, loadRecommend, loadHot, loadFollowing, ensureTabLoaded, loadMoreIfNeeded }
+  return {
+    tab, recommend, hot, following, currentState,
+    loadRecommend, loadHot, loadFollowing, ensureTabLoaded, loadMoreIfNeeded,
+    reportImpression, flushImpressions,
+  }
 }
@@ -1,11 +1,13 @@
 import { ref } from 'vue'
 import { useToastStore } from '../stores/toast'
+import { usePlayReporter } from './usePlayReporter'
 
 export function useVideoPlayer(scrollerRef: ReturnType<typeof ref<HTMLDivElement | null>>) {
   const toast = useToastStore()
   const muted = ref(true)
   const activeIndex = ref(0)
   const videoMap = new Map<number, HTMLVideoElement>()
+  const reporter = usePlayReporter()
 
   function getScrollerHeight() {
     return scrollerRef.value?.clientHeight ?? 0
@@ -55,6 +57,7 @@ export function useVideoPlayer(scrollerRef: ReturnType<typeof ref<HTMLDivElement
     video.muted = muted.value
     try {
       await video.play()
+      reporter.begin(activeItemId, video)
     } catch {
       /* ignore autoplay errors */
     }
@@ -74,5 +77,11 @@ export function useVideoPlayer(scrollerRef: ReturnType<typeof ref<HTMLDivElement
     else video.pause()
   }
 
-  return { muted, activeIndex, videoMap, setVideoRef, scrollToIndex, onScroll, playActive, toggleMute, togglePlayPause }
+  // 离开页面时结束当前播放并上报
+  function stopReporting() {
+    reporter.end()
+    void reporter.flush()
+  }
+
+  return { muted, activeIndex, videoMap, setVideoRef, scrollToIndex, onScroll, playActive, toggleMute, togglePlayPause, stopReporting }
 }